@@ -0,0 +1,80 @@
+// Package audit keeps a tamper-evident log of security events (logins, token
+// refreshes, logouts, registrations, password changes): each stored event
+// carries the hash of the previous one, and Verify walks the chain.
+package audit
+
+import (
+	"context"
+	"strings"
+)
+
+// event types
+const (
+	LoginSucceeded = "login.succeeded"
+	// password accepted, the session starts once the second factor is
+	LoginMFARequired = "login.mfa_required"
+	// Details["reason"] tells why
+	LoginFailed = "login.failed"
+	// a rotated refresh token was presented again, the session was revoked
+	RefreshTokenReused = "token.reuse_detected"
+	TokenRefreshed     = "token.refreshed"
+	TokenRevoked       = "token.revoked"
+	Logout             = "logout"
+	UserRegistered     = "user.registered"
+	PasswordReset      = "password.reset"
+	PasswordChanged    = "password.changed"
+	// the user ended one of their sessions from another one
+	SessionRevoked = "session.revoked"
+	// the user ended all sessions but the current one
+	OtherSessionsRevoked = "session.others_revoked"
+)
+
+// reasons of LoginFailed
+const (
+	ReasonInvalidCredentials = "invalid_credentials"
+	ReasonThrottled          = "throttled"
+	ReasonEmailNotVerified   = "email_not_verified"
+	ReasonInvalidOTP         = "invalid_otp"
+	ReasonInvalidChallenge   = "invalid_mfa_challenge"
+	ReasonInvalidPasskey     = "invalid_passkey"
+)
+
+// what happened to whom. Details are for identifiers (client, method, reason);
+// never put passwords, tokens or codes here, values of keys that look like
+// one are redacted anyway
+type Event struct {
+	Type      string
+	UserID    string
+	SessionID string
+	ClientIP  string
+	Details   map[string]string
+}
+
+// implemented by DBSink
+type AuditSink interface {
+	Record(ctx context.Context, event Event) error
+}
+
+const redacted = "[redacted]"
+
+var secretKeyParts = []string{"password", "token", "secret", "code", "otp", "hash", "key"}
+
+// a copy of details with the values of secret-looking keys replaced
+func redact(details map[string]string) map[string]string {
+	if len(details) == 0 {
+		return nil
+	}
+
+	clean := make(map[string]string, len(details))
+	for key, value := range details {
+		clean[key] = value
+		lower := strings.ToLower(key)
+		for _, part := range secretKeyParts {
+			if strings.Contains(lower, part) {
+				clean[key] = redacted
+				break
+			}
+		}
+	}
+	return clean
+}
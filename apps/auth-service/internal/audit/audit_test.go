@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+// in-memory stand-in for the audit_events and audit_chain tables;
+// tests tamper with events directly, like someone with database access
+type fakeEventStore struct {
+	events   []domain.AuditEvent
+	headSeq  int64
+	headHash string
+	hasHead  bool
+}
+
+func (store *fakeEventStore) NextLink(ctx context.Context) (int64, string, error) {
+	store.headSeq++
+	store.hasHead = true
+	return store.headSeq, store.headHash, nil
+}
+
+func (store *fakeEventStore) Append(ctx context.Context, event domain.AuditEvent) error {
+	store.events = append(store.events, event)
+	store.headHash = event.Hash
+	return nil
+}
+
+func (store *fakeEventStore) Head(ctx context.Context) (int64, string, error) {
+	if !store.hasHead {
+		return 0, "", errs.ErrNotFound
+	}
+	return store.headSeq, store.headHash, nil
+}
+
+func (store *fakeEventStore) ListAfter(ctx context.Context, afterSeq int64, limit int) ([]domain.AuditEvent, error) {
+	var events []domain.AuditEvent
+	for _, event := range store.events {
+		if event.Seq > afterSeq && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+type fakeTransactionMgr struct{}
+
+func (fakeTransactionMgr) BeginTransaction(ctx context.Context, readOnly bool) (storage.SQLExecutor, func(error), error) {
+	return nil, func(error) {}, nil
+}
+
+func newTestSink(test *testing.T, events int) (*DBSink, *fakeEventStore) {
+	test.Helper()
+
+	store := &fakeEventStore{}
+	sink := NewDBSink(fakeTransactionMgr{}, func(storage.SQLExecutor) storage.AuditEventStore { return store })
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	sink.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for i := 0; i < events; i++ {
+		err := sink.Record(context.Background(), Event{
+			Type:     LoginSucceeded,
+			UserID:   "user-1",
+			ClientIP: "192.0.2.1",
+			Details:  map[string]string{"method": "password"},
+		})
+		if err != nil {
+			test.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	return sink, store
+}
+
+func TestVerify_IntactChain(test *testing.T) {
+	sink, store := newTestSink(test, 3)
+
+	checked, head, err := sink.Verify(context.Background())
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	if checked != 3 || head != store.events[2].Hash {
+		test.Fatalf("expected 3 events up to the last hash, got %d, %s", checked, head)
+	}
+	if store.events[0].PrevHash != "" || store.events[1].PrevHash != store.events[0].Hash {
+		test.Fatalf("events are not chained: %+v", store.events)
+	}
+}
+
+func TestVerify_EmptyLog(test *testing.T) {
+	sink, _ := newTestSink(test, 0)
+
+	checked, _, err := sink.Verify(context.Background())
+	if err != nil || checked != 0 {
+		test.Fatalf("expected an empty, intact log, got %d, %v", checked, err)
+	}
+}
+
+func TestVerify_DetectsTampering(test *testing.T) {
+	cases := map[string]func(store *fakeEventStore){
+		"modified details": func(store *fakeEventStore) {
+			store.events[1].Details = map[string]string{"method": "passkey"}
+		},
+		"modified and rehashed": func(store *fakeEventStore) {
+			store.events[1].UserID = "user-2"
+			store.events[1].Hash = hashEvent(store.events[1])
+		},
+		"deleted event": func(store *fakeEventStore) {
+			store.events = append(store.events[:1], store.events[2:]...)
+		},
+		"cut off the end": func(store *fakeEventStore) {
+			store.events = store.events[:2]
+		},
+		"reordered": func(store *fakeEventStore) {
+			store.events[0], store.events[1] = store.events[1], store.events[0]
+		},
+	}
+
+	for name, tamper := range cases {
+		test.Run(name, func(test *testing.T) {
+			sink, store := newTestSink(test, 3)
+			tamper(store)
+
+			if _, _, err := sink.Verify(context.Background()); !errors.Is(err, errs.ErrAuditChainBroken) {
+				test.Fatalf("expected ErrAuditChainBroken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRecord_RedactsSecrets(test *testing.T) {
+	sink, store := newTestSink(test, 0)
+
+	err := sink.Record(context.Background(), Event{
+		Type: LoginFailed,
+		Details: map[string]string{
+			"reason":        ReasonInvalidCredentials,
+			"password":      "hunter2",
+			"refresh_token": "raw-token",
+			"otp_code":      "123456",
+		},
+	})
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	details := store.events[0].Details
+	if details["reason"] != ReasonInvalidCredentials {
+		test.Fatalf("expected the reason to be kept, got %v", details)
+	}
+	for _, key := range []string{"password", "refresh_token", "otp_code"} {
+		if details[key] != redacted {
+			test.Fatalf("expected %s to be redacted, got %v", key, details)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// SHA-256 over the fields of the event and the hash of its predecessor, encoded
+// as a JSON array so no two events share an encoding; map keys are sorted by
+// encoding/json, and times are hashed in UTC to the microsecond, as postgres keeps them
+func hashEvent(event domain.AuditEvent) string {
+	details := event.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+
+	encoded, err := json.Marshal([]any{
+		event.Seq,
+		event.Type,
+		event.UserID,
+		event.SessionID,
+		event.ClientIP,
+		details,
+		event.OccurredAt.UTC().Format(time.RFC3339Nano),
+		event.PrevHash,
+	})
+	if err != nil {
+		// strings and a map of strings always encode
+		panic(err)
+	}
+
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// checks one event against its predecessor (seq 0 and "" before the first)
+func checkLink(event domain.AuditEvent, prevSeq int64, prevHash string) error {
+	if event.Seq != prevSeq+1 {
+		return fmt.Errorf("%w: event %d follows event %d", errs.ErrAuditChainBroken, event.Seq, prevSeq)
+	}
+	if event.PrevHash != prevHash {
+		return fmt.Errorf("%w: event %d does not link to event %d", errs.ErrAuditChainBroken, event.Seq, prevSeq)
+	}
+	if hashEvent(event) != event.Hash {
+		return fmt.Errorf("%w: event %d was modified", errs.ErrAuditChainBroken, event.Seq)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+// events are checked in batches of this size
+const verifyBatch = 500
+
+// DBSink appends events to the audit_events table. Each Record is a transaction
+// of its own that holds the chain head only briefly; callers record after their
+// own transaction has ended (sqlite allows only one writer)
+type DBSink struct {
+	transactionMgr storage.TransactionMgr
+	events         storage.AuditEventStoreProvider
+	now            func() time.Time
+}
+
+func NewDBSink(transactionMgr storage.TransactionMgr, events storage.AuditEventStoreProvider) *DBSink {
+	return &DBSink{
+		transactionMgr: transactionMgr,
+		events:         events,
+		now:            time.Now,
+	}
+}
+
+func (sink *DBSink) Record(ctx context.Context, event Event) (err error) {
+	exec, finish, err := sink.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	store := sink.events(exec)
+
+	seq, prevHash, err := store.NextLink(ctx)
+	if err != nil {
+		return err
+	}
+
+	stored := domain.AuditEvent{
+		Seq:        seq,
+		Type:       event.Type,
+		UserID:     event.UserID,
+		SessionID:  event.SessionID,
+		ClientIP:   event.ClientIP,
+		Details:    redact(event.Details),
+		OccurredAt: sink.now().UTC().Truncate(time.Microsecond),
+		PrevHash:   prevHash,
+	}
+	stored.Hash = hashEvent(stored)
+
+	return store.Append(ctx, stored)
+}
+
+// walks the whole chain and returns the number of events checked;
+// ErrAuditChainBroken if an event was edited, removed, reordered or cut off the end.
+// Someone able to rewrite the whole table can recompute every hash, so keep
+// a copy of the returned head hash elsewhere (e.g. the output of a scheduled run)
+func (sink *DBSink) Verify(ctx context.Context) (checked int64, headHash string, err error) {
+	exec, finish, err := sink.transactionMgr.BeginTransaction(ctx, true)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	store := sink.events(exec)
+
+	headSeq, headHash, err := store.Head(ctx)
+	if errors.Is(err, errs.ErrNotFound) {
+		// nothing recorded yet, unless the head row was deleted
+		headSeq, headHash, err = 0, "", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	var prevSeq int64
+	prevHash := ""
+
+	for {
+		events, err := store.ListAfter(ctx, prevSeq, verifyBatch)
+		if err != nil {
+			return 0, "", err
+		}
+
+		for _, event := range events {
+			if err := checkLink(event, prevSeq, prevHash); err != nil {
+				return 0, "", err
+			}
+			prevSeq, prevHash = event.Seq, event.Hash
+		}
+
+		if len(events) < verifyBatch {
+			break
+		}
+	}
+
+	if prevSeq != headSeq || prevHash != headHash {
+		return 0, "", fmt.Errorf(
+			"%w: log ends at event %d, head is event %d",
+			errs.ErrAuditChainBroken,
+			prevSeq,
+			headSeq,
+		)
+	}
+
+	return prevSeq, headHash, nil
+}
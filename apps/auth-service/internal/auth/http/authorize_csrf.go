@@ -0,0 +1,47 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+// double-submit token of the /authorize login form: every rendered form gets
+// a fresh random value, in a hidden field and in a cookie only /authorize sees.
+// A cross-site page can post the form but neither read nor set the cookie,
+// so it cannot log the browser in to an account of its choosing (login CSRF)
+const (
+	authorizeCSRFCookie = "authorize_csrf"
+	authorizeCSRFField  = "csrf_token"
+)
+
+// sets the cookie and returns the value for the hidden field
+func issueAuthorizeCSRFToken(response http.ResponseWriter) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	http.SetCookie(response, &http.Cookie{
+		Name:     authorizeCSRFCookie,
+		Value:    token,
+		Path:     "/authorize",
+		Secure:   true,
+		HttpOnly: true,
+		// the form posts to its own site
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token, nil
+}
+
+// true if the posted field matches the cookie set with the form
+func validAuthorizeCSRFToken(request *http.Request) bool {
+	cookie, err := request.Cookie(authorizeCSRFCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	posted := request.PostForm.Get(authorizeCSRFField)
+	return subtle.ConstantTimeCompare([]byte(posted), []byte(cookie.Value)) == 1
+}
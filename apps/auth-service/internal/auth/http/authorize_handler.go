@@ -0,0 +1,211 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// Service interface expected by handler
+type AuthorizationService interface {
+	ValidateClient(clientID string, redirectURI string) error
+	ValidateRequest(req domain.AuthorizationRequest) error
+	Authorize(
+		ctx context.Context,
+		req domain.AuthorizationRequest,
+		email, password, otp, clientIP string,
+	) (code string, err error)
+}
+
+// minimal login page; the OAuth parameters travel along as hidden fields
+var authorizeForm = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Sign in to family-space</title></head>
+<body>
+<h1>Sign in to family-space</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="post" action="/authorize">
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<input type="hidden" name="response_type" value="code">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<label>Email <input type="email" name="email" autocomplete="username" required></label>
+<label>Password <input type="password" name="password" autocomplete="current-password" required></label>
+<label>One-time or recovery code <input type="text" name="otp" autocomplete="one-time-code"></label>
+<label><input type="checkbox" name="remember_me" value="true"{{if .Request.RememberMe}} checked{{end}}> Remember me</label>
+<button type="submit">Sign in</button>
+</form>
+</body>
+</html>
+`))
+
+type authorizeFormData struct {
+	Request   domain.AuthorizationRequest
+	Error     string
+	CSRFToken string
+}
+
+// OAuth 2.0 authorization endpoint (code flow with mandatory PKCE):
+// GET shows the login form, POST checks the credentials and
+// redirects back to the client with a code
+type AuthorizeHandler struct {
+	authorizationSvc AuthorizationService
+}
+
+func NewAuthorizeHandler(authorizationSvc AuthorizationService) *AuthorizeHandler {
+	return &AuthorizeHandler{
+		authorizationSvc: authorizationSvc,
+	}
+}
+
+func (handler *AuthorizeHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet && request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// GET reads the query, POST the form (which also falls back to the query)
+	if err := request.ParseForm(); err != nil {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+	authReq := readAuthorizationRequest(request.Form)
+
+	// an unverified redirect URI must never receive a redirect (open redirector)
+	if err := handler.authorizationSvc.ValidateClient(authReq.ClientID, authReq.RedirectURI); err != nil {
+		http.Error(response, "invalid client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	if request.Form.Get("response_type") != "code" {
+		redirectWithError(response, request, authReq, "unsupported_response_type")
+		return
+	}
+
+	if err := handler.authorizationSvc.ValidateRequest(authReq); err != nil {
+		redirectWithError(response, request, authReq, "invalid_request")
+		return
+	}
+
+	if request.Method == http.MethodGet {
+		renderAuthorizeForm(response, http.StatusOK, authReq, "")
+		return
+	}
+
+	// only the form this service rendered may log in; checked before the credentials
+	if !validAuthorizeCSRFToken(request) {
+		renderAuthorizeForm(response, http.StatusForbidden, authReq, "The sign-in form expired, please try again.")
+		return
+	}
+
+	code, err := handler.authorizationSvc.Authorize(
+		request.Context(),
+		authReq,
+		request.PostForm.Get("email"),
+		request.PostForm.Get("password"),
+		strings.TrimSpace(request.PostForm.Get("otp")),
+		clientIP(request),
+	)
+	if err != nil {
+		if errors.Is(err, errs.ErrInvalidCredentials) {
+			renderAuthorizeForm(response, http.StatusUnauthorized, authReq, "Invalid email or password.")
+			return
+		}
+		if errors.Is(err, errs.ErrMFARequired) {
+			renderAuthorizeForm(response, http.StatusUnauthorized, authReq, "Enter the one-time code from your authenticator app.")
+			return
+		}
+		if errors.Is(err, errs.ErrInvalidOTP) {
+			renderAuthorizeForm(response, http.StatusUnauthorized, authReq, "Invalid one-time code.")
+			return
+		}
+		if errors.Is(err, errs.ErrEmailNotVerified) {
+			renderAuthorizeForm(response, http.StatusForbidden, authReq, "Verify your email address first; check your inbox for the link.")
+			return
+		}
+		redirectWithError(response, request, authReq, "server_error")
+		return
+	}
+
+	redirectToClient(response, request, authReq, url.Values{"code": {code}})
+}
+
+func readAuthorizationRequest(form url.Values) domain.AuthorizationRequest {
+	return domain.AuthorizationRequest{
+		ClientID:            form.Get("client_id"),
+		RedirectURI:         form.Get("redirect_uri"),
+		Scope:               form.Get("scope"),
+		State:               form.Get("state"),
+		Nonce:               form.Get("nonce"),
+		CodeChallenge:       form.Get("code_challenge"),
+		CodeChallengeMethod: form.Get("code_challenge_method"),
+		RememberMe:          form.Get("remember_me") == "true",
+	}
+}
+
+func renderAuthorizeForm(
+	response http.ResponseWriter,
+	status int,
+	authReq domain.AuthorizationRequest,
+	message string,
+) {
+	csrfToken, err := issueAuthorizeCSRFToken(response)
+	if err != nil {
+		http.Error(response, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	response.Header().Set("Cache-Control", "no-store")
+	// the login form must not be framed (clickjacking)
+	response.Header().Set("X-Frame-Options", "DENY")
+	response.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	response.WriteHeader(status)
+	_ = authorizeForm.Execute(response, authorizeFormData{Request: authReq, Error: message, CSRFToken: csrfToken})
+}
+
+// RFC 6749, section 4.1.2.1
+func redirectWithError(
+	response http.ResponseWriter,
+	request *http.Request,
+	authReq domain.AuthorizationRequest,
+	code string,
+) {
+	redirectToClient(response, request, authReq, url.Values{"error": {code}})
+}
+
+// params are added to the query of the registered redirect URI, state is echoed back
+func redirectToClient(
+	response http.ResponseWriter,
+	request *http.Request,
+	authReq domain.AuthorizationRequest,
+	params url.Values,
+) {
+	target, err := url.Parse(authReq.RedirectURI)
+	if err != nil {
+		http.Error(response, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if authReq.State != "" {
+		query.Set("state", authReq.State)
+	}
+	target.RawQuery = query.Encode()
+
+	http.Redirect(response, request, target.String(), http.StatusFound)
+}
@@ -0,0 +1,302 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+const testRedirectURI = "https://wiki.family.example/callback"
+
+type fakeAuthorizationService struct {
+	clientErr    error
+	requestErr   error
+	code         string
+	authorizeErr error
+	gotEmail     string
+	gotOTP       string
+	gotRequest   domain.AuthorizationRequest
+}
+
+func (f *fakeAuthorizationService) ValidateClient(clientID string, redirectURI string) error {
+	return f.clientErr
+}
+
+func (f *fakeAuthorizationService) ValidateRequest(req domain.AuthorizationRequest) error {
+	return f.requestErr
+}
+
+func (f *fakeAuthorizationService) Authorize(
+	ctx context.Context,
+	req domain.AuthorizationRequest,
+	email, password, otp, clientIP string,
+) (string, error) {
+	f.gotEmail = email
+	f.gotOTP = otp
+	f.gotRequest = req
+	return f.code, f.authorizeErr
+}
+
+func authorizeParams() url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"wiki"},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {"openid"},
+		"state":                 {"xyz"},
+		"code_challenge":        {"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+const testCSRFToken = "csrf-token"
+
+// a post of the rendered form: its anti-CSRF field matches the cookie
+func newAuthorizePost(params url.Values) *http.Request {
+	if !params.Has(authorizeCSRFField) {
+		params.Set(authorizeCSRFField, testCSRFToken)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/authorize", strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: authorizeCSRFCookie, Value: testCSRFToken})
+	return req
+}
+
+func TestAuthorizeHandler_GetShowsLoginForm(test *testing.T) {
+	handler := NewAuthorizeHandler(&fakeAuthorizationService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/authorize?"+authorizeParams().Encode(), nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if !strings.Contains(handlerResponse.Body.String(), `name="password"`) {
+		test.Fatalf("expected login form")
+	}
+	if handlerResponse.Header().Get("X-Frame-Options") != "DENY" {
+		test.Fatalf("expected login form to deny framing")
+	}
+
+	var cookie *http.Cookie
+	for _, set := range handlerResponse.Result().Cookies() {
+		if set.Name == authorizeCSRFCookie {
+			cookie = set
+		}
+	}
+	if cookie == nil || cookie.Value == "" || !cookie.HttpOnly || !cookie.Secure || cookie.Path != "/authorize" {
+		test.Fatalf("expected an anti-CSRF cookie for /authorize, got %+v", cookie)
+	}
+	if !strings.Contains(handlerResponse.Body.String(), `name="csrf_token" value="`+cookie.Value+`"`) {
+		test.Fatalf("expected the anti-CSRF token of the cookie in the form")
+	}
+}
+
+// login CSRF: a cross-site page posting the form has no cookie, or not the one of its field
+func TestAuthorizeHandler_PostWithoutMatchingCSRFToken(test *testing.T) {
+	cases := map[string]func(req *http.Request){
+		"no cookie": func(req *http.Request) { req.Header.Del("Cookie") },
+		"other cookie": func(req *http.Request) {
+			req.Header.Del("Cookie")
+			req.AddCookie(&http.Cookie{Name: authorizeCSRFCookie, Value: "other-token"})
+		},
+	}
+
+	for name, tamper := range cases {
+		test.Run(name, func(test *testing.T) {
+			fakeSvc := &fakeAuthorizationService{code: "raw-code"}
+			handler := NewAuthorizeHandler(fakeSvc)
+
+			params := authorizeParams()
+			params.Set("email", "attacker@b.com")
+			params.Set("password", "pw")
+			req := newAuthorizePost(params)
+			tamper(req)
+			handlerResponse := httptest.NewRecorder()
+
+			handler.ServeHTTP(handlerResponse, req)
+
+			if handlerResponse.Code != http.StatusForbidden {
+				test.Fatalf("expected %d, got %d", http.StatusForbidden, handlerResponse.Code)
+			}
+			if handlerResponse.Header().Get("Location") != "" || fakeSvc.gotEmail != "" {
+				test.Fatalf("expected no login and no redirect")
+			}
+			// a fresh form, so the user can simply try again
+			if !strings.Contains(handlerResponse.Body.String(), `name="csrf_token"`) {
+				test.Fatalf("expected the login form again")
+			}
+		})
+	}
+}
+
+func TestAuthorizeHandler_PostRedirectsWithCode(test *testing.T) {
+	fakeSvc := &fakeAuthorizationService{code: "raw-code"}
+	handler := NewAuthorizeHandler(fakeSvc)
+
+	params := authorizeParams()
+	params.Set("email", "a@b.com")
+	params.Set("password", "pw")
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, newAuthorizePost(params))
+
+	if handlerResponse.Code != http.StatusFound {
+		test.Fatalf("expected %d, got %d", http.StatusFound, handlerResponse.Code)
+	}
+
+	location, err := url.Parse(handlerResponse.Header().Get("Location"))
+	if err != nil {
+		test.Fatalf("invalid redirect: %v", err)
+	}
+	if !strings.HasPrefix(location.String(), testRedirectURI) {
+		test.Fatalf("expected redirect to client, got %s", location)
+	}
+	if location.Query().Get("code") != "raw-code" || location.Query().Get("state") != "xyz" {
+		test.Fatalf("expected code and state in redirect, got %s", location.RawQuery)
+	}
+	if fakeSvc.gotEmail != "a@b.com" {
+		test.Fatalf("expected credentials to be passed, got %q", fakeSvc.gotEmail)
+	}
+	if fakeSvc.gotRequest.RememberMe {
+		test.Fatal("expected a short session without the checkbox")
+	}
+}
+
+func TestAuthorizeHandler_PostRememberMe(test *testing.T) {
+	fakeSvc := &fakeAuthorizationService{code: "raw-code"}
+	handler := NewAuthorizeHandler(fakeSvc)
+
+	params := authorizeParams()
+	params.Set("email", "a@b.com")
+	params.Set("password", "pw")
+	params.Set("remember_me", "true")
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, newAuthorizePost(params))
+
+	if handlerResponse.Code != http.StatusFound {
+		test.Fatalf("expected %d, got %d", http.StatusFound, handlerResponse.Code)
+	}
+	if !fakeSvc.gotRequest.RememberMe {
+		test.Fatal("expected remember me to be passed")
+	}
+}
+
+func TestAuthorizeHandler_InvalidCredentialsShowsFormAgain(test *testing.T) {
+	handler := NewAuthorizeHandler(&fakeAuthorizationService{authorizeErr: errs.ErrInvalidCredentials})
+
+	params := authorizeParams()
+	params.Set("email", "a@b.com")
+	params.Set("password", "wrong")
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, newAuthorizePost(params))
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+	if handlerResponse.Header().Get("Location") != "" {
+		test.Fatalf("expected no redirect on wrong password")
+	}
+}
+
+func TestAuthorizeHandler_MFARequiredShowsFormAgain(test *testing.T) {
+	fakeSvc := &fakeAuthorizationService{authorizeErr: errs.ErrMFARequired}
+	handler := NewAuthorizeHandler(fakeSvc)
+
+	params := authorizeParams()
+	params.Set("email", "a@b.com")
+	params.Set("password", "pw")
+	params.Set("otp", " 123456 ")
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, newAuthorizePost(params))
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+	if handlerResponse.Header().Get("Location") != "" {
+		test.Fatalf("expected no redirect while the second factor is missing")
+	}
+	if fakeSvc.gotOTP != "123456" {
+		test.Fatalf("expected trimmed one-time code to be passed, got %q", fakeSvc.gotOTP)
+	}
+	if !strings.Contains(handlerResponse.Body.String(), `name="otp"`) {
+		test.Fatalf("expected form with one-time code field")
+	}
+}
+
+func TestAuthorizeHandler_UnknownClientDoesNotRedirect(test *testing.T) {
+	handler := NewAuthorizeHandler(&fakeAuthorizationService{clientErr: errs.ErrInvalidRedirectURI})
+
+	req := httptest.NewRequest(http.MethodGet, "/authorize?"+authorizeParams().Encode(), nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+	if handlerResponse.Header().Get("Location") != "" {
+		test.Fatalf("unverified redirect_uri must not be redirected to")
+	}
+}
+
+func TestAuthorizeHandler_MissingPKCERedirectsWithError(test *testing.T) {
+	handler := NewAuthorizeHandler(&fakeAuthorizationService{requestErr: errs.ErrInvalidAuthorizationRequest})
+
+	req := httptest.NewRequest(http.MethodGet, "/authorize?"+authorizeParams().Encode(), nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusFound {
+		test.Fatalf("expected %d, got %d", http.StatusFound, handlerResponse.Code)
+	}
+	location, _ := url.Parse(handlerResponse.Header().Get("Location"))
+	if location.Query().Get("error") != "invalid_request" || location.Query().Get("state") != "xyz" {
+		test.Fatalf("expected invalid_request error with state, got %s", location.RawQuery)
+	}
+}
+
+func TestAuthorizeHandler_UnsupportedResponseType(test *testing.T) {
+	handler := NewAuthorizeHandler(&fakeAuthorizationService{})
+
+	params := authorizeParams()
+	params.Set("response_type", "token")
+	req := httptest.NewRequest(http.MethodGet, "/authorize?"+params.Encode(), nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	location, _ := url.Parse(handlerResponse.Header().Get("Location"))
+	if location.Query().Get("error") != "unsupported_response_type" {
+		test.Fatalf("expected unsupported_response_type, got %s", location.RawQuery)
+	}
+}
+
+func TestAuthorizeHandler_ServiceFailureRedirectsWithServerError(test *testing.T) {
+	handler := NewAuthorizeHandler(&fakeAuthorizationService{authorizeErr: errors.New("db unavailable")})
+
+	params := authorizeParams()
+	params.Set("email", "a@b.com")
+	params.Set("password", "pw")
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, newAuthorizePost(params))
+
+	location, _ := url.Parse(handlerResponse.Header().Get("Location"))
+	if location.Query().Get("error") != "server_error" {
+		test.Fatalf("expected server_error, got %s", location.RawQuery)
+	}
+}
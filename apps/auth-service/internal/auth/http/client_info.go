@@ -0,0 +1,58 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/clientinfo"
+)
+
+// optional, sent by apps that know what they run on ("Anna's phone");
+// shown in the session list
+const deviceNameHeader = "X-Device-Name"
+
+// headers are stored with the session; longer ones are cut
+const (
+	maxUserAgentLength  = 512
+	maxDeviceNameLength = 64
+)
+
+// ClientInfo puts the client IP, user agent and device name of every request
+// into its context (clientinfo.FromContext), for the services that record them.
+// Goes inside TrustedProxies, so the IP is the client's, not the gateway's.
+type ClientInfo struct {
+	next http.Handler
+}
+
+func NewClientInfo(next http.Handler) *ClientInfo {
+	return &ClientInfo{
+		next: next,
+	}
+}
+
+func (handler *ClientInfo) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	info := clientinfo.Info{
+		IP:         clientIP(request),
+		UserAgent:  cleanHeader(request.UserAgent(), maxUserAgentLength),
+		DeviceName: cleanHeader(request.Header.Get(deviceNameHeader), maxDeviceNameLength),
+	}
+
+	handler.next.ServeHTTP(response, request.WithContext(clientinfo.NewContext(request.Context(), info)))
+}
+
+// client supplied text ends up in a UI: no control characters, bounded length (in runes)
+func cleanHeader(value string, maxLength int) string {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return -1
+		}
+		return r
+	}, value)
+	value = strings.TrimSpace(value)
+
+	if runes := []rune(value); len(runes) > maxLength {
+		value = strings.TrimSpace(string(runes[:maxLength]))
+	}
+	return value
+}
@@ -0,0 +1,68 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authhttp "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/http"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/clientinfo"
+)
+
+// returns the client info the wrapped handler found in the request context
+func requestClientInfo(req *http.Request) clientinfo.Info {
+	var got clientinfo.Info
+	handler := authhttp.NewClientInfo(
+		http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			got = clientinfo.FromContext(request.Context())
+		}),
+	)
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	return got
+}
+
+func TestClientInfo_FromRequest(test *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = "203.0.113.7:4321"
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0")
+	req.Header.Set("X-Device-Name", "  Kitchen tablet ")
+
+	got := requestClientInfo(req)
+
+	if got.IP != "203.0.113.7" {
+		test.Fatalf("expected the client IP without port, got %q", got.IP)
+	}
+	if got.UserAgent != "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0" {
+		test.Fatalf("unexpected user agent %q", got.UserAgent)
+	}
+	if got.DeviceName != "Kitchen tablet" {
+		test.Fatalf("expected the trimmed device name, got %q", got.DeviceName)
+	}
+}
+
+func TestClientInfo_CleansDeviceName(test *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.Header.Set("X-Device-Name", "phone\x1b[31m"+strings.Repeat("x", 100))
+
+	got := requestClientInfo(req)
+
+	if strings.ContainsRune(got.DeviceName, '\x1b') {
+		test.Fatalf("expected control characters to be removed, got %q", got.DeviceName)
+	}
+	if len([]rune(got.DeviceName)) != 64 {
+		test.Fatalf("expected the device name to be cut to 64 characters, got %d", len([]rune(got.DeviceName)))
+	}
+}
+
+func TestClientInfo_NoHeaders(test *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	req.Header.Del("User-Agent")
+
+	got := requestClientInfo(req)
+
+	if got.UserAgent != "" || got.DeviceName != "" {
+		test.Fatalf("expected empty user agent and device name, got %+v", got)
+	}
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// the address a request came from, without the port; behind TrustedProxies
+// this is the client, not the gateway
+func clientIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+// TrustedProxies replaces RemoteAddr with the client address from X-Forwarded-For
+// when the request was forwarded by one of the proxies (the gateway). The header is
+// read from the right, skipping trusted hops, since anything further left was sent
+// by the client and may be forged. Without trusted proxies the header is ignored.
+type TrustedProxies struct {
+	proxies []netip.Prefix
+	next    http.Handler
+}
+
+func NewTrustedProxies(proxies []netip.Prefix, next http.Handler) *TrustedProxies {
+	return &TrustedProxies{
+		proxies: proxies,
+		next:    next,
+	}
+}
+
+func (handler *TrustedProxies) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if client, ok := handler.forwardedFor(request); ok {
+		request = request.Clone(request.Context())
+		request.RemoteAddr = net.JoinHostPort(client.String(), "0")
+	}
+
+	handler.next.ServeHTTP(response, request)
+}
+
+func (handler *TrustedProxies) forwardedFor(request *http.Request) (netip.Addr, bool) {
+	peer, err := netip.ParseAddr(clientIP(request))
+	if err != nil || !handler.trusted(peer) {
+		return netip.Addr{}, false
+	}
+
+	var hops []string
+	for _, header := range request.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		if !handler.trusted(hop) {
+			return hop.Unmap(), true
+		}
+	}
+
+	return netip.Addr{}, false
+}
+
+func (handler *TrustedProxies) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, proxy := range handler.proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	authhttp "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/http"
+)
+
+// returns the RemoteAddr the wrapped handler saw
+func forwardedRemoteAddr(remoteAddr string, forwardedFor ...string) string {
+	var got string
+	handler := authhttp.NewTrustedProxies(
+		[]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			got = request.RemoteAddr
+		}),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = remoteAddr
+	for _, header := range forwardedFor {
+		req.Header.Add("X-Forwarded-For", header)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	return got
+}
+
+func TestTrustedProxies_UsesForwardedClient(test *testing.T) {
+	got := forwardedRemoteAddr("10.0.0.5:4321", "203.0.113.7")
+	if got != "203.0.113.7:0" {
+		test.Fatalf("expected the forwarded client, got %q", got)
+	}
+}
+
+func TestTrustedProxies_SkipsTrustedHopsOnly(test *testing.T) {
+	// the client forged the first entry; the gateway appended the real address
+	got := forwardedRemoteAddr("10.0.0.5:4321", "198.51.100.1, 203.0.113.7", "10.0.0.9")
+	if got != "203.0.113.7:0" {
+		test.Fatalf("expected the address the first trusted proxy saw, got %q", got)
+	}
+}
+
+func TestTrustedProxies_IgnoresHeaderFromUntrustedPeer(test *testing.T) {
+	got := forwardedRemoteAddr("203.0.113.7:4321", "198.51.100.1")
+	if got != "203.0.113.7:4321" {
+		test.Fatalf("expected RemoteAddr to stay, got %q", got)
+	}
+}
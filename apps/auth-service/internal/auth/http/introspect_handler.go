@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+// Service interface expected by handler
+type IntrospectionService interface {
+	Introspect(ctx context.Context, token string, tokenTypeHint string) (domain.TokenInfo, error)
+}
+
+// RFC 7662 response; an inactive token carries nothing but "active": false
+type introspectResponse struct {
+	Active    bool   `json:"active"`
+	TokenType string `json:"token_type,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	FamilyID  string `json:"family_id,omitempty"`
+	Role      string `json:"role,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+// only internal callers may introspect: they authenticate with
+// HTTP Basic client credentials (client id -> secret)
+type IntrospectHandler struct {
+	introspectionSvc IntrospectionService
+	clients          map[string]string
+}
+
+func NewIntrospectHandler(introspectionSvc IntrospectionService, clients map[string]string) *IntrospectHandler {
+	return &IntrospectHandler{
+		introspectionSvc: introspectionSvc,
+		clients:          clients,
+	}
+}
+
+func (handler *IntrospectHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !handler.authenticateClient(request) {
+		response.Header().Set("WWW-Authenticate", `Basic realm="introspect"`)
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// RFC 7662 requests are form-encoded
+	token := request.PostFormValue("token")
+	if token == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	info, err := handler.introspectionSvc.Introspect(
+		request.Context(),
+		token,
+		request.PostFormValue("token_type_hint"),
+	)
+	if err != nil {
+		http.Error(response, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	respBody := introspectResponse{Active: false}
+	if info.Active {
+		respBody = introspectResponse{
+			Active:    true,
+			TokenType: info.TokenType,
+			Subject:   info.Subject,
+			FamilyID:  info.FamilyID,
+			Role:      info.Role,
+			IssuedAt:  unixTime(info.IssuedAt),
+			ExpiresAt: unixTime(info.ExpiresAt),
+		}
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(response).Encode(respBody)
+}
+
+func (handler *IntrospectHandler) authenticateClient(request *http.Request) bool {
+	clientID, secret, ok := request.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	expected, known := handler.clients[clientID]
+	if !known {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) == 1
+}
+
+// zero time is left out of the response instead of becoming a negative timestamp
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
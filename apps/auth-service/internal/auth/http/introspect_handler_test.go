@@ -0,0 +1,156 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+type fakeIntrospectionService struct {
+	info     domain.TokenInfo
+	err      error
+	gotToken string
+	gotHint  string
+}
+
+func (f *fakeIntrospectionService) Introspect(ctx context.Context, token string, tokenTypeHint string) (domain.TokenInfo, error) {
+	f.gotToken = token
+	f.gotHint = tokenTypeHint
+	return f.info, f.err
+}
+
+var introspectClients = map[string]string{"gateway": "gateway-secret"}
+
+func newIntrospectRequest(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("gateway", "gateway-secret")
+	return req
+}
+
+func TestIntrospectHandler_ActiveToken(test *testing.T) {
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	fakeSvc := &fakeIntrospectionService{
+		info: domain.TokenInfo{
+			Active:    true,
+			TokenType: domain.TokenTypeAccess,
+			Subject:   "user-1",
+			FamilyID:  "family-1",
+			Role:      "owner",
+			ExpiresAt: expiresAt,
+		},
+	}
+	handler := NewIntrospectHandler(fakeSvc, introspectClients)
+
+	req := newIntrospectRequest(url.Values{
+		"token":           {"some-token"},
+		"token_type_hint": {"access_token"},
+	})
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if fakeSvc.gotToken != "some-token" || fakeSvc.gotHint != "access_token" {
+		test.Fatalf("unexpected service input %q %q", fakeSvc.gotToken, fakeSvc.gotHint)
+	}
+	if handlerResponse.Header().Get("Cache-Control") != "no-store" {
+		test.Fatalf("expected Cache-Control no-store")
+	}
+
+	var resp introspectResponse
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if !resp.Active || resp.Subject != "user-1" || resp.FamilyID != "family-1" || resp.Role != "owner" {
+		test.Fatalf("unexpected response %+v", resp)
+	}
+	if resp.ExpiresAt != expiresAt.Unix() {
+		test.Fatalf("expected exp %d, got %d", expiresAt.Unix(), resp.ExpiresAt)
+	}
+}
+
+func TestIntrospectHandler_InactiveToken(test *testing.T) {
+	handler := NewIntrospectHandler(&fakeIntrospectionService{}, introspectClients)
+
+	req := newIntrospectRequest(url.Values{"token": {"some-token"}})
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if body := strings.TrimSpace(handlerResponse.Body.String()); body != `{"active":false}` {
+		test.Fatalf("expected only active=false, got %s", body)
+	}
+}
+
+func TestIntrospectHandler_Unauthorized(test *testing.T) {
+	handler := NewIntrospectHandler(&fakeIntrospectionService{}, introspectClients)
+
+	req := newIntrospectRequest(url.Values{"token": {"some-token"}})
+	req.SetBasicAuth("gateway", "wrong-secret")
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+	if handlerResponse.Header().Get("WWW-Authenticate") == "" {
+		test.Fatalf("expected WWW-Authenticate header")
+	}
+}
+
+func TestIntrospectHandler_MissingToken(test *testing.T) {
+	handler := NewIntrospectHandler(&fakeIntrospectionService{}, introspectClients)
+
+	req := newIntrospectRequest(url.Values{})
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+func TestIntrospectHandler_ServiceFailure(test *testing.T) {
+	handler := NewIntrospectHandler(
+		&fakeIntrospectionService{err: errors.New("db unavailable")},
+		introspectClients,
+	)
+
+	req := newIntrospectRequest(url.Values{"token": {"some-token"}})
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusInternalServerError {
+		test.Fatalf("expected %d, got %d", http.StatusInternalServerError, handlerResponse.Code)
+	}
+}
+
+func TestIntrospectHandler_MethodNotAllowed(test *testing.T) {
+	handler := NewIntrospectHandler(&fakeIntrospectionService{}, introspectClients)
+
+	req := httptest.NewRequest(http.MethodGet, "/introspect", nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusMethodNotAllowed {
+		test.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, handlerResponse.Code)
+	}
+}
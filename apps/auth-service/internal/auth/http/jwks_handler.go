@@ -0,0 +1,58 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+)
+
+// Key source expected by handler (implemented by the token signer)
+type KeySetProvider interface {
+	JWKS() jwt.JWKSet
+}
+
+// serves the public keys the gateway uses to verify access tokens
+type JWKSHandler struct {
+	keys   KeySetProvider
+	maxAge time.Duration
+}
+
+func NewJWKSHandler(keys KeySetProvider, maxAge time.Duration) *JWKSHandler {
+	return &JWKSHandler{
+		keys:   keys,
+		maxAge: maxAge,
+	}
+}
+
+func (handler *JWKSHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := json.Marshal(handler.keys.JWKS())
+	if err != nil {
+		http.Error(response, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	// keys change rarely: let the gateway cache them and revalidate cheaply
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:]) + `"`
+
+	response.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(handler.maxAge.Seconds())))
+	response.Header().Set("ETag", etag)
+
+	if request.Header.Get("If-None-Match") == etag {
+		response.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	_, _ = response.Write(body)
+}
@@ -0,0 +1,85 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	authhttp "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/http"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+)
+
+type fakeKeySetProvider struct {
+	keys jwt.JWKSet
+}
+
+func (f *fakeKeySetProvider) JWKS() jwt.JWKSet {
+	return f.keys
+}
+
+var testKeySet = jwt.JWKSet{
+	Keys: []jwt.JWK{{Kty: "RSA", Use: "sig", Alg: "RS256", Kid: "kid-1", N: "n", E: "AQAB"}},
+}
+
+func TestJWKSHandler_Success(test *testing.T) {
+	handler := authhttp.NewJWKSHandler(&fakeKeySetProvider{keys: testKeySet}, 5*time.Minute)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+
+	if cache := handlerResponse.Header().Get("Cache-Control"); cache != "public, max-age=300" {
+		test.Fatalf("unexpected Cache-Control %q", cache)
+	}
+
+	var resp map[string][]map[string]string
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid JSON response: %v", err)
+	}
+
+	if len(resp["keys"]) != 1 {
+		test.Fatalf("expected 1 key, got %d", len(resp["keys"]))
+	}
+
+	key := resp["keys"][0]
+	if key["kid"] != "kid-1" || key["alg"] != "RS256" || key["use"] != "sig" {
+		test.Fatalf("unexpected key %v", key)
+	}
+}
+
+func TestJWKSHandler_NotModified(test *testing.T) {
+	handler := authhttp.NewJWKSHandler(&fakeKeySetProvider{keys: testKeySet}, 5*time.Minute)
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusNotModified {
+		test.Fatalf("expected %d, got %d", http.StatusNotModified, handlerResponse.Code)
+	}
+}
+
+func TestJWKSHandler_MethodNotAllowed(test *testing.T) {
+	handler := authhttp.NewJWKSHandler(&fakeKeySetProvider{}, 5*time.Minute)
+
+	req := httptest.NewRequest(http.MethodPost, "/.well-known/jwks.json", nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusMethodNotAllowed {
+		test.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, handlerResponse.Code)
+	}
+}
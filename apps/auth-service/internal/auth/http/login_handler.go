@@ -8,38 +8,54 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
 )
 
 // Service interface expected by handler
 type LoginService interface {
-	Login(ctx context.Context, email, password string) (string, error)
+	Login(
+		ctx context.Context,
+		email, password, clientIP string,
+		idTokenReq domain.IDTokenRequest,
+		rememberMe bool,
+	) (domain.IssuedTokens, error)
+}
+
+// returned instead of tokens when the user has a second factor enabled;
+// the client completes the login at /login/mfa
+type mfaChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
 }
 
 type LoginHandler struct {
-	loginSvc LoginService
-	tokenTTL time.Duration
+	loginSvc      LoginService
+	tokenTTL      time.Duration
+	refreshCookie *RefreshCookie
 }
 
+// refreshCookie == nil returns the refresh token in the JSON body
 func NewLoginHandler(
 	loginSvc LoginService,
 	tokenTTL time.Duration,
+	refreshCookie *RefreshCookie,
 ) *LoginHandler {
 	return &LoginHandler{
-		loginSvc: loginSvc,
-		tokenTTL: tokenTTL,
+		loginSvc:      loginSvc,
+		tokenTTL:      tokenTTL,
+		refreshCookie: refreshCookie,
 	}
 }
 
+// client_id (and nonce) are sent by OpenID Connect clients that want an ID token;
+// remember_me asks for the long session lifetime
 type loginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-}
-
-type loginResponse struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
-	ExpiresIn   int64  `json:"expires_in"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	ClientID   string `json:"client_id"`
+	Nonce      string `json:"nonce"`
+	RememberMe bool   `json:"remember_me"`
 }
 
 func (handler *LoginHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
@@ -60,20 +76,61 @@ func (handler *LoginHandler) ServeHTTP(response http.ResponseWriter, request *ht
 		return
 	}
 
-	token, err := handler.loginSvc.Login(request.Context(), reqBody.Email, reqBody.Password)
+	tokens, err := handler.loginSvc.Login(
+		request.Context(),
+		reqBody.Email,
+		reqBody.Password,
+		clientIP(request),
+		domain.IDTokenRequest{ClientID: reqBody.ClientID, Nonce: reqBody.Nonce},
+		reqBody.RememberMe,
+	)
 	if err != nil {
+		if errors.Is(err, errs.ErrUnknownClient) {
+			http.Error(response, "unknown client", http.StatusBadRequest)
+			return
+		}
 		if errors.Is(err, errs.ErrInvalidCredentials) {
 			http.Error(response, "invalid credentials", http.StatusUnauthorized)
 			return
 		}
+		if errors.Is(err, errs.ErrEmailNotVerified) {
+			http.Error(response, "email not verified", http.StatusForbidden)
+			return
+		}
 		http.Error(response, "internal error", http.StatusInternalServerError)
 		return
 	}
 
-	respBody := loginResponse{
-		AccessToken: token,
-		TokenType:   "Bearer",
-		ExpiresIn:   int64(handler.tokenTTL.Seconds()),
+	if tokens.MFAToken != "" {
+		response.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(response).Encode(mfaChallengeResponse{
+			MFARequired: true,
+			MFAToken:    tokens.MFAToken,
+		})
+		return
+	}
+
+	writeLoginTokens(response, tokens, handler.tokenTTL, handler.refreshCookie.forRequest(request))
+}
+
+// shared by both login steps
+func writeLoginTokens(
+	response http.ResponseWriter,
+	tokens domain.IssuedTokens,
+	tokenTTL time.Duration,
+	refreshCookie *RefreshCookie,
+) {
+	respBody := tokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      tokens.IDToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokenTTL.Seconds()),
+	}
+
+	if refreshCookie != nil {
+		refreshCookie.set(response, tokens.RefreshToken, tokens.RefreshExpiresAt)
+		respBody.RefreshToken = ""
 	}
 
 	response.Header().Set("Content-Type", "application/json")
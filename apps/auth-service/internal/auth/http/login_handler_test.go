@@ -6,18 +6,27 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	authhttp "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/http"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
 )
 
 // fakeLoginService is a mock implementation of LoginService for testing.
 // we can configure it to return a specific token or error.
 type fakeLoginService struct {
-	token string
-	err   error
+	token            string
+	refreshToken     string
+	refreshExpiresAt time.Time
+	idToken          string
+	mfaToken         string
+	err              error
+	gotIDToken       domain.IDTokenRequest
+	gotClientIP      string
+	gotRemember      bool
 }
 
 // we skip real authentication and return predefined values
@@ -26,12 +35,28 @@ func (f *fakeLoginService) Login(
 	ctx context.Context,
 	email string,
 	password string,
-) (string, error) {
-	return f.token, f.err
+	clientIP string,
+	idTokenReq domain.IDTokenRequest,
+	rememberMe bool,
+) (domain.IssuedTokens, error) {
+	f.gotIDToken = idTokenReq
+	f.gotRemember = rememberMe
+	f.gotClientIP = clientIP
+	if f.err != nil {
+		return domain.IssuedTokens{}, f.err
+	}
+	return domain.IssuedTokens{
+		AccessToken:      f.token,
+		RefreshToken:     f.refreshToken,
+		IDToken:          f.idToken,
+		MFAToken:         f.mfaToken,
+		RefreshExpiresAt: f.refreshExpiresAt,
+	}, nil
 }
 
 const EXPIRATION_SECONDS time.Duration = 900 // 15 minutes
 const TOKEN = "test.jwt.token"
+const REFRESH_TOKEN = "test-refresh-token"
 const TOKEN_TYPE = "Bearer"
 
 var REQUEST_CREDS = map[string]string{
@@ -43,8 +68,9 @@ func TestLoginHandler_Success(test *testing.T) {
 
 	//PREPARE
 	fakeSvc := &fakeLoginService{
-		token: TOKEN,
-		err:   nil,
+		token:        TOKEN,
+		refreshToken: REFRESH_TOKEN,
+		err:          nil,
 	}
 	handler := createLoginHandler(fakeSvc)
 
@@ -71,6 +97,10 @@ func TestLoginHandler_Success(test *testing.T) {
 		test.Errorf("expected access_token %s, got %s", TOKEN, responseMap["access_token"])
 	}
 
+	if responseMap["refresh_token"] != REFRESH_TOKEN {
+		test.Errorf("expected refresh_token %s, got %s", REFRESH_TOKEN, responseMap["refresh_token"])
+	}
+
 	if responseMap["token_type"] != TOKEN_TYPE {
 		test.Errorf("expected token type %s, got %s", TOKEN_TYPE, responseMap["token_type"])
 	}
@@ -78,12 +108,129 @@ func TestLoginHandler_Success(test *testing.T) {
 	if responseMap["expires_in"] != float64(EXPIRATION_SECONDS) {
 		test.Errorf("expected expires_in %d, got %v", EXPIRATION_SECONDS, responseMap["expires_in"])
 	}
+
+	if _, present := responseMap["id_token"]; present {
+		test.Errorf("expected no id_token without client_id")
+	}
+}
+
+func TestLoginHandler_OIDCClient_ReturnsIDToken(test *testing.T) {
+	fakeSvc := &fakeLoginService{
+		token:        TOKEN,
+		refreshToken: REFRESH_TOKEN,
+		idToken:      "test.id.token",
+	}
+	handler := createLoginHandler(fakeSvc)
+
+	req := createRequest(map[string]string{
+		"email":     "user@",
+		"password":  "password123",
+		"client_id": "wiki",
+		"nonce":     "nonce-1",
+	})
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+
+	if fakeSvc.gotIDToken.ClientID != "wiki" || fakeSvc.gotIDToken.Nonce != "nonce-1" {
+		test.Fatalf("unexpected ID token request %+v", fakeSvc.gotIDToken)
+	}
+
+	var responseMap map[string]interface{}
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&responseMap); err != nil {
+		test.Fatalf("invalid JSON response %v. Error: %v", handlerResponse.Body, err)
+	}
+
+	if responseMap["id_token"] != "test.id.token" {
+		test.Errorf("expected id_token test.id.token, got %v", responseMap["id_token"])
+	}
+}
+
+// an ID token is addressed to client_id, only registered clients get one
+func TestLoginHandler_UnknownClient(test *testing.T) {
+	fakeSvc := &fakeLoginService{err: errs.ErrUnknownClient}
+	handler := createLoginHandler(fakeSvc)
+
+	req := createRequest(map[string]string{
+		"email":     "user@",
+		"password":  "password123",
+		"client_id": "evil-rp",
+	})
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+	if fakeSvc.gotIDToken.ClientID != "evil-rp" {
+		test.Fatalf("expected client_id to be passed on for the check, got %+v", fakeSvc.gotIDToken)
+	}
+	if strings.Contains(handlerResponse.Body.String(), "token") {
+		test.Fatalf("expected no tokens, got %q", handlerResponse.Body.String())
+	}
+}
+
+func TestLoginHandler_RememberMe(test *testing.T) {
+	fakeSvc := &fakeLoginService{token: TOKEN, refreshToken: REFRESH_TOKEN}
+	handler := createLoginHandler(fakeSvc)
+
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/login",
+		bytes.NewReader([]byte(`{"email":"user@","password":"password123","remember_me":true}`)),
+	)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if !fakeSvc.gotRemember {
+		test.Fatal("expected remember_me to be passed to the service")
+	}
+}
+
+func TestLoginHandler_MFAEnabled_ReturnsChallenge(test *testing.T) {
+	handler := authhttp.NewLoginHandler(
+		&fakeLoginService{mfaToken: "test.mfa.token"},
+		EXPIRATION_SECONDS*time.Second,
+		authhttp.NewRefreshCookie("refresh_token", http.SameSiteStrictMode),
+	)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, createRequest(REQUEST_CREDS))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+
+	var responseMap map[string]interface{}
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&responseMap); err != nil {
+		test.Fatalf("invalid JSON response: %v", err)
+	}
+
+	if responseMap["mfa_required"] != true || responseMap["mfa_token"] != "test.mfa.token" {
+		test.Fatalf("expected MFA challenge, got %v", responseMap)
+	}
+	if _, found := responseMap["access_token"]; found {
+		test.Errorf("no access token before the second factor")
+	}
+	if len(handlerResponse.Result().Cookies()) != 0 {
+		test.Errorf("no refresh token cookie before the second factor")
+	}
 }
 
 func createLoginHandler(fakeSvc authhttp.LoginService) authhttp.LoginHandler {
 	return *authhttp.NewLoginHandler(
 		fakeSvc,
 		EXPIRATION_SECONDS*time.Second,
+		nil,
 	)
 }
 
@@ -123,6 +270,39 @@ func TestLoginHandler_InvalidCredentials(test *testing.T) {
 	}
 }
 
+// the client must not learn about the lockout
+func TestLoginHandler_ThrottledLooksLikeInvalidCredentials(test *testing.T) {
+	invalidHandler := createLoginHandler(&fakeLoginService{err: errs.ErrInvalidCredentials})
+	invalid := httptest.NewRecorder()
+	invalidHandler.ServeHTTP(invalid, createRequest(REQUEST_CREDS))
+
+	throttledSvc := &fakeLoginService{err: errs.ErrLoginThrottled}
+	throttledHandler := createLoginHandler(throttledSvc)
+	throttled := httptest.NewRecorder()
+	throttledHandler.ServeHTTP(throttled, createRequest(REQUEST_CREDS))
+
+	if throttled.Code != invalid.Code || throttled.Body.String() != invalid.Body.String() {
+		test.Fatalf("expected the same response as invalid credentials, got %d %q", throttled.Code, throttled.Body.String())
+	}
+	// httptest requests come from 192.0.2.1
+	if throttledSvc.gotClientIP != "192.0.2.1" {
+		test.Fatalf("expected the client IP to be passed on, got %q", throttledSvc.gotClientIP)
+	}
+}
+
+func TestLoginHandler_EmailNotVerified(test *testing.T) {
+	handler := createLoginHandler(&fakeLoginService{
+		err: errs.ErrEmailNotVerified,
+	})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, createRequest(REQUEST_CREDS))
+
+	if handlerResponse.Code != http.StatusForbidden {
+		test.Fatalf("expected %d, got %d", http.StatusForbidden, handlerResponse.Code)
+	}
+}
+
 func TestLoginHandler_InvalidJSON(test *testing.T) {
 	handler := createLoginHandler(&fakeLoginService{})
 
@@ -170,3 +350,71 @@ func TestLoginHandler_MethodNotAllowed(test *testing.T) {
 		test.Fatalf("expected responce code %d, got %d", http.StatusMethodNotAllowed, handlerResponse.Code)
 	}
 }
+
+func TestLoginHandler_CookieMode(test *testing.T) {
+	// a remember me session
+	refreshExpiresAt := time.Now().Add(30 * 24 * time.Hour)
+	handler := authhttp.NewLoginHandler(
+		&fakeLoginService{token: TOKEN, refreshToken: REFRESH_TOKEN, refreshExpiresAt: refreshExpiresAt},
+		EXPIRATION_SECONDS*time.Second,
+		authhttp.NewRefreshCookie("refresh_token", http.SameSiteStrictMode),
+	)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, createRequest(REQUEST_CREDS))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+
+	var responseMap map[string]interface{}
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&responseMap); err != nil {
+		test.Fatalf("invalid JSON response: %v", err)
+	}
+
+	if responseMap["access_token"] != TOKEN {
+		test.Errorf("expected access_token %s, got %s", TOKEN, responseMap["access_token"])
+	}
+
+	if _, found := responseMap["refresh_token"]; found {
+		test.Errorf("refresh token must not be in the body in cookie mode")
+	}
+
+	cookies := handlerResponse.Result().Cookies()
+	if len(cookies) == 0 || cookies[0].Value != REFRESH_TOKEN || !cookies[0].HttpOnly {
+		test.Fatalf("expected HttpOnly refresh token cookie, got %+v", cookies)
+	}
+	if maxAge := time.Duration(cookies[0].MaxAge) * time.Second; time.Until(refreshExpiresAt)-maxAge > 5*time.Second {
+		test.Errorf("expected the cookie to expire with the token, got Max-Age %s", maxAge)
+	}
+}
+
+// a mobile app against a service in cookie mode asks for the body
+func TestLoginHandler_CookieMode_BodyTransportRequested(test *testing.T) {
+	handler := authhttp.NewLoginHandler(
+		&fakeLoginService{token: TOKEN, refreshToken: REFRESH_TOKEN},
+		EXPIRATION_SECONDS*time.Second,
+		authhttp.NewRefreshCookie("refresh_token", http.SameSiteStrictMode),
+	)
+
+	req := createRequest(REQUEST_CREDS)
+	req.Header.Set("X-Refresh-Token-Transport", "body")
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+
+	var responseMap map[string]interface{}
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&responseMap); err != nil {
+		test.Fatalf("invalid JSON response: %v", err)
+	}
+
+	if responseMap["refresh_token"] != REFRESH_TOKEN {
+		test.Errorf("expected refresh token %s in the body, got %v", REFRESH_TOKEN, responseMap["refresh_token"])
+	}
+	if len(handlerResponse.Result().Cookies()) != 0 {
+		test.Errorf("expected no cookie, got %+v", handlerResponse.Result().Cookies())
+	}
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// Service interface expected by handler
+type LoginMFAService interface {
+	LoginMFA(ctx context.Context, mfaToken string, code string, clientIP string) (domain.IssuedTokens, error)
+}
+
+// second step of a login with MFA: the challenge from /login plus a one-time
+// or recovery code
+type LoginMFAHandler struct {
+	loginSvc      LoginMFAService
+	tokenTTL      time.Duration
+	refreshCookie *RefreshCookie
+}
+
+// refreshCookie == nil returns the refresh token in the JSON body
+func NewLoginMFAHandler(
+	loginSvc LoginMFAService,
+	tokenTTL time.Duration,
+	refreshCookie *RefreshCookie,
+) *LoginMFAHandler {
+	return &LoginMFAHandler{
+		loginSvc:      loginSvc,
+		tokenTTL:      tokenTTL,
+		refreshCookie: refreshCookie,
+	}
+}
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+func (handler *LoginMFAHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reqBody loginMFARequest
+	if err := json.NewDecoder(request.Body).Decode(&reqBody); err != nil {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	reqBody.Code = strings.TrimSpace(reqBody.Code)
+	if reqBody.MFAToken == "" || reqBody.Code == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := handler.loginSvc.LoginMFA(request.Context(), reqBody.MFAToken, reqBody.Code, clientIP(request))
+	if err != nil {
+		if errors.Is(err, errs.ErrUnknownClient) {
+			http.Error(response, "unknown client", http.StatusBadRequest)
+			return
+		}
+		// ErrLoginThrottled while the account is blocked by failed codes or passwords
+		if errors.Is(err, errs.ErrInvalidOTP) ||
+			errors.Is(err, errs.ErrInvalidMFAChallenge) ||
+			errors.Is(err, errs.ErrInvalidCredentials) {
+			http.Error(response, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		http.Error(response, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	writeLoginTokens(response, tokens, handler.tokenTTL, handler.refreshCookie.forRequest(request))
+}
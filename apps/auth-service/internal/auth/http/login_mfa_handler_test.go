@@ -0,0 +1,92 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+type fakeLoginMFAService struct {
+	tokens      domain.IssuedTokens
+	err         error
+	gotMFAToken string
+	gotCode     string
+}
+
+func (f *fakeLoginMFAService) LoginMFA(ctx context.Context, mfaToken string, code string, clientIP string) (domain.IssuedTokens, error) {
+	f.gotMFAToken = mfaToken
+	f.gotCode = code
+	return f.tokens, f.err
+}
+
+func newLoginMFARequest(body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/login/mfa", bytes.NewReader([]byte(body)))
+}
+
+func TestLoginMFAHandler_Success(test *testing.T) {
+	fakeSvc := &fakeLoginMFAService{
+		tokens: domain.IssuedTokens{AccessToken: "access.jwt", RefreshToken: "refresh-1"},
+	}
+	handler := NewLoginMFAHandler(fakeSvc, 15*time.Minute, nil)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newLoginMFARequest(`{"mfa_token":"mfa.jwt","code":"123456"}`))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if fakeSvc.gotMFAToken != "mfa.jwt" || fakeSvc.gotCode != "123456" {
+		test.Fatalf("expected challenge and code to be passed, got %q %q", fakeSvc.gotMFAToken, fakeSvc.gotCode)
+	}
+
+	var resp tokenResponse
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if resp.AccessToken != "access.jwt" || resp.RefreshToken != "refresh-1" || resp.ExpiresIn != 900 {
+		test.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestLoginMFAHandler_InvalidCode(test *testing.T) {
+	for _, err := range []error{errs.ErrInvalidOTP, errs.ErrInvalidMFAChallenge, errs.ErrLoginThrottled} {
+		handler := NewLoginMFAHandler(&fakeLoginMFAService{err: err}, 15*time.Minute, nil)
+
+		handlerResponse := httptest.NewRecorder()
+		handler.ServeHTTP(handlerResponse, newLoginMFARequest(`{"mfa_token":"mfa.jwt","code":"000000"}`))
+
+		if handlerResponse.Code != http.StatusUnauthorized {
+			test.Fatalf("%v: expected %d, got %d", err, http.StatusUnauthorized, handlerResponse.Code)
+		}
+	}
+}
+
+func TestLoginMFAHandler_MissingFields(test *testing.T) {
+	handler := NewLoginMFAHandler(&fakeLoginMFAService{}, 15*time.Minute, nil)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newLoginMFARequest(`{"mfa_token":"mfa.jwt"}`))
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+func TestLoginMFAHandler_ServiceFailure(test *testing.T) {
+	handler := NewLoginMFAHandler(&fakeLoginMFAService{err: errors.New("db unavailable")}, 15*time.Minute, nil)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newLoginMFARequest(`{"mfa_token":"mfa.jwt","code":"123456"}`))
+
+	if handlerResponse.Code != http.StatusInternalServerError {
+		test.Fatalf("expected %d, got %d", http.StatusInternalServerError, handlerResponse.Code)
+	}
+}
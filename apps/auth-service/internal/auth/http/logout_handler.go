@@ -2,7 +2,6 @@ package http
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 )
 
@@ -12,11 +11,16 @@ type LogoutService interface {
 }
 
 type LogoutHandler struct {
-	logoutSvc LogoutService
+	logoutSvc     LogoutService
+	refreshCookie *RefreshCookie
 }
 
-func NewLogoutHandler(logoutSvc LogoutService) *LogoutHandler {
-	return &LogoutHandler{logoutSvc: logoutSvc}
+// refreshCookie == nil reads the refresh token from the JSON body
+func NewLogoutHandler(logoutSvc LogoutService, refreshCookie *RefreshCookie) *LogoutHandler {
+	return &LogoutHandler{
+		logoutSvc:     logoutSvc,
+		refreshCookie: refreshCookie,
+	}
 }
 
 func (handler *LogoutHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
@@ -25,26 +29,23 @@ func (handler *LogoutHandler) ServeHTTP(response http.ResponseWriter, request *h
 		return
 	}
 
-	var req struct {
-		RefreshToken string `json:"refresh_token"`
-	}
-
-	if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
+	refreshToken, refreshCookie, ok := readRefreshToken(request, handler.refreshCookie)
+	if !ok {
 		http.Error(response, "invalid request", http.StatusBadRequest)
 		return
 	}
 
-	if req.RefreshToken == "" {
-		http.Error(response, "invalid request", http.StatusBadRequest)
-		return
-	}
-
-	if err := handler.logoutSvc.Logout(request.Context(), req.RefreshToken); err != nil {
+	if err := handler.logoutSvc.Logout(request.Context(), refreshToken); err != nil {
 		// we DO NOT say logout failed, we say server failed
+		// cookie is kept so the client can retry
 		http.Error(response, "internal error", http.StatusInternalServerError)
 		return
 	}
 
+	if refreshCookie != nil {
+		refreshCookie.clear(response)
+	}
+
 	// 204 no content to return
 	response.WriteHeader(http.StatusNoContent)
 }
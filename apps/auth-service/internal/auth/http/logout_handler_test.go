@@ -19,7 +19,7 @@ func (f *fakeLogoutService) Logout(ctx context.Context, rawRefreshToken string)
 
 func TestLogoutHandler_Success(test *testing.T) {
 	fakeSvc := &fakeLogoutService{err: nil}
-	handler := NewLogoutHandler(fakeSvc)
+	handler := NewLogoutHandler(fakeSvc, nil)
 
 	body := []byte(`{"refresh_token":"some-token"}`)
 	req := httptest.NewRequest(http.MethodPost, "/logout", bytes.NewReader(body))
@@ -37,7 +37,7 @@ func TestLogoutHandler_Success(test *testing.T) {
 }
 
 func TestLogoutHandler_InvalidJSON(test *testing.T) {
-	handler := NewLogoutHandler(&fakeLogoutService{})
+	handler := NewLogoutHandler(&fakeLogoutService{}, nil)
 
 	req := httptest.NewRequest(
 		http.MethodPost,
@@ -54,7 +54,7 @@ func TestLogoutHandler_InvalidJSON(test *testing.T) {
 }
 
 func TestLogoutHandler_MissingToken(test *testing.T) {
-	handler := NewLogoutHandler(&fakeLogoutService{})
+	handler := NewLogoutHandler(&fakeLogoutService{}, nil)
 
 	req := httptest.NewRequest(
 		http.MethodPost,
@@ -74,7 +74,7 @@ func TestLogoutHandler_ServiceFailure(test *testing.T) {
 	fakeSvc := &fakeLogoutService{
 		err: errors.New("db unavailable"),
 	}
-	handler := NewLogoutHandler(fakeSvc)
+	handler := NewLogoutHandler(fakeSvc, nil)
 
 	body := []byte(`{"refresh_token":"some-token"}`)
 	req := httptest.NewRequest(http.MethodPost, "/logout", bytes.NewReader(body))
@@ -88,7 +88,7 @@ func TestLogoutHandler_ServiceFailure(test *testing.T) {
 }
 
 func TestLogoutHandler_MethodNotAllowed(test *testing.T) {
-	handler := NewLogoutHandler(&fakeLogoutService{})
+	handler := NewLogoutHandler(&fakeLogoutService{}, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/logout", nil)
 	handlerResponse := httptest.NewRecorder()
@@ -99,3 +99,28 @@ func TestLogoutHandler_MethodNotAllowed(test *testing.T) {
 		test.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, handlerResponse.Code)
 	}
 }
+
+func TestLogoutHandler_CookieMode_ClearsCookie(test *testing.T) {
+	cookie := NewRefreshCookie("refresh_token", http.SameSiteStrictMode)
+	handler := NewLogoutHandler(&fakeLogoutService{}, cookie)
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "some-token"})
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusNoContent {
+		test.Fatalf("expected %d, got %d", http.StatusNoContent, handlerResponse.Code)
+	}
+
+	cookies := handlerResponse.Result().Cookies()
+	if len(cookies) != len(refreshCookiePaths) {
+		test.Fatalf("expected %d cleared cookies, got %d", len(refreshCookiePaths), len(cookies))
+	}
+	for _, cleared := range cookies {
+		if cleared.Value != "" || cleared.MaxAge >= 0 {
+			test.Fatalf("expected cookie to be cleared: %+v", cleared)
+		}
+	}
+}
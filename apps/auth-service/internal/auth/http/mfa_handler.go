@@ -0,0 +1,214 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// Service interface expected by handlers
+type MFAService interface {
+	BeginTOTPEnrollment(ctx context.Context, accessToken string, currentPassword string) (domain.TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(ctx context.Context, accessToken string, currentPassword string, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, accessToken string, currentPassword string, code string) ([]string, error)
+}
+
+type totpEnrollRequest struct {
+	CurrentPassword string `json:"current_password"`
+}
+
+type totpEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// starts TOTP enrollment for the caller; the secret is returned once
+type TOTPEnrollHandler struct {
+	mfaSvc MFAService
+}
+
+func NewTOTPEnrollHandler(mfaSvc MFAService) *TOTPEnrollHandler {
+	return &TOTPEnrollHandler{
+		mfaSvc: mfaSvc,
+	}
+}
+
+func (handler *TOTPEnrollHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accessToken, ok := bearerToken(request)
+	if !ok {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var reqBody totpEnrollRequest
+	if err := json.NewDecoder(request.Body).Decode(&reqBody); err != nil || reqBody.CurrentPassword == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := handler.mfaSvc.BeginTOTPEnrollment(request.Context(), accessToken, reqBody.CurrentPassword)
+	if err != nil {
+		writeMFAError(response, err)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(response).Encode(totpEnrollmentResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+	})
+}
+
+// shown once; the user is expected to print or store them offline
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// finishes TOTP enrollment with the first code from the authenticator app
+// and returns the first batch of recovery codes
+type TOTPConfirmHandler struct {
+	mfaSvc MFAService
+}
+
+func NewTOTPConfirmHandler(mfaSvc MFAService) *TOTPConfirmHandler {
+	return &TOTPConfirmHandler{
+		mfaSvc: mfaSvc,
+	}
+}
+
+type totpConfirmRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+}
+
+func (handler *TOTPConfirmHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accessToken, ok := bearerToken(request)
+	if !ok {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var reqBody totpConfirmRequest
+	if err := json.NewDecoder(request.Body).Decode(&reqBody); err != nil {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	reqBody.Code = strings.TrimSpace(reqBody.Code)
+	if reqBody.CurrentPassword == "" || reqBody.Code == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, err := handler.mfaSvc.ConfirmTOTPEnrollment(
+		request.Context(),
+		accessToken,
+		reqBody.CurrentPassword,
+		reqBody.Code,
+	)
+	if err != nil {
+		writeMFAError(response, err)
+		return
+	}
+
+	writeRecoveryCodes(response, recoveryCodes)
+}
+
+// either of them proves the caller is the account owner
+type recoveryCodesRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+}
+
+// replaces the recovery codes of the caller with a new batch
+type RecoveryCodesHandler struct {
+	mfaSvc MFAService
+}
+
+func NewRecoveryCodesHandler(mfaSvc MFAService) *RecoveryCodesHandler {
+	return &RecoveryCodesHandler{
+		mfaSvc: mfaSvc,
+	}
+}
+
+func (handler *RecoveryCodesHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accessToken, ok := bearerToken(request)
+	if !ok {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var reqBody recoveryCodesRequest
+	if err := json.NewDecoder(request.Body).Decode(&reqBody); err != nil {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	reqBody.Code = strings.TrimSpace(reqBody.Code)
+	if reqBody.CurrentPassword == "" && reqBody.Code == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, err := handler.mfaSvc.RegenerateRecoveryCodes(
+		request.Context(),
+		accessToken,
+		reqBody.CurrentPassword,
+		reqBody.Code,
+	)
+	if err != nil {
+		writeMFAError(response, err)
+		return
+	}
+
+	writeRecoveryCodes(response, recoveryCodes)
+}
+
+func writeRecoveryCodes(response http.ResponseWriter, recoveryCodes []string) {
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(response).Encode(recoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+func writeMFAError(response http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errs.ErrInvalidAccessToken):
+		response.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+	case errors.Is(err, errs.ErrInvalidCredentials):
+		// not 401: the access token is fine, the current password is not
+		http.Error(response, "invalid current password", http.StatusForbidden)
+	case errors.Is(err, errs.ErrMFAAlreadyEnabled):
+		http.Error(response, "mfa already enabled", http.StatusConflict)
+	case errors.Is(err, errs.ErrMFANotEnrolled):
+		http.Error(response, "mfa not enrolled", http.StatusConflict)
+	case errors.Is(err, errs.ErrInvalidOTP):
+		http.Error(response, "invalid code", http.StatusBadRequest)
+	default:
+		http.Error(response, "internal error", http.StatusInternalServerError)
+	}
+}
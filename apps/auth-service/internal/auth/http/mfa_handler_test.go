@@ -0,0 +1,214 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+type fakeMFAService struct {
+	enrollment    domain.TOTPEnrollment
+	recoveryCodes []string
+	err           error
+	gotToken      string
+	gotPassword   string
+	gotCode       string
+}
+
+func (f *fakeMFAService) BeginTOTPEnrollment(ctx context.Context, accessToken string, currentPassword string) (domain.TOTPEnrollment, error) {
+	f.gotToken = accessToken
+	f.gotPassword = currentPassword
+	return f.enrollment, f.err
+}
+
+func (f *fakeMFAService) ConfirmTOTPEnrollment(ctx context.Context, accessToken string, currentPassword string, code string) ([]string, error) {
+	f.gotToken = accessToken
+	f.gotPassword = currentPassword
+	f.gotCode = code
+	return f.recoveryCodes, f.err
+}
+
+func (f *fakeMFAService) RegenerateRecoveryCodes(ctx context.Context, accessToken string, currentPassword string, code string) ([]string, error) {
+	f.gotToken = accessToken
+	f.gotPassword = currentPassword
+	f.gotCode = code
+	return f.recoveryCodes, f.err
+}
+
+func newMFARequest(path string, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(body)))
+	req.Header.Set("Authorization", "Bearer access.jwt.token")
+	return req
+}
+
+func TestTOTPEnrollHandler_Success(test *testing.T) {
+	fakeSvc := &fakeMFAService{
+		enrollment: domain.TOTPEnrollment{Secret: "JBSWY3DPEHPK3PXP", URI: "otpauth://totp/x"},
+	}
+	handler := NewTOTPEnrollHandler(fakeSvc)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newMFARequest("/mfa/totp/enroll", `{"current_password":"pw"}`))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if fakeSvc.gotToken != "access.jwt.token" || fakeSvc.gotPassword != "pw" {
+		test.Fatalf("expected bearer token and password to be passed, got %q %q", fakeSvc.gotToken, fakeSvc.gotPassword)
+	}
+
+	var resp totpEnrollmentResponse
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if resp.Secret != "JBSWY3DPEHPK3PXP" || resp.OTPAuthURI != "otpauth://totp/x" {
+		test.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestTOTPEnrollHandler_AlreadyEnabled(test *testing.T) {
+	handler := NewTOTPEnrollHandler(&fakeMFAService{err: errs.ErrMFAAlreadyEnabled})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newMFARequest("/mfa/totp/enroll", `{"current_password":"pw"}`))
+
+	if handlerResponse.Code != http.StatusConflict {
+		test.Fatalf("expected %d, got %d", http.StatusConflict, handlerResponse.Code)
+	}
+}
+
+func TestTOTPEnrollHandler_PasswordRequired(test *testing.T) {
+	fakeSvc := &fakeMFAService{}
+	handler := NewTOTPEnrollHandler(fakeSvc)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newMFARequest("/mfa/totp/enroll", `{}`))
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+	if fakeSvc.gotToken != "" {
+		test.Fatalf("service must not be called without the current password")
+	}
+}
+
+func TestTOTPEnrollHandler_WrongPassword(test *testing.T) {
+	handler := NewTOTPEnrollHandler(&fakeMFAService{err: errs.ErrInvalidCredentials})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newMFARequest("/mfa/totp/enroll", `{"current_password":"wrong"}`))
+
+	if handlerResponse.Code != http.StatusForbidden {
+		test.Fatalf("expected %d, got %d", http.StatusForbidden, handlerResponse.Code)
+	}
+}
+
+func TestTOTPEnrollHandler_MissingToken(test *testing.T) {
+	handler := NewTOTPEnrollHandler(&fakeMFAService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/mfa/totp/enroll", nil)
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+}
+
+func TestTOTPConfirmHandler_Success(test *testing.T) {
+	fakeSvc := &fakeMFAService{recoveryCodes: []string{"abcde-fghij", "klmno-pqrst"}}
+	handler := NewTOTPConfirmHandler(fakeSvc)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newMFARequest("/mfa/totp/confirm", `{"current_password":"pw","code":"123456"}`))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if fakeSvc.gotCode != "123456" {
+		test.Fatalf("expected code to be passed, got %q", fakeSvc.gotCode)
+	}
+
+	var resp recoveryCodesResponse
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if len(resp.RecoveryCodes) != 2 || resp.RecoveryCodes[0] != "abcde-fghij" {
+		test.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestTOTPConfirmHandler_InvalidCode(test *testing.T) {
+	handler := NewTOTPConfirmHandler(&fakeMFAService{err: errs.ErrInvalidOTP})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newMFARequest("/mfa/totp/confirm", `{"current_password":"pw","code":"000000"}`))
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+func TestTOTPConfirmHandler_InvalidAccessToken(test *testing.T) {
+	handler := NewTOTPConfirmHandler(&fakeMFAService{err: errs.ErrInvalidAccessToken})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newMFARequest("/mfa/totp/confirm", `{"current_password":"pw","code":"123456"}`))
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+	if handlerResponse.Header().Get("WWW-Authenticate") == "" {
+		test.Fatalf("expected WWW-Authenticate header")
+	}
+}
+
+func TestRecoveryCodesHandler_Success(test *testing.T) {
+	fakeSvc := &fakeMFAService{recoveryCodes: []string{"abcde-fghij"}}
+	handler := NewRecoveryCodesHandler(fakeSvc)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newMFARequest("/mfa/recovery-codes", `{"code":"123456"}`))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if handlerResponse.Header().Get("Cache-Control") != "no-store" {
+		test.Fatalf("recovery codes must not be cached")
+	}
+	if fakeSvc.gotToken != "access.jwt.token" || fakeSvc.gotCode != "123456" {
+		test.Fatalf("expected bearer token and code to be passed, got %q %q", fakeSvc.gotToken, fakeSvc.gotCode)
+	}
+}
+
+func TestRecoveryCodesHandler_ProofRequired(test *testing.T) {
+	fakeSvc := &fakeMFAService{}
+	handler := NewRecoveryCodesHandler(fakeSvc)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newMFARequest("/mfa/recovery-codes", `{}`))
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+	if fakeSvc.gotToken != "" {
+		test.Fatalf("service must not be called without the password or a code")
+	}
+}
+
+func TestRecoveryCodesHandler_MFANotEnrolled(test *testing.T) {
+	handler := NewRecoveryCodesHandler(&fakeMFAService{err: errs.ErrMFANotEnrolled})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newMFARequest("/mfa/recovery-codes", `{"code":"123456"}`))
+
+	if handlerResponse.Code != http.StatusConflict {
+		test.Fatalf("expected %d, got %d", http.StatusConflict, handlerResponse.Code)
+	}
+}
@@ -0,0 +1,19 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// error body of the OAuth endpoints (RFC 6749, section 5.2), which
+// off-the-shelf OAuth clients parse instead of plain text
+type oauthErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeOAuthError(response http.ResponseWriter, status int, code string) {
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Cache-Control", "no-store")
+	response.WriteHeader(status)
+	json.NewEncoder(response).Encode(oauthErrorResponse{Error: code})
+}
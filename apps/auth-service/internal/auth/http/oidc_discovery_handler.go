@@ -0,0 +1,69 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// OpenID Provider metadata (OpenID Connect Discovery 1.0, section 3)
+type oidcConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// serves /.well-known/openid-configuration, so OIDC client libraries
+// can configure themselves from the issuer URL alone
+type OIDCDiscoveryHandler struct {
+	body []byte
+}
+
+// issuerURL is the public base URL of the service, all endpoints hang off it
+func NewOIDCDiscoveryHandler(issuerURL string, signingAlg string) *OIDCDiscoveryHandler {
+	config := oidcConfiguration{
+		Issuer:                 issuerURL,
+		AuthorizationEndpoint:  issuerURL + "/authorize",
+		TokenEndpoint:          issuerURL + "/token",
+		JWKSURI:                issuerURL + "/.well-known/jwks.json",
+		UserInfoEndpoint:       issuerURL + "/userinfo",
+		RevocationEndpoint:     issuerURL + "/revoke",
+		IntrospectionEndpoint:  issuerURL + "/introspect",
+		ResponseTypesSupported: []string{"code"},
+		GrantTypesSupported:    []string{"authorization_code", "refresh_token"},
+		// PKCE is mandatory, public clients only
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		TokenEndpointAuthMethodsSupported: []string{"none"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{signingAlg},
+		ScopesSupported:                   []string{"openid", "email"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "amr", "email", "email_verified", "family_id", "role"},
+	}
+
+	// the document never changes at runtime
+	body, _ := json.Marshal(config)
+
+	return &OIDCDiscoveryHandler{body: body}
+}
+
+func (handler *OIDCDiscoveryHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Cache-Control", "public, max-age=3600")
+	_, _ = response.Write(handler.body)
+}
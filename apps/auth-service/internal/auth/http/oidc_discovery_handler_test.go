@@ -0,0 +1,58 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOIDCDiscoveryHandler_Document(test *testing.T) {
+	handler := NewOIDCDiscoveryHandler("https://auth.family.example", "ES256")
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+
+	var config oidcConfiguration
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&config); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+
+	if config.Issuer != "https://auth.family.example" {
+		test.Fatalf("unexpected issuer %q", config.Issuer)
+	}
+	if config.JWKSURI != "https://auth.family.example/.well-known/jwks.json" {
+		test.Fatalf("unexpected jwks_uri %q", config.JWKSURI)
+	}
+	if config.TokenEndpoint != "https://auth.family.example/token" {
+		test.Fatalf("unexpected token_endpoint %q", config.TokenEndpoint)
+	}
+	if len(config.CodeChallengeMethodsSupported) != 1 || config.CodeChallengeMethodsSupported[0] != "S256" {
+		test.Fatalf("expected S256 only, got %v", config.CodeChallengeMethodsSupported)
+	}
+	if config.UserInfoEndpoint != "https://auth.family.example/userinfo" {
+		test.Fatalf("unexpected userinfo_endpoint %q", config.UserInfoEndpoint)
+	}
+	if len(config.IDTokenSigningAlgValuesSupported) != 1 || config.IDTokenSigningAlgValuesSupported[0] != "ES256" {
+		test.Fatalf("unexpected signing algorithms %v", config.IDTokenSigningAlgValuesSupported)
+	}
+}
+
+func TestOIDCDiscoveryHandler_MethodNotAllowed(test *testing.T) {
+	handler := NewOIDCDiscoveryHandler("https://auth.family.example", "ES256")
+
+	req := httptest.NewRequest(http.MethodPost, "/.well-known/openid-configuration", nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusMethodNotAllowed {
+		test.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, handlerResponse.Code)
+	}
+}
@@ -0,0 +1,240 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/webauthn"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// Service interface expected by handlers
+type PasskeyService interface {
+	BeginRegistration(ctx context.Context, accessToken string) (string, webauthn.CreationOptions, error)
+	FinishRegistration(
+		ctx context.Context,
+		accessToken string,
+		ceremonyID string,
+		response webauthn.RegistrationResponse,
+	) error
+	BeginLogin(ctx context.Context) (string, webauthn.RequestOptions, error)
+	FinishLogin(
+		ctx context.Context,
+		ceremonyID string,
+		response webauthn.AssertionResponse,
+		idTokenReq domain.IDTokenRequest,
+		rememberMe bool,
+	) (domain.IssuedTokens, error)
+}
+
+// publicKey is passed to PublicKeyCredential.parseCreationOptionsFromJSON
+// (or parseRequestOptionsFromJSON); ceremony_id goes back with the credential
+type passkeyOptionsResponse struct {
+	CeremonyID string `json:"ceremony_id"`
+	PublicKey  any    `json:"publicKey"`
+}
+
+// credential is the result of PublicKeyCredential.toJSON
+type passkeyRegistrationRequest struct {
+	CeremonyID string                        `json:"ceremony_id"`
+	Credential webauthn.RegistrationResponse `json:"credential"`
+}
+
+// client_id (and nonce) are sent by OpenID Connect clients that want an ID token;
+// remember_me asks for the long session lifetime
+type passkeyLoginRequest struct {
+	CeremonyID string                     `json:"ceremony_id"`
+	Credential webauthn.AssertionResponse `json:"credential"`
+	ClientID   string                     `json:"client_id"`
+	Nonce      string                     `json:"nonce"`
+	RememberMe bool                       `json:"remember_me"`
+}
+
+// starts registering a passkey for the caller
+type PasskeyRegisterBeginHandler struct {
+	passkeySvc PasskeyService
+}
+
+func NewPasskeyRegisterBeginHandler(passkeySvc PasskeyService) *PasskeyRegisterBeginHandler {
+	return &PasskeyRegisterBeginHandler{
+		passkeySvc: passkeySvc,
+	}
+}
+
+func (handler *PasskeyRegisterBeginHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accessToken, ok := bearerToken(request)
+	if !ok {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ceremonyID, options, err := handler.passkeySvc.BeginRegistration(request.Context(), accessToken)
+	if err != nil {
+		writePasskeyError(response, err)
+		return
+	}
+
+	writePasskeyOptions(response, ceremonyID, options)
+}
+
+// stores the passkey the browser created for the caller
+type PasskeyRegisterFinishHandler struct {
+	passkeySvc PasskeyService
+}
+
+func NewPasskeyRegisterFinishHandler(passkeySvc PasskeyService) *PasskeyRegisterFinishHandler {
+	return &PasskeyRegisterFinishHandler{
+		passkeySvc: passkeySvc,
+	}
+}
+
+func (handler *PasskeyRegisterFinishHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accessToken, ok := bearerToken(request)
+	if !ok {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var reqBody passkeyRegistrationRequest
+	if err := json.NewDecoder(request.Body).Decode(&reqBody); err != nil || reqBody.CeremonyID == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err := handler.passkeySvc.FinishRegistration(
+		request.Context(),
+		accessToken,
+		reqBody.CeremonyID,
+		reqBody.Credential,
+	)
+	if err != nil {
+		writePasskeyError(response, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusCreated)
+}
+
+// starts a passkey login; no credentials needed
+type PasskeyLoginBeginHandler struct {
+	passkeySvc PasskeyService
+}
+
+func NewPasskeyLoginBeginHandler(passkeySvc PasskeyService) *PasskeyLoginBeginHandler {
+	return &PasskeyLoginBeginHandler{
+		passkeySvc: passkeySvc,
+	}
+}
+
+func (handler *PasskeyLoginBeginHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ceremonyID, options, err := handler.passkeySvc.BeginLogin(request.Context())
+	if err != nil {
+		writePasskeyError(response, err)
+		return
+	}
+
+	writePasskeyOptions(response, ceremonyID, options)
+}
+
+// finishes a passkey login with the same response as /login
+type PasskeyLoginFinishHandler struct {
+	passkeySvc    PasskeyService
+	tokenTTL      time.Duration
+	refreshCookie *RefreshCookie
+}
+
+// refreshCookie == nil returns the refresh token in the JSON body
+func NewPasskeyLoginFinishHandler(
+	passkeySvc PasskeyService,
+	tokenTTL time.Duration,
+	refreshCookie *RefreshCookie,
+) *PasskeyLoginFinishHandler {
+	return &PasskeyLoginFinishHandler{
+		passkeySvc:    passkeySvc,
+		tokenTTL:      tokenTTL,
+		refreshCookie: refreshCookie,
+	}
+}
+
+func (handler *PasskeyLoginFinishHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reqBody passkeyLoginRequest
+	if err := json.NewDecoder(request.Body).Decode(&reqBody); err != nil || reqBody.CeremonyID == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := handler.passkeySvc.FinishLogin(
+		request.Context(),
+		reqBody.CeremonyID,
+		reqBody.Credential,
+		domain.IDTokenRequest{ClientID: reqBody.ClientID, Nonce: reqBody.Nonce},
+		reqBody.RememberMe,
+	)
+	if err != nil {
+		if errors.Is(err, errs.ErrUnknownClient) {
+			http.Error(response, "unknown client", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, errs.ErrInvalidPasskey) || errors.Is(err, errs.ErrInvalidPasskeyCeremony) {
+			http.Error(response, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, errs.ErrEmailNotVerified) {
+			http.Error(response, "email not verified", http.StatusForbidden)
+			return
+		}
+		http.Error(response, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	writeLoginTokens(response, tokens, handler.tokenTTL, handler.refreshCookie.forRequest(request))
+}
+
+func writePasskeyOptions(response http.ResponseWriter, ceremonyID string, options any) {
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(response).Encode(passkeyOptionsResponse{
+		CeremonyID: ceremonyID,
+		PublicKey:  options,
+	})
+}
+
+func writePasskeyError(response http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errs.ErrInvalidAccessToken):
+		response.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+	case errors.Is(err, errs.ErrInvalidPasskey):
+		http.Error(response, "invalid passkey", http.StatusBadRequest)
+	case errors.Is(err, errs.ErrInvalidPasskeyCeremony):
+		http.Error(response, "invalid or expired ceremony", http.StatusBadRequest)
+	default:
+		http.Error(response, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,208 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/webauthn"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+type fakePasskeyService struct {
+	tokens        domain.IssuedTokens
+	err           error
+	gotToken      string
+	gotCeremonyID string
+	gotCredential string
+	gotIDTokenReq domain.IDTokenRequest
+	gotRemember   bool
+}
+
+func (f *fakePasskeyService) BeginRegistration(
+	ctx context.Context,
+	accessToken string,
+) (string, webauthn.CreationOptions, error) {
+	f.gotToken = accessToken
+	return "ceremony-1", webauthn.CreationOptions{Challenge: "challenge-1"}, f.err
+}
+
+func (f *fakePasskeyService) FinishRegistration(
+	ctx context.Context,
+	accessToken string,
+	ceremonyID string,
+	response webauthn.RegistrationResponse,
+) error {
+	f.gotToken = accessToken
+	f.gotCeremonyID = ceremonyID
+	f.gotCredential = response.RawID
+	return f.err
+}
+
+func (f *fakePasskeyService) BeginLogin(ctx context.Context) (string, webauthn.RequestOptions, error) {
+	return "ceremony-2", webauthn.RequestOptions{Challenge: "challenge-2"}, f.err
+}
+
+func (f *fakePasskeyService) FinishLogin(
+	ctx context.Context,
+	ceremonyID string,
+	response webauthn.AssertionResponse,
+	idTokenReq domain.IDTokenRequest,
+	rememberMe bool,
+) (domain.IssuedTokens, error) {
+	f.gotCeremonyID = ceremonyID
+	f.gotCredential = response.RawID
+	f.gotIDTokenReq = idTokenReq
+	f.gotRemember = rememberMe
+	return f.tokens, f.err
+}
+
+func newPasskeyRequest(path string, body string, accessToken string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(body)))
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return req
+}
+
+func TestPasskeyRegisterBeginHandler_Success(test *testing.T) {
+	fakeSvc := &fakePasskeyService{}
+	handler := NewPasskeyRegisterBeginHandler(fakeSvc)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newPasskeyRequest("/passkeys/register/begin", "", "access.jwt.token"))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if fakeSvc.gotToken != "access.jwt.token" {
+		test.Fatalf("expected bearer token to be passed, got %q", fakeSvc.gotToken)
+	}
+
+	var resp struct {
+		CeremonyID string `json:"ceremony_id"`
+		PublicKey  struct {
+			Challenge string `json:"challenge"`
+		} `json:"publicKey"`
+	}
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if resp.CeremonyID != "ceremony-1" || resp.PublicKey.Challenge != "challenge-1" {
+		test.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestPasskeyRegisterBeginHandler_MissingBearerToken(test *testing.T) {
+	handler := NewPasskeyRegisterBeginHandler(&fakePasskeyService{})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newPasskeyRequest("/passkeys/register/begin", "", ""))
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+}
+
+func TestPasskeyRegisterFinishHandler_Success(test *testing.T) {
+	fakeSvc := &fakePasskeyService{}
+	handler := NewPasskeyRegisterFinishHandler(fakeSvc)
+
+	body := `{"ceremony_id":"ceremony-1","credential":{"id":"cred-1","rawId":"cred-1","type":"public-key"}}`
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newPasskeyRequest("/passkeys/register/finish", body, "access.jwt.token"))
+
+	if handlerResponse.Code != http.StatusCreated {
+		test.Fatalf("expected %d, got %d", http.StatusCreated, handlerResponse.Code)
+	}
+	if fakeSvc.gotCeremonyID != "ceremony-1" || fakeSvc.gotCredential != "cred-1" {
+		test.Fatalf("expected ceremony and credential to be passed, got %q %q", fakeSvc.gotCeremonyID, fakeSvc.gotCredential)
+	}
+}
+
+func TestPasskeyRegisterFinishHandler_InvalidPasskey(test *testing.T) {
+	handler := NewPasskeyRegisterFinishHandler(&fakePasskeyService{err: errs.ErrInvalidPasskey})
+
+	body := `{"ceremony_id":"ceremony-1","credential":{}}`
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newPasskeyRequest("/passkeys/register/finish", body, "access.jwt.token"))
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+func TestPasskeyRegisterFinishHandler_MissingCeremony(test *testing.T) {
+	handler := NewPasskeyRegisterFinishHandler(&fakePasskeyService{})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newPasskeyRequest("/passkeys/register/finish", `{"credential":{}}`, "access.jwt.token"))
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+func TestPasskeyLoginBeginHandler_Success(test *testing.T) {
+	handler := NewPasskeyLoginBeginHandler(&fakePasskeyService{})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newPasskeyRequest("/passkeys/login/begin", "", ""))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if cacheControl := handlerResponse.Header().Get("Cache-Control"); cacheControl != "no-store" {
+		test.Fatalf("expected no-store, got %q", cacheControl)
+	}
+}
+
+func TestPasskeyLoginFinishHandler_Success(test *testing.T) {
+	fakeSvc := &fakePasskeyService{
+		tokens: domain.IssuedTokens{AccessToken: "access.jwt", RefreshToken: "refresh-1"},
+	}
+	handler := NewPasskeyLoginFinishHandler(fakeSvc, 15*time.Minute, nil)
+
+	body := `{"ceremony_id":"ceremony-2","credential":{"rawId":"cred-1"},"client_id":"web","nonce":"n-1","remember_me":true}`
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newPasskeyRequest("/passkeys/login/finish", body, ""))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if fakeSvc.gotCeremonyID != "ceremony-2" || fakeSvc.gotCredential != "cred-1" {
+		test.Fatalf("expected ceremony and credential to be passed, got %q %q", fakeSvc.gotCeremonyID, fakeSvc.gotCredential)
+	}
+	if fakeSvc.gotIDTokenReq.ClientID != "web" || fakeSvc.gotIDTokenReq.Nonce != "n-1" {
+		test.Fatalf("unexpected ID token request %+v", fakeSvc.gotIDTokenReq)
+	}
+	if !fakeSvc.gotRemember {
+		test.Fatal("expected remember_me to be passed to the service")
+	}
+
+	var resp tokenResponse
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if resp.AccessToken != "access.jwt" || resp.RefreshToken != "refresh-1" || resp.ExpiresIn != 900 {
+		test.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestPasskeyLoginFinishHandler_InvalidPasskey(test *testing.T) {
+	for _, err := range []error{errs.ErrInvalidPasskey, errs.ErrInvalidPasskeyCeremony} {
+		handler := NewPasskeyLoginFinishHandler(&fakePasskeyService{err: err}, 15*time.Minute, nil)
+
+		handlerResponse := httptest.NewRecorder()
+		handler.ServeHTTP(handlerResponse, newPasskeyRequest("/passkeys/login/finish", `{"ceremony_id":"c","credential":{}}`, ""))
+
+		if handlerResponse.Code != http.StatusUnauthorized {
+			test.Fatalf("%v: expected %d, got %d", err, http.StatusUnauthorized, handlerResponse.Code)
+		}
+	}
+}
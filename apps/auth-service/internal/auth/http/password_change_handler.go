@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// Service interface expected by handler
+type PasswordChangeService interface {
+	ChangePassword(
+		ctx context.Context,
+		accessToken string,
+		currentPassword string,
+		newPassword string,
+		refreshToken string,
+	) error
+}
+
+// refresh_token (body mode) keeps the caller's session; in cookie mode the cookie does
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+	RefreshToken    string `json:"refresh_token"`
+}
+
+// changes the caller's password; all other sessions end
+type ChangePasswordHandler struct {
+	changeSvc     PasswordChangeService
+	refreshCookie *RefreshCookie
+}
+
+// refreshCookie == nil reads the refresh token from the JSON body
+func NewChangePasswordHandler(
+	changeSvc PasswordChangeService,
+	refreshCookie *RefreshCookie,
+) *ChangePasswordHandler {
+	return &ChangePasswordHandler{
+		changeSvc:     changeSvc,
+		refreshCookie: refreshCookie,
+	}
+}
+
+func (handler *ChangePasswordHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accessToken, ok := bearerToken(request)
+	if !ok {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var reqBody changePasswordRequest
+	if err := json.NewDecoder(request.Body).Decode(&reqBody); err != nil {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if reqBody.CurrentPassword == "" || reqBody.NewPassword == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	// the cookie if the browser sent one, the body field of mobile clients otherwise
+	refreshToken := strings.TrimSpace(reqBody.RefreshToken)
+	if refreshCookie := handler.refreshCookie.forRequest(request); refreshCookie != nil {
+		if token := refreshCookie.read(request); token != "" {
+			refreshToken = token
+		}
+	}
+
+	err := handler.changeSvc.ChangePassword(
+		request.Context(),
+		accessToken,
+		reqBody.CurrentPassword,
+		reqBody.NewPassword,
+		refreshToken,
+	)
+	if err != nil {
+		if writeWeakPassword(response, err) {
+			return
+		}
+
+		switch {
+		case errors.Is(err, errs.ErrInvalidAccessToken):
+			response.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(response, "unauthorized", http.StatusUnauthorized)
+		case errors.Is(err, errs.ErrInvalidCredentials):
+			// not 401: the access token is fine, the current password is not
+			http.Error(response, "invalid current password", http.StatusForbidden)
+		default:
+			http.Error(response, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,139 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authhttp "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/http"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+type fakePasswordChangeService struct {
+	err             error
+	gotAccessToken  string
+	gotRefreshToken string
+}
+
+func (svc *fakePasswordChangeService) ChangePassword(
+	ctx context.Context,
+	accessToken string,
+	currentPassword string,
+	newPassword string,
+	refreshToken string,
+) error {
+	svc.gotAccessToken = accessToken
+	svc.gotRefreshToken = refreshToken
+	return svc.err
+}
+
+func newChangePasswordRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/password/change", bytes.NewReader([]byte(body)))
+	req.Header.Set("Authorization", "Bearer access.jwt.token")
+	return req
+}
+
+func TestChangePasswordHandler_Success(test *testing.T) {
+	svc := &fakePasswordChangeService{}
+	handler := authhttp.NewChangePasswordHandler(svc, nil)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newChangePasswordRequest(
+		`{"current_password":"old","new_password":"new","refresh_token":"refresh"}`,
+	))
+
+	if handlerResponse.Code != http.StatusNoContent {
+		test.Fatalf("expected %d, got %d", http.StatusNoContent, handlerResponse.Code)
+	}
+	if svc.gotAccessToken != "access.jwt.token" || svc.gotRefreshToken != "refresh" {
+		test.Fatalf("unexpected service call: %+v", svc)
+	}
+}
+
+func TestChangePasswordHandler_CookieMode(test *testing.T) {
+	svc := &fakePasswordChangeService{}
+	handler := authhttp.NewChangePasswordHandler(svc, newTestRefreshCookie())
+
+	// token in the body is ignored in cookie mode
+	req := newChangePasswordRequest(`{"current_password":"old","new_password":"new","refresh_token":"body"}`)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "cookie"})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusNoContent {
+		test.Fatalf("expected %d, got %d", http.StatusNoContent, handlerResponse.Code)
+	}
+	if svc.gotRefreshToken != "cookie" {
+		test.Fatalf("expected the refresh token from the cookie, got '%s'", svc.gotRefreshToken)
+	}
+}
+
+func TestChangePasswordHandler_WrongCurrentPassword(test *testing.T) {
+	handler := authhttp.NewChangePasswordHandler(&fakePasswordChangeService{
+		err: errs.ErrInvalidCredentials,
+	}, nil)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newChangePasswordRequest(`{"current_password":"old","new_password":"new"}`))
+
+	if handlerResponse.Code != http.StatusForbidden {
+		test.Fatalf("expected %d, got %d", http.StatusForbidden, handlerResponse.Code)
+	}
+}
+
+func TestChangePasswordHandler_InvalidAccessToken(test *testing.T) {
+	handler := authhttp.NewChangePasswordHandler(&fakePasswordChangeService{
+		err: errs.ErrInvalidAccessToken,
+	}, nil)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newChangePasswordRequest(`{"current_password":"old","new_password":"new"}`))
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+}
+
+func TestChangePasswordHandler_MissingBearerToken(test *testing.T) {
+	handler := authhttp.NewChangePasswordHandler(&fakePasswordChangeService{}, nil)
+
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/password/change",
+		bytes.NewReader([]byte(`{"current_password":"old","new_password":"new"}`)),
+	)
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+}
+
+func TestChangePasswordHandler_MissingNewPassword(test *testing.T) {
+	handler := authhttp.NewChangePasswordHandler(&fakePasswordChangeService{}, nil)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newChangePasswordRequest(`{"current_password":"old"}`))
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+func TestChangePasswordHandler_WeakPassword(test *testing.T) {
+	handler := authhttp.NewChangePasswordHandler(&fakePasswordChangeService{
+		err: &password.PolicyError{Violations: []password.Violation{{Rule: password.RuleMaxLength}}},
+	}, nil)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newChangePasswordRequest(`{"current_password":"old","new_password":"new"}`))
+
+	if handlerResponse.Code != http.StatusUnprocessableEntity {
+		test.Fatalf("expected %d, got %d", http.StatusUnprocessableEntity, handlerResponse.Code)
+	}
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// Service interface expected by handlers
+type PasswordResetService interface {
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, newPassword string) error
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// emails a reset link; always 202 so the endpoint cannot be used
+// to find out which addresses are registered
+type ForgotPasswordHandler struct {
+	resetSvc PasswordResetService
+}
+
+func NewForgotPasswordHandler(resetSvc PasswordResetService) *ForgotPasswordHandler {
+	return &ForgotPasswordHandler{
+		resetSvc: resetSvc,
+	}
+}
+
+func (handler *ForgotPasswordHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reqBody forgotPasswordRequest
+	if err := json.NewDecoder(request.Body).Decode(&reqBody); err != nil {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	reqBody.Email = strings.TrimSpace(reqBody.Email)
+	if reqBody.Email == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := handler.resetSvc.RequestPasswordReset(request.Context(), reqBody.Email); err != nil {
+		log.Printf("password reset request failed: %v", err)
+	}
+
+	response.WriteHeader(http.StatusAccepted)
+}
+
+// sets a new password with the token from the emailed link
+type ResetPasswordHandler struct {
+	resetSvc PasswordResetService
+}
+
+func NewResetPasswordHandler(resetSvc PasswordResetService) *ResetPasswordHandler {
+	return &ResetPasswordHandler{
+		resetSvc: resetSvc,
+	}
+}
+
+func (handler *ResetPasswordHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reqBody resetPasswordRequest
+	if err := json.NewDecoder(request.Body).Decode(&reqBody); err != nil {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if reqBody.Token == "" || reqBody.Password == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err := handler.resetSvc.ResetPassword(request.Context(), reqBody.Token, reqBody.Password)
+	if err != nil {
+		if errors.Is(err, errs.ErrInvalidResetToken) {
+			http.Error(response, "invalid or expired token", http.StatusBadRequest)
+			return
+		}
+		if writeWeakPassword(response, err) {
+			return
+		}
+		http.Error(response, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
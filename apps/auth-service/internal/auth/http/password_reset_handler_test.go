@@ -0,0 +1,138 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authhttp "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/http"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+type fakePasswordResetService struct {
+	err         error
+	requestedBy string
+	gotToken    string
+	gotPassword string
+}
+
+func (svc *fakePasswordResetService) RequestPasswordReset(ctx context.Context, email string) error {
+	svc.requestedBy = email
+	return svc.err
+}
+
+func (svc *fakePasswordResetService) ResetPassword(ctx context.Context, token string, newPassword string) error {
+	svc.gotToken = token
+	svc.gotPassword = newPassword
+	return svc.err
+}
+
+func TestForgotPasswordHandler_AlwaysAccepted(test *testing.T) {
+	svc := &fakePasswordResetService{err: errors.New("smtp relay down")}
+	handler := authhttp.NewForgotPasswordHandler(svc)
+
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/password/forgot",
+		bytes.NewReader([]byte(`{"email":" a@b.com "}`)),
+	)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusAccepted {
+		test.Fatalf("expected %d, got %d", http.StatusAccepted, handlerResponse.Code)
+	}
+	if svc.requestedBy != "a@b.com" {
+		test.Fatalf("expected trimmed email, got '%s'", svc.requestedBy)
+	}
+}
+
+func TestForgotPasswordHandler_MissingEmail(test *testing.T) {
+	handler := authhttp.NewForgotPasswordHandler(&fakePasswordResetService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/password/forgot", bytes.NewReader([]byte(`{}`)))
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+func TestResetPasswordHandler_Success(test *testing.T) {
+	svc := &fakePasswordResetService{}
+	handler := authhttp.NewResetPasswordHandler(svc)
+
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/password/reset",
+		bytes.NewReader([]byte(`{"token":"tok","password":"new password"}`)),
+	)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusNoContent {
+		test.Fatalf("expected %d, got %d", http.StatusNoContent, handlerResponse.Code)
+	}
+	if svc.gotToken != "tok" || svc.gotPassword != "new password" {
+		test.Fatalf("unexpected service call: token '%s', password '%s'", svc.gotToken, svc.gotPassword)
+	}
+}
+
+func TestResetPasswordHandler_InvalidToken(test *testing.T) {
+	handler := authhttp.NewResetPasswordHandler(&fakePasswordResetService{
+		err: errs.ErrInvalidResetToken,
+	})
+
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/password/reset",
+		bytes.NewReader([]byte(`{"token":"tok","password":"new password"}`)),
+	)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+func TestResetPasswordHandler_MissingPassword(test *testing.T) {
+	handler := authhttp.NewResetPasswordHandler(&fakePasswordResetService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/password/reset", bytes.NewReader([]byte(`{"token":"tok"}`)))
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+func TestResetPasswordHandler_WeakPassword(test *testing.T) {
+	handler := authhttp.NewResetPasswordHandler(&fakePasswordResetService{
+		err: &password.PolicyError{Violations: []password.Violation{{Rule: password.RuleBreached}}},
+	})
+
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/password/reset",
+		bytes.NewReader([]byte(`{"token":"tok","password":"password"}`)),
+	)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusUnprocessableEntity {
+		test.Fatalf("expected %d, got %d", http.StatusUnprocessableEntity, handlerResponse.Code)
+	}
+}
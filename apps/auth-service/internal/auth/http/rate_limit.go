@@ -0,0 +1,160 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/ratelimit"
+)
+
+// the bodies with an email are tiny; more is not read to find it
+const maxRateLimitBody = 64 << 10
+
+// Service interface expected by handler
+// (implemented by ratelimit.MemoryStore and ratelimit.DBStore)
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Decision, error)
+}
+
+// what a request is counted against; "" if the request has no such key
+type RateLimitKey func(request *http.Request) string
+
+func ByClientIP(request *http.Request) string {
+	return "ip:" + clientIP(request)
+}
+
+// the normalised email of a JSON body (login, forgot password) or a form (the
+// login page of /authorize), so requests spread over many IPs still count
+// against one account; the body is kept for the handler
+func ByEmail(request *http.Request) string {
+	if request.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(request.Body, maxRateLimitBody))
+	request.Body.Close()
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var login struct {
+		Email string `json:"email"`
+	}
+	if strings.HasPrefix(request.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return ""
+		}
+		login.Email = form.Get("email")
+	} else if err := json.Unmarshal(body, &login); err != nil {
+		return ""
+	}
+
+	email := strings.ToLower(strings.TrimSpace(login.Email))
+	if email == "" {
+		return ""
+	}
+	return "email:" + email
+}
+
+// RateLimit answers 429 once a key of the request has used up its limit; every
+// key has its own bucket per route. Responses carry the RateLimit headers
+// (draft-ietf-httpapi-ratelimit-headers) of the most restrictive bucket.
+type RateLimit struct {
+	store RateLimitStore
+	route string
+	limit ratelimit.Limit
+	keys  []RateLimitKey
+	next  http.Handler
+}
+
+// routes sharing a name share their buckets (e.g. all of /password/*)
+func NewRateLimit(
+	store RateLimitStore,
+	route string,
+	limit ratelimit.Limit,
+	next http.Handler,
+	keys ...RateLimitKey,
+) *RateLimit {
+	return &RateLimit{
+		store: store,
+		route: route,
+		limit: limit,
+		keys:  keys,
+		next:  next,
+	}
+}
+
+func (handler *RateLimit) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if handler.limit.Unlimited() {
+		handler.next.ServeHTTP(response, request)
+		return
+	}
+
+	var strictest *ratelimit.Decision
+
+	for _, key := range handler.keys {
+		value := key(request)
+		if value == "" {
+			continue
+		}
+
+		decision, err := handler.store.Take(request.Context(), handler.route+":"+value, handler.limit)
+		if err != nil {
+			// an unavailable store must not take the logins down with it
+			log.Printf("rate limit check failed (route=%s): %v", handler.route, err)
+			continue
+		}
+
+		if strictest == nil || stricter(decision, *strictest) {
+			strictest = &decision
+		}
+	}
+
+	if strictest == nil {
+		handler.next.ServeHTTP(response, request)
+		return
+	}
+
+	writeRateLimitHeaders(response, *strictest)
+
+	if !strictest.Allowed {
+		response.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(strictest.RetryAfter)))
+		http.Error(response, "too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	handler.next.ServeHTTP(response, request)
+}
+
+func stricter(decision ratelimit.Decision, than ratelimit.Decision) bool {
+	if decision.Allowed != than.Allowed {
+		return !decision.Allowed
+	}
+	if !decision.Allowed {
+		return decision.RetryAfter > than.RetryAfter
+	}
+	return decision.Remaining < than.Remaining
+}
+
+func writeRateLimitHeaders(response http.ResponseWriter, decision ratelimit.Decision) {
+	header := response.Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(decision.Limit.Requests))
+	header.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+	header.Set("RateLimit-Policy", strconv.Itoa(decision.Limit.Requests)+";w="+strconv.Itoa(ceilSeconds(decision.Limit.Period)))
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
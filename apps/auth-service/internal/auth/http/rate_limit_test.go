@@ -0,0 +1,172 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	authhttp "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/http"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/ratelimit"
+)
+
+type failingRateLimitStore struct{}
+
+func (store failingRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Decision, error) {
+	return ratelimit.Decision{}, errors.New("database down")
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func loginRequest(remoteAddr string, email string) *http.Request {
+	body, _ := json.Marshal(map[string]string{"email": email, "password": "pw"})
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
+func TestRateLimit_TooManyRequests(test *testing.T) {
+	handler := authhttp.NewRateLimit(
+		ratelimit.NewMemoryStore(),
+		"register",
+		ratelimit.Limit{Requests: 2, Period: time.Minute},
+		okHandler(),
+		authhttp.ByClientIP,
+	)
+
+	for i := 0; i < 2; i++ {
+		handlerResponse := httptest.NewRecorder()
+		handler.ServeHTTP(handlerResponse, loginRequest("192.0.2.1:1234", "a@b.com"))
+		if handlerResponse.Code != http.StatusNoContent {
+			test.Fatalf("request %d: expected %d, got %d", i+1, http.StatusNoContent, handlerResponse.Code)
+		}
+	}
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, loginRequest("192.0.2.1:1234", "a@b.com"))
+
+	if handlerResponse.Code != http.StatusTooManyRequests {
+		test.Fatalf("expected %d, got %d", http.StatusTooManyRequests, handlerResponse.Code)
+	}
+	header := handlerResponse.Header()
+	if header.Get("Retry-After") != "30" || header.Get("RateLimit-Remaining") != "0" {
+		test.Fatalf("unexpected headers %v", header)
+	}
+	if header.Get("RateLimit-Limit") != "2" || header.Get("RateLimit-Policy") != "2;w=60" {
+		test.Fatalf("unexpected policy headers %v", header)
+	}
+
+	// another client is not affected
+	handlerResponse = httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, loginRequest("192.0.2.2:1234", "a@b.com"))
+	if handlerResponse.Code != http.StatusNoContent {
+		test.Fatalf("expected %d, got %d", http.StatusNoContent, handlerResponse.Code)
+	}
+}
+
+func TestRateLimit_LoginEmailAcrossIPs(test *testing.T) {
+	var gotEmail string
+	next := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		var body struct {
+			Email string `json:"email"`
+		}
+		json.NewDecoder(request.Body).Decode(&body)
+		gotEmail = body.Email
+	})
+
+	handler := authhttp.NewRateLimit(
+		ratelimit.NewMemoryStore(),
+		"login",
+		ratelimit.Limit{Requests: 1, Period: time.Minute},
+		next,
+		authhttp.ByClientIP,
+		authhttp.ByEmail,
+	)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, loginRequest("192.0.2.1:1234", "a@b.com"))
+	if handlerResponse.Code != http.StatusOK || gotEmail != "a@b.com" {
+		test.Fatalf("expected the handler to read the body, got %d %q", handlerResponse.Code, gotEmail)
+	}
+
+	// same account (normalised) from another IP
+	handlerResponse = httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, loginRequest("192.0.2.2:1234", " A@B.com"))
+	if handlerResponse.Code != http.StatusTooManyRequests {
+		test.Fatalf("expected %d, got %d", http.StatusTooManyRequests, handlerResponse.Code)
+	}
+}
+
+func TestRateLimit_AuthorizeFormEmail(test *testing.T) {
+	var gotEmail string
+	next := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		request.ParseForm()
+		gotEmail = request.PostForm.Get("email")
+	})
+
+	// /authorize shares the buckets of /login
+	handler := authhttp.NewRateLimit(
+		ratelimit.NewMemoryStore(),
+		"login",
+		ratelimit.Limit{Requests: 1, Period: time.Minute},
+		next,
+		authhttp.ByClientIP,
+		authhttp.ByEmail,
+	)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, loginRequest("192.0.2.1:1234", "a@b.com"))
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+
+	form := url.Values{"email": {"A@b.com"}, "password": {"pw"}}
+	req := httptest.NewRequest(http.MethodPost, "/authorize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "192.0.2.2:1234"
+
+	handlerResponse = httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, req)
+	if handlerResponse.Code != http.StatusTooManyRequests {
+		test.Fatalf("expected %d, got %d", http.StatusTooManyRequests, handlerResponse.Code)
+	}
+
+	// the form is still there for the handler
+	form.Set("email", "c@d.com")
+	req = httptest.NewRequest(http.MethodPost, "/authorize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "192.0.2.3:1234"
+
+	handlerResponse = httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, req)
+	if handlerResponse.Code != http.StatusOK || gotEmail != "c@d.com" {
+		test.Fatalf("expected the handler to read the form, got %d %q", handlerResponse.Code, gotEmail)
+	}
+}
+
+func TestRateLimit_StoreFailureLetsRequestsThrough(test *testing.T) {
+	handler := authhttp.NewRateLimit(
+		failingRateLimitStore{},
+		"login",
+		ratelimit.Limit{Requests: 1, Period: time.Minute},
+		okHandler(),
+		authhttp.ByClientIP,
+	)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, loginRequest("192.0.2.1:1234", "a@b.com"))
+
+	if handlerResponse.Code != http.StatusNoContent {
+		test.Fatalf("expected %d, got %d", http.StatusNoContent, handlerResponse.Code)
+	}
+}
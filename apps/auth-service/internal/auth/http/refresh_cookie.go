@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net/http"
+	"time"
+)
+
+// paths the browser sends the refresh token cookie to; nowhere else
+var refreshCookiePaths = []string{"/refresh", "/logout", "/password/change"}
+
+// sent by mobile clients when the service runs in cookie mode for the browser:
+// with "body" they get and send the refresh token in the JSON body as before
+const refreshTransportHeader = "X-Refresh-Token-Transport"
+
+// RefreshCookie switches handlers from JSON-body transport of the refresh token
+// (mobile clients) to a Secure, HttpOnly cookie (browser UI).
+// Handlers given a nil *RefreshCookie keep using the JSON body.
+type RefreshCookie struct {
+	name     string
+	sameSite http.SameSite
+}
+
+func NewRefreshCookie(
+	name string,
+	sameSite http.SameSite,
+) *RefreshCookie {
+	return &RefreshCookie{
+		name:     name,
+		sameSite: sameSite,
+	}
+}
+
+// the cookie, or nil (JSON body) if there is none or the client asked for the body
+func (cookie *RefreshCookie) forRequest(request *http.Request) *RefreshCookie {
+	if cookie == nil || request.Header.Get(refreshTransportHeader) == "body" {
+		return nil
+	}
+	return cookie
+}
+
+// a cookie has a single path, so one cookie is set per endpoint that needs it;
+// it lives as long as the token, which depends on the session's profile (remember me)
+func (cookie *RefreshCookie) set(response http.ResponseWriter, token string, expiresAt time.Time) {
+	// at least a second: MaxAge 0 would leave the cookie without Max-Age
+	maxAge := max(int(time.Until(expiresAt).Seconds()), 1)
+	for _, path := range refreshCookiePaths {
+		http.SetCookie(response, cookie.build(path, token, maxAge))
+	}
+}
+
+func (cookie *RefreshCookie) clear(response http.ResponseWriter) {
+	for _, path := range refreshCookiePaths {
+		// negative MaxAge deletes the cookie immediately
+		http.SetCookie(response, cookie.build(path, "", -1))
+	}
+}
+
+func (cookie *RefreshCookie) read(request *http.Request) string {
+	stored, err := request.Cookie(cookie.name)
+	if err != nil {
+		return ""
+	}
+	return stored.Value
+}
+
+func (cookie *RefreshCookie) build(path string, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     cookie.name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: cookie.sameSite,
+	}
+}
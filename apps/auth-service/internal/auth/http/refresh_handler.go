@@ -5,9 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
-	"strings"
 	"time"
 
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
 )
 
@@ -16,21 +16,25 @@ type RefreshService interface {
 	Refresh(
 		ctx context.Context,
 		rawRefreshToken string,
-	) (accessToken string, refreshToken string, err error)
+	) (domain.IssuedTokens, error)
 }
 
 type RefreshHandler struct {
-	refreshSvc RefreshService
-	accessTTL  time.Duration
+	refreshSvc    RefreshService
+	accessTTL     time.Duration
+	refreshCookie *RefreshCookie
 }
 
+// refreshCookie == nil reads and returns the refresh token in the JSON body
 func NewRefreshHandler(
 	refreshSvc RefreshService,
 	accessTTL time.Duration,
+	refreshCookie *RefreshCookie,
 ) *RefreshHandler {
 	return &RefreshHandler{
-		refreshSvc: refreshSvc,
-		accessTTL:  accessTTL,
+		refreshSvc:    refreshSvc,
+		accessTTL:     accessTTL,
+		refreshCookie: refreshCookie,
 	}
 }
 
@@ -40,23 +44,20 @@ func (handler *RefreshHandler) ServeHTTP(response http.ResponseWriter, request *
 		return
 	}
 
-	var req refreshRequest
-	if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
+	rawRefreshToken, refreshCookie, ok := readRefreshToken(request, handler.refreshCookie)
+	if !ok {
 		http.Error(response, "invalid request", http.StatusBadRequest)
 		return
 	}
 
-	req.RefreshToken = strings.TrimSpace(req.RefreshToken)
-	if req.RefreshToken == "" {
-		http.Error(response, "invalid request", http.StatusBadRequest)
-		return
-	}
-
-	accessToken, newRefreshToken, err :=
-		handler.refreshSvc.Refresh(request.Context(), req.RefreshToken)
+	tokens, err := handler.refreshSvc.Refresh(request.Context(), rawRefreshToken)
 
 	if err != nil {
 		if errors.Is(err, errs.ErrInvalidRefreshToken) {
+			// stale cookie would only trigger the same error again
+			if refreshCookie != nil {
+				refreshCookie.clear(response)
+			}
 			http.Error(response, "invalid refresh token", http.StatusUnauthorized)
 			return
 		}
@@ -64,13 +65,18 @@ func (handler *RefreshHandler) ServeHTTP(response http.ResponseWriter, request *
 		return
 	}
 
-	resp := refreshResponse{
-		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
+	resp := tokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 		TokenType:    "Bearer",
 		ExpiresIn:    int64(handler.accessTTL.Seconds()),
 	}
 
+	if refreshCookie != nil {
+		refreshCookie.set(response, tokens.RefreshToken, tokens.RefreshExpiresAt)
+		resp.RefreshToken = ""
+	}
+
 	response.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(response).Encode(resp)
 }
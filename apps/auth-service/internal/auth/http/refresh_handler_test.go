@@ -10,20 +10,26 @@ import (
 	"time"
 
 	authhttp "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/http"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
 )
 
 type fakeRefreshService struct {
-	accessToken  string
-	refreshToken string
-	err          error
+	accessToken      string
+	refreshToken     string
+	refreshExpiresAt time.Time
+	err              error
 }
 
 func (f *fakeRefreshService) Refresh(
 	ctx context.Context,
 	rawRefreshToken string,
-) (string, string, error) {
-	return f.accessToken, f.refreshToken, f.err
+) (domain.IssuedTokens, error) {
+	return domain.IssuedTokens{
+		AccessToken:      f.accessToken,
+		RefreshToken:     f.refreshToken,
+		RefreshExpiresAt: f.refreshExpiresAt,
+	}, f.err
 }
 
 func TestRefreshHandler_Success(test *testing.T) {
@@ -32,7 +38,7 @@ func TestRefreshHandler_Success(test *testing.T) {
 		refreshToken: "new-refresh-token",
 	}
 
-	handler := authhttp.NewRefreshHandler(fakeSvc, 15*time.Minute)
+	handler := authhttp.NewRefreshHandler(fakeSvc, 15*time.Minute, nil)
 
 	body := []byte(`{"refresh_token":"old-refresh-token"}`)
 	req := httptest.NewRequest(http.MethodPost, "/refresh", bytes.NewReader(body))
@@ -68,7 +74,7 @@ func TestRefreshHandler_InvalidToken(test *testing.T) {
 		err: errs.ErrInvalidRefreshToken,
 	}
 
-	handler := authhttp.NewRefreshHandler(fakeSvc, 15*time.Minute)
+	handler := authhttp.NewRefreshHandler(fakeSvc, 15*time.Minute, nil)
 
 	body := []byte(`{"refresh_token":"bad-token"}`)
 	req := httptest.NewRequest(http.MethodPost, "/refresh", bytes.NewReader(body))
@@ -83,7 +89,7 @@ func TestRefreshHandler_InvalidToken(test *testing.T) {
 
 // 400
 func TestRefreshHandler_InvalidJSON(test *testing.T) {
-	handler := authhttp.NewRefreshHandler(&fakeRefreshService{}, 15*time.Minute)
+	handler := authhttp.NewRefreshHandler(&fakeRefreshService{}, 15*time.Minute, nil)
 
 	req := httptest.NewRequest(
 		http.MethodPost,
@@ -101,7 +107,7 @@ func TestRefreshHandler_InvalidJSON(test *testing.T) {
 
 // 400
 func TestRefreshHandler_MissingToken(test *testing.T) {
-	handler := authhttp.NewRefreshHandler(&fakeRefreshService{}, 15*time.Minute)
+	handler := authhttp.NewRefreshHandler(&fakeRefreshService{}, 15*time.Minute, nil)
 
 	req := httptest.NewRequest(
 		http.MethodPost,
@@ -116,3 +122,105 @@ func TestRefreshHandler_MissingToken(test *testing.T) {
 		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
 	}
 }
+
+func newTestRefreshCookie() *authhttp.RefreshCookie {
+	return authhttp.NewRefreshCookie("refresh_token", http.SameSiteStrictMode)
+}
+
+func TestRefreshHandler_CookieMode(test *testing.T) {
+	fakeSvc := &fakeRefreshService{
+		accessToken:      "new.jwt.token",
+		refreshToken:     "new-refresh-token",
+		refreshExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+
+	handler := authhttp.NewRefreshHandler(fakeSvc, 15*time.Minute, newTestRefreshCookie())
+
+	req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "old-refresh-token"})
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+
+	var resp map[string]interface{}
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid JSON response")
+	}
+
+	if _, found := resp["refresh_token"]; found {
+		test.Fatalf("refresh token must not be in the body in cookie mode")
+	}
+
+	cookies := handlerResponse.Result().Cookies()
+	if len(cookies) != 3 {
+		test.Fatalf("expected cookies for /refresh, /logout and /password/change, got %d", len(cookies))
+	}
+
+	for _, cookie := range cookies {
+		if cookie.Value != "new-refresh-token" {
+			test.Fatalf("unexpected cookie value %q", cookie.Value)
+		}
+		if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode {
+			test.Fatalf("cookie must be Secure, HttpOnly and SameSite: %+v", cookie)
+		}
+		if cookie.Path != "/refresh" && cookie.Path != "/logout" && cookie.Path != "/password/change" {
+			test.Fatalf("unexpected cookie path %q", cookie.Path)
+		}
+		// as long as the token, not a fixed TTL
+		if cookie.MaxAge < 24*60*60-5 || cookie.MaxAge > 24*60*60 {
+			test.Fatalf("expected the cookie to expire with the token in 24h, got Max-Age %d", cookie.MaxAge)
+		}
+	}
+}
+
+// 400
+func TestRefreshHandler_CookieMode_MissingCookie(test *testing.T) {
+	handler := authhttp.NewRefreshHandler(&fakeRefreshService{}, 15*time.Minute, newTestRefreshCookie())
+
+	// neither cookie nor body
+	req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+// mobile clients keep using the body when the browser UI uses cookies
+func TestRefreshHandler_CookieMode_BodyFallback(test *testing.T) {
+	fakeSvc := &fakeRefreshService{
+		accessToken:  "new.jwt.token",
+		refreshToken: "new-refresh-token",
+	}
+
+	handler := authhttp.NewRefreshHandler(fakeSvc, 15*time.Minute, newTestRefreshCookie())
+
+	body := []byte(`{"refresh_token":"old-refresh-token"}`)
+	req := httptest.NewRequest(http.MethodPost, "/refresh", bytes.NewReader(body))
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+
+	var resp map[string]interface{}
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid JSON response")
+	}
+
+	// answered the way it was asked
+	if resp["refresh_token"] != "new-refresh-token" {
+		test.Fatalf("expected the new refresh token in the body, got %v", resp["refresh_token"])
+	}
+	if len(handlerResponse.Result().Cookies()) != 0 {
+		test.Fatal("expected no cookie for a body client")
+	}
+}
@@ -1,5 +1,30 @@
 package http
 
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
 type refreshRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
+
+// refresh token comes from the cookie in cookie mode, from the JSON body otherwise;
+// mobile clients have no cookie and keep sending it in the body in cookie mode too.
+// The returned cookie is how to answer: nil for a token that came in the body
+func readRefreshToken(request *http.Request, cookie *RefreshCookie) (string, *RefreshCookie, bool) {
+	if cookie = cookie.forRequest(request); cookie != nil {
+		if token := cookie.read(request); token != "" {
+			return token, cookie, true
+		}
+	}
+
+	var req refreshRequest
+	if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
+		return "", nil, false
+	}
+
+	req.RefreshToken = strings.TrimSpace(req.RefreshToken)
+	return req.RefreshToken, nil, req.RefreshToken != ""
+}
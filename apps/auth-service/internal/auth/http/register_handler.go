@@ -55,6 +55,10 @@ func (handler *RegisterHandler) ServeHTTP(response http.ResponseWriter, request
 }
 
 func (handler *RegisterHandler) handleError(response http.ResponseWriter, err error) {
+	if writeWeakPassword(response, err) {
+		return
+	}
+
 	switch {
 	case errors.Is(err, errs.ErrUserAlreadyExists):
 		http.Error(response, "user already exists", http.StatusConflict)
@@ -3,12 +3,14 @@ package http_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	authhttp "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/http"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
 )
 
@@ -118,3 +120,36 @@ func TestRegisterHandler_MethodNotAllowed(test *testing.T) {
 		test.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, handlerResponse.Code)
 	}
 }
+
+func TestRegisterHandler_WeakPassword(test *testing.T) {
+	handler := authhttp.NewRegisterHandler(&fakeRegistrationService{
+		err: &password.PolicyError{Violations: []password.Violation{
+			{Rule: password.RuleMinLength, Message: "too short"},
+			{Rule: password.RuleBreached, Message: "breached"},
+		}},
+	})
+
+	body := []byte(`{"email":"a@b.com","password":"secret"}`)
+	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewReader(body))
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusUnprocessableEntity {
+		test.Fatalf("expected %d, got %d", http.StatusUnprocessableEntity, handlerResponse.Code)
+	}
+
+	var respBody struct {
+		Error      string               `json:"error"`
+		Violations []password.Violation `json:"violations"`
+	}
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&respBody); err != nil {
+		test.Fatalf("invalid JSON response: %v", err)
+	}
+	if respBody.Error != "weak_password" || len(respBody.Violations) != 2 {
+		test.Fatalf("unexpected response %+v", respBody)
+	}
+	if respBody.Violations[0].Rule != password.RuleMinLength {
+		test.Fatalf("unexpected first violation %+v", respBody.Violations[0])
+	}
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"context"
+	"net/http"
+)
+
+// Service interface expected by handler
+type RevocationService interface {
+	Revoke(ctx context.Context, token string, tokenTypeHint string) error
+}
+
+// RFC 7009 token revocation, so standard OAuth client libraries can sign users out.
+// Possession of the refresh token is what authorizes revoking it.
+type RevokeHandler struct {
+	revocationSvc RevocationService
+}
+
+func NewRevokeHandler(revocationSvc RevocationService) *RevokeHandler {
+	return &RevokeHandler{
+		revocationSvc: revocationSvc,
+	}
+}
+
+func (handler *RevokeHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// RFC 7009 requests are form-encoded
+	token := request.PostFormValue("token")
+	if token == "" {
+		writeOAuthError(response, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	err := handler.revocationSvc.Revoke(
+		request.Context(),
+		token,
+		request.PostFormValue("token_type_hint"),
+	)
+	if err != nil {
+		// the client may retry later (RFC 7009, section 2.2.1)
+		writeOAuthError(response, http.StatusServiceUnavailable, "temporarily_unavailable")
+		return
+	}
+
+	// unknown, invalid and already revoked tokens also get 200:
+	// the token is no longer usable either way
+	response.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type fakeRevocationService struct {
+	err      error
+	gotToken string
+	gotHint  string
+}
+
+func (f *fakeRevocationService) Revoke(ctx context.Context, token string, tokenTypeHint string) error {
+	f.gotToken = token
+	f.gotHint = tokenTypeHint
+	return f.err
+}
+
+func newRevokeRequest(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/revoke", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestRevokeHandler_Success(test *testing.T) {
+	fakeSvc := &fakeRevocationService{}
+	handler := NewRevokeHandler(fakeSvc)
+
+	req := newRevokeRequest(url.Values{
+		"token":           {"some-token"},
+		"token_type_hint": {"refresh_token"},
+	})
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if fakeSvc.gotToken != "some-token" || fakeSvc.gotHint != "refresh_token" {
+		test.Fatalf("unexpected service input %q %q", fakeSvc.gotToken, fakeSvc.gotHint)
+	}
+}
+
+func TestRevokeHandler_MissingToken(test *testing.T) {
+	handler := NewRevokeHandler(&fakeRevocationService{})
+
+	req := newRevokeRequest(url.Values{"token_type_hint": {"refresh_token"}})
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+
+	var resp oauthErrorResponse
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if resp.Error != "invalid_request" {
+		test.Fatalf("expected invalid_request, got %q", resp.Error)
+	}
+}
+
+func TestRevokeHandler_ServiceFailure(test *testing.T) {
+	handler := NewRevokeHandler(&fakeRevocationService{err: errors.New("db unavailable")})
+
+	req := newRevokeRequest(url.Values{"token": {"some-token"}})
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusServiceUnavailable {
+		test.Fatalf("expected %d, got %d", http.StatusServiceUnavailable, handlerResponse.Code)
+	}
+}
+
+func TestRevokeHandler_MethodNotAllowed(test *testing.T) {
+	handler := NewRevokeHandler(&fakeRevocationService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/revoke", nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusMethodNotAllowed {
+		test.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, handlerResponse.Code)
+	}
+}
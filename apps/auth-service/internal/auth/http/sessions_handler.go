@@ -0,0 +1,170 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// Service interface expected by handlers
+type SessionService interface {
+	List(ctx context.Context, accessToken string) ([]domain.Session, error)
+	Revoke(ctx context.Context, accessToken string, sessionID string) error
+	RevokeOthers(ctx context.Context, accessToken string) error
+}
+
+type sessionResponse struct {
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	AMR         []string  `json:"amr"`
+	DeviceLabel string    `json:"device_label"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+	CreatedIP   string    `json:"created_ip,omitempty"`
+	LastUsedIP  string    `json:"last_used_ip,omitempty"`
+	Current     bool      `json:"current"`
+}
+
+type sessionsResponse struct {
+	Sessions []sessionResponse `json:"sessions"`
+}
+
+// lists where the caller is logged in
+type SessionsHandler struct {
+	sessionSvc SessionService
+}
+
+func NewSessionsHandler(sessionSvc SessionService) *SessionsHandler {
+	return &SessionsHandler{
+		sessionSvc: sessionSvc,
+	}
+}
+
+func (handler *SessionsHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accessToken, ok := bearerToken(request)
+	if !ok {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := handler.sessionSvc.List(request.Context(), accessToken)
+	if err != nil {
+		writeSessionError(response, err)
+		return
+	}
+
+	respBody := sessionsResponse{Sessions: []sessionResponse{}}
+	for _, session := range sessions {
+		respBody.Sessions = append(respBody.Sessions, sessionResponse{
+			ID:          session.ID,
+			CreatedAt:   session.CreatedAt.UTC(),
+			LastUsedAt:  session.LastUsedAt.UTC(),
+			ExpiresAt:   session.ExpiresAt.UTC(),
+			AMR:         session.AMR,
+			DeviceLabel: session.DeviceLabel,
+			UserAgent:   session.UserAgent,
+			CreatedIP:   session.CreatedIP,
+			LastUsedIP:  session.LastUsedIP,
+			Current:     session.Current,
+		})
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(response).Encode(respBody)
+}
+
+// ends one session of the caller; registered as "/sessions/{id}"
+type RevokeSessionHandler struct {
+	sessionSvc SessionService
+}
+
+func NewRevokeSessionHandler(sessionSvc SessionService) *RevokeSessionHandler {
+	return &RevokeSessionHandler{
+		sessionSvc: sessionSvc,
+	}
+}
+
+func (handler *RevokeSessionHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodDelete {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accessToken, ok := bearerToken(request)
+	if !ok {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := request.PathValue("id")
+	if sessionID == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := handler.sessionSvc.Revoke(request.Context(), accessToken, sessionID); err != nil {
+		writeSessionError(response, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// ends every session of the caller but the current one
+type RevokeOtherSessionsHandler struct {
+	sessionSvc SessionService
+}
+
+func NewRevokeOtherSessionsHandler(sessionSvc SessionService) *RevokeOtherSessionsHandler {
+	return &RevokeOtherSessionsHandler{
+		sessionSvc: sessionSvc,
+	}
+}
+
+func (handler *RevokeOtherSessionsHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accessToken, ok := bearerToken(request)
+	if !ok {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := handler.sessionSvc.RevokeOthers(request.Context(), accessToken); err != nil {
+		writeSessionError(response, err)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+func writeSessionError(response http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errs.ErrInvalidAccessToken):
+		response.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+	case errors.Is(err, errs.ErrNotFound):
+		// also for sessions of other users, their IDs are not revealed
+		http.Error(response, "session not found", http.StatusNotFound)
+	default:
+		http.Error(response, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,212 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+type fakeSessionService struct {
+	sessions []domain.Session
+	err      error
+
+	gotToken     string
+	gotSessionID string
+	othersCalled bool
+}
+
+func (f *fakeSessionService) List(ctx context.Context, accessToken string) ([]domain.Session, error) {
+	f.gotToken = accessToken
+	return f.sessions, f.err
+}
+
+func (f *fakeSessionService) Revoke(ctx context.Context, accessToken string, sessionID string) error {
+	f.gotToken = accessToken
+	f.gotSessionID = sessionID
+	return f.err
+}
+
+func (f *fakeSessionService) RevokeOthers(ctx context.Context, accessToken string) error {
+	f.gotToken = accessToken
+	f.othersCalled = true
+	return f.err
+}
+
+// routes as registered in main, so path values are set
+func newSessionsMux(sessionSvc SessionService) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/sessions", NewSessionsHandler(sessionSvc))
+	mux.Handle("/sessions/revoke-others", NewRevokeOtherSessionsHandler(sessionSvc))
+	mux.Handle("/sessions/{id}", NewRevokeSessionHandler(sessionSvc))
+	return mux
+}
+
+func newSessionsRequest(method string, target string, authorization string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return req
+}
+
+func TestSessionsHandler_List(test *testing.T) {
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	fakeSvc := &fakeSessionService{
+		sessions: []domain.Session{
+			{
+				ID:          "s1",
+				CreatedAt:   createdAt,
+				LastUsedAt:  createdAt.Add(time.Hour),
+				ExpiresAt:   createdAt.Add(24 * time.Hour),
+				AMR:         []string{"pwd"},
+				DeviceLabel: "Firefox on Linux",
+				UserAgent:   "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0",
+				LastUsedIP:  "203.0.113.7",
+				Current:     true,
+			},
+		},
+	}
+
+	handlerResponse := httptest.NewRecorder()
+	newSessionsMux(fakeSvc).ServeHTTP(
+		handlerResponse,
+		newSessionsRequest(http.MethodGet, "/sessions", "Bearer access.jwt.token"),
+	)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if fakeSvc.gotToken != "access.jwt.token" {
+		test.Fatalf("expected bearer token to be passed, got %q", fakeSvc.gotToken)
+	}
+
+	var resp sessionsResponse
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if len(resp.Sessions) != 1 {
+		test.Fatalf("expected 1 session, got %+v", resp.Sessions)
+	}
+
+	session := resp.Sessions[0]
+	if session.ID != "s1" || !session.Current || session.DeviceLabel != "Firefox on Linux" || session.LastUsedIP != "203.0.113.7" {
+		test.Fatalf("unexpected session %+v", session)
+	}
+	if !session.CreatedAt.Equal(createdAt) || !session.LastUsedAt.Equal(createdAt.Add(time.Hour)) {
+		test.Fatalf("unexpected session times %+v", session)
+	}
+}
+
+func TestSessionsHandler_List_Empty(test *testing.T) {
+	handlerResponse := httptest.NewRecorder()
+	newSessionsMux(&fakeSessionService{}).ServeHTTP(
+		handlerResponse,
+		newSessionsRequest(http.MethodGet, "/sessions", "Bearer access.jwt.token"),
+	)
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+
+	var resp map[string]json.RawMessage
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if string(resp["sessions"]) != "[]" {
+		test.Fatalf("expected an empty list, got %s", resp["sessions"])
+	}
+}
+
+func TestSessionsHandler_MissingToken(test *testing.T) {
+	handlerResponse := httptest.NewRecorder()
+	newSessionsMux(&fakeSessionService{}).ServeHTTP(
+		handlerResponse,
+		newSessionsRequest(http.MethodGet, "/sessions", ""),
+	)
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+	if handlerResponse.Header().Get("WWW-Authenticate") != "Bearer" {
+		test.Fatalf("expected Bearer challenge, got %q", handlerResponse.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestSessionsHandler_InvalidToken(test *testing.T) {
+	handlerResponse := httptest.NewRecorder()
+	newSessionsMux(&fakeSessionService{err: errs.ErrInvalidAccessToken}).ServeHTTP(
+		handlerResponse,
+		newSessionsRequest(http.MethodGet, "/sessions", "Bearer expired.jwt.token"),
+	)
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+	if handlerResponse.Header().Get("WWW-Authenticate") != `Bearer error="invalid_token"` {
+		test.Fatalf("expected invalid_token challenge, got %q", handlerResponse.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestRevokeSessionHandler_Success(test *testing.T) {
+	fakeSvc := &fakeSessionService{}
+
+	handlerResponse := httptest.NewRecorder()
+	newSessionsMux(fakeSvc).ServeHTTP(
+		handlerResponse,
+		newSessionsRequest(http.MethodDelete, "/sessions/s2", "Bearer access.jwt.token"),
+	)
+
+	if handlerResponse.Code != http.StatusNoContent {
+		test.Fatalf("expected %d, got %d", http.StatusNoContent, handlerResponse.Code)
+	}
+	if fakeSvc.gotSessionID != "s2" {
+		test.Fatalf("expected session s2, got %q", fakeSvc.gotSessionID)
+	}
+}
+
+func TestRevokeSessionHandler_NotFound(test *testing.T) {
+	handlerResponse := httptest.NewRecorder()
+	newSessionsMux(&fakeSessionService{err: errs.ErrNotFound}).ServeHTTP(
+		handlerResponse,
+		newSessionsRequest(http.MethodDelete, "/sessions/unknown", "Bearer access.jwt.token"),
+	)
+
+	if handlerResponse.Code != http.StatusNotFound {
+		test.Fatalf("expected %d, got %d", http.StatusNotFound, handlerResponse.Code)
+	}
+}
+
+func TestRevokeSessionHandler_MethodNotAllowed(test *testing.T) {
+	handlerResponse := httptest.NewRecorder()
+	newSessionsMux(&fakeSessionService{}).ServeHTTP(
+		handlerResponse,
+		newSessionsRequest(http.MethodGet, "/sessions/s2", "Bearer access.jwt.token"),
+	)
+
+	if handlerResponse.Code != http.StatusMethodNotAllowed {
+		test.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, handlerResponse.Code)
+	}
+}
+
+func TestRevokeOtherSessionsHandler_Success(test *testing.T) {
+	fakeSvc := &fakeSessionService{}
+
+	handlerResponse := httptest.NewRecorder()
+	newSessionsMux(fakeSvc).ServeHTTP(
+		handlerResponse,
+		newSessionsRequest(http.MethodPost, "/sessions/revoke-others", "Bearer access.jwt.token"),
+	)
+
+	if handlerResponse.Code != http.StatusNoContent {
+		test.Fatalf("expected %d, got %d", http.StatusNoContent, handlerResponse.Code)
+	}
+	if !fakeSvc.othersCalled || fakeSvc.gotSessionID != "" {
+		test.Fatal("expected the other sessions to be revoked, not a single one")
+	}
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// Service interface expected by handler
+type CodeExchangeService interface {
+	Exchange(ctx context.Context, exchange domain.CodeExchange) (domain.IssuedTokens, error)
+}
+
+// OAuth 2.0 token endpoint: authorization_code (with PKCE) and refresh_token grants.
+// Clients are public, so there is no client authentication.
+type TokenHandler struct {
+	exchangeSvc CodeExchangeService
+	refreshSvc  RefreshService
+	accessTTL   time.Duration
+}
+
+func NewTokenHandler(
+	exchangeSvc CodeExchangeService,
+	refreshSvc RefreshService,
+	accessTTL time.Duration,
+) *TokenHandler {
+	return &TokenHandler{
+		exchangeSvc: exchangeSvc,
+		refreshSvc:  refreshSvc,
+		accessTTL:   accessTTL,
+	}
+}
+
+func (handler *TokenHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var (
+		tokens domain.IssuedTokens
+		err    error
+	)
+
+	switch request.PostFormValue("grant_type") {
+	case "authorization_code":
+		exchange := domain.CodeExchange{
+			Code:         request.PostFormValue("code"),
+			ClientID:     request.PostFormValue("client_id"),
+			RedirectURI:  request.PostFormValue("redirect_uri"),
+			CodeVerifier: request.PostFormValue("code_verifier"),
+		}
+		if exchange.Code == "" || exchange.ClientID == "" || exchange.RedirectURI == "" || exchange.CodeVerifier == "" {
+			writeOAuthError(response, http.StatusBadRequest, "invalid_request")
+			return
+		}
+		tokens, err = handler.exchangeSvc.Exchange(request.Context(), exchange)
+
+	case "refresh_token":
+		refreshToken := request.PostFormValue("refresh_token")
+		if refreshToken == "" {
+			writeOAuthError(response, http.StatusBadRequest, "invalid_request")
+			return
+		}
+		tokens, err = handler.refreshSvc.Refresh(request.Context(), refreshToken)
+
+	case "":
+		writeOAuthError(response, http.StatusBadRequest, "invalid_request")
+		return
+
+	default:
+		writeOAuthError(response, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	if err != nil {
+		if errors.Is(err, errs.ErrInvalidGrant) || errors.Is(err, errs.ErrInvalidRefreshToken) {
+			writeOAuthError(response, http.StatusBadRequest, "invalid_grant")
+			return
+		}
+		writeOAuthError(response, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	respBody := tokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      tokens.IDToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(handler.accessTTL.Seconds()),
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(response).Encode(respBody)
+}
@@ -0,0 +1,163 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+type fakeCodeExchangeService struct {
+	tokens      domain.IssuedTokens
+	err         error
+	gotExchange domain.CodeExchange
+}
+
+func (f *fakeCodeExchangeService) Exchange(ctx context.Context, exchange domain.CodeExchange) (domain.IssuedTokens, error) {
+	f.gotExchange = exchange
+	return f.tokens, f.err
+}
+
+type fakeTokenRefreshService struct {
+	accessToken  string
+	refreshToken string
+	err          error
+}
+
+func (f *fakeTokenRefreshService) Refresh(ctx context.Context, rawRefreshToken string) (domain.IssuedTokens, error) {
+	return domain.IssuedTokens{AccessToken: f.accessToken, RefreshToken: f.refreshToken}, f.err
+}
+
+func newTokenRequest(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func codeGrantForm() url.Values {
+	return url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {"raw-code"},
+		"client_id":     {"wiki"},
+		"redirect_uri":  {"https://wiki.family.example/callback"},
+		"code_verifier": {"dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"},
+	}
+}
+
+func decodeOAuthError(test *testing.T, handlerResponse *httptest.ResponseRecorder) string {
+	test.Helper()
+
+	var resp oauthErrorResponse
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	return resp.Error
+}
+
+func TestTokenHandler_AuthorizationCodeGrant(test *testing.T) {
+	exchangeSvc := &fakeCodeExchangeService{
+		tokens: domain.IssuedTokens{AccessToken: "access", RefreshToken: "refresh", IDToken: "id"},
+	}
+	handler := NewTokenHandler(exchangeSvc, &fakeTokenRefreshService{}, 15*time.Minute)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newTokenRequest(codeGrantForm()))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if exchangeSvc.gotExchange.Code != "raw-code" || exchangeSvc.gotExchange.ClientID != "wiki" {
+		test.Fatalf("unexpected exchange %+v", exchangeSvc.gotExchange)
+	}
+	if handlerResponse.Header().Get("Cache-Control") != "no-store" {
+		test.Fatalf("expected Cache-Control no-store")
+	}
+
+	var resp tokenResponse
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if resp.AccessToken != "access" || resp.RefreshToken != "refresh" || resp.IDToken != "id" || resp.ExpiresIn != 900 {
+		test.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestTokenHandler_RefreshTokenGrant(test *testing.T) {
+	refreshSvc := &fakeTokenRefreshService{accessToken: "new-access", refreshToken: "new-refresh"}
+	handler := NewTokenHandler(&fakeCodeExchangeService{}, refreshSvc, 15*time.Minute)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newTokenRequest(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {"old-refresh"},
+	}))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+
+	var resp tokenResponse
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if resp.AccessToken != "new-access" || resp.RefreshToken != "new-refresh" {
+		test.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestTokenHandler_InvalidGrant(test *testing.T) {
+	handler := NewTokenHandler(&fakeCodeExchangeService{err: errs.ErrInvalidGrant}, &fakeTokenRefreshService{}, time.Minute)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newTokenRequest(codeGrantForm()))
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+	if code := decodeOAuthError(test, handlerResponse); code != "invalid_grant" {
+		test.Fatalf("expected invalid_grant, got %q", code)
+	}
+}
+
+func TestTokenHandler_MissingCodeVerifier(test *testing.T) {
+	handler := NewTokenHandler(&fakeCodeExchangeService{}, &fakeTokenRefreshService{}, time.Minute)
+
+	form := codeGrantForm()
+	form.Del("code_verifier")
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newTokenRequest(form))
+
+	if code := decodeOAuthError(test, handlerResponse); code != "invalid_request" {
+		test.Fatalf("expected invalid_request, got %q", code)
+	}
+}
+
+func TestTokenHandler_UnsupportedGrantType(test *testing.T) {
+	handler := NewTokenHandler(&fakeCodeExchangeService{}, &fakeTokenRefreshService{}, time.Minute)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newTokenRequest(url.Values{"grant_type": {"password"}}))
+
+	if code := decodeOAuthError(test, handlerResponse); code != "unsupported_grant_type" {
+		test.Fatalf("expected unsupported_grant_type, got %q", code)
+	}
+}
+
+func TestTokenHandler_ServiceFailure(test *testing.T) {
+	handler := NewTokenHandler(&fakeCodeExchangeService{err: errors.New("db unavailable")}, &fakeTokenRefreshService{}, time.Minute)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newTokenRequest(codeGrantForm()))
+
+	if handlerResponse.Code != http.StatusInternalServerError {
+		test.Fatalf("expected %d, got %d", http.StatusInternalServerError, handlerResponse.Code)
+	}
+}
@@ -0,0 +1,12 @@
+package http
+
+// token pair returned by /login and /refresh;
+// refresh token is omitted when it is sent as a cookie,
+// ID token unless an OpenID Connect client asked for one
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
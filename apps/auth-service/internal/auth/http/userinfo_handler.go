@@ -0,0 +1,86 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// Service interface expected by handler
+type UserInfoService interface {
+	UserInfo(ctx context.Context, accessToken string) (domain.UserInfo, error)
+}
+
+// standard OpenID Connect claims plus our family claims
+type userInfoResponse struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	FamilyID      string `json:"family_id"`
+	Role          string `json:"role"`
+}
+
+type UserInfoHandler struct {
+	userInfoSvc UserInfoService
+}
+
+func NewUserInfoHandler(userInfoSvc UserInfoService) *UserInfoHandler {
+	return &UserInfoHandler{
+		userInfoSvc: userInfoSvc,
+	}
+}
+
+// GET and POST are both allowed (OpenID Connect Core, section 5.3.1)
+func (handler *UserInfoHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet && request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accessToken, ok := bearerToken(request)
+	if !ok {
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	info, err := handler.userInfoSvc.UserInfo(request.Context(), accessToken)
+	if err != nil {
+		if errors.Is(err, errs.ErrInvalidAccessToken) {
+			// RFC 6750: tells the client to refresh or log in again
+			response.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(response, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.Error(response, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	respBody := userInfoResponse{
+		Subject:       info.Subject,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+		FamilyID:      info.FamilyID,
+		Role:          info.Role,
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(response).Encode(respBody)
+}
+
+// access token from "Authorization: Bearer <token>" (RFC 6750)
+func bearerToken(request *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(request.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+type fakeUserInfoService struct {
+	info     domain.UserInfo
+	err      error
+	gotToken string
+}
+
+func (f *fakeUserInfoService) UserInfo(ctx context.Context, accessToken string) (domain.UserInfo, error) {
+	f.gotToken = accessToken
+	return f.info, f.err
+}
+
+func newUserInfoRequest(authorization string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/userinfo", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return req
+}
+
+func TestUserInfoHandler_Success(test *testing.T) {
+	fakeSvc := &fakeUserInfoService{
+		info: domain.UserInfo{Subject: "user-1", Email: "a@b.com", EmailVerified: true, FamilyID: "family-1", Role: "owner"},
+	}
+	handler := NewUserInfoHandler(fakeSvc)
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newUserInfoRequest("Bearer access.jwt.token"))
+
+	if handlerResponse.Code != http.StatusOK {
+		test.Fatalf("expected %d, got %d", http.StatusOK, handlerResponse.Code)
+	}
+	if fakeSvc.gotToken != "access.jwt.token" {
+		test.Fatalf("expected bearer token to be passed, got %q", fakeSvc.gotToken)
+	}
+
+	var resp userInfoResponse
+	if err := json.NewDecoder(handlerResponse.Body).Decode(&resp); err != nil {
+		test.Fatalf("invalid json response: %v", err)
+	}
+	if resp.Subject != "user-1" || resp.Email != "a@b.com" || resp.FamilyID != "family-1" || resp.Role != "owner" {
+		test.Fatalf("unexpected response %+v", resp)
+	}
+	if !resp.EmailVerified {
+		test.Fatalf("expected email_verified, got %+v", resp)
+	}
+}
+
+func TestUserInfoHandler_MissingToken(test *testing.T) {
+	handler := NewUserInfoHandler(&fakeUserInfoService{})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newUserInfoRequest(""))
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+	if handlerResponse.Header().Get("WWW-Authenticate") != "Bearer" {
+		test.Fatalf("expected Bearer challenge, got %q", handlerResponse.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestUserInfoHandler_InvalidToken(test *testing.T) {
+	handler := NewUserInfoHandler(&fakeUserInfoService{err: errs.ErrInvalidAccessToken})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newUserInfoRequest("Bearer expired.jwt.token"))
+
+	if handlerResponse.Code != http.StatusUnauthorized {
+		test.Fatalf("expected %d, got %d", http.StatusUnauthorized, handlerResponse.Code)
+	}
+	if handlerResponse.Header().Get("WWW-Authenticate") != `Bearer error="invalid_token"` {
+		test.Fatalf("expected invalid_token challenge, got %q", handlerResponse.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestUserInfoHandler_ServiceFailure(test *testing.T) {
+	handler := NewUserInfoHandler(&fakeUserInfoService{err: errors.New("db unavailable")})
+
+	handlerResponse := httptest.NewRecorder()
+	handler.ServeHTTP(handlerResponse, newUserInfoRequest("Bearer access.jwt.token"))
+
+	if handlerResponse.Code != http.StatusInternalServerError {
+		test.Fatalf("expected %d, got %d", http.StatusInternalServerError, handlerResponse.Code)
+	}
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// Service interface expected by handlers
+type EmailVerificationService interface {
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerificationEmail(ctx context.Context, email string) error
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type resendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// marks the address verified with the token from the emailed link
+type VerifyEmailHandler struct {
+	verificationSvc EmailVerificationService
+}
+
+func NewVerifyEmailHandler(verificationSvc EmailVerificationService) *VerifyEmailHandler {
+	return &VerifyEmailHandler{
+		verificationSvc: verificationSvc,
+	}
+}
+
+func (handler *VerifyEmailHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reqBody verifyEmailRequest
+	if err := json.NewDecoder(request.Body).Decode(&reqBody); err != nil || reqBody.Token == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err := handler.verificationSvc.VerifyEmail(request.Context(), reqBody.Token)
+	if err != nil {
+		if errors.Is(err, errs.ErrInvalidVerificationToken) {
+			http.Error(response, "invalid or expired token", http.StatusBadRequest)
+			return
+		}
+		http.Error(response, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// sends a new link; always 202 so the endpoint cannot be used
+// to find out which addresses are registered
+type ResendVerificationHandler struct {
+	verificationSvc EmailVerificationService
+}
+
+func NewResendVerificationHandler(verificationSvc EmailVerificationService) *ResendVerificationHandler {
+	return &ResendVerificationHandler{
+		verificationSvc: verificationSvc,
+	}
+}
+
+func (handler *ResendVerificationHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var reqBody resendVerificationRequest
+	if err := json.NewDecoder(request.Body).Decode(&reqBody); err != nil {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	reqBody.Email = strings.TrimSpace(reqBody.Email)
+	if reqBody.Email == "" {
+		http.Error(response, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err := handler.verificationSvc.ResendVerificationEmail(request.Context(), reqBody.Email)
+	if err != nil {
+		log.Printf("resending verification email failed: %v", err)
+	}
+
+	response.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,108 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authhttp "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/http"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+type fakeEmailVerificationService struct {
+	err      error
+	gotToken string
+	resentTo string
+}
+
+func (svc *fakeEmailVerificationService) VerifyEmail(ctx context.Context, token string) error {
+	svc.gotToken = token
+	return svc.err
+}
+
+func (svc *fakeEmailVerificationService) ResendVerificationEmail(ctx context.Context, email string) error {
+	svc.resentTo = email
+	return svc.err
+}
+
+func TestVerifyEmailHandler_Success(test *testing.T) {
+	svc := &fakeEmailVerificationService{}
+	handler := authhttp.NewVerifyEmailHandler(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/verify-email", bytes.NewReader([]byte(`{"token":"tok"}`)))
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusNoContent {
+		test.Fatalf("expected %d, got %d", http.StatusNoContent, handlerResponse.Code)
+	}
+	if svc.gotToken != "tok" {
+		test.Fatalf("expected token 'tok', got '%s'", svc.gotToken)
+	}
+}
+
+func TestVerifyEmailHandler_InvalidToken(test *testing.T) {
+	handler := authhttp.NewVerifyEmailHandler(&fakeEmailVerificationService{
+		err: errs.ErrInvalidVerificationToken,
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/verify-email", bytes.NewReader([]byte(`{"token":"tok"}`)))
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+func TestVerifyEmailHandler_MissingToken(test *testing.T) {
+	handler := authhttp.NewVerifyEmailHandler(&fakeEmailVerificationService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/verify-email", bytes.NewReader([]byte(`{}`)))
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusBadRequest {
+		test.Fatalf("expected %d, got %d", http.StatusBadRequest, handlerResponse.Code)
+	}
+}
+
+func TestResendVerificationHandler_AlwaysAccepted(test *testing.T) {
+	svc := &fakeEmailVerificationService{err: errors.New("smtp relay down")}
+	handler := authhttp.NewResendVerificationHandler(svc)
+
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/verify-email/resend",
+		bytes.NewReader([]byte(`{"email":" a@b.com "}`)),
+	)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusAccepted {
+		test.Fatalf("expected %d, got %d", http.StatusAccepted, handlerResponse.Code)
+	}
+	if svc.resentTo != "a@b.com" {
+		test.Fatalf("expected trimmed email, got '%s'", svc.resentTo)
+	}
+}
+
+func TestResendVerificationHandler_MethodNotAllowed(test *testing.T) {
+	handler := authhttp.NewResendVerificationHandler(&fakeEmailVerificationService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/verify-email/resend", nil)
+	handlerResponse := httptest.NewRecorder()
+
+	handler.ServeHTTP(handlerResponse, req)
+
+	if handlerResponse.Code != http.StatusMethodNotAllowed {
+		test.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, handlerResponse.Code)
+	}
+}
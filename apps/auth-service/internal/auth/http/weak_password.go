@@ -0,0 +1,32 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
+)
+
+// lists every rule the password failed, so a form can show them all at once
+type weakPasswordResponse struct {
+	Error      string               `json:"error"`
+	Violations []password.Violation `json:"violations"`
+}
+
+// writes 422 with the violations if err is a *password.PolicyError;
+// false for any other error
+func writeWeakPassword(response http.ResponseWriter, err error) bool {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(response).Encode(weakPasswordResponse{
+		Error:      "weak_password",
+		Violations: policyErr.Violations,
+	})
+	return true
+}
@@ -1,10 +1,45 @@
 package jwt
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
 
 type Claims struct {
 	jwt.RegisteredClaims
 
 	FamilyID string `json:"family_id"`
 	Role     string `json:"role"`
+	// authentication methods used at login (RFC 8176), e.g. ["pwd", "otp", "mfa"]
+	AMR []string `json:"amr,omitempty"`
+	// refresh token session the token was issued for (OpenID Connect sid)
+	SessionID string `json:"sid,omitempty"`
+}
+
+// same claims regardless of the signing algorithm
+func newAccessClaims(
+	issuer string,
+	audience string,
+	ttl time.Duration,
+	user User,
+	membership Membership,
+	amr []string,
+	sessionID string,
+) Claims {
+	now := time.Now()
+
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Audience:  []string{audience},
+			Subject:   user.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		FamilyID:  membership.FamilyID,
+		Role:      membership.Role,
+		AMR:       amr,
+		SessionID: sessionID,
+	}
 }
@@ -0,0 +1,86 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// audience of email verification tokens: never accepted where an access token is expected
+const emailVerificationAudience = "family-space-email-verification"
+
+type emailVerificationClaims struct {
+	jwt.RegisteredClaims
+
+	Email string `json:"email"`
+}
+
+// EmailVerificationTokens issues and verifies the token in the link of a
+// verification email. Stateless: the token names the user and the address,
+// so a link stops working once the address changes.
+type EmailVerificationTokens struct {
+	keys   *KeyRing
+	method jwt.SigningMethod
+	issuer string
+	ttl    time.Duration
+}
+
+// the active key of the ring must match alg
+func NewEmailVerificationTokens(
+	alg string,
+	keys *KeyRing,
+	issuer string,
+	ttl time.Duration,
+) (*EmailVerificationTokens, error) {
+	method, err := signingMethod(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EmailVerificationTokens{
+		keys:   keys,
+		method: method,
+		issuer: issuer,
+		ttl:    ttl,
+	}, nil
+}
+
+func (t *EmailVerificationTokens) Issue(verification domain.EmailVerification) (string, error) {
+	now := time.Now()
+
+	claims := emailVerificationClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.issuer,
+			Audience:  []string{emailVerificationAudience},
+			Subject:   verification.UserID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.ttl)),
+		},
+		Email: verification.Email,
+	}
+
+	return signWithActiveKey(t.keys, t.method, claims)
+}
+
+func (t *EmailVerificationTokens) Verify(tokenString string) (domain.EmailVerification, error) {
+	claims := &emailVerificationClaims{}
+
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		verificationKeyFunc(t.keys),
+		jwt.WithValidMethods(verificationMethods),
+		jwt.WithIssuer(t.issuer),
+		jwt.WithAudience(emailVerificationAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return domain.EmailVerification{}, err
+	}
+
+	return domain.EmailVerification{
+		UserID: claims.Subject,
+		Email:  claims.Email,
+	}, nil
+}
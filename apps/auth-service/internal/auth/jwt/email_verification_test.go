@@ -0,0 +1,79 @@
+package jwt_test
+
+import (
+	"testing"
+	"time"
+
+	authjwt "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+func TestEmailVerificationTokens_RoundTrip(t *testing.T) {
+	ring := newTestKeyRing(t, generateTestECKey(t))
+
+	tokens, err := authjwt.NewEmailVerificationTokens(authjwt.AlgES256, ring, ISSUER, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create verification tokens: %v", err)
+	}
+
+	tokenString, err := tokens.Issue(domain.EmailVerification{UserID: "user-123", Email: "anna@example.com"})
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	verification, err := tokens.Verify(tokenString)
+	if err != nil {
+		t.Fatalf("failed to verify token: %v", err)
+	}
+
+	if verification.UserID != "user-123" || verification.Email != "anna@example.com" {
+		t.Errorf("unexpected verification %+v", verification)
+	}
+}
+
+// RS256 -> ES256 migration: links sent before it stay valid while the RSA key retires
+func TestEmailVerificationTokens_SignedByRetiringKeyOfOtherAlgorithm(t *testing.T) {
+	ring := newTestKeyRing(t, generateTestKey(t))
+
+	before, _ := authjwt.NewEmailVerificationTokens(authjwt.AlgRS256, ring, ISSUER, 24*time.Hour)
+	tokenString, err := before.Issue(domain.EmailVerification{UserID: "user-123", Email: "anna@example.com"})
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	if err := ring.Rotate(generateTestECKey(t)); err != nil {
+		t.Fatalf("rotate failed: %v", err)
+	}
+	after, _ := authjwt.NewEmailVerificationTokens(authjwt.AlgES256, ring, ISSUER, 24*time.Hour)
+
+	verification, err := after.Verify(tokenString)
+	if err != nil {
+		t.Fatalf("expected the link signed by the retiring key to verify: %v", err)
+	}
+	if verification.UserID != "user-123" {
+		t.Errorf("unexpected verification %+v", verification)
+	}
+}
+
+func TestEmailVerificationTokens_Expired(t *testing.T) {
+	ring := newTestKeyRing(t, generateTestECKey(t))
+
+	tokens, _ := authjwt.NewEmailVerificationTokens(authjwt.AlgES256, ring, ISSUER, -time.Minute)
+	tokenString, _ := tokens.Issue(domain.EmailVerification{UserID: "user-123", Email: "anna@example.com"})
+
+	if _, err := tokens.Verify(tokenString); err == nil {
+		t.Fatal("expired verification token must be rejected")
+	}
+}
+
+func TestEmailVerificationTokens_MFAChallengeIsNotAVerification(t *testing.T) {
+	ring := newTestKeyRing(t, generateTestECKey(t))
+
+	challenges, _ := authjwt.NewMFAChallengeTokens(authjwt.AlgES256, ring, ISSUER, 5*time.Minute)
+	challenge, _ := challenges.Issue(domain.MFAChallenge{UserID: "user-123", AuthTime: time.Now()})
+
+	tokens, _ := authjwt.NewEmailVerificationTokens(authjwt.AlgES256, ring, ISSUER, 24*time.Hour)
+	if _, err := tokens.Verify(challenge); err == nil {
+		t.Fatal("MFA challenge token must not be accepted as an email verification token")
+	}
+}
@@ -0,0 +1,75 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OpenID Connect ID token claims; aud is the client the token was issued to
+type IDClaims struct {
+	jwt.RegisteredClaims
+
+	AuthTime      *jwt.NumericDate `json:"auth_time"`
+	Nonce         string           `json:"nonce,omitempty"`
+	Email         string           `json:"email"`
+	EmailVerified bool             `json:"email_verified"`
+	AMR           []string         `json:"amr,omitempty"`
+}
+
+// signs ID tokens with the same key ring (and JWKS) as access tokens,
+// but with the OIDC issuer URL as iss
+type IDTokenSigner struct {
+	keys   *KeyRing
+	method jwt.SigningMethod
+	issuer string
+	ttl    time.Duration
+}
+
+// the active key of the ring must match alg
+func NewIDTokenSigner(
+	alg string,
+	keys *KeyRing,
+	issuer string,
+	ttl time.Duration,
+) (*IDTokenSigner, error) {
+	method, err := signingMethod(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IDTokenSigner{
+		keys:   keys,
+		method: method,
+		issuer: issuer,
+		ttl:    ttl,
+	}, nil
+}
+
+// nonce is echoed back unchanged so the client can bind the token to its request
+func (s *IDTokenSigner) GenerateSignedIDToken(
+	user User,
+	clientID string,
+	authTime time.Time,
+	nonce string,
+	amr []string,
+) (string, error) {
+	now := time.Now()
+
+	claims := IDClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			Audience:  []string{clientID},
+			Subject:   user.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.ttl)),
+		},
+		AuthTime:      jwt.NewNumericDate(authTime),
+		Nonce:         nonce,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		AMR:           amr,
+	}
+
+	return signWithActiveKey(s.keys, s.method, claims)
+}
@@ -0,0 +1,97 @@
+package jwt_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	authjwt "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+)
+
+const OIDC_ISSUER = "https://auth.family.example"
+
+func TestIDTokenSigner_SignAndVerify(t *testing.T) {
+	privateKey := generateTestECKey(t)
+
+	signer, err := authjwt.NewIDTokenSigner(
+		authjwt.AlgES256,
+		newTestKeyRing(t, privateKey),
+		OIDC_ISSUER,
+		15*time.Minute,
+	)
+	if err != nil {
+		t.Fatalf("failed to create ID token signer: %v", err)
+	}
+
+	authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+	user := authjwt.User{ID: "user-123", Email: "a@b.com"}
+
+	tokenString, err := signer.GenerateSignedIDToken(user, "wiki", authTime, "n-0S6_WzA2Mj", []string{"pwd"})
+	if err != nil {
+		t.Fatalf("failed to generate ID token: %v", err)
+	}
+
+	claims := &authjwt.IDClaims{}
+	_, err = jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		func(token *jwt.Token) (interface{}, error) {
+			return &privateKey.PublicKey, nil
+		},
+		jwt.WithAudience("wiki"),
+		jwt.WithIssuer(OIDC_ISSUER),
+	)
+	if err != nil {
+		t.Fatalf("failed to parse ID token: %v", err)
+	}
+
+	if claims.Subject != "user-123" || claims.Email != "a@b.com" {
+		t.Errorf("unexpected subject/email: %s %s", claims.Subject, claims.Email)
+	}
+	if claims.EmailVerified {
+		t.Errorf("expected an unverified email")
+	}
+	if claims.Nonce != "n-0S6_WzA2Mj" {
+		t.Errorf("expected nonce to be echoed, got %q", claims.Nonce)
+	}
+	if claims.AuthTime == nil || !claims.AuthTime.Time.Equal(authTime) {
+		t.Errorf("expected auth_time %v, got %v", authTime, claims.AuthTime)
+	}
+}
+
+func TestNewIDTokenSigner_UnsupportedAlgorithm(t *testing.T) {
+	_, err := authjwt.NewIDTokenSigner("HS256", newTestKeyRing(t, generateTestKey(t)), OIDC_ISSUER, time.Minute)
+	if err == nil {
+		t.Fatal("expected error for unsupported algorithm")
+	}
+}
+
+func TestIDTokenSigner_EmailVerified(t *testing.T) {
+	privateKey := generateTestECKey(t)
+
+	signer, err := authjwt.NewIDTokenSigner(authjwt.AlgES256, newTestKeyRing(t, privateKey), OIDC_ISSUER, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create ID token signer: %v", err)
+	}
+
+	verifiedAt := time.Now()
+	user := authjwt.User{ID: "user-123", Email: "a@b.com", EmailVerifiedAt: &verifiedAt}
+
+	tokenString, err := signer.GenerateSignedIDToken(user, "wiki", time.Now(), "", []string{"pwd"})
+	if err != nil {
+		t.Fatalf("failed to generate ID token: %v", err)
+	}
+
+	claims := &authjwt.IDClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return &privateKey.PublicKey, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to parse ID token: %v", err)
+	}
+
+	if !claims.EmailVerified {
+		t.Errorf("expected email_verified to be true")
+	}
+}
@@ -0,0 +1,157 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported signing key type")
+
+// JWK is the public part of a signing key as published in the JWKS (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+
+	// RSA public key parameters
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC (P-256) and OKP (Ed25519) public key parameters
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK of any supported public key type
+func NewPublicJWK(publicKey crypto.PublicKey) (JWK, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return NewRSAPublicJWK(key), nil
+	case *ecdsa.PublicKey:
+		return NewECPublicJWK(key)
+	case ed25519.PublicKey:
+		return NewEd25519PublicJWK(key), nil
+	default:
+		return JWK{}, ErrUnsupportedKey
+	}
+}
+
+// KeyID is the stable key ID (JWK thumbprint) of any supported public key type
+func KeyID(publicKey crypto.PublicKey) (string, error) {
+	jwk, err := NewPublicJWK(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return jwk.Kid, nil
+}
+
+func NewRSAPublicJWK(publicKey *rsa.PublicKey) JWK {
+	n := base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: AlgRS256,
+		Kid: rsaThumbprint(n, e),
+		N:   n,
+		E:   e,
+	}
+}
+
+// only P-256 (ES256) is supported
+func NewECPublicJWK(publicKey *ecdsa.PublicKey) (JWK, error) {
+	if publicKey.Curve != elliptic.P256() {
+		return JWK{}, ErrUnsupportedKey
+	}
+
+	ecdhKey, err := publicKey.ECDH()
+	if err != nil {
+		return JWK{}, err
+	}
+
+	// uncompressed point: 0x04 || X || Y, 32 bytes per coordinate
+	point := ecdhKey.Bytes()
+	x := base64.RawURLEncoding.EncodeToString(point[1:33])
+	y := base64.RawURLEncoding.EncodeToString(point[33:])
+
+	return JWK{
+		Kty: "EC",
+		Use: "sig",
+		Alg: AlgES256,
+		Kid: ecThumbprint(x, y),
+		Crv: "P-256",
+		X:   x,
+		Y:   y,
+	}, nil
+}
+
+// Ed25519 keys are "OKP" keys (RFC 8037)
+func NewEd25519PublicJWK(publicKey ed25519.PublicKey) JWK {
+	x := base64.RawURLEncoding.EncodeToString(publicKey)
+
+	return JWK{
+		Kty: "OKP",
+		Use: "sig",
+		Alg: AlgEdDSA,
+		Kid: okpThumbprint(x),
+		Crv: "Ed25519",
+		X:   x,
+	}
+}
+
+// RSAKeyID derives a stable key ID from the public key itself:
+// the JWK thumbprint (RFC 7638), so the same key always gets the same kid
+func RSAKeyID(publicKey *rsa.PublicKey) string {
+	return NewRSAPublicJWK(publicKey).Kid
+}
+
+// thumbprints hash the required members in lexicographic order,
+// without whitespace (RFC 7638); struct fields are declared in that order
+
+func rsaThumbprint(n string, e string) string {
+	return thumbprint(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{E: e, Kty: "RSA", N: n})
+}
+
+func ecThumbprint(x string, y string) string {
+	return thumbprint(struct {
+		Crv string `json:"crv"`
+		Kty string `json:"kty"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}{Crv: "P-256", Kty: "EC", X: x, Y: y})
+}
+
+func okpThumbprint(x string) string {
+	return thumbprint(struct {
+		Crv string `json:"crv"`
+		Kty string `json:"kty"`
+		X   string `json:"x"`
+	}{Crv: "Ed25519", Kty: "OKP", X: x})
+}
+
+func thumbprint(requiredMembers any) string {
+	thumbprintInput, _ := json.Marshal(requiredMembers)
+
+	sum := sha256.Sum256(thumbprintInput)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
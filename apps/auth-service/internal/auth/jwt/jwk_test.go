@@ -0,0 +1,68 @@
+package jwt_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	authjwt "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+)
+
+// example key and thumbprint from RFC 7638, section 3.1
+const rfc7638Modulus = "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+const rfc7638Thumbprint = "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"
+
+func TestRSAKeyID_RFC7638Thumbprint(t *testing.T) {
+	modulus, err := base64.RawURLEncoding.DecodeString(rfc7638Modulus)
+	if err != nil {
+		t.Fatalf("invalid test modulus: %v", err)
+	}
+
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: 65537}
+
+	if kid := authjwt.RSAKeyID(publicKey); kid != rfc7638Thumbprint {
+		t.Fatalf("expected kid %s, got %s", rfc7638Thumbprint, kid)
+	}
+}
+
+// example key and thumbprint from RFC 8037, appendix A.3
+const rfc8037X = "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"
+const rfc8037Thumbprint = "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"
+
+func TestEd25519PublicJWK_RFC8037Thumbprint(t *testing.T) {
+	x, err := base64.RawURLEncoding.DecodeString(rfc8037X)
+	if err != nil {
+		t.Fatalf("invalid test key: %v", err)
+	}
+
+	jwk := authjwt.NewEd25519PublicJWK(ed25519.PublicKey(x))
+
+	if jwk.Kid != rfc8037Thumbprint {
+		t.Fatalf("expected kid %s, got %s", rfc8037Thumbprint, jwk.Kid)
+	}
+	if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || jwk.Alg != "EdDSA" || jwk.X != rfc8037X {
+		t.Fatalf("unexpected JWK: %+v", jwk)
+	}
+}
+
+func TestECPublicJWK(t *testing.T) {
+	privateKey := generateTestECKey(t)
+
+	jwk, err := authjwt.NewECPublicJWK(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if jwk.Kty != "EC" || jwk.Crv != "P-256" || jwk.Alg != "ES256" || jwk.Kid == "" {
+		t.Fatalf("unexpected JWK: %+v", jwk)
+	}
+
+	for _, coordinate := range []string{jwk.X, jwk.Y} {
+		decoded, err := base64.RawURLEncoding.DecodeString(coordinate)
+		if err != nil || len(decoded) != 32 {
+			t.Fatalf("coordinates must be 32 byte base64url values, got %q", coordinate)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package jwt
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+var ErrOverlapTooShort = errors.New("key overlap shorter than a token lifetime")
+
+// KeyRing holds one active signing key plus verify-only retiring keys.
+// On rotation the previous key stays published (verify-only) for the overlap
+// window, so tokens it signed remain verifiable until they expire.
+// Safe for concurrent use.
+type KeyRing struct {
+	mu       sync.RWMutex
+	active   activeKey
+	retiring []retiringKey
+	overlap  time.Duration
+}
+
+type activeKey struct {
+	id  string
+	key crypto.Signer
+}
+
+type retiringKey struct {
+	id        string
+	publicKey crypto.PublicKey
+	notAfter  time.Time
+}
+
+// overlap must be at least the longest TTL of the tokens signed with the ring,
+// see RequireOverlap
+func NewKeyRing(active crypto.Signer, overlap time.Duration) (*KeyRing, error) {
+	kid, err := KeyID(active.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyRing{
+		active:  activeKey{id: kid, key: active},
+		overlap: overlap,
+	}, nil
+}
+
+// adds a verify-only key (e.g. the key used before the last deployment),
+// published until notAfter
+func (ring *KeyRing) AddRetiringKey(publicKey crypto.PublicKey, notAfter time.Time) error {
+	kid, err := KeyID(publicKey)
+	if err != nil {
+		return err
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	ring.retiring = append(ring.retiring, retiringKey{
+		id:        kid,
+		publicKey: publicKey,
+		notAfter:  notAfter,
+	})
+	return nil
+}
+
+// every token signed with the ring (access and ID tokens, but also the links
+// of verification emails) must stay verifiable until it expires, even if the
+// key that signed it is rotated right after
+func (ring *KeyRing) RequireOverlap(tokenTTL time.Duration) error {
+	if ring.overlap < tokenTTL {
+		return fmt.Errorf("%w: %s, tokens live for %s", ErrOverlapTooShort, ring.overlap, tokenTTL)
+	}
+	return nil
+}
+
+// makes newActive the signing key; the previous one becomes verify-only
+// for the overlap window
+func (ring *KeyRing) Rotate(newActive crypto.Signer) error {
+	kid, err := KeyID(newActive.Public())
+	if err != nil {
+		return err
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	now := time.Now()
+
+	// keys past their overlap window are never served again
+	stillValid := ring.retiring[:0]
+	for _, key := range ring.retiring {
+		if now.Before(key.notAfter) {
+			stillValid = append(stillValid, key)
+		}
+	}
+
+	ring.retiring = append(stillValid, retiringKey{
+		id:        ring.active.id,
+		publicKey: ring.active.key.Public(),
+		notAfter:  now.Add(ring.overlap),
+	})
+	ring.active = activeKey{id: kid, key: newActive}
+	return nil
+}
+
+// key to sign new tokens with, and its kid for the JWT header
+func (ring *KeyRing) SigningKey() (string, crypto.Signer) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	return ring.active.id, ring.active.key
+}
+
+// public key for the kid of a token: the active key or a retiring key
+// whose overlap window has not ended yet
+func (ring *KeyRing) VerificationKey(kid string) (crypto.PublicKey, error) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	if kid == ring.active.id {
+		return ring.active.key.Public(), nil
+	}
+
+	now := time.Now()
+	for _, key := range ring.retiring {
+		if key.id == kid && now.Before(key.notAfter) {
+			return key.publicKey, nil
+		}
+	}
+
+	return nil, ErrUnknownKey
+}
+
+// active key first, followed by retiring keys still within their overlap window
+func (ring *KeyRing) JWKS() JWKSet {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	// key types were validated when the keys were added
+	activeJWK, _ := NewPublicJWK(ring.active.key.Public())
+	keys := []JWK{activeJWK}
+
+	now := time.Now()
+	for _, key := range ring.retiring {
+		if now.Before(key.notAfter) {
+			retiringJWK, _ := NewPublicJWK(key.publicKey)
+			keys = append(keys, retiringJWK)
+		}
+	}
+
+	return JWKSet{Keys: keys}
+}
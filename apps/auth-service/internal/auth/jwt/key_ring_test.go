@@ -0,0 +1,123 @@
+package jwt_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	authjwt "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+func TestKeyRing_JWKS(t *testing.T) {
+	privateKey := generateTestKey(t)
+	ring := newTestKeyRing(t, privateKey)
+
+	keys := ring.JWKS().Keys
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 key, got %d", len(keys))
+	}
+
+	jwk := keys[0]
+	if jwk.Kty != "RSA" || jwk.Alg != "RS256" || jwk.Use != "sig" {
+		t.Fatalf("unexpected JWK header fields: %+v", jwk)
+	}
+	if jwk.Kid != authjwt.RSAKeyID(&privateKey.PublicKey) {
+		t.Fatalf("unexpected kid: %s", jwk.Kid)
+	}
+	if jwk.E != "AQAB" {
+		t.Fatalf("unexpected e: %s", jwk.E)
+	}
+}
+
+func TestKeyRing_Rotate(t *testing.T) {
+	oldKey := generateTestKey(t)
+	newKey := generateTestKey(t)
+	ring := newTestKeyRing(t, oldKey)
+	oldKid, _ := ring.SigningKey()
+
+	if err := ring.Rotate(newKey); err != nil {
+		t.Fatalf("rotate failed: %v", err)
+	}
+
+	newKid, signingKey := ring.SigningKey()
+	if newKid == oldKid || signingKey != newKey {
+		t.Fatal("expected the new key to be active")
+	}
+
+	// old key is verify-only within the overlap window
+	keys := ring.JWKS().Keys
+	if len(keys) != 2 || keys[0].Kid != newKid || keys[1].Kid != oldKid {
+		t.Fatalf("expected active and retiring key in JWKS, got %+v", keys)
+	}
+
+	if _, err := ring.VerificationKey(oldKid); err != nil {
+		t.Fatalf("expected retiring key to verify: %v", err)
+	}
+}
+
+func TestKeyRing_RetiringKeyExpires(t *testing.T) {
+	ring := newTestKeyRing(t, generateTestKey(t))
+	expired := generateTestKey(t)
+
+	if err := ring.AddRetiringKey(&expired.PublicKey, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("failed to add retiring key: %v", err)
+	}
+
+	if keys := ring.JWKS().Keys; len(keys) != 1 {
+		t.Fatalf("expected expired key to be unpublished, got %d keys", len(keys))
+	}
+
+	_, err := ring.VerificationKey(authjwt.RSAKeyID(&expired.PublicKey))
+	if !errors.Is(err, authjwt.ErrUnknownKey) {
+		t.Fatalf("expected %v, got %v", authjwt.ErrUnknownKey, err)
+	}
+}
+
+// a verification link outlives an access token by far; the key that signed
+// it must stay published after a rotation until the link expires
+func TestKeyRing_Rotate_TokenSignedBeforeStillVerifies(t *testing.T) {
+	ttl := 24 * time.Hour
+	ring, err := authjwt.NewKeyRing(generateTestECKey(t), ttl)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+	if err := ring.RequireOverlap(ttl); err != nil {
+		t.Fatalf("unexpected overlap error: %v", err)
+	}
+
+	tokens, err := authjwt.NewEmailVerificationTokens(authjwt.AlgES256, ring, ISSUER, ttl)
+	if err != nil {
+		t.Fatalf("failed to create verification tokens: %v", err)
+	}
+	link, err := tokens.Issue(domain.EmailVerification{UserID: "user-123", Email: "anna@example.com"})
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	if err := ring.Rotate(generateTestECKey(t)); err != nil {
+		t.Fatalf("rotate failed: %v", err)
+	}
+
+	verification, err := tokens.Verify(link)
+	if err != nil {
+		t.Fatalf("expected the token signed before the rotation to verify: %v", err)
+	}
+	if verification.UserID != "user-123" {
+		t.Errorf("unexpected verification %+v", verification)
+	}
+}
+
+func TestKeyRing_RequireOverlap_TooShort(t *testing.T) {
+	ring, err := authjwt.NewKeyRing(generateTestECKey(t), 15*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+
+	if err := ring.RequireOverlap(15 * time.Minute); err != nil {
+		t.Fatalf("unexpected overlap error: %v", err)
+	}
+	if err := ring.RequireOverlap(24 * time.Hour); !errors.Is(err, authjwt.ErrOverlapTooShort) {
+		t.Fatalf("expected %v, got %v", authjwt.ErrOverlapTooShort, err)
+	}
+}
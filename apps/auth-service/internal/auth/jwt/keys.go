@@ -1,24 +1,37 @@
 package jwt
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"os"
 )
 
+// loads the private key for the configured signing algorithm
+func LoadSigningKey(alg string, path string) (crypto.Signer, error) {
+	switch alg {
+	case AlgRS256:
+		return LoadRSAPrivateKey(path)
+	case AlgES256:
+		return LoadECPrivateKey(path)
+	case AlgEdDSA:
+		return LoadEd25519PrivateKey(path)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
 func LoadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
-	data, err := os.ReadFile(path)
+	block, err := readPEMBlock(path, "private")
 	if err != nil {
 		return nil, err
 	}
 
-	block, _ := pem.Decode(data)
-	if block == nil {
-		return nil, errors.New("invalid PEM private key")
-	}
-
 	// Parse the RSA private key from the PEM block
 	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
 	if err != nil {
@@ -33,19 +46,51 @@ func LoadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
 	return key, nil
 }
 
-func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
-	data, err := os.ReadFile(path)
+// accepts PKCS#8 ("PRIVATE KEY") and SEC 1 ("EC PRIVATE KEY", openssl ecparam output)
+func LoadECPrivateKey(path string) (*ecdsa.PrivateKey, error) {
+	block, err := readPEMBlock(path, "private")
 	if err != nil {
 		return nil, err
 	}
 
-	block, _ := pem.Decode(data)
-	if block == nil {
-		return nil, errors.New("invalid PEM public key")
+	if block.Type == "EC PRIVATE KEY" {
+		return x509.ParseECPrivateKey(block.Bytes)
 	}
 
-	// Parse the RSA public key from the PEM block
-	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not EC private key")
+	}
+
+	return key, nil
+}
+
+func LoadEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEMBlock(path, "private")
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not Ed25519 private key")
+	}
+
+	return key, nil
+}
+
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	pub, err := LoadPublicKey(path)
 	if err != nil {
 		return nil, err
 	}
@@ -57,3 +102,37 @@ func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
 
 	return key, nil
 }
+
+// loads a PKIX public key of any supported type (e.g. a retiring verify-only key)
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEMBlock(path, "public")
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the public key from the PEM block
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := NewPublicJWK(pub); err != nil {
+		return nil, err
+	}
+
+	return pub, nil
+}
+
+func readPEMBlock(path string, kind string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM %s key", kind)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,59 @@
+package jwt_test
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	authjwt "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+)
+
+func writePKCS8Key(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return path
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	_, ed25519Key := generateTestEd25519Key(t)
+
+	keys := map[string]crypto.Signer{
+		authjwt.AlgRS256: generateTestKey(t),
+		authjwt.AlgES256: generateTestECKey(t),
+		authjwt.AlgEdDSA: ed25519Key,
+	}
+
+	for alg, key := range keys {
+		loaded, err := authjwt.LoadSigningKey(alg, writePKCS8Key(t, key))
+		if err != nil {
+			t.Fatalf("%s: failed to load key: %v", alg, err)
+		}
+
+		expectedKid, _ := authjwt.KeyID(key.Public())
+		loadedKid, _ := authjwt.KeyID(loaded.Public())
+		if loadedKid != expectedKid {
+			t.Fatalf("%s: loaded key does not match", alg)
+		}
+	}
+}
+
+func TestLoadSigningKey_WrongKeyType(t *testing.T) {
+	path := writePKCS8Key(t, generateTestECKey(t))
+
+	if _, err := authjwt.LoadSigningKey(authjwt.AlgRS256, path); err == nil {
+		t.Fatal("expected EC key to be rejected for RS256")
+	}
+}
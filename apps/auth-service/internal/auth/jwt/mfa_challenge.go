@@ -0,0 +1,102 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// audience of MFA challenge tokens: never accepted where an access token is expected
+const mfaChallengeAudience = "family-space-mfa"
+
+type mfaChallengeClaims struct {
+	jwt.RegisteredClaims
+
+	AuthTime *jwt.NumericDate `json:"auth_time"`
+	ClientID string           `json:"client_id,omitempty"`
+	Nonce    string           `json:"nonce,omitempty"`
+	// the session is to get the long lifetime
+	RememberMe bool `json:"remember_me,omitempty"`
+}
+
+// MFAChallengeTokens issues and verifies the short-lived token returned by
+// the password step of a login with MFA enabled. Stateless: the login state
+// (user, auth time, OIDC request) travels inside the signed token.
+type MFAChallengeTokens struct {
+	keys   *KeyRing
+	method jwt.SigningMethod
+	issuer string
+	ttl    time.Duration
+}
+
+// the active key of the ring must match alg
+func NewMFAChallengeTokens(
+	alg string,
+	keys *KeyRing,
+	issuer string,
+	ttl time.Duration,
+) (*MFAChallengeTokens, error) {
+	method, err := signingMethod(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAChallengeTokens{
+		keys:   keys,
+		method: method,
+		issuer: issuer,
+		ttl:    ttl,
+	}, nil
+}
+
+func (t *MFAChallengeTokens) Issue(challenge domain.MFAChallenge) (string, error) {
+	now := time.Now()
+
+	claims := mfaChallengeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.issuer,
+			Audience:  []string{mfaChallengeAudience},
+			Subject:   challenge.UserID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.ttl)),
+		},
+		AuthTime:   jwt.NewNumericDate(challenge.AuthTime),
+		ClientID:   challenge.IDToken.ClientID,
+		Nonce:      challenge.IDToken.Nonce,
+		RememberMe: challenge.RememberMe,
+	}
+
+	return signWithActiveKey(t.keys, t.method, claims)
+}
+
+func (t *MFAChallengeTokens) Verify(tokenString string) (domain.MFAChallenge, error) {
+	claims := &mfaChallengeClaims{}
+
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		verificationKeyFunc(t.keys),
+		jwt.WithValidMethods(verificationMethods),
+		jwt.WithIssuer(t.issuer),
+		jwt.WithAudience(mfaChallengeAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return domain.MFAChallenge{}, err
+	}
+
+	challenge := domain.MFAChallenge{
+		UserID: claims.Subject,
+		IDToken: domain.IDTokenRequest{
+			ClientID: claims.ClientID,
+			Nonce:    claims.Nonce,
+		},
+		RememberMe: claims.RememberMe,
+	}
+	if claims.AuthTime != nil {
+		challenge.AuthTime = claims.AuthTime.Time
+	}
+
+	return challenge, nil
+}
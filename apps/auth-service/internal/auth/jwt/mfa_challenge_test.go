@@ -0,0 +1,89 @@
+package jwt_test
+
+import (
+	"testing"
+	"time"
+
+	authjwt "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+func TestMFAChallengeTokens_RoundTrip(t *testing.T) {
+	ring := newTestKeyRing(t, generateTestECKey(t))
+
+	tokens, err := authjwt.NewMFAChallengeTokens(authjwt.AlgES256, ring, ISSUER, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create challenge tokens: %v", err)
+	}
+
+	authTime := time.Now().Truncate(time.Second)
+	tokenString, err := tokens.Issue(domain.MFAChallenge{
+		UserID:     "user-123",
+		AuthTime:   authTime,
+		IDToken:    domain.IDTokenRequest{ClientID: "wiki", Nonce: "nonce-1"},
+		RememberMe: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to issue challenge: %v", err)
+	}
+
+	challenge, err := tokens.Verify(tokenString)
+	if err != nil {
+		t.Fatalf("failed to verify challenge: %v", err)
+	}
+
+	if challenge.UserID != "user-123" || !challenge.AuthTime.Equal(authTime) || !challenge.RememberMe {
+		t.Errorf("unexpected challenge %+v", challenge)
+	}
+	if challenge.IDToken.ClientID != "wiki" || challenge.IDToken.Nonce != "nonce-1" {
+		t.Errorf("unexpected OIDC request %+v", challenge.IDToken)
+	}
+}
+
+// RS256 -> ES256 migration: a login halfway through its second step can finish
+func TestMFAChallengeTokens_SignedByRetiringKeyOfOtherAlgorithm(t *testing.T) {
+	ring := newTestKeyRing(t, generateTestKey(t))
+
+	before, _ := authjwt.NewMFAChallengeTokens(authjwt.AlgRS256, ring, ISSUER, 5*time.Minute)
+	tokenString, err := before.Issue(domain.MFAChallenge{UserID: "user-123", AuthTime: time.Now()})
+	if err != nil {
+		t.Fatalf("failed to issue challenge: %v", err)
+	}
+
+	if err := ring.Rotate(generateTestECKey(t)); err != nil {
+		t.Fatalf("rotate failed: %v", err)
+	}
+	after, _ := authjwt.NewMFAChallengeTokens(authjwt.AlgES256, ring, ISSUER, 5*time.Minute)
+
+	challenge, err := after.Verify(tokenString)
+	if err != nil {
+		t.Fatalf("expected the challenge signed by the retiring key to verify: %v", err)
+	}
+	if challenge.UserID != "user-123" {
+		t.Errorf("unexpected challenge %+v", challenge)
+	}
+}
+
+func TestMFAChallengeToken_IsNotAnAccessToken(t *testing.T) {
+	ring := newTestKeyRing(t, generateTestECKey(t))
+
+	tokens, _ := authjwt.NewMFAChallengeTokens(authjwt.AlgES256, ring, ISSUER, 5*time.Minute)
+	tokenString, _ := tokens.Issue(domain.MFAChallenge{UserID: "user-123", AuthTime: time.Now()})
+
+	verifier := authjwt.NewAccessTokenVerifier(ring, ISSUER, AUDIENCE)
+	if _, err := verifier.Verify(tokenString); err == nil {
+		t.Fatal("MFA challenge token must not be accepted as an access token")
+	}
+}
+
+func TestMFAChallengeTokens_AccessTokenIsNotAChallenge(t *testing.T) {
+	ring := newTestKeyRing(t, generateTestECKey(t))
+
+	signer := newTestSigner(t, authjwt.AlgES256, ring, AUDIENCE, 15*time.Minute)
+	accessToken, _ := signer.GenerateSignedAccessToken(authjwt.User{ID: "user-123"}, authjwt.Membership{}, nil, "")
+
+	tokens, _ := authjwt.NewMFAChallengeTokens(authjwt.AlgES256, ring, ISSUER, 5*time.Minute)
+	if _, err := tokens.Verify(accessToken); err == nil {
+		t.Fatal("access token must not be accepted as an MFA challenge")
+	}
+}
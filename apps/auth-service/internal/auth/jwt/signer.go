@@ -1,5 +1,100 @@
 package jwt
 
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type User = domain.User
+type Membership = domain.Membership
+
+// access token signing algorithms, selected by configuration
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// accepted when verifying; a retiring key may use another algorithm than the
+// active one, the kid decides which key (and so which algorithm) applies
+var verificationMethods = []string{AlgRS256, AlgES256, AlgEdDSA}
+
 type TokenSigner interface {
-	GenerateSignedAccessToken(user User, membership Membership) (string, error)
+	// sessionID is the refresh token session (login) the token belongs to
+	GenerateSignedAccessToken(user User, membership Membership, amr []string, sessionID string) (string, error)
+}
+
+// signs access tokens with the active key of the key ring;
+// RS256, ES256 (smaller and faster) or EdDSA (smallest and fastest)
+type AccessTokenSigner struct {
+	keys     *KeyRing
+	method   jwt.SigningMethod
+	issuer   string
+	audience string
+	ttl      time.Duration
+}
+
+// the active key of the ring must match alg
+func NewAccessTokenSigner(
+	alg string,
+	keys *KeyRing,
+	issuer string,
+	audience string,
+	ttl time.Duration,
+) (*AccessTokenSigner, error) {
+	method, err := signingMethod(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccessTokenSigner{
+		keys:     keys,
+		method:   method,
+		issuer:   issuer,
+		audience: audience,
+		ttl:      ttl,
+	}, nil
+}
+
+func (s *AccessTokenSigner) GenerateSignedAccessToken(
+	user User,
+	membership Membership,
+	amr []string,
+	sessionID string,
+) (string, error) {
+
+	claims := newAccessClaims(s.issuer, s.audience, s.ttl, user, membership, amr, sessionID)
+	return signWithActiveKey(s.keys, s.method, claims)
+}
+
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgES256:
+		return jwt.SigningMethodES256, nil
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
+// signs with the active key of the ring and stamps its kid into the header,
+// which lets the verifier pick the right key from the JWKS during rotation
+func signWithActiveKey(keys *KeyRing, method jwt.SigningMethod, claims jwt.Claims) (string, error) {
+	kid, signingKey := keys.SigningKey()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(signingKey)
+	if errors.Is(err, jwt.ErrInvalidKeyType) || errors.Is(err, jwt.ErrInvalidKey) {
+		return "", fmt.Errorf("active signing key cannot sign %s: %w", method.Alg(), ErrUnsupportedKey)
+	}
+	return signed, err
 }
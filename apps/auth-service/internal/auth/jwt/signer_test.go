@@ -1,8 +1,13 @@
 package jwt_test
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"errors"
 	"testing"
 	"time"
 
@@ -24,13 +29,54 @@ func generateTestKey(t *testing.T) *rsa.PrivateKey {
 	return key
 }
 
+func generateTestECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	return key
+}
+
+func generateTestEd25519Key(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+	return publicKey, privateKey
+}
+
+func newTestKeyRing(t *testing.T, key crypto.Signer) *authjwt.KeyRing {
+	t.Helper()
+
+	ring, err := authjwt.NewKeyRing(key, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+	return ring
+}
+
+func newTestSigner(t *testing.T, alg string, ring *authjwt.KeyRing, audience string, ttl time.Duration) *authjwt.AccessTokenSigner {
+	t.Helper()
+
+	signer, err := authjwt.NewAccessTokenSigner(alg, ring, ISSUER, audience, ttl)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	return signer
+}
+
 func TestRS256Signer_SignAndVerify(t *testing.T) {
 	privateKey := generateTestKey(t)
 	publicKey := &privateKey.PublicKey
 
-	signer := authjwt.NewRS256Signer(
-		privateKey,
-		ISSUER,
+	signer := newTestSigner(
+		t,
+		authjwt.AlgRS256,
+		newTestKeyRing(t, privateKey),
 		AUDIENCE,
 		15*time.Minute,
 	)
@@ -43,7 +89,7 @@ func TestRS256Signer_SignAndVerify(t *testing.T) {
 		Role:     "admin",
 	}
 
-	tokenString, err := signer.GenerateSignedAccessToken(user, membership)
+	tokenString, err := signer.GenerateSignedAccessToken(user, membership, nil, "")
 	if err != nil {
 		t.Fatalf("failed to generate signed token: %v", err)
 	}
@@ -88,9 +134,10 @@ func TestRS256Signer_ExpiredToken(t *testing.T) {
 	privateKey := generateTestKey(t)
 	publicKey := &privateKey.PublicKey
 
-	signer := authjwt.NewRS256Signer(
-		privateKey,
-		ISSUER,
+	signer := newTestSigner(
+		t,
+		authjwt.AlgRS256,
+		newTestKeyRing(t, privateKey),
 		AUDIENCE,
 		-1*time.Minute, // already expired
 	)
@@ -101,7 +148,7 @@ func TestRS256Signer_ExpiredToken(t *testing.T) {
 		Role:     "admin",
 	}
 
-	tokenString, err := signer.GenerateSignedAccessToken(user, membership)
+	tokenString, err := signer.GenerateSignedAccessToken(user, membership, nil, "")
 	if err != nil {
 		t.Fatalf("failed to sign token: %v", err)
 	}
@@ -125,9 +172,10 @@ func TestRS256Signer_WrongAudience(t *testing.T) {
 	privateKey := generateTestKey(t)
 	publicKey := &privateKey.PublicKey
 
-	signer := authjwt.NewRS256Signer(
-		privateKey,
-		ISSUER,
+	signer := newTestSigner(
+		t,
+		authjwt.AlgRS256,
+		newTestKeyRing(t, privateKey),
 		AUDIENCE,
 		15*time.Minute,
 	)
@@ -135,6 +183,8 @@ func TestRS256Signer_WrongAudience(t *testing.T) {
 	tokenString, _ := signer.GenerateSignedAccessToken(
 		authjwt.User{ID: "user-123"},
 		authjwt.Membership{FamilyID: "f", Role: "admin"},
+		nil,
+		"",
 	)
 
 	_, err := jwt.ParseWithClaims(
@@ -151,3 +201,132 @@ func TestRS256Signer_WrongAudience(t *testing.T) {
 		t.Fatal("expected audience validation to fail")
 	}
 }
+
+func TestRS256Signer_KidHeader(t *testing.T) {
+	privateKey := generateTestKey(t)
+	ring := newTestKeyRing(t, privateKey)
+
+	signer := newTestSigner(t, authjwt.AlgRS256, ring, AUDIENCE, 15*time.Minute)
+
+	tokenString, err := signer.GenerateSignedAccessToken(
+		authjwt.User{ID: "user-123"},
+		authjwt.Membership{FamilyID: "f", Role: "admin"},
+		nil,
+		"",
+	)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	// verifier resolves the key by kid, as the gateway does with the JWKS
+	parsedToken, err := jwt.ParseWithClaims(
+		tokenString,
+		&authjwt.Claims{},
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return ring.VerificationKey(kid)
+		},
+		jwt.WithAudience(AUDIENCE),
+		jwt.WithIssuer(ISSUER),
+	)
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+
+	if parsedToken.Header["kid"] != authjwt.RSAKeyID(&privateKey.PublicKey) {
+		t.Errorf("unexpected kid: %v", parsedToken.Header["kid"])
+	}
+}
+
+func TestAccessTokenSigner(t *testing.T) {
+	rsaKey := generateTestKey(t)
+	ecKey := generateTestECKey(t)
+	ed25519PublicKey, ed25519Key := generateTestEd25519Key(t)
+
+	cases := map[string]struct {
+		publicKey crypto.PublicKey
+		ring      *authjwt.KeyRing
+		// a ring whose active key cannot sign with the algorithm
+		wrongRing *authjwt.KeyRing
+	}{
+		authjwt.AlgRS256: {&rsaKey.PublicKey, newTestKeyRing(t, rsaKey), newTestKeyRing(t, ecKey)},
+		authjwt.AlgES256: {&ecKey.PublicKey, newTestKeyRing(t, ecKey), newTestKeyRing(t, rsaKey)},
+		authjwt.AlgEdDSA: {ed25519PublicKey, newTestKeyRing(t, ed25519Key), newTestKeyRing(t, ecKey)},
+	}
+
+	for alg, tc := range cases {
+		t.Run(alg, func(t *testing.T) {
+			// verifier resolves the key by kid, as the gateway does with the JWKS
+			parse := func(tokenString string, audience string) (*jwt.Token, error) {
+				return jwt.ParseWithClaims(
+					tokenString,
+					&authjwt.Claims{},
+					func(token *jwt.Token) (interface{}, error) {
+						kid, _ := token.Header["kid"].(string)
+						return tc.ring.VerificationKey(kid)
+					},
+					jwt.WithValidMethods([]string{alg}),
+					jwt.WithAudience(audience),
+					jwt.WithIssuer(ISSUER),
+				)
+			}
+
+			signer := newTestSigner(t, alg, tc.ring, AUDIENCE, 15*time.Minute)
+			tokenString, err := signer.GenerateSignedAccessToken(
+				authjwt.User{ID: "user-123"},
+				authjwt.Membership{FamilyID: "family-456", Role: "admin"},
+				nil,
+				"",
+			)
+			if err != nil {
+				t.Fatalf("failed to generate signed token: %v", err)
+			}
+
+			parsedToken, err := parse(tokenString, AUDIENCE)
+			if err != nil {
+				t.Fatalf("failed to parse token: %v", err)
+			}
+
+			claims, ok := parsedToken.Claims.(*authjwt.Claims)
+			if !ok {
+				t.Fatal("claims type mismatch")
+			}
+			if claims.Subject != "user-123" || claims.FamilyID != "family-456" || claims.Role != "admin" {
+				t.Errorf("unexpected claims: %+v", claims)
+			}
+
+			kid, err := authjwt.KeyID(tc.publicKey)
+			if err != nil {
+				t.Fatalf("failed to compute kid: %v", err)
+			}
+			if parsedToken.Header["kid"] != kid {
+				t.Errorf("unexpected kid: %v", parsedToken.Header["kid"])
+			}
+
+			if _, err := parse(tokenString, "some-other-api"); err == nil {
+				t.Error("expected audience validation to fail")
+			}
+
+			expired := newTestSigner(t, alg, tc.ring, AUDIENCE, -1*time.Minute)
+			tokenString, err = expired.GenerateSignedAccessToken(authjwt.User{ID: "user-123"}, authjwt.Membership{}, nil, "")
+			if err != nil {
+				t.Fatalf("failed to sign token: %v", err)
+			}
+			if _, err := parse(tokenString, AUDIENCE); err == nil {
+				t.Error("expected token to be expired")
+			}
+
+			wrongKey := newTestSigner(t, alg, tc.wrongRing, AUDIENCE, 15*time.Minute)
+			_, err = wrongKey.GenerateSignedAccessToken(authjwt.User{ID: "user-123"}, authjwt.Membership{}, nil, "")
+			if !errors.Is(err, authjwt.ErrUnsupportedKey) {
+				t.Errorf("expected %v, got %v", authjwt.ErrUnsupportedKey, err)
+			}
+		})
+	}
+}
+
+func TestNewAccessTokenSigner_UnsupportedAlgorithm(t *testing.T) {
+	if _, err := authjwt.NewAccessTokenSigner("HS256", newTestKeyRing(t, generateTestKey(t)), ISSUER, AUDIENCE, time.Minute); err == nil {
+		t.Fatal("expected unsupported algorithm to be rejected")
+	}
+}
@@ -0,0 +1,70 @@
+package jwt
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// checks our own access tokens: signature by a key of the ring (selected by kid),
+// issuer, audience and expiry
+type AccessTokenVerifier struct {
+	keys     *KeyRing
+	issuer   string
+	audience string
+}
+
+func NewAccessTokenVerifier(
+	keys *KeyRing,
+	issuer string,
+	audience string,
+) *AccessTokenVerifier {
+	return &AccessTokenVerifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+	}
+}
+
+func (v *AccessTokenVerifier) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		verificationKeyFunc(v.keys),
+		jwt.WithValidMethods(verificationMethods),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// selects the public key by the kid of the token header;
+// shared by all tokens signed with the key ring
+func verificationKeyFunc(keys *KeyRing) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		publicKey, err := keys.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// the header must not pick an algorithm the key was not issued for
+		jwk, err := NewPublicJWK(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if jwk.Alg != token.Method.Alg() {
+			return nil, errors.New("signing algorithm does not match key")
+		}
+
+		return publicKey, nil
+	}
+}
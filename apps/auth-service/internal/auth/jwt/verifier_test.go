@@ -0,0 +1,63 @@
+package jwt_test
+
+import (
+	"testing"
+	"time"
+
+	authjwt "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+)
+
+func TestAccessTokenVerifier_Verify(t *testing.T) {
+	ring := newTestKeyRing(t, generateTestECKey(t))
+	signer := newTestSigner(t, authjwt.AlgES256, ring, AUDIENCE, 15*time.Minute)
+	verifier := authjwt.NewAccessTokenVerifier(ring, ISSUER, AUDIENCE)
+
+	tokenString, err := signer.GenerateSignedAccessToken(
+		authjwt.User{ID: "user-123"},
+		authjwt.Membership{FamilyID: "family-456", Role: "admin"},
+		[]string{"pwd", "otp", "mfa"},
+		"session-789",
+	)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	claims, err := verifier.Verify(tokenString)
+	if err != nil {
+		t.Fatalf("failed to verify token: %v", err)
+	}
+
+	if claims.Subject != "user-123" || claims.FamilyID != "family-456" || claims.Role != "admin" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+	if len(claims.AMR) != 3 || claims.AMR[1] != "otp" {
+		t.Errorf("expected amr [pwd otp mfa], got %v", claims.AMR)
+	}
+	if claims.SessionID != "session-789" {
+		t.Errorf("expected sid session-789, got %q", claims.SessionID)
+	}
+}
+
+func TestAccessTokenVerifier_UnknownKey(t *testing.T) {
+	signer := newTestSigner(t, authjwt.AlgRS256, newTestKeyRing(t, generateTestKey(t)), AUDIENCE, 15*time.Minute)
+	// verifier knows a different key only
+	verifier := authjwt.NewAccessTokenVerifier(newTestKeyRing(t, generateTestKey(t)), ISSUER, AUDIENCE)
+
+	tokenString, _ := signer.GenerateSignedAccessToken(authjwt.User{ID: "user-123"}, authjwt.Membership{}, nil, "")
+
+	if _, err := verifier.Verify(tokenString); err == nil {
+		t.Fatal("expected token signed by an unknown key to be rejected")
+	}
+}
+
+func TestAccessTokenVerifier_WrongAudience(t *testing.T) {
+	ring := newTestKeyRing(t, generateTestKey(t))
+	signer := newTestSigner(t, authjwt.AlgRS256, ring, "some-other-api", 15*time.Minute)
+	verifier := authjwt.NewAccessTokenVerifier(ring, ISSUER, AUDIENCE)
+
+	tokenString, _ := signer.GenerateSignedAccessToken(authjwt.User{ID: "user-123"}, authjwt.Membership{}, nil, "")
+
+	if _, err := verifier.Verify(tokenString); err == nil {
+		t.Fatal("expected audience validation to fail")
+	}
+}
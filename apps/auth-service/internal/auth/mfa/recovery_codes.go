@@ -0,0 +1,52 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// codes in a batch; a new batch replaces all codes of the user
+const RecoveryCodeCount = 10
+
+// 10 base32 characters (50 bits), shown as xxxxx-xxxxx
+const recoveryCodeLength = 10
+
+const recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		// 256 is a multiple of 32: no modulo bias
+		for j := range b {
+			b[j] = recoveryCodeAlphabet[int(b[j])%len(recoveryCodeAlphabet)]
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+	}
+	return codes, nil
+}
+
+// case, spaces and dashes do not matter when a code is typed in;
+// the normalized form is what gets hashed
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// tells a recovery code apart from a 6-digit TOTP code
+func IsRecoveryCode(code string) bool {
+	normalized := NormalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return false
+	}
+	for _, c := range normalized {
+		if !strings.ContainsRune(recoveryCodeAlphabet, c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package mfa
+
+import "testing"
+
+func TestGenerateRecoveryCodes(test *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(codes) != RecoveryCodeCount {
+		test.Fatalf("expected %d codes, got %d", RecoveryCodeCount, len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if !IsRecoveryCode(code) || len(code) != 11 || code[5] != '-' {
+			test.Fatalf("unexpected code format %q", code)
+		}
+		if seen[code] {
+			test.Fatalf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestNormalizeRecoveryCode(test *testing.T) {
+	if got := NormalizeRecoveryCode(" ABCDE-fgh23 "); got != "abcdefgh23" {
+		test.Fatalf("unexpected normalized code %q", got)
+	}
+	if IsRecoveryCode("123456") {
+		test.Fatalf("a TOTP code is not a recovery code")
+	}
+}
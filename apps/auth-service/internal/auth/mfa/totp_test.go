@@ -0,0 +1,92 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238, Appendix B: SHA1 test vectors, secret "12345678901234567890"
+func TestTOTPCode_RFC6238Vectors(test *testing.T) {
+	key := []byte("12345678901234567890")
+
+	vectors := []struct {
+		unix int64
+		code string // last 6 digits of the 8-digit RFC values
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, vector := range vectors {
+		if got := totpCode(key, vector.unix/30); got != vector.code {
+			test.Errorf("at %d: expected %s, got %s", vector.unix, vector.code, got)
+		}
+	}
+}
+
+func TestValidateTOTP_AcceptsCurrentCodeOnce(test *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+
+	step, ok := ValidateTOTP(secret, "050471", now, 0)
+	if !ok {
+		test.Fatal("expected current code to be accepted")
+	}
+
+	// same code again: replay
+	if _, ok := ValidateTOTP(secret, "050471", now, step); ok {
+		test.Fatal("expected replayed code to be rejected")
+	}
+}
+
+func TestValidateTOTP_RejectsWrongCode(test *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		test.Fatalf("failed to generate secret: %v", err)
+	}
+
+	if _, ok := ValidateTOTP(secret, "000000", time.Now(), 0); ok {
+		// a random secret producing 000000 in three windows is practically impossible
+		test.Fatal("expected wrong code to be rejected")
+	}
+}
+
+func TestOTPAuthURI(test *testing.T) {
+	uri := OTPAuthURI("family-space", "a@b.com", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/family-space:a@b.com?") {
+		test.Fatalf("unexpected URI %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=family-space") {
+		test.Fatalf("expected secret and issuer in URI %s", uri)
+	}
+}
+
+func TestSecretBox_RoundTrip(test *testing.T) {
+	box, err := NewSecretBox([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		test.Fatalf("failed to create secret box: %v", err)
+	}
+
+	sealed, err := box.Seal("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		test.Fatalf("failed to seal: %v", err)
+	}
+	if strings.Contains(sealed, "JBSWY3DPEHPK3PXP") {
+		test.Fatal("secret must not be stored in plain text")
+	}
+
+	opened, err := box.Open(sealed)
+	if err != nil || opened != "JBSWY3DPEHPK3PXP" {
+		test.Fatalf("expected round trip, got %q, %v", opened, err)
+	}
+
+	if _, err := box.Open(sealed[:len(sealed)-2] + "AA"); err == nil {
+		test.Fatal("expected tampered ciphertext to be rejected")
+	}
+}
@@ -0,0 +1,60 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+// ClientRegistry holds the apps allowed to use the authorization code flow.
+// Clients are configured, not registered at runtime; read-only after creation.
+type ClientRegistry struct {
+	clients map[string]domain.OAuthClient
+}
+
+func NewClientRegistry(clients []domain.OAuthClient) *ClientRegistry {
+	registry := &ClientRegistry{clients: make(map[string]domain.OAuthClient, len(clients))}
+	for _, client := range clients {
+		registry.clients[client.ID] = client
+	}
+	return registry
+}
+
+// reads a JSON array of clients:
+// [{"client_id": "wiki", "name": "Wiki", "redirect_uris": ["https://wiki.home/callback"]}]
+func LoadClientRegistry(path string) (*ClientRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var clients []domain.OAuthClient
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return nil, fmt.Errorf("invalid OAuth clients file: %w", err)
+	}
+
+	for _, client := range clients {
+		if client.ID == "" || len(client.RedirectURIs) == 0 {
+			return nil, fmt.Errorf("OAuth client %q needs a client_id and redirect_uris", client.Name)
+		}
+	}
+
+	return NewClientRegistry(clients), nil
+}
+
+func (registry *ClientRegistry) Lookup(clientID string) (domain.OAuthClient, bool) {
+	client, ok := registry.clients[clientID]
+	return client, ok
+}
+
+// redirect URIs are compared exactly, no prefix or wildcard matching
+func AllowsRedirectURI(client domain.OAuthClient, redirectURI string) bool {
+	for _, allowed := range client.RedirectURIs {
+		if allowed == redirectURI {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package oauth_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/oauth"
+)
+
+func TestLoadClientRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clients.json")
+	content := `[{"client_id": "wiki", "name": "Wiki", "redirect_uris": ["https://wiki.home/callback"]}]`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write clients file: %v", err)
+	}
+
+	registry, err := oauth.LoadClientRegistry(path)
+	if err != nil {
+		t.Fatalf("failed to load clients: %v", err)
+	}
+
+	client, ok := registry.Lookup("wiki")
+	if !ok {
+		t.Fatal("expected wiki client")
+	}
+	if !oauth.AllowsRedirectURI(client, "https://wiki.home/callback") {
+		t.Fatal("expected registered redirect URI to be allowed")
+	}
+	if oauth.AllowsRedirectURI(client, "https://wiki.home/callback/evil") {
+		t.Fatal("expected only exact redirect URI matches")
+	}
+
+	if _, ok := registry.Lookup("unknown"); ok {
+		t.Fatal("expected unknown client to be missing")
+	}
+}
+
+func TestLoadClientRegistry_MissingRedirectURIs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clients.json")
+	if err := os.WriteFile(path, []byte(`[{"client_id": "wiki"}]`), 0o600); err != nil {
+		t.Fatalf("failed to write clients file: %v", err)
+	}
+
+	if _, err := oauth.LoadClientRegistry(path); err == nil {
+		t.Fatal("expected error for client without redirect_uris")
+	}
+}
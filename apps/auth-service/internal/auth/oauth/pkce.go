@@ -0,0 +1,46 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// the only PKCE method accepted; "plain" would leak the verifier with the challenge
+const PKCEMethodS256 = "S256"
+
+// code_challenge is BASE64URL(SHA256(code_verifier)) without padding: always 43 chars
+func ValidCodeChallenge(challenge string) bool {
+	if len(challenge) != 43 {
+		return false
+	}
+	_, err := base64.RawURLEncoding.DecodeString(challenge)
+	return err == nil
+}
+
+// code_verifier is 43-128 unreserved characters (RFC 7636, section 4.1)
+func ValidCodeVerifier(verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	for _, char := range verifier {
+		switch {
+		case char >= 'A' && char <= 'Z', char >= 'a' && char <= 'z', char >= '0' && char <= '9':
+		case char == '-', char == '.', char == '_', char == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func VerifyPKCE(verifier string, challenge string) bool {
+	if !ValidCodeVerifier(verifier) {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,41 @@
+package oauth_test
+
+import (
+	"testing"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/oauth"
+)
+
+// example from RFC 7636, Appendix B
+const (
+	rfcVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestVerifyPKCE_RFC7636Example(t *testing.T) {
+	if !oauth.VerifyPKCE(rfcVerifier, rfcChallenge) {
+		t.Fatal("expected RFC 7636 verifier to match its challenge")
+	}
+}
+
+func TestVerifyPKCE_WrongVerifier(t *testing.T) {
+	wrong := "aBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	if oauth.VerifyPKCE(wrong, rfcChallenge) {
+		t.Fatal("expected mismatch for a different verifier")
+	}
+}
+
+func TestVerifyPKCE_RejectsShortVerifier(t *testing.T) {
+	if oauth.VerifyPKCE("short", rfcChallenge) {
+		t.Fatal("expected verifier below 43 chars to be rejected")
+	}
+}
+
+func TestValidCodeChallenge(t *testing.T) {
+	if !oauth.ValidCodeChallenge(rfcChallenge) {
+		t.Fatal("expected RFC 7636 challenge to be valid")
+	}
+	if oauth.ValidCodeChallenge("not a challenge") {
+		t.Fatal("expected malformed challenge to be invalid")
+	}
+}
@@ -0,0 +1,95 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// cheap parameters, tests do not need a hard hash
+var testArgon2idParams = Argon2idParams{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestArgon2idHasher_HashAndCompare(test *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+
+	password := "my-very-secure-password"
+
+	hash, err := hasher.Hash(password)
+	if err != nil {
+		test.Fatalf("hash failed: %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		test.Fatalf("expected PHC formatted hash, got %q", hash)
+	}
+
+	if err := hasher.Compare(hash, password); err != nil {
+		test.Fatalf("expected password to match: %v", err)
+	}
+
+	if err := hasher.Compare(hash, "wrong-password"); !errors.Is(err, ErrMismatch) {
+		test.Fatalf("expected %v, got %v", ErrMismatch, err)
+	}
+}
+
+func TestArgon2idHasher_LongPasswordsAreNotTruncated(test *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+
+	password := strings.Repeat("a", 100)
+
+	hash, err := hasher.Hash(password)
+	if err != nil {
+		test.Fatalf("hash failed: %v", err)
+	}
+
+	// same first 72 bytes, which bcrypt could not tell apart
+	if err := hasher.Compare(hash, strings.Repeat("a", 72)); err == nil {
+		test.Fatalf("expected compare to fail for a truncated password")
+	}
+}
+
+func TestArgon2idHasher_ComparesWithStoredParameters(test *testing.T) {
+	old := NewArgon2idHasher(testArgon2idParams)
+	hash, err := old.Hash("password")
+	if err != nil {
+		test.Fatalf("hash failed: %v", err)
+	}
+
+	stronger := testArgon2idParams
+	stronger.Iterations = 2
+	hasher := NewArgon2idHasher(stronger)
+
+	if err := hasher.Compare(hash, "password"); err != nil {
+		test.Fatalf("expected hash with older parameters to verify: %v", err)
+	}
+
+	if !hasher.NeedsRehash(hash) {
+		test.Fatalf("expected hash with older parameters to need a rehash")
+	}
+
+	if old.NeedsRehash(hash) {
+		test.Fatalf("hash with current parameters must not need a rehash")
+	}
+}
+
+func TestArgon2idHasher_MalformedHash(test *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+
+	for _, hash := range []string{
+		"",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$not base64!$a2V5",
+	} {
+		if err := hasher.Compare(hash, "password"); !errors.Is(err, ErrUnknownHashFormat) {
+			test.Fatalf("expected %v for %q, got %v", ErrUnknownHashFormat, hash, err)
+		}
+	}
+}
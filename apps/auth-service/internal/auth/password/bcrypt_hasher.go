@@ -1,6 +1,10 @@
 package password
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 type BcryptHasher struct {
 	cost int
@@ -15,6 +19,7 @@ func NewBcryptHasher(cost int) *BcryptHasher {
 	return &BcryptHasher{cost: cost}
 }
 
+// passwords longer than 72 bytes are rejected (bcrypt would ignore the rest)
 func (h *BcryptHasher) Hash(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword(
 		[]byte(password),
@@ -24,8 +29,17 @@ func (h *BcryptHasher) Hash(password string) (string, error) {
 }
 
 func (h *BcryptHasher) Compare(hash string, password string) error {
-	return bcrypt.CompareHashAndPassword(
+	err := bcrypt.CompareHashAndPassword(
 		[]byte(hash),
 		[]byte(password),
 	)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+	return err
+}
+
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < h.cost
 }
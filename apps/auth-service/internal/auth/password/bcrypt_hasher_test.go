@@ -36,3 +36,17 @@ func TestBcryptHasher_CompareFails(test *testing.T) {
 		test.Fatalf("expected compare to fail for wrong password")
 	}
 }
+
+func TestBcryptHasher_NeedsRehash(test *testing.T) {
+	weak, err := NewBcryptHasher(4).Hash("password")
+	if err != nil {
+		test.Fatalf("hash failed: %v", err)
+	}
+
+	if !NewBcryptHasher(5).NeedsRehash(weak) {
+		test.Fatalf("expected a hash with a lower cost to need a rehash")
+	}
+	if NewBcryptHasher(4).NeedsRehash(weak) {
+		test.Fatalf("hash with the current cost must not need a rehash")
+	}
+}
@@ -0,0 +1,63 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedPasswordDir looks passwords up in a local copy of the Have I Been Pwned
+// range files, as written by the official downloader: one file per 5 character
+// SHA-1 prefix (21BD1.txt), each line the remaining 35 characters of a breached
+// hash and its count (SUFFIX:COUNT). Like the k-anonymity API, only the file of
+// the password's prefix is read. Prefixes without a file count as clean, so a
+// partial copy (e.g. the most common passwords only) works too.
+type BreachedPasswordDir struct {
+	dir string
+}
+
+func NewBreachedPasswordDir(dir string) (*BreachedPasswordDir, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	return &BreachedPasswordDir{dir: dir}, nil
+}
+
+func (d *BreachedPasswordDir) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(d.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// CRLF in files saved from the API
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, _ := strings.Cut(line, ":")
+		// count 0: padding entry of an API response, not a real hash
+		if strings.EqualFold(candidate, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
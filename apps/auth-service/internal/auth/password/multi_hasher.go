@@ -0,0 +1,51 @@
+package password
+
+import "strings"
+
+// MultiHasher hashes new passwords with Argon2id and still verifies the
+// bcrypt hashes of existing users. Every bcrypt hash needs a rehash, so users
+// move to Argon2id on their next login, without a forced reset.
+type MultiHasher struct {
+	argon2id *Argon2idHasher
+	bcrypt   *BcryptHasher
+}
+
+func NewMultiHasher(argon2id *Argon2idHasher, bcrypt *BcryptHasher) *MultiHasher {
+	return &MultiHasher{
+		argon2id: argon2id,
+		bcrypt:   bcrypt,
+	}
+}
+
+func (h *MultiHasher) Hash(password string) (string, error) {
+	return h.argon2id.Hash(password)
+}
+
+func (h *MultiHasher) Compare(hash string, password string) error {
+	switch {
+	case isArgon2id(hash):
+		return h.argon2id.Compare(hash, password)
+	case isBcrypt(hash):
+		return h.bcrypt.Compare(hash, password)
+	default:
+		return ErrUnknownHashFormat
+	}
+}
+
+func (h *MultiHasher) NeedsRehash(hash string) bool {
+	if isArgon2id(hash) {
+		return h.argon2id.NeedsRehash(hash)
+	}
+	return true
+}
+
+func isArgon2id(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+// $2a$, $2b$ and $2y$ (the variant written by the PHP library)
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
@@ -0,0 +1,59 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestMultiHasher() *MultiHasher {
+	return NewMultiHasher(NewArgon2idHasher(testArgon2idParams), NewBcryptHasher(bcrypt.MinCost))
+}
+
+func TestMultiHasher_HashesWithArgon2id(test *testing.T) {
+	hasher := newTestMultiHasher()
+
+	hash, err := hasher.Hash("password")
+	if err != nil {
+		test.Fatalf("hash failed: %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		test.Fatalf("expected an argon2id hash, got %q", hash)
+	}
+	if err := hasher.Compare(hash, "password"); err != nil {
+		test.Fatalf("expected password to match: %v", err)
+	}
+	if hasher.NeedsRehash(hash) {
+		test.Fatalf("current argon2id hash must not need a rehash")
+	}
+}
+
+func TestMultiHasher_VerifiesLegacyBcrypt(test *testing.T) {
+	hasher := newTestMultiHasher()
+
+	legacy, err := NewBcryptHasher(bcrypt.MinCost).Hash("password")
+	if err != nil {
+		test.Fatalf("hash failed: %v", err)
+	}
+
+	if err := hasher.Compare(legacy, "password"); err != nil {
+		test.Fatalf("expected bcrypt hash to verify: %v", err)
+	}
+	if err := hasher.Compare(legacy, "wrong-password"); !errors.Is(err, ErrMismatch) {
+		test.Fatalf("expected %v, got %v", ErrMismatch, err)
+	}
+	if !hasher.NeedsRehash(legacy) {
+		test.Fatalf("bcrypt hash must need a rehash")
+	}
+}
+
+func TestMultiHasher_UnknownFormat(test *testing.T) {
+	hasher := newTestMultiHasher()
+
+	if err := hasher.Compare("plaintext", "plaintext"); !errors.Is(err, ErrUnknownHashFormat) {
+		test.Fatalf("expected %v, got %v", ErrUnknownHashFormat, err)
+	}
+}
@@ -0,0 +1,137 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// rules of the policy, stable names for clients
+const (
+	RuleMinLength    = "min_length"
+	RuleMaxLength    = "max_length"
+	RulePersonalInfo = "personal_info"
+	RuleBreached     = "breached"
+)
+
+// shorter user inputs (e.g. "al" from al@example.com) are not checked,
+// too many good passwords would contain them
+const minPersonalInfoLength = 3
+
+// one failed rule; Message is meant for people, Rule for code
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError lists every rule the password failed, so all can be shown at once.
+// errors.Is(err, errs.ErrWeakPassword) holds for it.
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	rules := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		rules[i] = violation.Rule
+	}
+	return fmt.Sprintf("%v: %s", errs.ErrWeakPassword, strings.Join(rules, ", "))
+}
+
+func (e *PolicyError) Unwrap() error {
+	return errs.ErrWeakPassword
+}
+
+// implemented by BreachedPasswordDir
+type BreachedPasswords interface {
+	Contains(password string) (bool, error)
+}
+
+// Policy is applied wherever a password is chosen: registration, reset and change
+type Policy struct {
+	// in characters
+	minLength int
+	// in bytes, the unit of hasher limits (bcrypt stops at 72)
+	maxLength int
+	// nil disables the breached password check
+	breached BreachedPasswords
+}
+
+func NewPolicy(minLength int, maxLength int, breached BreachedPasswords) *Policy {
+	return &Policy{
+		minLength: minLength,
+		maxLength: maxLength,
+		breached:  breached,
+	}
+}
+
+// userInputs are values the user entered elsewhere (email, family name);
+// the password must not contain any of them. Returns a *PolicyError
+// for a weak password, other errors only if the breached check failed.
+func (p *Policy) Validate(password string, userInputs ...string) error {
+	var violations []Violation
+
+	if utf8.RuneCountInString(password) < p.minLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("must be at least %d characters long", p.minLength),
+		})
+	}
+
+	if len(password) > p.maxLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("must be at most %d bytes long", p.maxLength),
+		})
+	}
+
+	if containsPersonalInfo(password, userInputs) {
+		violations = append(violations, Violation{
+			Rule:    RulePersonalInfo,
+			Message: "must not contain your email address or family name",
+		})
+	}
+
+	if p.breached != nil {
+		breached, err := p.breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, Violation{
+				Rule:    RuleBreached,
+				Message: "appeared in a data breach, choose another one",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// case-insensitive; for an email address its local part is checked too
+func containsPersonalInfo(password string, userInputs []string) bool {
+	password = strings.ToLower(password)
+
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+
+		candidates := []string{input}
+		if local, _, ok := strings.Cut(input, "@"); ok {
+			candidates = append(candidates, local)
+		}
+
+		for _, candidate := range candidates {
+			if utf8.RuneCountInString(candidate) >= minPersonalInfoLength &&
+				strings.Contains(password, candidate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
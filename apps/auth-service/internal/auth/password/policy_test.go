@@ -0,0 +1,163 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+type fakeBreachedPasswords struct {
+	breached map[string]bool
+	err      error
+}
+
+func (f *fakeBreachedPasswords) Contains(password string) (bool, error) {
+	return f.breached[password], f.err
+}
+
+func violatedRules(test *testing.T, err error) []string {
+	test.Helper()
+
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		test.Fatalf("expected *PolicyError, got %v", err)
+	}
+	if !errors.Is(err, errs.ErrWeakPassword) {
+		test.Fatalf("expected error to match %v", errs.ErrWeakPassword)
+	}
+
+	rules := make([]string, len(policyErr.Violations))
+	for i, violation := range policyErr.Violations {
+		rules[i] = violation.Rule
+	}
+	return rules
+}
+
+func TestPolicy_AcceptsGoodPassword(test *testing.T) {
+	policy := NewPolicy(8, 72, &fakeBreachedPasswords{})
+
+	if err := policy.Validate("correct horse battery staple", "anna@example.com", "Smith"); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPolicy_Length(test *testing.T) {
+	policy := NewPolicy(8, 72, nil)
+
+	rules := violatedRules(test, policy.Validate("short"))
+	if len(rules) != 1 || rules[0] != RuleMinLength {
+		test.Fatalf("expected min_length, got %v", rules)
+	}
+
+	rules = violatedRules(test, policy.Validate(strings.Repeat("a", 73)))
+	if len(rules) != 1 || rules[0] != RuleMaxLength {
+		test.Fatalf("expected max_length, got %v", rules)
+	}
+
+	// characters, not bytes: 8 two-byte runes are long enough
+	if err := policy.Validate(strings.Repeat("ä", 8)); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPolicy_PersonalInfo(test *testing.T) {
+	policy := NewPolicy(8, 72, nil)
+
+	for _, password := range []string{
+		"my Anna@Example.com password",
+		"anna-is-the-best",
+		"the SMITH family rocks",
+	} {
+		rules := violatedRules(test, policy.Validate(password, "anna@example.com", "Smith"))
+		if len(rules) != 1 || rules[0] != RulePersonalInfo {
+			test.Fatalf("expected personal_info for %q, got %v", password, rules)
+		}
+	}
+
+	// too short to be checked
+	if err := policy.Validate("albatross flies", "al@example.com", "Al"); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPolicy_Breached(test *testing.T) {
+	policy := NewPolicy(8, 72, &fakeBreachedPasswords{breached: map[string]bool{"password123": true}})
+
+	rules := violatedRules(test, policy.Validate("password123"))
+	if len(rules) != 1 || rules[0] != RuleBreached {
+		test.Fatalf("expected breached, got %v", rules)
+	}
+}
+
+func TestPolicy_ReportsAllViolations(test *testing.T) {
+	policy := NewPolicy(8, 72, &fakeBreachedPasswords{breached: map[string]bool{"anna": true}})
+
+	rules := violatedRules(test, policy.Validate("anna", "anna@example.com"))
+	if strings.Join(rules, ",") != "min_length,personal_info,breached" {
+		test.Fatalf("unexpected violations %v", rules)
+	}
+}
+
+func TestPolicy_BreachedCheckFailure(test *testing.T) {
+	lookupErr := errors.New("disk failure")
+	policy := NewPolicy(8, 72, &fakeBreachedPasswords{err: lookupErr})
+
+	if err := policy.Validate("correct horse battery staple"); !errors.Is(err, lookupErr) {
+		test.Fatalf("expected %v, got %v", lookupErr, err)
+	}
+}
+
+func TestBreachedPasswordDir(test *testing.T) {
+	dir := test.TempDir()
+
+	// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	content := "003D68EB55068C33ACE09247EE4C639306B:3\r\n" +
+		"1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n"
+	if err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(content), 0o600); err != nil {
+		test.Fatalf("write failed: %v", err)
+	}
+
+	breached, err := NewBreachedPasswordDir(dir)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	found, err := breached.Contains("password")
+	if err != nil || !found {
+		test.Fatalf("expected password to be breached, got %v, %v", found, err)
+	}
+
+	// no file for the prefix
+	found, err = breached.Contains("correct horse battery staple")
+	if err != nil || found {
+		test.Fatalf("expected a clean password, got %v, %v", found, err)
+	}
+}
+
+func TestBreachedPasswordDir_IgnoresPadding(test *testing.T) {
+	dir := test.TempDir()
+
+	content := "1E4C9B93F3F0682250B6CF8331B7EE68FD8:0\n"
+	if err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(content), 0o600); err != nil {
+		test.Fatalf("write failed: %v", err)
+	}
+
+	breached, err := NewBreachedPasswordDir(dir)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if found, _ := breached.Contains("password"); found {
+		test.Fatalf("padding entries must not count as breached")
+	}
+}
+
+func TestNewBreachedPasswordDir_Missing(test *testing.T) {
+	if _, err := NewBreachedPasswordDir(filepath.Join(test.TempDir(), "missing")); err == nil {
+		test.Fatalf("expected an error for a missing directory")
+	}
+}
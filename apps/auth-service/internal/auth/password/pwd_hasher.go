@@ -1,6 +1,16 @@
 package password
 
+import "errors"
+
+var (
+	ErrMismatch          = errors.New("password does not match")
+	ErrUnknownHashFormat = errors.New("unknown password hash format")
+)
+
 type PasswordHasher interface {
 	Hash(password string) (string, error)
 	Compare(hash string, password string) error
+	// true if the hash was made with an outdated algorithm or parameters;
+	// login replaces it while the plaintext password is at hand
+	NeedsRehash(hash string) bool
 }
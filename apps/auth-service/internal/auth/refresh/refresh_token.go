@@ -3,10 +3,33 @@ package refresh
 import "time"
 
 type RefreshToken struct {
-	ID        string
-	UserID    string
+	ID     string
+	UserID string
+	// all tokens rotated from the same login share one session (chain) ID
+	SessionID string
+	// token this one was rotated from; nil for the first token of a session
+	ParentID *string
+	// authentication methods of the login that started the session (amr claim)
+	AMR       []string
 	TokenHash string
 	ExpiresAt time.Time
 	RevokedAt *time.Time
 	CreatedAt time.Time
+
+	// time of the login that started the session; copied on rotation,
+	// so it outlives the first token
+	SessionCreatedAt time.Time
+	// the session ends then however often it is refreshed (absolute lifetime);
+	// ExpiresAt never goes past it
+	SessionExpiresAt time.Time
+	// the login asked to be remembered: the long lifetime profile
+	RememberMe bool
+
+	// client that started the session, copied on rotation too; empty if not known
+	UserAgent  string
+	CreatedIP  string
+	DeviceName string
+	// client and time of the rotation that issued this token (the login for the first one)
+	LastUsedIP string
+	LastUsedAt time.Time
 }
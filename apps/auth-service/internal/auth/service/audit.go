@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+type AuditSink = audit.AuditSink
+
+// records the events of an operation once its transaction has ended: the sink
+// writes in a transaction of its own. The operation already happened, so a lost
+// event is logged rather than failing it, and a client hanging up does not cancel the write
+func recordAudit(ctx context.Context, sink AuditSink, events ...audit.Event) {
+	ctx = context.WithoutCancel(ctx)
+	for _, event := range events {
+		if err := sink.Record(ctx, event); err != nil {
+			log.Printf("audit event %s not recorded (user=%s): %v", event.Type, event.UserID, err)
+		}
+	}
+}
+
+// outcome of a login attempt; none for internal errors, those are logged with the
+// rolled back transaction. amr lists the methods the user got through or tried;
+// userID and clientIP may be empty
+func loginEvents(userID string, clientIP string, amr []string, mfaRequired bool, err error) []audit.Event {
+	event := audit.Event{
+		Type:     audit.LoginFailed,
+		UserID:   userID,
+		ClientIP: clientIP,
+		Details:  map[string]string{"amr": strings.Join(amr, " ")},
+	}
+
+	switch {
+	case err == nil && mfaRequired, errors.Is(err, errs.ErrMFARequired):
+		event.Type = audit.LoginMFARequired
+	case err == nil:
+		event.Type = audit.LoginSucceeded
+	case errors.Is(err, errs.ErrLoginThrottled):
+		event.Details["reason"] = audit.ReasonThrottled
+	case errors.Is(err, errs.ErrInvalidCredentials):
+		event.Details["reason"] = audit.ReasonInvalidCredentials
+	case errors.Is(err, errs.ErrEmailNotVerified):
+		event.Details["reason"] = audit.ReasonEmailNotVerified
+	case errors.Is(err, errs.ErrInvalidOTP):
+		event.Details["reason"] = audit.ReasonInvalidOTP
+	case errors.Is(err, errs.ErrInvalidMFAChallenge):
+		event.Details["reason"] = audit.ReasonInvalidChallenge
+	case errors.Is(err, errs.ErrInvalidPasskey), errors.Is(err, errs.ErrInvalidPasskeyCeremony):
+		event.Details["reason"] = audit.ReasonInvalidPasskey
+	default:
+		return nil
+	}
+
+	return []audit.Event{event}
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/oauth"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+	"github.com/google/uuid"
+)
+
+type AuthorizationRequest = domain.AuthorizationRequest
+type CodeExchange = domain.CodeExchange
+
+// implemented by oauth.ClientRegistry
+type ClientRegistry interface {
+	Lookup(clientID string) (domain.OAuthClient, bool)
+}
+
+// AuthorizationService implements the OAuth 2.0 authorization code grant with
+// mandatory PKCE (S256): /authorize checks the password and issues a short-lived
+// code, /token exchanges it for the same tokens a password login returns
+type AuthorizationService struct {
+	transactionMgr storage.TransactionMgr
+	login          *LoginService
+	codeStore      storage.AuthorizationCodeStoreProvider
+	clients        ClientRegistry
+	codeHasher     refresh.RefreshTokenHasher
+	codeGen        refresh.RefreshTokenGenerator
+	codeTTL        time.Duration
+}
+
+// login provides the credential check and token issuance;
+// codes are generated and hashed like refresh tokens
+func NewAuthorizationService(
+	transactionMgr storage.TransactionMgr,
+	login *LoginService,
+	codeStore storage.AuthorizationCodeStoreProvider,
+	clients ClientRegistry,
+	codeHasher refresh.RefreshTokenHasher,
+	codeGen refresh.RefreshTokenGenerator,
+	codeTTL time.Duration,
+) *AuthorizationService {
+	return &AuthorizationService{
+		transactionMgr: transactionMgr,
+		login:          login,
+		codeStore:      codeStore,
+		clients:        clients,
+		codeHasher:     codeHasher,
+		codeGen:        codeGen,
+		codeTTL:        codeTTL,
+	}
+}
+
+// errors of this check must not be sent to the redirect URI: it is not trusted yet
+func (svc *AuthorizationService) ValidateClient(clientID string, redirectURI string) error {
+	client, ok := svc.clients.Lookup(clientID)
+	if !ok {
+		return errs.ErrUnknownClient
+	}
+	if !oauth.AllowsRedirectURI(client, redirectURI) {
+		return errs.ErrInvalidRedirectURI
+	}
+	return nil
+}
+
+// checked before the user is asked to log in; errors go to the redirect URI
+func (svc *AuthorizationService) ValidateRequest(req AuthorizationRequest) error {
+	if err := svc.ValidateClient(req.ClientID, req.RedirectURI); err != nil {
+		return err
+	}
+	if req.CodeChallengeMethod != oauth.PKCEMethodS256 || !oauth.ValidCodeChallenge(req.CodeChallenge) {
+		return errs.ErrInvalidAuthorizationRequest
+	}
+	return nil
+}
+
+// authenticates the user with the login credential check and returns the raw code;
+// users with TOTP enabled must also send a one-time or recovery code (ErrMFARequired otherwise)
+func (svc *AuthorizationService) Authorize(
+	ctx context.Context,
+	req AuthorizationRequest,
+	email string,
+	password string,
+	otp string,
+	clientIP string,
+) (code string, err error) {
+
+	if err := svc.ValidateRequest(req); err != nil {
+		return "", err
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return "", err
+	}
+
+	var user User
+	amr := passwordAMR
+
+	defer func() {
+		finish(commitOnFailedLogin(err))
+		events := loginEvents(user.ID, clientIP, amr, false, err)
+		for _, event := range events {
+			event.Details["client_id"] = req.ClientID
+		}
+		recordAudit(ctx, svc.login.audit, events...)
+	}()
+
+	user, _, err = svc.login.authenticate(ctx, exec, email, password, clientIP)
+	if err != nil {
+		return "", err
+	}
+
+	mfaEnabled, err := svc.login.secondFactor.enabled(ctx, exec, user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if mfaEnabled {
+		if otp == "" {
+			return "", errs.ErrMFARequired
+		}
+		verified, err := svc.login.verifySecondFactor(ctx, exec, user, otp, clientIP)
+		if err != nil {
+			return "", err
+		}
+		amr = verified
+	}
+
+	if err := svc.login.throttle.reset(ctx, exec, email, clientIP); err != nil {
+		return "", err
+	}
+
+	code, err = svc.codeGen.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := svc.codeHasher.Hash(code)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = svc.codeStore(exec).Create(ctx, domain.AuthorizationCode{
+		ID:            uuid.NewString(),
+		CodeHash:      hash,
+		ClientID:      req.ClientID,
+		UserID:        user.ID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         req.Scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AMR:           amr,
+		AuthTime:      now,
+		RememberMe:    req.RememberMe,
+		ExpiresAt:     now.Add(svc.codeTTL),
+		CreatedAt:     now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// redeems a code once; every mismatch is the same invalid grant to the client
+func (svc *AuthorizationService) Exchange(
+	ctx context.Context,
+	exchange CodeExchange,
+) (tokens IssuedTokens, err error) {
+
+	hash, err := svc.codeHasher.Hash(exchange.Code)
+	if err != nil {
+		return IssuedTokens{}, errs.ErrInvalidGrant
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+	defer func() {
+		// a code presented with a wrong verifier or redirect URI stays burned
+		if errors.Is(err, errs.ErrInvalidGrant) {
+			finish(nil)
+			return
+		}
+		finish(err)
+	}()
+
+	codeStore := svc.codeStore(exec)
+
+	stored, err := codeStore.GetByHash(ctx, hash)
+	if errors.Is(err, errs.ErrNotFound) {
+		return IssuedTokens{}, errs.ErrInvalidGrant
+	}
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	err = codeStore.MarkUsed(ctx, stored.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		// already exchanged
+		return IssuedTokens{}, errs.ErrInvalidGrant
+	}
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	if time.Now().After(stored.ExpiresAt) ||
+		stored.ClientID != exchange.ClientID ||
+		stored.RedirectURI != exchange.RedirectURI ||
+		!oauth.VerifyPKCE(exchange.CodeVerifier, stored.CodeChallenge) {
+		return IssuedTokens{}, errs.ErrInvalidGrant
+	}
+
+	user, err := svc.login.userStoreProvider(exec).GetById(ctx, stored.UserID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return IssuedTokens{}, errs.ErrInvalidGrant
+	}
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	membership, err := svc.login.membershipProvider(exec).GetByUserID(ctx, user.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return IssuedTokens{}, errs.ErrInvalidGrant
+	}
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	var idTokenReq IDTokenRequest
+	if slices.Contains(strings.Fields(stored.Scope), "openid") {
+		idTokenReq = IDTokenRequest{ClientID: stored.ClientID, Nonce: stored.Nonce}
+	}
+
+	// codes issued before AMR was recorded come from a password login
+	amr := stored.AMR
+	if len(amr) == 0 {
+		amr = passwordAMR
+	}
+
+	return svc.login.issueTokens(ctx, exec, user, membership, idTokenReq, amr, stored.AuthTime, stored.RememberMe)
+}
@@ -0,0 +1,369 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// example from RFC 7636, Appendix B
+const (
+	CODE_VERIFIER  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	CODE_CHALLENGE = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	REDIRECT_URI   = "https://wiki.family.example/callback"
+)
+
+func newAuthorizationService(
+	userStore *fakeUserStore,
+	codeStore *fakeAuthorizationCodeStore,
+	idTokenSigner *fakeIDTokenSigner,
+) *service.AuthorizationService {
+	return newAuthorizationServiceWithTOTP(userStore, codeStore, idTokenSigner, &fakeTOTPStore{}, &fakeLoginThrottleStore{})
+}
+
+func newAuthorizationServiceWithTOTP(
+	userStore *fakeUserStore,
+	codeStore *fakeAuthorizationCodeStore,
+	idTokenSigner *fakeIDTokenSigner,
+	totpStore *fakeTOTPStore,
+	throttleStore *fakeLoginThrottleStore,
+) *service.AuthorizationService {
+	loginSvc := service.NewLoginService(
+		&fakeDB{},
+		&fakeHasher{hash: HASH},
+		userStoreProvider(userStore),
+		membershipStoreProvider(&fakeMembershipStore{
+			membership: Membership{UserID: "u1", FamilyID: "f1", Role: "owner"},
+		}),
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
+		&fakeSigner{token: JWTToken},
+		idTokenSigner,
+		registeredClients(),
+		totpStoreProvider(totpStore),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{},
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(throttleStore),
+		service.DefaultLoginThrottling,
+		&fakeAuditSink{},
+	)
+
+	return service.NewAuthorizationService(
+		&fakeDB{},
+		loginSvc,
+		authorizationCodeStoreProvider(codeStore),
+		&fakeClientRegistry{clients: map[string]domain.OAuthClient{
+			"wiki": {ID: "wiki", RedirectURIs: []string{REDIRECT_URI}},
+		}},
+		&fakeRefreshTokenHasher{hash: "code-hash"},
+		&fakeRefreshTokenGenerator{token: "raw-code"},
+		time.Minute,
+	)
+}
+
+func validAuthorizationRequest() service.AuthorizationRequest {
+	return service.AuthorizationRequest{
+		ClientID:            "wiki",
+		RedirectURI:         REDIRECT_URI,
+		Scope:               "openid email",
+		Nonce:               "nonce-1",
+		CodeChallenge:       CODE_CHALLENGE,
+		CodeChallengeMethod: "S256",
+	}
+}
+
+func storedAuthorizationCode() domain.AuthorizationCode {
+	return domain.AuthorizationCode{
+		ID:            "code-id",
+		CodeHash:      "code-hash",
+		ClientID:      "wiki",
+		UserID:        "u1",
+		RedirectURI:   REDIRECT_URI,
+		Scope:         "openid email",
+		Nonce:         "nonce-1",
+		CodeChallenge: CODE_CHALLENGE,
+		AuthTime:      time.Now(),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+}
+
+func validCodeExchange() service.CodeExchange {
+	return service.CodeExchange{
+		Code:         "raw-code",
+		ClientID:     "wiki",
+		RedirectURI:  REDIRECT_URI,
+		CodeVerifier: CODE_VERIFIER,
+	}
+}
+
+func TestAuthorizationService_Authorize_StoresCode(test *testing.T) {
+	codeStore := &fakeAuthorizationCodeStore{}
+	svc := newAuthorizationService(
+		&fakeUserStore{user: User{ID: "u1", PasswordHash: HASH}},
+		codeStore,
+		&fakeIDTokenSigner{},
+	)
+
+	req := validAuthorizationRequest()
+	req.RememberMe = true
+
+	code, err := svc.Authorize(context.Background(), req, "a@b.com", "pw", "", "")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if code != "raw-code" {
+		test.Fatalf("expected raw code, got %q", code)
+	}
+
+	// only the hash is stored, bound to client, redirect URI and challenge
+	created := codeStore.created
+	if created.CodeHash != "code-hash" || created.UserID != "u1" || created.ClientID != "wiki" ||
+		created.RedirectURI != REDIRECT_URI || created.CodeChallenge != CODE_CHALLENGE {
+		test.Fatalf("unexpected stored code %+v", created)
+	}
+	// the session is started at the exchange, with the profile chosen here
+	if !created.RememberMe {
+		test.Fatalf("expected remember me on the stored code %+v", created)
+	}
+}
+
+func TestAuthorizationService_Authorize_InvalidCredentials(test *testing.T) {
+	codeStore := &fakeAuthorizationCodeStore{}
+	svc := newAuthorizationService(
+		&fakeUserStore{user: User{ID: "u1", PasswordHash: "wronghash"}},
+		codeStore,
+		&fakeIDTokenSigner{},
+	)
+
+	_, err := svc.Authorize(context.Background(), validAuthorizationRequest(), "a@b.com", "pw", "", "")
+	if !errors.Is(err, errs.ErrInvalidCredentials) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidCredentials, err)
+	}
+
+	if codeStore.createCalled {
+		test.Fatalf("no code must be issued for invalid credentials")
+	}
+}
+
+func TestAuthorizationService_Authorize_MFARequired(test *testing.T) {
+	codeStore := &fakeAuthorizationCodeStore{}
+	svc := newAuthorizationServiceWithTOTP(
+		&fakeUserStore{user: User{ID: "u1", PasswordHash: HASH}},
+		codeStore,
+		&fakeIDTokenSigner{},
+		&fakeTOTPStore{credential: confirmedTOTPCredential()},
+		&fakeLoginThrottleStore{},
+	)
+
+	_, err := svc.Authorize(context.Background(), validAuthorizationRequest(), "a@b.com", "pw", "", "")
+	if !errors.Is(err, errs.ErrMFARequired) {
+		test.Fatalf("expected %v, got %v", errs.ErrMFARequired, err)
+	}
+
+	_, err = svc.Authorize(context.Background(), validAuthorizationRequest(), "a@b.com", "pw", "000000", "")
+	if !errors.Is(err, errs.ErrInvalidOTP) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidOTP, err)
+	}
+
+	if codeStore.createCalled {
+		test.Fatalf("no code must be issued without the second factor")
+	}
+}
+
+func TestAuthorizationService_Authorize_WithOTPRecordsAMR(test *testing.T) {
+	codeStore := &fakeAuthorizationCodeStore{}
+	svc := newAuthorizationServiceWithTOTP(
+		&fakeUserStore{user: User{ID: "u1", PasswordHash: HASH}},
+		codeStore,
+		&fakeIDTokenSigner{},
+		&fakeTOTPStore{credential: confirmedTOTPCredential()},
+		&fakeLoginThrottleStore{},
+	)
+
+	_, err := svc.Authorize(context.Background(), validAuthorizationRequest(), "a@b.com", "pw", currentTOTPCode(test), "")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(codeStore.created.AMR, []string{"pwd", "otp", "mfa"}) {
+		test.Fatalf("expected otp login in amr, got %v", codeStore.created.AMR)
+	}
+}
+
+// the right password must not clear the count of wrong codes, or the
+// login form would let anyone with the password try every code
+func TestAuthorizationService_Authorize_LocksOutAfterWrongCodes(test *testing.T) {
+	throttleStore := &fakeLoginThrottleStore{throttles: map[string]domain.LoginThrottle{}}
+	codeStore := &fakeAuthorizationCodeStore{}
+	svc := newAuthorizationServiceWithTOTP(
+		&fakeUserStore{user: User{ID: "u1", PasswordHash: HASH, Email: "a@b.com"}},
+		codeStore,
+		&fakeIDTokenSigner{},
+		&fakeTOTPStore{credential: confirmedTOTPCredential()},
+		throttleStore,
+	)
+
+	lockoutAfter := service.DefaultLoginThrottling.Account.LockoutAfter
+	key := domain.ThrottleAccount + "/a@b.com"
+	for attempt := 1; attempt <= lockoutAfter; attempt++ {
+		// wait out the backoff
+		throttle := throttleStore.throttles[key]
+		throttle.BlockedUntil = nil
+		throttleStore.throttles[key] = throttle
+
+		_, err := svc.Authorize(context.Background(), validAuthorizationRequest(), "a@b.com", "pw", "000000", "")
+		if !errors.Is(err, errs.ErrInvalidOTP) {
+			test.Fatalf("attempt %d: expected %v, got %v", attempt, errs.ErrInvalidOTP, err)
+		}
+	}
+
+	account := throttleStore.throttles[key]
+	if account.Failures != lockoutAfter || account.BlockedUntil == nil || time.Until(*account.BlockedUntil) < 14*time.Minute {
+		test.Fatalf("expected a lockout after %d wrong codes, got %+v", lockoutAfter, account)
+	}
+
+	_, err := svc.Authorize(context.Background(), validAuthorizationRequest(), "a@b.com", "pw", currentTOTPCode(test), "")
+	if !errors.Is(err, errs.ErrLoginThrottled) {
+		test.Fatalf("expected %v, got %v", errs.ErrLoginThrottled, err)
+	}
+	if codeStore.createCalled {
+		test.Fatalf("no code must be issued while locked")
+	}
+}
+
+func TestAuthorizationService_Authorize_RequiresPKCE(test *testing.T) {
+	svc := newAuthorizationService(&fakeUserStore{}, &fakeAuthorizationCodeStore{}, &fakeIDTokenSigner{})
+
+	req := validAuthorizationRequest()
+	req.CodeChallengeMethod = "plain"
+
+	_, err := svc.Authorize(context.Background(), req, "a@b.com", "pw", "", "")
+	if !errors.Is(err, errs.ErrInvalidAuthorizationRequest) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidAuthorizationRequest, err)
+	}
+}
+
+func TestAuthorizationService_ValidateClient(test *testing.T) {
+	svc := newAuthorizationService(&fakeUserStore{}, &fakeAuthorizationCodeStore{}, &fakeIDTokenSigner{})
+
+	if err := svc.ValidateClient("unknown", REDIRECT_URI); !errors.Is(err, errs.ErrUnknownClient) {
+		test.Fatalf("expected %v, got %v", errs.ErrUnknownClient, err)
+	}
+
+	if err := svc.ValidateClient("wiki", "https://evil.example/callback"); !errors.Is(err, errs.ErrInvalidRedirectURI) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidRedirectURI, err)
+	}
+}
+
+func TestAuthorizationService_Exchange_Success(test *testing.T) {
+	codeStore := &fakeAuthorizationCodeStore{code: storedAuthorizationCode()}
+	idTokenSigner := &fakeIDTokenSigner{token: IDToken}
+	svc := newAuthorizationService(
+		&fakeUserStore{user: User{ID: "u1", Email: "a@b.com"}},
+		codeStore,
+		idTokenSigner,
+	)
+
+	tokens, err := svc.Exchange(context.Background(), validCodeExchange())
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if !codeStore.markUsedCalled {
+		test.Fatalf("expected code to be marked as used")
+	}
+
+	if tokens.AccessToken != JWTToken || tokens.RefreshToken != RefreshToken || tokens.IDToken != IDToken {
+		test.Fatalf("unexpected tokens %+v", tokens)
+	}
+
+	// openid scope: ID token for the client, with the nonce of the authorization request
+	if idTokenSigner.gotClientID != "wiki" || idTokenSigner.gotNonce != "nonce-1" {
+		test.Fatalf("unexpected ID token request %q %q", idTokenSigner.gotClientID, idTokenSigner.gotNonce)
+	}
+}
+
+func TestAuthorizationService_Exchange_WrongVerifier(test *testing.T) {
+	svc := newAuthorizationService(
+		&fakeUserStore{user: User{ID: "u1"}},
+		&fakeAuthorizationCodeStore{code: storedAuthorizationCode()},
+		&fakeIDTokenSigner{},
+	)
+
+	exchange := validCodeExchange()
+	exchange.CodeVerifier = "aBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+	_, err := svc.Exchange(context.Background(), exchange)
+	if !errors.Is(err, errs.ErrInvalidGrant) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidGrant, err)
+	}
+}
+
+func TestAuthorizationService_Exchange_RedirectURIMismatch(test *testing.T) {
+	svc := newAuthorizationService(
+		&fakeUserStore{user: User{ID: "u1"}},
+		&fakeAuthorizationCodeStore{code: storedAuthorizationCode()},
+		&fakeIDTokenSigner{},
+	)
+
+	exchange := validCodeExchange()
+	exchange.RedirectURI = "https://wiki.family.example/other"
+
+	_, err := svc.Exchange(context.Background(), exchange)
+	if !errors.Is(err, errs.ErrInvalidGrant) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidGrant, err)
+	}
+}
+
+func TestAuthorizationService_Exchange_ExpiredCode(test *testing.T) {
+	code := storedAuthorizationCode()
+	code.ExpiresAt = time.Now().Add(-time.Second)
+
+	svc := newAuthorizationService(
+		&fakeUserStore{user: User{ID: "u1"}},
+		&fakeAuthorizationCodeStore{code: code},
+		&fakeIDTokenSigner{},
+	)
+
+	_, err := svc.Exchange(context.Background(), validCodeExchange())
+	if !errors.Is(err, errs.ErrInvalidGrant) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidGrant, err)
+	}
+}
+
+func TestAuthorizationService_Exchange_CodeAlreadyUsed(test *testing.T) {
+	svc := newAuthorizationService(
+		&fakeUserStore{user: User{ID: "u1"}},
+		&fakeAuthorizationCodeStore{code: storedAuthorizationCode(), markUsedErr: errs.ErrNotFound},
+		&fakeIDTokenSigner{},
+	)
+
+	_, err := svc.Exchange(context.Background(), validCodeExchange())
+	if !errors.Is(err, errs.ErrInvalidGrant) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidGrant, err)
+	}
+}
+
+func TestAuthorizationService_Exchange_UnknownCode(test *testing.T) {
+	svc := newAuthorizationService(
+		&fakeUserStore{},
+		&fakeAuthorizationCodeStore{getErr: errs.ErrNotFound},
+		&fakeIDTokenSigner{},
+	)
+
+	_, err := svc.Exchange(context.Background(), validCodeExchange())
+	if !errors.Is(err, errs.ErrInvalidGrant) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidGrant, err)
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/mail"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+// implemented by jwt.EmailVerificationTokens
+type EmailVerificationTokens interface {
+	Issue(verification domain.EmailVerification) (string, error)
+	Verify(token string) (domain.EmailVerification, error)
+}
+
+// whether login waits for the email address to be verified
+type EmailVerificationPolicy int
+
+const (
+	// unverified users can log in (verification is informational)
+	EmailVerificationOptional EmailVerificationPolicy = iota
+	// login fails with ErrEmailNotVerified until the link was opened
+	EmailVerificationRequired
+)
+
+// EmailVerificationService sends the verification link after registration
+// and marks the address verified when the token of the link comes back
+type EmailVerificationService struct {
+	transactionMgr    storage.TransactionMgr
+	userStoreProvider storage.UserStoreProvider
+	tokens            EmailVerificationTokens
+	mailer            mail.Mailer
+	// page of the web UI that posts the token to /verify-email
+	verifyURL string
+	// resent emails still on their way
+	sending sync.WaitGroup
+}
+
+func NewEmailVerificationService(
+	transactionMgr storage.TransactionMgr,
+	userStore storage.UserStoreProvider,
+	tokens EmailVerificationTokens,
+	mailer mail.Mailer,
+	verifyURL string,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		transactionMgr:    transactionMgr,
+		userStoreProvider: userStore,
+		tokens:            tokens,
+		mailer:            mailer,
+		verifyURL:         verifyURL,
+	}
+}
+
+func (svc *EmailVerificationService) SendVerificationEmail(ctx context.Context, user User) error {
+	token, err := svc.tokens.Issue(domain.EmailVerification{
+		UserID: user.ID,
+		Email:  user.Email,
+	})
+	if err != nil {
+		return err
+	}
+
+	link := svc.verifyURL + "?token=" + url.QueryEscape(token)
+
+	return svc.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Welcome to Family Space!\n\nOpen this link to verify your email address:\n%s\n\n"+
+				"If you did not create an account, you can ignore this email.\n",
+			link,
+		),
+	})
+}
+
+// sends a new link to an unverified address; unknown and already verified
+// addresses are silently ignored. The email goes out in the background, so the
+// response takes as long for an unverified account as for any other address.
+func (svc *EmailVerificationService) ResendVerificationEmail(ctx context.Context, email string) error {
+	user, found, err := svc.unverifiedUser(ctx, email)
+	if err != nil || !found {
+		return err
+	}
+
+	// after the commit, the SMTP round-trip must not hold the transaction open;
+	// the request context ends with the response
+	svc.sending.Add(1)
+	go func() {
+		defer svc.sending.Done()
+
+		if err := svc.SendVerificationEmail(context.WithoutCancel(ctx), user); err != nil {
+			log.Printf("failed to resend verification email (user=%s): %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// blocks until the resent emails were handed to the mailer
+func (svc *EmailVerificationService) WaitForSending() {
+	svc.sending.Wait()
+}
+
+func (svc *EmailVerificationService) unverifiedUser(ctx context.Context, email string) (user User, found bool, err error) {
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, true)
+	if err != nil {
+		return User{}, false, err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	user, err = svc.userStoreProvider(exec).GetByEmail(ctx, email)
+	if errors.Is(err, errs.ErrNotFound) {
+		return User{}, false, nil
+	}
+	if err != nil {
+		return User{}, false, err
+	}
+
+	return user, user.EmailVerifiedAt == nil, nil
+}
+
+// verifying twice is not an error: the link may be opened more than once
+func (svc *EmailVerificationService) VerifyEmail(ctx context.Context, token string) (err error) {
+	verification, err := svc.tokens.Verify(token)
+	if err != nil {
+		return errs.ErrInvalidVerificationToken
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	userStore := svc.userStoreProvider(exec)
+
+	user, err := userStore.GetById(ctx, verification.UserID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+
+	// the link was sent to an address the user no longer has
+	if user.Email != verification.Email {
+		return errs.ErrInvalidVerificationToken
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	err = userStore.MarkEmailVerified(ctx, user.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		// verified concurrently
+		return nil
+	}
+	return err
+}
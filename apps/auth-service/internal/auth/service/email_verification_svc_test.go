@@ -0,0 +1,206 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/mail"
+)
+
+const VERIFY_URL = "https://family.example/verify-email"
+
+func newEmailVerificationService(
+	userStore *fakeUserStore,
+	tokens *fakeEmailVerificationTokens,
+	mailer *mail.MemoryMailer,
+) *service.EmailVerificationService {
+	return service.NewEmailVerificationService(
+		&fakeDB{
+			exec: &fakeSQLExecutor{},
+		},
+		userStoreProvider(userStore),
+		tokens,
+		mailer,
+		VERIFY_URL,
+	)
+}
+
+func TestEmailVerificationService_SendVerificationEmail(test *testing.T) {
+	mailer := mail.NewMemoryMailer()
+	tokens := &fakeEmailVerificationTokens{}
+	verificationSvc := newEmailVerificationService(&fakeUserStore{}, tokens, mailer)
+
+	err := verificationSvc.SendVerificationEmail(context.Background(), User{ID: "u1", Email: "a@b.com"})
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := mailer.Sent()
+	if len(sent) != 1 || sent[0].To != "a@b.com" {
+		test.Fatalf("expected one email to a@b.com, got %+v", sent)
+	}
+	if !strings.Contains(sent[0].Body, VERIFY_URL+"?token=a%40b.com") {
+		test.Fatalf("verification link missing from body: %q", sent[0].Body)
+	}
+	if tokens.userID != "u1" {
+		test.Fatalf("token issued for %q", tokens.userID)
+	}
+}
+
+func TestEmailVerificationService_Resend_UnknownEmail(test *testing.T) {
+	mailer := mail.NewMemoryMailer()
+	verificationSvc := newEmailVerificationService(
+		&fakeUserStore{err: errs.ErrNotFound},
+		&fakeEmailVerificationTokens{},
+		mailer,
+	)
+
+	err := verificationSvc.ResendVerificationEmail(context.Background(), "nobody@b.com")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	verificationSvc.WaitForSending()
+	if len(mailer.Sent()) != 0 {
+		test.Fatalf("no email expected for an unknown address")
+	}
+}
+
+func TestEmailVerificationService_Resend_AlreadyVerified(test *testing.T) {
+	verifiedAt := time.Now()
+	mailer := mail.NewMemoryMailer()
+	verificationSvc := newEmailVerificationService(
+		&fakeUserStore{user: User{ID: "u1", Email: "a@b.com", EmailVerifiedAt: &verifiedAt}},
+		&fakeEmailVerificationTokens{},
+		mailer,
+	)
+
+	err := verificationSvc.ResendVerificationEmail(context.Background(), "a@b.com")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	verificationSvc.WaitForSending()
+	if len(mailer.Sent()) != 0 {
+		test.Fatalf("no email expected for a verified address")
+	}
+}
+
+func TestEmailVerificationService_Resend_Unverified(test *testing.T) {
+	mailer := mail.NewMemoryMailer()
+	verificationSvc := newEmailVerificationService(
+		&fakeUserStore{user: User{ID: "u1", Email: "a@b.com"}},
+		&fakeEmailVerificationTokens{},
+		mailer,
+	)
+
+	err := verificationSvc.ResendVerificationEmail(context.Background(), "a@b.com")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	verificationSvc.WaitForSending()
+	if len(mailer.Sent()) != 1 {
+		test.Fatalf("expected a new verification email")
+	}
+}
+
+// mail delivery waits until the test lets it through
+type blockingMailer struct {
+	*mail.MemoryMailer
+	release chan struct{}
+	// transactions finished when Send was called
+	finishedBeforeSend int
+	db                 *fakeDB
+}
+
+func (mailer *blockingMailer) Send(ctx context.Context, message mail.Message) error {
+	mailer.db.mutex.Lock()
+	mailer.finishedBeforeSend = len(mailer.db.finished)
+	mailer.db.mutex.Unlock()
+
+	<-mailer.release
+	return mailer.MemoryMailer.Send(ctx, message)
+}
+
+func TestEmailVerificationService_Resend_SendsAfterCommitInBackground(test *testing.T) {
+	db := &fakeDB{exec: &fakeSQLExecutor{}}
+	mailer := &blockingMailer{MemoryMailer: mail.NewMemoryMailer(), release: make(chan struct{}), db: db}
+	verificationSvc := service.NewEmailVerificationService(
+		db,
+		userStoreProvider(&fakeUserStore{user: User{ID: "u1", Email: "a@b.com"}}),
+		&fakeEmailVerificationTokens{},
+		mailer,
+		VERIFY_URL,
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// returns while the mail is still stuck in delivery
+	if err := verificationSvc.ResendVerificationEmail(ctx, "a@b.com"); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	// the request is over before the mail is out
+	cancel()
+	close(mailer.release)
+	verificationSvc.WaitForSending()
+
+	if mailer.finishedBeforeSend != 1 {
+		test.Fatalf("expected the transaction to be finished before sending, %d were", mailer.finishedBeforeSend)
+	}
+	if len(mailer.Sent()) != 1 {
+		test.Fatalf("expected a new verification email")
+	}
+}
+
+func TestEmailVerificationService_VerifyEmail(test *testing.T) {
+	userStore := &fakeUserStore{user: User{ID: "u1", Email: "a@b.com"}}
+	verificationSvc := newEmailVerificationService(
+		userStore,
+		&fakeEmailVerificationTokens{userID: "u1"},
+		mail.NewMemoryMailer(),
+	)
+
+	if err := verificationSvc.VerifyEmail(context.Background(), "a@b.com"); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	if !userStore.verified {
+		test.Fatalf("expected the address to be marked verified")
+	}
+
+	// opening the link again is fine
+	if err := verificationSvc.VerifyEmail(context.Background(), "a@b.com"); err != nil {
+		test.Fatalf("unexpected error on second verification: %v", err)
+	}
+}
+
+func TestEmailVerificationService_VerifyEmail_InvalidToken(test *testing.T) {
+	verificationSvc := newEmailVerificationService(
+		&fakeUserStore{user: User{ID: "u1", Email: "a@b.com"}},
+		&fakeEmailVerificationTokens{userID: "u1"},
+		mail.NewMemoryMailer(),
+	)
+
+	err := verificationSvc.VerifyEmail(context.Background(), "bad")
+	if !errors.Is(err, errs.ErrInvalidVerificationToken) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidVerificationToken, err)
+	}
+}
+
+func TestEmailVerificationService_VerifyEmail_ChangedAddress(test *testing.T) {
+	userStore := &fakeUserStore{user: User{ID: "u1", Email: "new@b.com"}}
+	verificationSvc := newEmailVerificationService(
+		userStore,
+		&fakeEmailVerificationTokens{userID: "u1"},
+		mail.NewMemoryMailer(),
+	)
+
+	err := verificationSvc.VerifyEmail(context.Background(), "old@b.com")
+	if !errors.Is(err, errs.ErrInvalidVerificationToken) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidVerificationToken, err)
+	}
+	if userStore.verified {
+		test.Fatalf("the new address must not be marked verified")
+	}
+}
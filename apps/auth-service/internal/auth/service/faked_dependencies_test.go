@@ -3,7 +3,12 @@ package service_test
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
@@ -14,12 +19,15 @@ import (
 type User = domain.User
 type Membership = domain.Membership
 type Family = domain.Family
+type IDTokenRequest = domain.IDTokenRequest
 
 type UserStore = storage.UserStore
 type MembershipStore = storage.MembershipStore
 type FamilyStore = storage.FamilyStore
 
 const JWTToken = "jwt.token"
+const RefreshToken = "refresh.token"
+const IDToken = "id.token"
 
 /**FAKE DATABASE FOR UNIT TESTS **/
 type fakeSQLExecutor struct{}
@@ -40,9 +48,20 @@ func (fakeSqlExec *fakeSQLExecutor) QueryRowContext(
 	panic("QueryRowContext should not be called in service unit test")
 }
 
+func (fakeSqlExec *fakeSQLExecutor) QueryContext(
+	ctx context.Context,
+	query string,
+	args ...any,
+) (*sql.Rows, error) {
+	panic("QueryContext should not be called in service unit test")
+}
+
 type fakeDB struct {
 	exec     storage.SQLExecutor
 	beginErr error
+	// what each transaction ended with, nil is a commit
+	finished []error
+	mutex    sync.Mutex
 }
 
 // immitates starting db transaction but actually does nothing
@@ -55,6 +74,9 @@ func (fakeDB *fakeDB) BeginTransaction(
 	}
 
 	finish := func(err error) {
+		fakeDB.mutex.Lock()
+		defer fakeDB.mutex.Unlock()
+		fakeDB.finished = append(fakeDB.finished, err)
 	}
 
 	return fakeDB.exec, finish, nil
@@ -62,9 +84,10 @@ func (fakeDB *fakeDB) BeginTransaction(
 
 /********** USER STORE **********/
 type fakeUserStore struct {
-	user   User
-	err    error
-	called bool
+	user     User
+	err      error
+	called   bool
+	verified bool
 }
 
 func (fakeUserStore *fakeUserStore) GetByEmail(ctx context.Context, email string) (User, error) {
@@ -79,6 +102,24 @@ func (fakeUserStore *fakeUserStore) GetById(ctx context.Context, id string) (Use
 	return fakeUserStore.user, fakeUserStore.err
 }
 
+func (fakeUserStore *fakeUserStore) MarkEmailVerified(ctx context.Context, id string) error {
+	if fakeUserStore.user.EmailVerifiedAt != nil {
+		return errs.ErrNotFound
+	}
+	now := time.Now()
+	fakeUserStore.user.EmailVerifiedAt = &now
+	fakeUserStore.verified = true
+	return nil
+}
+
+func (fakeUserStore *fakeUserStore) UpdatePasswordHash(ctx context.Context, id string, passwordHash string) error {
+	if fakeUserStore.err != nil {
+		return fakeUserStore.err
+	}
+	fakeUserStore.user.PasswordHash = passwordHash
+	return nil
+}
+
 func userStoreProvider(store *fakeUserStore) storage.UserStoreProvider {
 	return func(exec storage.SQLExecutor) storage.UserStore {
 		return store
@@ -125,6 +166,27 @@ func (fakeFamilyStore *fakeFamilyStore) Create(ctx context.Context, family Famil
 	return fakeFamilyStore.err
 }
 
+func (fakeFamilyStore *fakeFamilyStore) GetByID(ctx context.Context, id string) (Family, error) {
+	return fakeFamilyStore.family, fakeFamilyStore.err
+}
+
+func familyStoreProvider(store *fakeFamilyStore) storage.FamilyStoreProvider {
+	return func(exec storage.SQLExecutor) storage.FamilyStore {
+		return store
+	}
+}
+
+/********** PASSWORD POLICY **********/
+type fakePasswordPolicy struct {
+	err       error
+	gotInputs []string
+}
+
+func (policy *fakePasswordPolicy) Validate(password string, userInputs ...string) error {
+	policy.gotInputs = userInputs
+	return policy.err
+}
+
 /********** HASHER INTERFACE **********/
 const HASH = "hash"
 
@@ -132,10 +194,15 @@ type fakeHasher struct {
 	err    error
 	hash   string
 	called bool
+	// every stored hash is outdated
+	rehash bool
+	// hashes passwords were compared with, in order
+	compared []string
 }
 
 func (hasher *fakeHasher) Compare(hash, password string) error {
 	hasher.called = true
+	hasher.compared = append(hasher.compared, hash)
 	if hash == HASH {
 		return nil
 	}
@@ -147,17 +214,53 @@ func (hasher *fakeHasher) Hash(password string) (string, error) {
 	return hasher.hash, hasher.err
 }
 
+func (hasher *fakeHasher) NeedsRehash(hash string) bool {
+	return hasher.rehash
+}
+
 /********** SIGNER INTERFACE **********/
 
 type fakeSigner struct {
-	token string
-	err   error
+	token        string
+	err          error
+	gotAMR       []string
+	gotSessionID string
 }
 
-func (signer *fakeSigner) GenerateSignedAccessToken(user User, m Membership) (string, error) {
+func (signer *fakeSigner) GenerateSignedAccessToken(user User, m Membership, amr []string, sessionID string) (string, error) {
+	signer.gotAMR = amr
+	signer.gotSessionID = sessionID
 	return signer.token, signer.err
 }
 
+type fakeIDTokenSigner struct {
+	token       string
+	err         error
+	gotClientID string
+	gotNonce    string
+}
+
+func (signer *fakeIDTokenSigner) GenerateSignedIDToken(
+	user User,
+	clientID string,
+	authTime time.Time,
+	nonce string,
+	amr []string,
+) (string, error) {
+	signer.gotClientID = clientID
+	signer.gotNonce = nonce
+	return signer.token, signer.err
+}
+
+type fakeAccessTokenVerifier struct {
+	claims *jwt.Claims
+	err    error
+}
+
+func (verifier *fakeAccessTokenVerifier) Verify(tokenString string) (*jwt.Claims, error) {
+	return verifier.claims, verifier.err
+}
+
 // ******** Logout and refresh service **********/
 type fakeRefreshTokenHasher struct {
 	hash string
@@ -176,12 +279,27 @@ func (hasher *fakeRefreshTokenHasher) Compare(hash, token string) error {
 }
 
 type fakeRefreshTokenStore struct {
-	token        refresh.RefreshToken
-	getErr       error
-	revokeErr    error
-	createErr    error
-	revokeCalled bool
-	createCalled bool
+	token               refresh.RefreshToken
+	created             refresh.RefreshToken
+	getErr              error
+	revokeErr           error
+	createErr           error
+	revokeSessionErr    error
+	revokeCalled        bool
+	createCalled        bool
+	revokeSessionCalled bool
+	revokeAllCalled     bool
+	keptSessionID       string
+
+	listed               []refresh.RefreshToken
+	revokeUserSessionErr error
+	revokedSessionID     string
+
+	// rows deleted by each DeleteStale call, in order; 0 once used up
+	staleBatches   []int64
+	deleteStaleErr error
+	staleBefore    time.Time
+	staleCalls     int
 }
 
 func (refreshStore *fakeRefreshTokenStore) GetByHash(
@@ -207,9 +325,69 @@ func (refreshStore *fakeRefreshTokenStore) Create(
 	token refresh.RefreshToken,
 ) error {
 	refreshStore.createCalled = true
+	refreshStore.created = token
 	return refreshStore.createErr
 }
 
+func (refreshStore *fakeRefreshTokenStore) RevokeSession(
+	ctx context.Context,
+	sessionID string,
+) error {
+	refreshStore.revokeSessionCalled = true
+	return refreshStore.revokeSessionErr
+}
+
+func (refreshStore *fakeRefreshTokenStore) RevokeAllForUser(
+	ctx context.Context,
+	userID string,
+) error {
+	refreshStore.revokeAllCalled = true
+	return nil
+}
+
+func (refreshStore *fakeRefreshTokenStore) RevokeOtherSessions(
+	ctx context.Context,
+	userID string,
+	keepSessionID string,
+) error {
+	refreshStore.keptSessionID = keepSessionID
+	return nil
+}
+
+func (refreshStore *fakeRefreshTokenStore) ListByUser(
+	ctx context.Context,
+	userID string,
+) ([]refresh.RefreshToken, error) {
+	return refreshStore.listed, nil
+}
+
+func (refreshStore *fakeRefreshTokenStore) RevokeUserSession(
+	ctx context.Context,
+	userID string,
+	sessionID string,
+) error {
+	refreshStore.revokedSessionID = sessionID
+	return refreshStore.revokeUserSessionErr
+}
+
+func (refreshStore *fakeRefreshTokenStore) DeleteStale(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) (int64, error) {
+	refreshStore.staleBefore = before
+	refreshStore.staleCalls++
+	if refreshStore.deleteStaleErr != nil {
+		return 0, refreshStore.deleteStaleErr
+	}
+	if len(refreshStore.staleBatches) == 0 {
+		return 0, nil
+	}
+	batch := refreshStore.staleBatches[0]
+	refreshStore.staleBatches = refreshStore.staleBatches[1:]
+	return batch, nil
+}
+
 func refreshStoreProvider(store *fakeRefreshTokenStore) storage.RefreshTokenStoreProvider {
 	return func(exec storage.SQLExecutor) storage.RefreshTokenStore {
 		return store
@@ -227,3 +405,460 @@ func (f *fakeRefreshTokenGenerator) Generate() (string, error) {
 	}
 	return f.token, nil
 }
+
+// ******** Authorization code flow **********/
+
+type fakeAuthorizationCodeStore struct {
+	code        domain.AuthorizationCode
+	created     domain.AuthorizationCode
+	getErr      error
+	markUsedErr error
+	createErr   error
+
+	createCalled   bool
+	markUsedCalled bool
+}
+
+func (codeStore *fakeAuthorizationCodeStore) Create(
+	ctx context.Context,
+	code domain.AuthorizationCode,
+) error {
+	codeStore.createCalled = true
+	codeStore.created = code
+	return codeStore.createErr
+}
+
+func (codeStore *fakeAuthorizationCodeStore) GetByHash(
+	ctx context.Context,
+	hash string,
+) (domain.AuthorizationCode, error) {
+	if codeStore.getErr != nil {
+		return domain.AuthorizationCode{}, codeStore.getErr
+	}
+	return codeStore.code, nil
+}
+
+func (codeStore *fakeAuthorizationCodeStore) MarkUsed(ctx context.Context, id string) error {
+	codeStore.markUsedCalled = true
+	return codeStore.markUsedErr
+}
+
+func authorizationCodeStoreProvider(store *fakeAuthorizationCodeStore) storage.AuthorizationCodeStoreProvider {
+	return func(exec storage.SQLExecutor) storage.AuthorizationCodeStore {
+		return store
+	}
+}
+
+type fakeClientRegistry struct {
+	clients map[string]domain.OAuthClient
+}
+
+func (registry *fakeClientRegistry) Lookup(clientID string) (domain.OAuthClient, bool) {
+	client, ok := registry.clients[clientID]
+	return client, ok
+}
+
+// the clients ID tokens may be issued to at login
+func registeredClients() *fakeClientRegistry {
+	return &fakeClientRegistry{clients: map[string]domain.OAuthClient{
+		"wiki": {ID: "wiki"},
+	}}
+}
+
+// ******** Multi-factor authentication **********/
+
+// nil credential: the user never enrolled
+type fakeTOTPStore struct {
+	credential  *domain.TOTPCredential
+	pending     domain.TOTPCredential
+	getErr      error
+	saveErr     error
+	confirmErr  error
+	updateErr   error
+	savedCalled bool
+
+	confirmedStep int64
+	updatedStep   int64
+}
+
+func (totpStore *fakeTOTPStore) SavePending(ctx context.Context, credential domain.TOTPCredential) error {
+	totpStore.savedCalled = true
+	totpStore.pending = credential
+	return totpStore.saveErr
+}
+
+func (totpStore *fakeTOTPStore) GetByUserID(ctx context.Context, userID string) (domain.TOTPCredential, error) {
+	if totpStore.getErr != nil {
+		return domain.TOTPCredential{}, totpStore.getErr
+	}
+	if totpStore.credential == nil {
+		return domain.TOTPCredential{}, errs.ErrNotFound
+	}
+	return *totpStore.credential, nil
+}
+
+func (totpStore *fakeTOTPStore) Confirm(ctx context.Context, userID string, lastUsedStep int64) error {
+	totpStore.confirmedStep = lastUsedStep
+	return totpStore.confirmErr
+}
+
+func (totpStore *fakeTOTPStore) UpdateLastUsedStep(ctx context.Context, userID string, step int64) error {
+	totpStore.updatedStep = step
+	return totpStore.updateErr
+}
+
+func totpStoreProvider(store *fakeTOTPStore) storage.TOTPStoreProvider {
+	return func(exec storage.SQLExecutor) storage.TOTPStore {
+		return store
+	}
+}
+
+// "encrypts" by prefixing, so tests can tell sealed from plain secrets
+type fakeSecretBox struct{}
+
+func (box *fakeSecretBox) Seal(plaintext string) (string, error) {
+	return "sealed:" + plaintext, nil
+}
+
+func (box *fakeSecretBox) Open(sealed string) (string, error) {
+	return strings.TrimPrefix(sealed, "sealed:"), nil
+}
+
+type fakeMFAChallenges struct {
+	token     string
+	challenge domain.MFAChallenge
+	verifyErr error
+	issued    domain.MFAChallenge
+}
+
+func (challenges *fakeMFAChallenges) Issue(challenge domain.MFAChallenge) (string, error) {
+	challenges.issued = challenge
+	return challenges.token, nil
+}
+
+func (challenges *fakeMFAChallenges) Verify(token string) (domain.MFAChallenge, error) {
+	return challenges.challenge, challenges.verifyErr
+}
+
+type fakeRecoveryCodeStore struct {
+	code        domain.RecoveryCode
+	replaced    []domain.RecoveryCode
+	getErr      error
+	markUsedErr error
+
+	markUsedCalled bool
+}
+
+func (recoveryStore *fakeRecoveryCodeStore) ReplaceAll(
+	ctx context.Context,
+	userID string,
+	codes []domain.RecoveryCode,
+) error {
+	recoveryStore.replaced = codes
+	return nil
+}
+
+func (recoveryStore *fakeRecoveryCodeStore) GetByHash(
+	ctx context.Context,
+	userID string,
+	hash string,
+) (domain.RecoveryCode, error) {
+	if recoveryStore.getErr != nil {
+		return domain.RecoveryCode{}, recoveryStore.getErr
+	}
+	return recoveryStore.code, nil
+}
+
+func (recoveryStore *fakeRecoveryCodeStore) MarkUsed(ctx context.Context, id string) error {
+	recoveryStore.markUsedCalled = true
+	return recoveryStore.markUsedErr
+}
+
+func recoveryCodeStoreProvider(store *fakeRecoveryCodeStore) storage.RecoveryCodeStoreProvider {
+	return func(exec storage.SQLExecutor) storage.RecoveryCodeStore {
+		return store
+	}
+}
+
+/********** PASSKEYS **********/
+
+// in memory, so a whole ceremony can run against it
+type fakeWebAuthnCredentialStore struct {
+	credentials map[string]domain.WebAuthnCredential
+	createErr   error
+}
+
+func (credentialStore *fakeWebAuthnCredentialStore) Create(
+	ctx context.Context,
+	credential domain.WebAuthnCredential,
+) error {
+	if credentialStore.createErr != nil {
+		return credentialStore.createErr
+	}
+	if credentialStore.credentials == nil {
+		credentialStore.credentials = map[string]domain.WebAuthnCredential{}
+	}
+	credentialStore.credentials[credential.ID] = credential
+	return nil
+}
+
+func (credentialStore *fakeWebAuthnCredentialStore) GetByID(
+	ctx context.Context,
+	id string,
+) (domain.WebAuthnCredential, error) {
+	credential, ok := credentialStore.credentials[id]
+	if !ok {
+		return domain.WebAuthnCredential{}, errs.ErrNotFound
+	}
+	return credential, nil
+}
+
+func (credentialStore *fakeWebAuthnCredentialStore) ListByUserID(
+	ctx context.Context,
+	userID string,
+) ([]domain.WebAuthnCredential, error) {
+	var credentials []domain.WebAuthnCredential
+	for _, credential := range credentialStore.credentials {
+		if credential.UserID == userID {
+			credentials = append(credentials, credential)
+		}
+	}
+	return credentials, nil
+}
+
+func (credentialStore *fakeWebAuthnCredentialStore) UpdateSignCount(
+	ctx context.Context,
+	id string,
+	signCount uint32,
+) error {
+	credential, ok := credentialStore.credentials[id]
+	if !ok {
+		return errs.ErrNotFound
+	}
+	credential.SignCount = signCount
+	credentialStore.credentials[id] = credential
+	return nil
+}
+
+func webAuthnCredentialStoreProvider(store *fakeWebAuthnCredentialStore) storage.WebAuthnCredentialStoreProvider {
+	return func(exec storage.SQLExecutor) storage.WebAuthnCredentialStore {
+		return store
+	}
+}
+
+type fakeWebAuthnChallengeStore struct {
+	challenges map[string]domain.WebAuthnChallenge
+	// DeleteExpired reports these counts, one per call
+	expiredBatches []int64
+	expiredBefore  time.Time
+}
+
+func (challengeStore *fakeWebAuthnChallengeStore) Create(
+	ctx context.Context,
+	challenge domain.WebAuthnChallenge,
+) error {
+	if challengeStore.challenges == nil {
+		challengeStore.challenges = map[string]domain.WebAuthnChallenge{}
+	}
+	challengeStore.challenges[challenge.ID] = challenge
+	return nil
+}
+
+func (challengeStore *fakeWebAuthnChallengeStore) Consume(
+	ctx context.Context,
+	id string,
+) (domain.WebAuthnChallenge, error) {
+	challenge, ok := challengeStore.challenges[id]
+	if !ok {
+		return domain.WebAuthnChallenge{}, errs.ErrNotFound
+	}
+	delete(challengeStore.challenges, id)
+	return challenge, nil
+}
+
+func (challengeStore *fakeWebAuthnChallengeStore) DeleteExpired(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) (int64, error) {
+	challengeStore.expiredBefore = before
+	if len(challengeStore.expiredBatches) == 0 {
+		return 0, nil
+	}
+	deleted := challengeStore.expiredBatches[0]
+	challengeStore.expiredBatches = challengeStore.expiredBatches[1:]
+	return deleted, nil
+}
+
+func webAuthnChallengeStoreProvider(store *fakeWebAuthnChallengeStore) storage.WebAuthnChallengeStoreProvider {
+	return func(exec storage.SQLExecutor) storage.WebAuthnChallengeStore {
+		return store
+	}
+}
+
+/********** EMAIL VERIFICATION **********/
+type fakeVerificationSender struct {
+	err    error
+	sentTo string
+}
+
+func (sender *fakeVerificationSender) SendVerificationEmail(ctx context.Context, user User) error {
+	sender.sentTo = user.Email
+	return sender.err
+}
+
+// token is the email address; "bad" fails verification
+type fakeEmailVerificationTokens struct {
+	userID string
+}
+
+func (tokens *fakeEmailVerificationTokens) Issue(verification domain.EmailVerification) (string, error) {
+	tokens.userID = verification.UserID
+	return verification.Email, nil
+}
+
+func (tokens *fakeEmailVerificationTokens) Verify(token string) (domain.EmailVerification, error) {
+	if token == "bad" {
+		return domain.EmailVerification{}, errs.ErrInvalidVerificationToken
+	}
+	return domain.EmailVerification{UserID: tokens.userID, Email: token}, nil
+}
+
+/********** PASSWORD RESET **********/
+type fakePasswordResetTokenStore struct {
+	tokens map[string]domain.PasswordResetToken
+}
+
+func (resetStore *fakePasswordResetTokenStore) Create(
+	ctx context.Context,
+	token domain.PasswordResetToken,
+) error {
+	if resetStore.tokens == nil {
+		resetStore.tokens = map[string]domain.PasswordResetToken{}
+	}
+	resetStore.tokens[token.TokenHash] = token
+	return nil
+}
+
+func (resetStore *fakePasswordResetTokenStore) GetByHash(
+	ctx context.Context,
+	hash string,
+) (domain.PasswordResetToken, error) {
+	token, ok := resetStore.tokens[hash]
+	if !ok {
+		return domain.PasswordResetToken{}, errs.ErrNotFound
+	}
+	return token, nil
+}
+
+func (resetStore *fakePasswordResetTokenStore) MarkUsed(ctx context.Context, id string) error {
+	for hash, token := range resetStore.tokens {
+		if token.ID == id && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			resetStore.tokens[hash] = token
+			return nil
+		}
+	}
+	return errs.ErrNotFound
+}
+
+func (resetStore *fakePasswordResetTokenStore) InvalidateAllForUser(ctx context.Context, userID string) error {
+	for hash, token := range resetStore.tokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			resetStore.tokens[hash] = token
+		}
+	}
+	return nil
+}
+
+func passwordResetTokenStoreProvider(store *fakePasswordResetTokenStore) storage.PasswordResetTokenStoreProvider {
+	return func(exec storage.SQLExecutor) storage.PasswordResetTokenStore {
+		return store
+	}
+}
+
+type fakeLoginThrottleStore struct {
+	throttles map[string]domain.LoginThrottle
+}
+
+func (throttleStore *fakeLoginThrottleStore) Get(
+	ctx context.Context,
+	kind string,
+	subject string,
+) (domain.LoginThrottle, error) {
+	throttle, ok := throttleStore.throttles[kind+"/"+subject]
+	if !ok {
+		return domain.LoginThrottle{}, errs.ErrNotFound
+	}
+	return throttle, nil
+}
+
+func (throttleStore *fakeLoginThrottleStore) RecordFailure(
+	ctx context.Context,
+	kind string,
+	subject string,
+	failedAt time.Time,
+	resetBefore time.Time,
+) (int, error) {
+	if throttleStore.throttles == nil {
+		throttleStore.throttles = map[string]domain.LoginThrottle{}
+	}
+
+	throttle, ok := throttleStore.throttles[kind+"/"+subject]
+	if !ok || throttle.LastFailedAt.Before(resetBefore) {
+		throttle = domain.LoginThrottle{Kind: kind, Subject: subject}
+	}
+	throttle.Failures++
+	throttle.LastFailedAt = failedAt
+
+	throttleStore.throttles[kind+"/"+subject] = throttle
+	return throttle.Failures, nil
+}
+
+func (throttleStore *fakeLoginThrottleStore) Block(
+	ctx context.Context,
+	kind string,
+	subject string,
+	until time.Time,
+) error {
+	throttle := throttleStore.throttles[kind+"/"+subject]
+	throttle.BlockedUntil = &until
+	throttleStore.throttles[kind+"/"+subject] = throttle
+	return nil
+}
+
+func (throttleStore *fakeLoginThrottleStore) Delete(ctx context.Context, kind string, subject string) error {
+	delete(throttleStore.throttles, kind+"/"+subject)
+	return nil
+}
+
+func loginThrottleStoreProvider(store *fakeLoginThrottleStore) storage.LoginThrottleStoreProvider {
+	return func(exec storage.SQLExecutor) storage.LoginThrottleStore {
+		return store
+	}
+}
+
+// ******** Audit log **********/
+type fakeAuditSink struct {
+	events []audit.Event
+	mutex  sync.Mutex
+}
+
+func (sink *fakeAuditSink) Record(ctx context.Context, event audit.Event) error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	sink.events = append(sink.events, event)
+	return nil
+}
+
+// types of the recorded events, in order
+func (sink *fakeAuditSink) types() []string {
+	var types []string
+	for _, event := range sink.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
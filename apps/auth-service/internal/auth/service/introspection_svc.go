@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type TokenInfo = domain.TokenInfo
+
+// implemented by jwt.AccessTokenVerifier
+type AccessTokenVerifier interface {
+	Verify(tokenString string) (*jwt.Claims, error)
+}
+
+// IntrospectionService answers "is this token active?" for internal callers (RFC 7662)
+type IntrospectionService struct {
+	transactionMgr     storage.TransactionMgr
+	refreshTokenStore  storage.RefreshTokenStoreProvider
+	membershipProvider storage.MembershipStoreProvider
+	refreshTokenHasher refresh.RefreshTokenHasher
+	accessVerifier     AccessTokenVerifier
+}
+
+func NewIntrospectionService(
+	transactionMgr storage.TransactionMgr,
+	refreshTokenStore storage.RefreshTokenStoreProvider,
+	membershipStore storage.MembershipStoreProvider,
+	refreshHasher refresh.RefreshTokenHasher,
+	accessVerifier AccessTokenVerifier,
+) *IntrospectionService {
+	return &IntrospectionService{
+		transactionMgr:     transactionMgr,
+		refreshTokenStore:  refreshTokenStore,
+		membershipProvider: membershipStore,
+		refreshTokenHasher: refreshHasher,
+		accessVerifier:     accessVerifier,
+	}
+}
+
+// tokenTypeHint only decides which token type is tried first (RFC 7662, section 2.1);
+// unknown, invalid, expired and revoked tokens are all just inactive
+func (svc *IntrospectionService) Introspect(
+	ctx context.Context,
+	token string,
+	tokenTypeHint string,
+) (TokenInfo, error) {
+
+	if tokenTypeHint == domain.TokenTypeRefresh {
+		info, err := svc.introspectRefreshToken(ctx, token)
+		if err != nil || info.Active {
+			return info, err
+		}
+		return svc.introspectAccessToken(token), nil
+	}
+
+	if info := svc.introspectAccessToken(token); info.Active {
+		return info, nil
+	}
+	return svc.introspectRefreshToken(ctx, token)
+}
+
+// access tokens are checked offline: signature and claims
+func (svc *IntrospectionService) introspectAccessToken(token string) TokenInfo {
+	claims, err := svc.accessVerifier.Verify(token)
+	if err != nil {
+		return TokenInfo{Active: false}
+	}
+
+	info := TokenInfo{
+		Active:    true,
+		TokenType: domain.TokenTypeAccess,
+		Subject:   claims.Subject,
+		FamilyID:  claims.FamilyID,
+		Role:      claims.Role,
+	}
+	if claims.IssuedAt != nil {
+		info.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		info.ExpiresAt = claims.ExpiresAt.Time
+	}
+
+	return info
+}
+
+// refresh tokens are opaque: looked up by HMAC hash in the store
+func (svc *IntrospectionService) introspectRefreshToken(
+	ctx context.Context,
+	token string,
+) (info TokenInfo, err error) {
+
+	hash, err := svc.refreshTokenHasher.Hash(token)
+	if err != nil {
+		return TokenInfo{Active: false}, nil
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, true)
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	stored, err := svc.refreshTokenStore(exec).GetByHash(ctx, hash)
+	if errors.Is(err, errs.ErrNotFound) {
+		return TokenInfo{Active: false}, nil
+	}
+	if err != nil {
+		return TokenInfo{}, err
+	}
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return TokenInfo{Active: false}, nil
+	}
+
+	membership, err := svc.membershipProvider(exec).GetByUserID(ctx, stored.UserID)
+	if errors.Is(err, errs.ErrNotFound) {
+		// user no longer belongs to a family: nothing to authorize
+		return TokenInfo{Active: false}, nil
+	}
+	if err != nil {
+		return TokenInfo{}, err
+	}
+
+	return TokenInfo{
+		Active:    true,
+		TokenType: domain.TokenTypeRefresh,
+		Subject:   stored.UserID,
+		FamilyID:  membership.FamilyID,
+		Role:      membership.Role,
+		IssuedAt:  stored.CreatedAt,
+		ExpiresAt: stored.ExpiresAt,
+	}, nil
+}
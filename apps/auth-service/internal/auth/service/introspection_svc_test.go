@@ -0,0 +1,132 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+var errInvalidJWT = errors.New("invalid jwt")
+
+func newIntrospectionService(
+	refreshStore *fakeRefreshTokenStore,
+	verifier *fakeAccessTokenVerifier,
+) *service.IntrospectionService {
+	return service.NewIntrospectionService(
+		&fakeDB{},
+		refreshStoreProvider(refreshStore),
+		membershipStoreProvider(&fakeMembershipStore{
+			membership: Membership{UserID: "user-1", FamilyID: "family-1", Role: "owner"},
+		}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		verifier,
+	)
+}
+
+func TestIntrospectionService_ActiveAccessToken(test *testing.T) {
+	verifier := &fakeAccessTokenVerifier{
+		claims: &jwt.Claims{
+			RegisteredClaims: gojwt.RegisteredClaims{
+				Subject:   "user-1",
+				ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+			FamilyID: "family-1",
+			Role:     "admin",
+		},
+	}
+	svc := newIntrospectionService(&fakeRefreshTokenStore{getErr: errs.ErrNotFound}, verifier)
+
+	info, err := svc.Introspect(context.Background(), "access.jwt.token", "")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if !info.Active || info.TokenType != domain.TokenTypeAccess {
+		test.Fatalf("expected active access token, got %+v", info)
+	}
+	if info.Subject != "user-1" || info.FamilyID != "family-1" || info.Role != "admin" {
+		test.Fatalf("unexpected token info %+v", info)
+	}
+}
+
+func TestIntrospectionService_ActiveRefreshToken(test *testing.T) {
+	refreshStore := &fakeRefreshTokenStore{
+		token: refresh.RefreshToken{
+			ID:        "id",
+			UserID:    "user-1",
+			ExpiresAt: time.Now().Add(time.Hour),
+		},
+	}
+	svc := newIntrospectionService(refreshStore, &fakeAccessTokenVerifier{err: errInvalidJWT})
+
+	info, err := svc.Introspect(context.Background(), "raw-refresh-token", domain.TokenTypeRefresh)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if !info.Active || info.TokenType != domain.TokenTypeRefresh {
+		test.Fatalf("expected active refresh token, got %+v", info)
+	}
+	if info.Subject != "user-1" || info.FamilyID != "family-1" || info.Role != "owner" {
+		test.Fatalf("unexpected token info %+v", info)
+	}
+}
+
+func TestIntrospectionService_RevokedRefreshToken(test *testing.T) {
+	now := time.Now()
+	refreshStore := &fakeRefreshTokenStore{
+		token: refresh.RefreshToken{
+			ID:        "id",
+			UserID:    "user-1",
+			ExpiresAt: now.Add(time.Hour),
+			RevokedAt: &now,
+		},
+	}
+	svc := newIntrospectionService(refreshStore, &fakeAccessTokenVerifier{err: errInvalidJWT})
+
+	info, err := svc.Introspect(context.Background(), "raw-refresh-token", "")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if info.Active {
+		test.Fatalf("revoked token must be inactive")
+	}
+}
+
+func TestIntrospectionService_UnknownToken(test *testing.T) {
+	svc := newIntrospectionService(
+		&fakeRefreshTokenStore{getErr: errs.ErrNotFound},
+		&fakeAccessTokenVerifier{err: errInvalidJWT},
+	)
+
+	info, err := svc.Introspect(context.Background(), "garbage", domain.TokenTypeRefresh)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if info.Active {
+		test.Fatalf("unknown token must be inactive")
+	}
+}
+
+func TestIntrospectionService_StoreFailure(test *testing.T) {
+	svc := newIntrospectionService(
+		&fakeRefreshTokenStore{getErr: errors.New("db failure")},
+		&fakeAccessTokenVerifier{err: errInvalidJWT},
+	)
+
+	_, err := svc.Introspect(context.Background(), "raw-refresh-token", "")
+	if err == nil {
+		test.Fatalf("expected error")
+	}
+}
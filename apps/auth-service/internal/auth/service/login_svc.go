@@ -2,12 +2,17 @@ package service
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+	"github.com/google/uuid"
 )
 
 type User = domain.User
@@ -15,13 +20,45 @@ type Membership = domain.Membership
 type UserStore = storage.UserStore
 type UserStoreProvider = storage.UserStoreProvider
 type TransactionManager = storage.TransactionMgr
+type IssuedTokens = domain.IssuedTokens
+type IDTokenRequest = domain.IDTokenRequest
+
+// implemented by jwt.IDTokenSigner
+type IDTokenSigner interface {
+	GenerateSignedIDToken(user User, clientID string, authTime time.Time, nonce string, amr []string) (string, error)
+}
+
+// implemented by jwt.MFAChallengeTokens
+type MFAChallengeTokens interface {
+	Issue(challenge domain.MFAChallenge) (string, error)
+	Verify(token string) (domain.MFAChallenge, error)
+}
+
+// methods of a plain password login (RFC 8176)
+var passwordAMR = []string{domain.AMRPassword}
+
+// methods of a password login completed with a one-time code
+var totpAMR = []string{domain.AMRPassword, domain.AMROTP, domain.AMRMFA}
 
 type LoginService struct {
 	userStoreProvider  UserStoreProvider
 	membershipProvider MembershipStoreProvider
+	refreshTokenStore  storage.RefreshTokenStoreProvider
 	hash               password.PasswordHasher
 	db                 TransactionManager
 	tokenSigner        jwt.TokenSigner
+	idTokenSigner      IDTokenSigner
+	clients            ClientRegistry
+	secondFactor       secondFactor
+	mfaChallenges      MFAChallengeTokens
+	refreshIssuer      refreshTokenIssuer
+	emailPolicy        EmailVerificationPolicy
+	throttle           loginThrottle
+	audit              AuditSink
+
+	// hash of no one's password, made on first use; see compareDummyHash
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
 
 func NewLoginService(
@@ -29,60 +66,279 @@ func NewLoginService(
 	hash password.PasswordHasher,
 	userStoreProvider UserStoreProvider,
 	memberStoreProvider MembershipStoreProvider,
+	refreshTokenStore storage.RefreshTokenStoreProvider,
+	refreshHasher refresh.RefreshTokenHasher,
+	refreshGen refresh.RefreshTokenGenerator,
 	tokenSigner jwt.TokenSigner,
+	idTokenSigner IDTokenSigner,
+	clients ClientRegistry,
+	totpStore storage.TOTPStoreProvider,
+	recoveryCodeStore storage.RecoveryCodeStoreProvider,
+	secretBox SecretBox,
+	mfaChallenges MFAChallengeTokens,
+	sessionLifetimes SessionLifetimes,
+	emailPolicy EmailVerificationPolicy,
+	throttleStore storage.LoginThrottleStoreProvider,
+	throttling LoginThrottling,
+	auditSink AuditSink,
 ) *LoginService {
 	return &LoginService{
 		db:                 db,
 		hash:               hash,
 		userStoreProvider:  userStoreProvider,
 		membershipProvider: memberStoreProvider,
+		refreshTokenStore:  refreshTokenStore,
 		tokenSigner:        tokenSigner,
+		idTokenSigner:      idTokenSigner,
+		clients:            clients,
+		secondFactor: secondFactor{
+			totp:          totpStore,
+			recoveryCodes: recoveryCodeStore,
+			refreshTokens: refreshTokenStore,
+			secrets:       secretBox,
+			codeHasher:    refreshHasher,
+		},
+		mfaChallenges: mfaChallenges,
+		refreshIssuer: refreshTokenIssuer{
+			generator: refreshGen,
+			hasher:    refreshHasher,
+			lifetimes: sessionLifetimes,
+		},
+		emailPolicy: emailPolicy,
+		throttle: loginThrottle{
+			store:   throttleStore,
+			account: throttling.Account,
+			ip:      throttling.IP,
+		},
+		audit: auditSink,
 	}
 }
 
+// authenticates the user and starts a new session:
+// returns a signed access token and the first refresh token of the session,
+// plus an ID token when an OpenID Connect client asked for one.
+// Users with a confirmed TOTP enrollment get only an MFA challenge token;
+// the session starts once LoginMFA accepts the one-time code.
+// clientIP (may be empty) is throttled along with the account.
+// rememberMe selects the long session lifetime.
 func (svc *LoginService) Login(
 	ctx context.Context,
 	email string,
 	password string,
-) (string, error) {
-	user, membership, err := svc.authenticate(ctx, email, password)
+	clientIP string,
+	idTokenReq IDTokenRequest,
+	rememberMe bool,
+) (tokens IssuedTokens, err error) {
+
+	if err := svc.checkIDTokenClient(idTokenReq); err != nil {
+		return IssuedTokens{}, err
+	}
+
+	// here the decision is made to use a transaction,
+	// i.e. exec is *sql.Tx (transactional)
+	// non-transactional path would be exec := svc.db
+	// (svc.db implements SQLExecutor)
+	// not read-only: the refresh token is stored in the same transaction
+	exec, finish, err := svc.db.BeginTransaction(ctx, false)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	var user User
+	var membership Membership
+
+	// commit or rollback at the end, depending on error presence
+	defer func() {
+		finish(commitOnFailedLogin(err))
+		recordAudit(ctx, svc.audit, loginEvents(user.ID, clientIP, passwordAMR, tokens.MFAToken != "", err)...)
+	}()
+
+	user, membership, err = svc.authenticate(ctx, exec, email, password, clientIP)
 	if err != nil {
-		return "", err
+		return IssuedTokens{}, err
 	}
 
-	token, err := svc.tokenSigner.GenerateSignedAccessToken(user, membership)
+	mfaEnabled, err := svc.secondFactor.enabled(ctx, exec, user.ID)
 	if err != nil {
-		return "", err
+		return IssuedTokens{}, err
 	}
 
-	return token, nil
+	if mfaEnabled {
+		tokens.MFAToken, err = svc.mfaChallenges.Issue(domain.MFAChallenge{
+			UserID:     user.ID,
+			AuthTime:   time.Now(),
+			IDToken:    idTokenReq,
+			RememberMe: rememberMe,
+		})
+		if err != nil {
+			return IssuedTokens{}, err
+		}
+		return tokens, nil
+	}
+
+	if err := svc.throttle.reset(ctx, exec, email, clientIP); err != nil {
+		return IssuedTokens{}, err
+	}
+
+	return svc.issueTokens(ctx, exec, user, membership, idTokenReq, passwordAMR, time.Now(), rememberMe)
 }
 
-func (svc *LoginService) authenticate(ctx context.Context, email string, password string) (User, Membership, error) {
+// second step of a login with MFA: checks the one-time code (or a recovery code)
+// against the challenge returned by Login and starts the session.
+// The challenge may be presented again until it expires, so wrong codes
+// are throttled like wrong passwords, for the account and clientIP.
+func (svc *LoginService) LoginMFA(
+	ctx context.Context,
+	mfaToken string,
+	code string,
+	clientIP string,
+) (tokens IssuedTokens, err error) {
 
-	// here the decision is made to use a transaction,
-	// i.e. exec is *sql.Tx (transactional)
-	// non-transactional path would be exec := svc.db
-	// (svc.db implements SQLExecutor)
-	exec, finish, err := svc.db.BeginTransaction(ctx, true)
+	var challenge domain.MFAChallenge
+	// methods the user got through, for the audit log
+	amr := passwordAMR
+
+	// registered first, so it runs once the transaction has ended
+	defer func() {
+		recordAudit(ctx, svc.audit, loginEvents(challenge.UserID, clientIP, amr, false, err)...)
+	}()
+
+	// the user of a rejected challenge is not known
+	verifiedChallenge, err := svc.mfaChallenges.Verify(mfaToken)
 	if err != nil {
-		return User{}, Membership{}, err
+		return IssuedTokens{}, errs.ErrInvalidMFAChallenge
+	}
+	challenge = verifiedChallenge
+
+	// checked at Login already, the client may have been removed since
+	if err := svc.checkIDTokenClient(challenge.IDToken); err != nil {
+		return IssuedTokens{}, err
+	}
+
+	// not read-only: the used TOTP period and the refresh token are stored
+	exec, finish, err := svc.db.BeginTransaction(ctx, false)
+	if err != nil {
+		return IssuedTokens{}, err
 	}
-	// commit or rollback at the end, depending on error presence
 	defer func() {
-		finish(err)
+		finish(commitOnFailedLogin(err))
 	}()
 
+	user, err := svc.userStoreProvider(exec).GetById(ctx, challenge.UserID)
+	if err != nil {
+		return IssuedTokens{}, errs.ErrInvalidMFAChallenge
+	}
+
+	membership, err := svc.membershipProvider(exec).GetByUserID(ctx, user.ID)
+	if err != nil {
+		return IssuedTokens{}, errs.ErrInvalidMFAChallenge
+	}
+
+	if err := svc.throttle.check(ctx, exec, user.Email, clientIP); err != nil {
+		return IssuedTokens{}, err
+	}
+
+	verified, err := svc.verifySecondFactor(ctx, exec, user, code, clientIP)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+	amr = verified
+
+	if err := svc.throttle.reset(ctx, exec, user.Email, clientIP); err != nil {
+		return IssuedTokens{}, err
+	}
+
+	return svc.issueTokens(ctx, exec, user, membership, challenge.IDToken, amr, challenge.AuthTime, challenge.RememberMe)
+}
+
+// starts a new session for an authenticated user; shared by password login
+// and the authorization code exchange
+func (svc *LoginService) issueTokens(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	user User,
+	membership Membership,
+	idTokenReq IDTokenRequest,
+	amr []string,
+	authTime time.Time,
+	rememberMe bool,
+) (tokens IssuedTokens, err error) {
+
+	// the access token names its session (sid), so the user can tell it apart in /sessions
+	sessionID := uuid.NewString()
+
+	tokens.AccessToken, err = svc.tokenSigner.GenerateSignedAccessToken(user, membership, amr, sessionID)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	if idTokenReq.ClientID != "" {
+		tokens.IDToken, err = svc.idTokenSigner.GenerateSignedIDToken(
+			user,
+			idTokenReq.ClientID,
+			authTime,
+			idTokenReq.Nonce,
+			amr,
+		)
+		if err != nil {
+			return IssuedTokens{}, err
+		}
+	}
+
+	tokens.RefreshToken, tokens.RefreshExpiresAt, err = svc.refreshIssuer.startSession(
+		ctx,
+		svc.refreshTokenStore(exec),
+		user.ID,
+		sessionID,
+		amr,
+		rememberMe,
+	)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	return tokens, nil
+}
+
+// the password step of a login; a wrong password (or an unknown email) is
+// counted by the throttle, see commitOnFailedLogin. The counters are reset
+// by the caller once the whole login succeeded, a right password alone
+// must not clear the failed one-time codes of the account.
+// The user is returned along with a wrong password or an unverified email
+// too, for the audit log
+func (svc *LoginService) authenticate(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	email string,
+	password string,
+	clientIP string,
+) (User, Membership, error) {
+
+	if err := svc.throttle.check(ctx, exec, email, clientIP); err != nil {
+		svc.compareDummyHash(password)
+		return User{}, Membership{}, err
+	}
+
 	// USER retrieval
 	userStore := svc.userStoreProvider(exec)
 	user, err := userStore.GetByEmail(ctx, email)
 	if err != nil {
 		// hide “user not found” vs “wrong password” distinction
-		return User{}, Membership{}, errs.ErrInvalidCredentials
+		svc.compareDummyHash(password)
+		return User{}, Membership{}, svc.failedLogin(ctx, exec, email, clientIP)
 	}
 
 	if err := svc.hash.Compare(user.PasswordHash, password); err != nil {
-		return User{}, Membership{}, errs.ErrInvalidCredentials
+		return user, Membership{}, svc.failedLogin(ctx, exec, email, clientIP)
+	}
+
+	if err := svc.rehashIfOutdated(ctx, exec, user, password); err != nil {
+		return User{}, Membership{}, err
+	}
+
+	// only after the password matched: tells nothing about unknown addresses
+	if err := svc.checkEmailVerified(user); err != nil {
+		return user, Membership{}, err
 	}
 
 	// MEMBERSHIP retrieval
@@ -94,3 +350,98 @@ func (svc *LoginService) authenticate(ctx context.Context, email string, passwor
 
 	return user, membership, nil
 }
+
+// the code step of a login; a wrong code is counted by the throttle
+// of the account like a wrong password, see commitOnFailedLogin
+func (svc *LoginService) verifySecondFactor(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	user User,
+	code string,
+	clientIP string,
+) ([]string, error) {
+
+	amr, err := svc.secondFactor.verify(ctx, exec, user.ID, code)
+	if errors.Is(err, errs.ErrInvalidOTP) {
+		if err := svc.throttle.recordFailure(ctx, exec, user.Email, clientIP); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return amr, nil
+}
+
+// costs as much as checking a real password, so a refused attempt
+// (throttled, unknown email) cannot be told apart by its response time
+func (svc *LoginService) compareDummyHash(password string) {
+	svc.dummyHashOnce.Do(func() {
+		// on error the compare fails fast; the login is refused either way
+		svc.dummyHash, _ = svc.hash.Hash("not a password of any user")
+	})
+	_ = svc.hash.Compare(svc.dummyHash, password)
+}
+
+func (svc *LoginService) failedLogin(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	email string,
+	clientIP string,
+) error {
+	if err := svc.throttle.recordFailure(ctx, exec, email, clientIP); err != nil {
+		return err
+	}
+	return errs.ErrInvalidCredentials
+}
+
+// the login is rejected, but the counted failure (wrong password or one-time
+// code) must still be committed (like a detected refresh token reuse);
+// anything else the login wrote by then (a rehash) is safe to keep
+func commitOnFailedLogin(err error) error {
+	if errors.Is(err, errs.ErrInvalidCredentials) || errors.Is(err, errs.ErrInvalidOTP) {
+		return nil
+	}
+	return err
+}
+
+// replaces a hash made with an outdated algorithm (bcrypt) or parameters
+// while the plaintext password is at hand; users migrate on their next login
+func (svc *LoginService) rehashIfOutdated(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	user User,
+	password string,
+) error {
+
+	if !svc.hash.NeedsRehash(user.PasswordHash) {
+		return nil
+	}
+
+	hash, err := svc.hash.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	return svc.userStoreProvider(exec).UpdatePasswordHash(ctx, user.ID, hash)
+}
+
+// the ID token names the client as its audience, so only a registered
+// client gets one; the login is refused before the credentials are checked
+func (svc *LoginService) checkIDTokenClient(idTokenReq IDTokenRequest) error {
+	if idTokenReq.ClientID == "" {
+		return nil
+	}
+	if _, ok := svc.clients.Lookup(idTokenReq.ClientID); !ok {
+		return errs.ErrUnknownClient
+	}
+	return nil
+}
+
+func (svc *LoginService) checkEmailVerified(user User) error {
+	if svc.emailPolicy == EmailVerificationRequired && user.EmailVerifiedAt == nil {
+		return errs.ErrEmailNotVerified
+	}
+	return nil
+}
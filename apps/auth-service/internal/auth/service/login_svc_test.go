@@ -3,14 +3,21 @@ package service_test
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/clientinfo"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
 )
 
 func TestLoginService_Success(test *testing.T) {
+	store := &fakeRefreshTokenStore{}
+	refreshStore := refreshStoreProvider(store)
 
 	loginSvc := service.NewLoginService(
 		&fakeDB{
@@ -34,16 +41,125 @@ func TestLoginService_Success(test *testing.T) {
 					Role:     "admin",
 				},
 			}
-		}, &fakeSigner{token: JWTToken},
+		},
+		refreshStore,
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
+		&fakeSigner{token: JWTToken},
+		&fakeIDTokenSigner{},
+		registeredClients(),
+		totpStoreProvider(&fakeTOTPStore{}),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{},
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(&fakeLoginThrottleStore{}),
+		service.DefaultLoginThrottling,
+		&fakeAuditSink{},
 	)
 
-	token, err := loginSvc.Login(context.Background(), "a@b.com", "pw")
+	ctx := clientinfo.NewContext(context.Background(), clientinfo.Info{
+		IP:         "203.0.113.7",
+		UserAgent:  "Mozilla/5.0 Firefox/128.0",
+		DeviceName: "Laptop",
+	})
+
+	tokens, err := loginSvc.Login(ctx, "a@b.com", "pw", "", IDTokenRequest{}, false)
 	if err != nil {
 		test.Fatalf("unexpected Login error: %v", err)
 	}
 
-	if token != JWTToken {
-		test.Fatalf("expected token %s, got '%s'", JWTToken, token)
+	if tokens.AccessToken != JWTToken {
+		test.Fatalf("expected token %s, got '%s'", JWTToken, tokens.AccessToken)
+	}
+
+	if tokens.RefreshToken != RefreshToken {
+		test.Fatalf("expected refresh token %s, got '%s'", RefreshToken, tokens.RefreshToken)
+	}
+
+	// no OpenID Connect client, no ID token
+	if tokens.IDToken != "" {
+		test.Fatalf("expected no ID token, got '%s'", tokens.IDToken)
+	}
+
+	if !store.createCalled {
+		test.Fatalf("expected refresh token to be stored")
+	}
+
+	// login starts a new session chain
+	if store.created.SessionID == "" || store.created.ParentID != nil {
+		test.Fatalf("expected new session without parent, got %+v", store.created)
+	}
+	// the session remembers the client of the login
+	created := store.created
+	if created.UserAgent != "Mozilla/5.0 Firefox/128.0" || created.DeviceName != "Laptop" ||
+		created.CreatedIP != "203.0.113.7" || created.LastUsedIP != "203.0.113.7" || created.LastUsedAt.IsZero() {
+		test.Fatalf("expected client metadata on the session, got %+v", created)
+	}
+}
+
+func TestLoginService_SessionLifetimeProfiles(test *testing.T) {
+	lifetimes := service.SessionLifetimes{
+		Short: service.SessionLifetime{IdleTimeout: time.Hour, MaxLifetime: 24 * time.Hour},
+		Long:  service.SessionLifetime{IdleTimeout: 48 * time.Hour, MaxLifetime: 30 * 24 * time.Hour},
+	}
+
+	for _, rememberMe := range []bool{false, true} {
+		store := &fakeRefreshTokenStore{}
+		loginSvc := service.NewLoginService(
+			&fakeDB{
+				exec: &fakeSQLExecutor{},
+			},
+			&fakeHasher{hash: HASH},
+			userStoreProvider(&fakeUserStore{
+				user: User{ID: "u1", PasswordHash: HASH, Email: "a@b.com"},
+			}),
+			membershipStoreProvider(&fakeMembershipStore{
+				membership: Membership{UserID: "u1", FamilyID: "f1", Role: "admin"},
+			}),
+			refreshStoreProvider(store),
+			&fakeRefreshTokenHasher{hash: "hash"},
+			&fakeRefreshTokenGenerator{token: RefreshToken},
+			&fakeSigner{token: JWTToken},
+			&fakeIDTokenSigner{},
+			registeredClients(),
+			totpStoreProvider(&fakeTOTPStore{}),
+			recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+			&fakeSecretBox{},
+			&fakeMFAChallenges{},
+			lifetimes,
+			service.EmailVerificationOptional,
+			loginThrottleStoreProvider(&fakeLoginThrottleStore{}),
+			service.DefaultLoginThrottling,
+			&fakeAuditSink{},
+		)
+
+		before := time.Now()
+		tokens, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, rememberMe)
+		if err != nil {
+			test.Fatalf("unexpected Login error: %v", err)
+		}
+
+		want := lifetimes.Short
+		if rememberMe {
+			want = lifetimes.Long
+		}
+
+		created := store.created
+		if created.RememberMe != rememberMe {
+			test.Fatalf("expected remember me %v, got %+v", rememberMe, created)
+		}
+		if created.ExpiresAt.Sub(before) < want.IdleTimeout || created.ExpiresAt.Sub(before) > want.IdleTimeout+time.Minute {
+			test.Fatalf("expected idle timeout %v, token expires %v", want.IdleTimeout, created.ExpiresAt)
+		}
+		if created.SessionExpiresAt.Sub(before) < want.MaxLifetime || created.SessionExpiresAt.Sub(before) > want.MaxLifetime+time.Minute {
+			test.Fatalf("expected max lifetime %v, session expires %v", want.MaxLifetime, created.SessionExpiresAt)
+		}
+		// the refresh cookie lives as long as the token
+		if !tokens.RefreshExpiresAt.Equal(created.ExpiresAt) {
+			test.Fatalf("expected the expiry of the token, got %v", tokens.RefreshExpiresAt)
+		}
 	}
 }
 
@@ -61,22 +177,36 @@ func TestLoginService_InvalidPassword(test *testing.T) {
 		func(exec storage.SQLExecutor) MembershipStore {
 			return &fakeMembershipStore{}
 		},
-
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
 		&fakeSigner{},
+		&fakeIDTokenSigner{},
+		registeredClients(),
+		totpStoreProvider(&fakeTOTPStore{}),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{},
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(&fakeLoginThrottleStore{}),
+		service.DefaultLoginThrottling,
+		&fakeAuditSink{},
 	)
 
-	_, err := loginSvc.Login(context.Background(), "a@b.com", "pw")
+	_, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false)
 	if !errors.Is(err, errs.ErrInvalidCredentials) {
 		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidCredentials, err)
 	}
 }
 
 func TestLoginService_UserNotFound(test *testing.T) {
+	hasher := &fakeHasher{hash: "dummy"}
 	loginSvc := service.NewLoginService(
 		&fakeDB{
 			exec: &fakeSQLExecutor{},
 		}, // db unused in unit test
-		&fakeHasher{hash: HASH},
+		hasher,
 		func(exec storage.SQLExecutor) UserStore {
 			//whatever error userStore returns, login svc should obscure it as invalid credentials
 			return &fakeUserStore{
@@ -86,13 +216,31 @@ func TestLoginService_UserNotFound(test *testing.T) {
 		func(exec storage.SQLExecutor) MembershipStore {
 			return &fakeMembershipStore{}
 		},
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
 		&fakeSigner{token: JWTToken},
+		&fakeIDTokenSigner{},
+		registeredClients(),
+		totpStoreProvider(&fakeTOTPStore{}),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{},
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(&fakeLoginThrottleStore{}),
+		service.DefaultLoginThrottling,
+		&fakeAuditSink{},
 	)
 
-	_, err := loginSvc.Login(context.Background(), "a@b.com", "pw")
+	_, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false)
 	if !errors.Is(err, errs.ErrInvalidCredentials) {
 		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidCredentials, err)
 	}
+	// takes as long as a wrong password
+	if !slices.Equal(hasher.compared, []string{"dummy"}) {
+		test.Fatalf("expected the password to be compared with a dummy hash, got %v", hasher.compared)
+	}
 }
 
 func TestLoginService_MembershipNotFound(test *testing.T) {
@@ -114,11 +262,813 @@ func TestLoginService_MembershipNotFound(test *testing.T) {
 				err: errs.ErrNotFound,
 			}
 		},
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
 		&fakeSigner{token: JWTToken},
+		&fakeIDTokenSigner{},
+		registeredClients(),
+		totpStoreProvider(&fakeTOTPStore{}),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{},
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(&fakeLoginThrottleStore{}),
+		service.DefaultLoginThrottling,
+		&fakeAuditSink{},
 	)
 
-	_, err := loginSvc.Login(context.Background(), "a@b.com", "pw")
+	_, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false)
 	if !errors.Is(err, errs.ErrInvalidCredentials) {
 		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidCredentials, err)
 	}
 }
+
+func TestLoginService_RefreshTokenStoreFailure(test *testing.T) {
+	loginSvc := service.NewLoginService(
+		&fakeDB{
+			exec: &fakeSQLExecutor{},
+		}, // db unused in unit test
+		&fakeHasher{hash: HASH},
+		userStoreProvider(&fakeUserStore{
+			user: User{ID: "u1", PasswordHash: HASH},
+		}),
+		membershipStoreProvider(&fakeMembershipStore{
+			membership: Membership{UserID: "u1", FamilyID: "f1"},
+		}),
+		refreshStoreProvider(&fakeRefreshTokenStore{
+			createErr: errors.New("db failure"),
+		}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
+		&fakeSigner{token: JWTToken},
+		&fakeIDTokenSigner{},
+		registeredClients(),
+		totpStoreProvider(&fakeTOTPStore{}),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{},
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(&fakeLoginThrottleStore{}),
+		service.DefaultLoginThrottling,
+		&fakeAuditSink{},
+	)
+
+	_, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false)
+	if err == nil {
+		test.Fatalf("expected error")
+	}
+}
+
+func TestLoginService_IssuesIDTokenForOIDCClient(test *testing.T) {
+	idTokenSigner := &fakeIDTokenSigner{token: IDToken}
+
+	loginSvc := service.NewLoginService(
+		&fakeDB{
+			exec: &fakeSQLExecutor{},
+		},
+		&fakeHasher{hash: HASH},
+		userStoreProvider(&fakeUserStore{
+			user: User{ID: "u1", PasswordHash: HASH, Email: "a@b.com"},
+		}),
+		membershipStoreProvider(&fakeMembershipStore{
+			membership: Membership{UserID: "u1", FamilyID: "f1", Role: "admin"},
+		}),
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
+		&fakeSigner{token: JWTToken},
+		idTokenSigner,
+		registeredClients(),
+		totpStoreProvider(&fakeTOTPStore{}),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{},
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(&fakeLoginThrottleStore{}),
+		service.DefaultLoginThrottling,
+		&fakeAuditSink{},
+	)
+
+	tokens, err := loginSvc.Login(
+		context.Background(),
+		"a@b.com",
+		"pw",
+		"",
+		IDTokenRequest{ClientID: "wiki", Nonce: "nonce-1"},
+		false,
+	)
+	if err != nil {
+		test.Fatalf("unexpected Login error: %v", err)
+	}
+
+	if tokens.IDToken != IDToken {
+		test.Fatalf("expected ID token %s, got '%s'", IDToken, tokens.IDToken)
+	}
+
+	if idTokenSigner.gotClientID != "wiki" || idTokenSigner.gotNonce != "nonce-1" {
+		test.Fatalf("unexpected ID token request %q %q", idTokenSigner.gotClientID, idTokenSigner.gotNonce)
+	}
+}
+
+func newMFALoginService(
+	signer *fakeSigner,
+	totpStore *fakeTOTPStore,
+	challenges *fakeMFAChallenges,
+) *service.LoginService {
+	return newRecoveryLoginService(signer, totpStore, &fakeRecoveryCodeStore{}, &fakeRefreshTokenStore{}, challenges)
+}
+
+func newRecoveryLoginService(
+	signer *fakeSigner,
+	totpStore *fakeTOTPStore,
+	recoveryStore *fakeRecoveryCodeStore,
+	refreshStore *fakeRefreshTokenStore,
+	challenges *fakeMFAChallenges,
+) *service.LoginService {
+	return newAuditedLoginService(signer, totpStore, recoveryStore, refreshStore, challenges, &fakeAuditSink{})
+}
+
+func newAuditedLoginService(
+	signer *fakeSigner,
+	totpStore *fakeTOTPStore,
+	recoveryStore *fakeRecoveryCodeStore,
+	refreshStore *fakeRefreshTokenStore,
+	challenges *fakeMFAChallenges,
+	auditSink *fakeAuditSink,
+) *service.LoginService {
+	return service.NewLoginService(
+		&fakeDB{
+			exec: &fakeSQLExecutor{},
+		},
+		&fakeHasher{hash: HASH},
+		userStoreProvider(&fakeUserStore{
+			user: User{ID: "u1", PasswordHash: HASH, Email: "a@b.com"},
+		}),
+		membershipStoreProvider(&fakeMembershipStore{
+			membership: Membership{UserID: "u1", FamilyID: "f1", Role: "admin"},
+		}),
+		refreshStoreProvider(refreshStore),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
+		signer,
+		&fakeIDTokenSigner{},
+		registeredClients(),
+		totpStoreProvider(totpStore),
+		recoveryCodeStoreProvider(recoveryStore),
+		&fakeSecretBox{},
+		challenges,
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(&fakeLoginThrottleStore{}),
+		service.DefaultLoginThrottling,
+		auditSink,
+	)
+}
+
+func TestLoginService_MFAEnabled_ReturnsChallengeOnly(test *testing.T) {
+	challenges := &fakeMFAChallenges{token: "mfa.token"}
+	loginSvc := newMFALoginService(
+		&fakeSigner{token: JWTToken},
+		&fakeTOTPStore{credential: confirmedTOTPCredential()},
+		challenges,
+	)
+
+	tokens, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{ClientID: "wiki"}, true)
+	if err != nil {
+		test.Fatalf("unexpected Login error: %v", err)
+	}
+
+	if tokens.MFAToken != "mfa.token" || tokens.AccessToken != "" || tokens.RefreshToken != "" {
+		test.Fatalf("expected only an MFA challenge, got %+v", tokens)
+	}
+
+	// the second step starts the session, it has to know the profile
+	if challenges.issued.UserID != "u1" || challenges.issued.IDToken.ClientID != "wiki" || !challenges.issued.RememberMe {
+		test.Fatalf("unexpected challenge %+v", challenges.issued)
+	}
+}
+
+func TestLoginService_UnknownClient_NoIDToken(test *testing.T) {
+	idTokenSigner := &fakeIDTokenSigner{}
+	hasher := &fakeHasher{hash: HASH}
+	loginSvc := service.NewLoginService(
+		&fakeDB{exec: &fakeSQLExecutor{}},
+		hasher,
+		userStoreProvider(&fakeUserStore{user: User{ID: "u1", PasswordHash: HASH, Email: "a@b.com"}}),
+		membershipStoreProvider(&fakeMembershipStore{membership: Membership{UserID: "u1"}}),
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
+		&fakeSigner{token: JWTToken},
+		idTokenSigner,
+		registeredClients(),
+		totpStoreProvider(&fakeTOTPStore{}),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{},
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(&fakeLoginThrottleStore{}),
+		service.DefaultLoginThrottling,
+		&fakeAuditSink{},
+	)
+
+	tokens, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{ClientID: "evil-rp"}, false)
+	if !errors.Is(err, errs.ErrUnknownClient) {
+		test.Fatalf("expected %v, got %v", errs.ErrUnknownClient, err)
+	}
+	if tokens.IDToken != "" || tokens.AccessToken != "" || idTokenSigner.gotClientID != "" {
+		test.Fatalf("expected no tokens for an unknown client, got %+v", tokens)
+	}
+	if hasher.called {
+		test.Fatalf("the password must not be checked for an unknown client")
+	}
+}
+
+func TestLoginService_PendingEnrollment_DoesNotRequireMFA(test *testing.T) {
+	pending := confirmedTOTPCredential()
+	pending.ConfirmedAt = nil
+	signer := &fakeSigner{token: JWTToken}
+	loginSvc := newMFALoginService(signer, &fakeTOTPStore{credential: pending}, &fakeMFAChallenges{})
+
+	tokens, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false)
+	if err != nil {
+		test.Fatalf("unexpected Login error: %v", err)
+	}
+
+	if tokens.AccessToken != JWTToken || tokens.MFAToken != "" {
+		test.Fatalf("expected tokens without MFA, got %+v", tokens)
+	}
+	if len(signer.gotAMR) != 1 || signer.gotAMR[0] != "pwd" {
+		test.Fatalf("expected amr [pwd], got %v", signer.gotAMR)
+	}
+}
+
+func TestLoginService_LoginMFA_Success(test *testing.T) {
+	signer := &fakeSigner{token: JWTToken}
+	totpStore := &fakeTOTPStore{credential: confirmedTOTPCredential()}
+	loginSvc := newMFALoginService(signer, totpStore, &fakeMFAChallenges{
+		challenge: domain.MFAChallenge{UserID: "u1", AuthTime: time.Now()},
+	})
+
+	tokens, err := loginSvc.LoginMFA(context.Background(), "mfa.token", currentTOTPCode(test), "")
+	if err != nil {
+		test.Fatalf("unexpected LoginMFA error: %v", err)
+	}
+
+	if tokens.AccessToken != JWTToken || tokens.RefreshToken != RefreshToken {
+		test.Fatalf("unexpected tokens %+v", tokens)
+	}
+	if len(signer.gotAMR) != 3 || signer.gotAMR[1] != "otp" {
+		test.Fatalf("expected amr [pwd otp mfa], got %v", signer.gotAMR)
+	}
+	if totpStore.updatedStep == 0 {
+		test.Fatalf("expected the used period to be stored")
+	}
+}
+
+func TestLoginService_LoginMFA_InvalidCode(test *testing.T) {
+	loginSvc := newMFALoginService(
+		&fakeSigner{token: JWTToken},
+		&fakeTOTPStore{credential: confirmedTOTPCredential()},
+		&fakeMFAChallenges{challenge: domain.MFAChallenge{UserID: "u1"}},
+	)
+
+	_, err := loginSvc.LoginMFA(context.Background(), "mfa.token", "000000", "")
+	if !errors.Is(err, errs.ErrInvalidOTP) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidOTP, err)
+	}
+}
+
+func TestLoginService_LoginMFA_ReplayedCode(test *testing.T) {
+	// concurrent login used the same period first
+	loginSvc := newMFALoginService(
+		&fakeSigner{token: JWTToken},
+		&fakeTOTPStore{credential: confirmedTOTPCredential(), updateErr: errs.ErrNotFound},
+		&fakeMFAChallenges{challenge: domain.MFAChallenge{UserID: "u1"}},
+	)
+
+	_, err := loginSvc.LoginMFA(context.Background(), "mfa.token", currentTOTPCode(test), "")
+	if !errors.Is(err, errs.ErrInvalidOTP) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidOTP, err)
+	}
+}
+
+func TestLoginService_LoginMFA_InvalidChallenge(test *testing.T) {
+	loginSvc := newMFALoginService(
+		&fakeSigner{token: JWTToken},
+		&fakeTOTPStore{credential: confirmedTOTPCredential()},
+		&fakeMFAChallenges{verifyErr: errors.New("expired")},
+	)
+
+	_, err := loginSvc.LoginMFA(context.Background(), "mfa.token", "123456", "")
+	if !errors.Is(err, errs.ErrInvalidMFAChallenge) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidMFAChallenge, err)
+	}
+}
+
+func TestLoginService_LoginMFA_RecoveryCodeRevokesSessions(test *testing.T) {
+	signer := &fakeSigner{token: JWTToken}
+	recoveryStore := &fakeRecoveryCodeStore{code: domain.RecoveryCode{ID: "rc1", UserID: "u1"}}
+	refreshStore := &fakeRefreshTokenStore{}
+	loginSvc := newRecoveryLoginService(
+		signer,
+		&fakeTOTPStore{credential: confirmedTOTPCredential()},
+		recoveryStore,
+		refreshStore,
+		&fakeMFAChallenges{challenge: domain.MFAChallenge{UserID: "u1"}},
+	)
+
+	tokens, err := loginSvc.LoginMFA(context.Background(), "mfa.token", "ABCDE-FGH23", "")
+	if err != nil {
+		test.Fatalf("unexpected LoginMFA error: %v", err)
+	}
+
+	if tokens.AccessToken != JWTToken {
+		test.Fatalf("unexpected tokens %+v", tokens)
+	}
+	if !recoveryStore.markUsedCalled {
+		test.Fatalf("expected the recovery code to be marked used")
+	}
+	if !refreshStore.revokeAllCalled {
+		test.Fatalf("expected all sessions of the user to be revoked")
+	}
+	// the new session starts after the old ones were revoked
+	if !refreshStore.createCalled {
+		test.Fatalf("expected a new session")
+	}
+	if len(signer.gotAMR) != 2 || signer.gotAMR[1] != "mfa" {
+		test.Fatalf("expected amr [pwd mfa], got %v", signer.gotAMR)
+	}
+}
+
+func TestLoginService_LoginMFA_UsedRecoveryCode(test *testing.T) {
+	refreshStore := &fakeRefreshTokenStore{}
+	loginSvc := newRecoveryLoginService(
+		&fakeSigner{token: JWTToken},
+		&fakeTOTPStore{credential: confirmedTOTPCredential()},
+		&fakeRecoveryCodeStore{code: domain.RecoveryCode{ID: "rc1"}, markUsedErr: errs.ErrNotFound},
+		refreshStore,
+		&fakeMFAChallenges{challenge: domain.MFAChallenge{UserID: "u1"}},
+	)
+
+	_, err := loginSvc.LoginMFA(context.Background(), "mfa.token", "abcde-fgh23", "")
+	if !errors.Is(err, errs.ErrInvalidOTP) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidOTP, err)
+	}
+	if refreshStore.revokeAllCalled {
+		test.Fatalf("a rejected code must not revoke sessions")
+	}
+}
+
+func newVerificationLoginService(user User, policy service.EmailVerificationPolicy) *service.LoginService {
+	return service.NewLoginService(
+		&fakeDB{
+			exec: &fakeSQLExecutor{},
+		},
+		&fakeHasher{hash: HASH},
+		userStoreProvider(&fakeUserStore{user: user}),
+		membershipStoreProvider(&fakeMembershipStore{
+			membership: Membership{UserID: "u1", FamilyID: "f1", Role: "admin"},
+		}),
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
+		&fakeSigner{token: JWTToken},
+		&fakeIDTokenSigner{},
+		registeredClients(),
+		totpStoreProvider(&fakeTOTPStore{}),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{},
+		service.DefaultSessionLifetimes,
+		policy,
+		loginThrottleStoreProvider(&fakeLoginThrottleStore{}),
+		service.DefaultLoginThrottling,
+		&fakeAuditSink{},
+	)
+}
+
+func TestLoginService_EmailVerificationRequired_Unverified(test *testing.T) {
+	loginSvc := newVerificationLoginService(
+		User{ID: "u1", PasswordHash: HASH, Email: "a@b.com"},
+		service.EmailVerificationRequired,
+	)
+
+	_, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false)
+	if !errors.Is(err, errs.ErrEmailNotVerified) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrEmailNotVerified, err)
+	}
+}
+
+func TestLoginService_EmailVerificationRequired_WrongPasswordHidesStatus(test *testing.T) {
+	loginSvc := newVerificationLoginService(
+		User{ID: "u1", PasswordHash: "wronghash", Email: "a@b.com"},
+		service.EmailVerificationRequired,
+	)
+
+	// an unverified address is only revealed to someone who knows the password
+	_, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false)
+	if !errors.Is(err, errs.ErrInvalidCredentials) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidCredentials, err)
+	}
+}
+
+func TestLoginService_EmailVerificationRequired_Verified(test *testing.T) {
+	verifiedAt := time.Now()
+	loginSvc := newVerificationLoginService(
+		User{ID: "u1", PasswordHash: HASH, Email: "a@b.com", EmailVerifiedAt: &verifiedAt},
+		service.EmailVerificationRequired,
+	)
+
+	tokens, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false)
+	if err != nil {
+		test.Fatalf("unexpected Login error: %v", err)
+	}
+	if tokens.AccessToken != JWTToken {
+		test.Fatalf("expected token %s, got '%s'", JWTToken, tokens.AccessToken)
+	}
+}
+
+func newRehashLoginService(userStore *fakeUserStore, hasher *fakeHasher) *service.LoginService {
+	return service.NewLoginService(
+		&fakeDB{
+			exec: &fakeSQLExecutor{},
+		},
+		hasher,
+		userStoreProvider(userStore),
+		membershipStoreProvider(&fakeMembershipStore{
+			membership: Membership{UserID: "u1", FamilyID: "f1", Role: "admin"},
+		}),
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
+		&fakeSigner{token: JWTToken},
+		&fakeIDTokenSigner{},
+		registeredClients(),
+		totpStoreProvider(&fakeTOTPStore{}),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{},
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(&fakeLoginThrottleStore{}),
+		service.DefaultLoginThrottling,
+		&fakeAuditSink{},
+	)
+}
+
+func TestLoginService_RehashesOutdatedHash(test *testing.T) {
+	userStore := &fakeUserStore{
+		user: User{ID: "u1", PasswordHash: HASH, Email: "a@b.com"},
+	}
+	loginSvc := newRehashLoginService(userStore, &fakeHasher{hash: "argon2id-hash", rehash: true})
+
+	if _, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false); err != nil {
+		test.Fatalf("unexpected Login error: %v", err)
+	}
+
+	if userStore.user.PasswordHash != "argon2id-hash" {
+		test.Fatalf("expected the outdated hash to be replaced, got %q", userStore.user.PasswordHash)
+	}
+}
+
+func TestLoginService_KeepsCurrentHash(test *testing.T) {
+	userStore := &fakeUserStore{
+		user: User{ID: "u1", PasswordHash: HASH, Email: "a@b.com"},
+	}
+	loginSvc := newRehashLoginService(userStore, &fakeHasher{hash: "argon2id-hash"})
+
+	if _, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false); err != nil {
+		test.Fatalf("unexpected Login error: %v", err)
+	}
+
+	if userStore.user.PasswordHash != HASH {
+		test.Fatalf("a current hash must not be replaced")
+	}
+}
+
+func TestLoginService_NoRehashOnWrongPassword(test *testing.T) {
+	userStore := &fakeUserStore{
+		user: User{ID: "u1", PasswordHash: "wronghash", Email: "a@b.com"},
+	}
+	loginSvc := newRehashLoginService(userStore, &fakeHasher{hash: "argon2id-hash", rehash: true})
+
+	_, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false)
+	if !errors.Is(err, errs.ErrInvalidCredentials) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidCredentials, err)
+	}
+
+	if userStore.user.PasswordHash != "wronghash" {
+		test.Fatalf("the hash must not change on a failed login")
+	}
+}
+
+// backoff after 2 failures, lockout after 4
+var testLoginThrottling = service.LoginThrottling{
+	Account: service.LoginThrottlePolicy{
+		FreeAttempts:    2,
+		BaseDelay:       time.Minute,
+		MaxDelay:        time.Hour,
+		LockoutAfter:    4,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	},
+	IP: service.LoginThrottlePolicy{
+		FreeAttempts:    100,
+		BaseDelay:       time.Minute,
+		MaxDelay:        time.Hour,
+		LockoutAfter:    1000,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	},
+}
+
+func newThrottledLoginService(
+	userStore *fakeUserStore,
+	hasher *fakeHasher,
+	throttleStore *fakeLoginThrottleStore,
+	auditSink *fakeAuditSink,
+) *service.LoginService {
+	return service.NewLoginService(
+		&fakeDB{
+			exec: &fakeSQLExecutor{},
+		},
+		hasher,
+		userStoreProvider(userStore),
+		membershipStoreProvider(&fakeMembershipStore{
+			membership: Membership{UserID: "u1", FamilyID: "f1", Role: "admin"},
+		}),
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
+		&fakeSigner{token: JWTToken},
+		&fakeIDTokenSigner{},
+		registeredClients(),
+		totpStoreProvider(&fakeTOTPStore{}),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{},
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(throttleStore),
+		testLoginThrottling,
+		auditSink,
+	)
+}
+
+func TestLoginService_ThrottlesAfterFailedAttempts(test *testing.T) {
+	userStore := &fakeUserStore{
+		user: User{ID: "u1", PasswordHash: "wronghash", Email: "a@b.com"},
+	}
+	hasher := &fakeHasher{}
+	throttleStore := &fakeLoginThrottleStore{}
+	loginSvc := newThrottledLoginService(userStore, hasher, throttleStore, &fakeAuditSink{})
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		_, err := loginSvc.Login(context.Background(), "a@b.com", "guess", "192.0.2.1", IDTokenRequest{}, false)
+		if !errors.Is(err, errs.ErrInvalidCredentials) || errors.Is(err, errs.ErrLoginThrottled) {
+			test.Fatalf("attempt %d: expected a plain %v, but got: %v", attempt, errs.ErrInvalidCredentials, err)
+		}
+	}
+
+	account := throttleStore.throttles[domain.ThrottleAccount+"/a@b.com"]
+	if account.Failures != 3 || account.BlockedUntil == nil {
+		test.Fatalf("expected 3 failures and a backoff, got %+v", account)
+	}
+	if delay := time.Until(*account.BlockedUntil); delay <= 0 || delay > time.Minute {
+		test.Fatalf("expected a backoff of one minute, got %s", delay)
+	}
+	if throttleStore.throttles[domain.ThrottleIP+"/192.0.2.1"].Failures != 3 {
+		test.Fatalf("expected the failures of the client IP to be counted")
+	}
+
+	// even the right password is refused meanwhile, compared only with a dummy
+	// hash so that the refusal takes as long as a wrong password
+	userStore.user.PasswordHash = HASH
+	hasher.hash = "dummy"
+	hasher.compared = nil
+
+	_, err := loginSvc.Login(context.Background(), "A@b.com ", "pw", "192.0.2.1", IDTokenRequest{}, false)
+	if !errors.Is(err, errs.ErrLoginThrottled) || !errors.Is(err, errs.ErrInvalidCredentials) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrLoginThrottled, err)
+	}
+	if !slices.Equal(hasher.compared, []string{"dummy"}) {
+		test.Fatalf("expected only a dummy hash compare while throttled, got %v", hasher.compared)
+	}
+	if throttleStore.throttles[domain.ThrottleAccount+"/a@b.com"].Failures != 3 {
+		test.Fatalf("refused attempts must not be counted")
+	}
+}
+
+func TestLoginService_LocksOutAfterRepeatedFailures(test *testing.T) {
+	userStore := &fakeUserStore{
+		user: User{ID: "u1", PasswordHash: "wronghash", Email: "a@b.com"},
+	}
+	throttleStore := &fakeLoginThrottleStore{throttles: map[string]domain.LoginThrottle{}}
+	loginSvc := newThrottledLoginService(userStore, &fakeHasher{}, throttleStore, &fakeAuditSink{})
+
+	key := domain.ThrottleAccount + "/a@b.com"
+	for attempt := 1; attempt <= 4; attempt++ {
+		// wait out the backoff
+		throttle := throttleStore.throttles[key]
+		throttle.BlockedUntil = nil
+		throttleStore.throttles[key] = throttle
+
+		_, err := loginSvc.Login(context.Background(), "a@b.com", "guess", "", IDTokenRequest{}, false)
+		if !errors.Is(err, errs.ErrInvalidCredentials) {
+			test.Fatalf("attempt %d: expected %v, but got: %v", attempt, errs.ErrInvalidCredentials, err)
+		}
+	}
+
+	account := throttleStore.throttles[key]
+	if account.BlockedUntil == nil || time.Until(*account.BlockedUntil) < 14*time.Minute {
+		test.Fatalf("expected a 15 minute lockout, got %+v", account)
+	}
+	if _, ok := throttleStore.throttles[domain.ThrottleIP+"/"]; ok {
+		test.Fatalf("an unknown client IP must not be tracked")
+	}
+}
+
+func newThrottledMFALoginService(
+	db *fakeDB,
+	throttleStore *fakeLoginThrottleStore,
+) *service.LoginService {
+	return service.NewLoginService(
+		db,
+		&fakeHasher{hash: HASH},
+		userStoreProvider(&fakeUserStore{
+			user: User{ID: "u1", PasswordHash: HASH, Email: "a@b.com"},
+		}),
+		membershipStoreProvider(&fakeMembershipStore{
+			membership: Membership{UserID: "u1", FamilyID: "f1", Role: "admin"},
+		}),
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: RefreshToken},
+		&fakeSigner{token: JWTToken},
+		&fakeIDTokenSigner{},
+		registeredClients(),
+		totpStoreProvider(&fakeTOTPStore{credential: confirmedTOTPCredential()}),
+		recoveryCodeStoreProvider(&fakeRecoveryCodeStore{}),
+		&fakeSecretBox{},
+		&fakeMFAChallenges{token: "mfa.token", challenge: domain.MFAChallenge{UserID: "u1"}},
+		service.DefaultSessionLifetimes,
+		service.EmailVerificationOptional,
+		loginThrottleStoreProvider(throttleStore),
+		testLoginThrottling,
+		&fakeAuditSink{},
+	)
+}
+
+// the challenge can be replayed until it expires: without counting the
+// codes, the password alone would be enough to try all of them
+func TestLoginService_LoginMFA_LocksOutAfterWrongCodes(test *testing.T) {
+	db := &fakeDB{exec: &fakeSQLExecutor{}}
+	throttleStore := &fakeLoginThrottleStore{throttles: map[string]domain.LoginThrottle{}}
+	loginSvc := newThrottledMFALoginService(db, throttleStore)
+
+	key := domain.ThrottleAccount + "/a@b.com"
+	for attempt := 1; attempt <= 4; attempt++ {
+		// wait out the backoff
+		throttle := throttleStore.throttles[key]
+		throttle.BlockedUntil = nil
+		throttleStore.throttles[key] = throttle
+
+		_, err := loginSvc.LoginMFA(context.Background(), "mfa.token", "000000", "192.0.2.1")
+		if !errors.Is(err, errs.ErrInvalidOTP) {
+			test.Fatalf("attempt %d: expected %v, but got: %v", attempt, errs.ErrInvalidOTP, err)
+		}
+		// the counted failure is committed, not rolled back
+		if last := db.finished[len(db.finished)-1]; last != nil {
+			test.Fatalf("attempt %d: expected a commit, got a rollback for %v", attempt, last)
+		}
+	}
+
+	account := throttleStore.throttles[key]
+	if account.Failures != 4 || account.BlockedUntil == nil || time.Until(*account.BlockedUntil) < 14*time.Minute {
+		test.Fatalf("expected a 15 minute lockout after 4 wrong codes, got %+v", account)
+	}
+	if throttleStore.throttles[domain.ThrottleIP+"/192.0.2.1"].Failures != 4 {
+		test.Fatalf("expected the failures of the client IP to be counted")
+	}
+
+	// even the right code is refused meanwhile
+	_, err := loginSvc.LoginMFA(context.Background(), "mfa.token", currentTOTPCode(test), "192.0.2.1")
+	if !errors.Is(err, errs.ErrLoginThrottled) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrLoginThrottled, err)
+	}
+
+	// and so is the password step
+	_, err = loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false)
+	if !errors.Is(err, errs.ErrLoginThrottled) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrLoginThrottled, err)
+	}
+}
+
+func TestLoginService_LoginMFA_SuccessResetsThrottle(test *testing.T) {
+	throttleStore := &fakeLoginThrottleStore{}
+	loginSvc := newThrottledMFALoginService(&fakeDB{exec: &fakeSQLExecutor{}}, throttleStore)
+
+	if _, err := loginSvc.LoginMFA(context.Background(), "mfa.token", "000000", ""); !errors.Is(err, errs.ErrInvalidOTP) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidOTP, err)
+	}
+
+	// the right password alone keeps the count of wrong codes
+	tokens, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "", IDTokenRequest{}, false)
+	if err != nil || tokens.MFAToken == "" {
+		test.Fatalf("expected an MFA challenge, got %+v, %v", tokens, err)
+	}
+	if throttleStore.throttles[domain.ThrottleAccount+"/a@b.com"].Failures != 1 {
+		test.Fatalf("expected the wrong code to still count, got %+v", throttleStore.throttles)
+	}
+
+	if _, err := loginSvc.LoginMFA(context.Background(), "mfa.token", currentTOTPCode(test), ""); err != nil {
+		test.Fatalf("unexpected LoginMFA error: %v", err)
+	}
+	if len(throttleStore.throttles) != 0 {
+		test.Fatalf("expected the counters to be reset, got %+v", throttleStore.throttles)
+	}
+}
+
+func TestLoginService_SuccessResetsThrottle(test *testing.T) {
+	userStore := &fakeUserStore{
+		user: User{ID: "u1", PasswordHash: "wronghash", Email: "a@b.com"},
+	}
+	throttleStore := &fakeLoginThrottleStore{}
+	loginSvc := newThrottledLoginService(userStore, &fakeHasher{}, throttleStore, &fakeAuditSink{})
+
+	_, err := loginSvc.Login(context.Background(), "a@b.com", "guess", "192.0.2.1", IDTokenRequest{}, false)
+	if !errors.Is(err, errs.ErrInvalidCredentials) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidCredentials, err)
+	}
+
+	userStore.user.PasswordHash = HASH
+	if _, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "192.0.2.1", IDTokenRequest{}, false); err != nil {
+		test.Fatalf("unexpected Login error: %v", err)
+	}
+
+	if len(throttleStore.throttles) != 0 {
+		test.Fatalf("expected the counters to be reset, got %+v", throttleStore.throttles)
+	}
+}
+
+func TestLoginService_AuditsLoginAttempts(test *testing.T) {
+	userStore := &fakeUserStore{
+		user: User{ID: "u1", PasswordHash: "wronghash", Email: "a@b.com"},
+	}
+	auditSink := &fakeAuditSink{}
+	loginSvc := newThrottledLoginService(userStore, &fakeHasher{}, &fakeLoginThrottleStore{}, auditSink)
+
+	// three free failures, then throttled
+	for attempt := 1; attempt <= 4; attempt++ {
+		_, _ = loginSvc.Login(context.Background(), "a@b.com", "guess", "192.0.2.1", IDTokenRequest{}, false)
+	}
+
+	if len(auditSink.events) != 4 {
+		test.Fatalf("expected an event per attempt, got %v", auditSink.types())
+	}
+
+	failed := auditSink.events[0]
+	if failed.Type != audit.LoginFailed ||
+		failed.UserID != "u1" ||
+		failed.ClientIP != "192.0.2.1" ||
+		failed.Details["reason"] != audit.ReasonInvalidCredentials {
+		test.Fatalf("unexpected event for a wrong password: %+v", failed)
+	}
+	if reason := auditSink.events[3].Details["reason"]; reason != audit.ReasonThrottled {
+		test.Fatalf("expected the refused attempt to be audited as throttled, got %q", reason)
+	}
+
+	for _, event := range auditSink.events {
+		for key, value := range event.Details {
+			if value == "guess" {
+				test.Fatalf("the password must not be audited, found it in %q", key)
+			}
+		}
+	}
+}
+
+func TestLoginService_AuditsSuccess(test *testing.T) {
+	userStore := &fakeUserStore{
+		user: User{ID: "u1", PasswordHash: HASH, Email: "a@b.com"},
+	}
+	auditSink := &fakeAuditSink{}
+	loginSvc := newThrottledLoginService(userStore, &fakeHasher{}, &fakeLoginThrottleStore{}, auditSink)
+
+	if _, err := loginSvc.Login(context.Background(), "a@b.com", "pw", "192.0.2.1", IDTokenRequest{}, false); err != nil {
+		test.Fatalf("unexpected Login error: %v", err)
+	}
+
+	if len(auditSink.events) != 1 {
+		test.Fatalf("expected one event, got %v", auditSink.types())
+	}
+	event := auditSink.events[0]
+	if event.Type != audit.LoginSucceeded || event.UserID != "u1" || event.Details["amr"] != domain.AMRPassword {
+		test.Fatalf("unexpected event: %+v", event)
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+// how failed password logins of one account (or client IP) slow down guessing:
+// the first FreeAttempts failures cost nothing, each further one blocks logins
+// for BaseDelay, doubling up to MaxDelay, and LockoutAfter failures lock the
+// account for LockoutDuration. Failures more than Window apart start over.
+type LoginThrottlePolicy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// a shared NAT address sees the logins of many users, so the IP policy is looser
+type LoginThrottling struct {
+	Account LoginThrottlePolicy
+	IP      LoginThrottlePolicy
+}
+
+var DefaultLoginThrottling = LoginThrottling{
+	Account: LoginThrottlePolicy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    10,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	},
+	IP: LoginThrottlePolicy{
+		FreeAttempts:    20,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    100,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	},
+}
+
+// zero time if the failure is still free; locked reports a lockout, not just a delay
+func (policy LoginThrottlePolicy) blockedUntil(failures int, failedAt time.Time) (until time.Time, locked bool) {
+	if policy.LockoutAfter > 0 && failures >= policy.LockoutAfter {
+		return failedAt.Add(policy.LockoutDuration), true
+	}
+
+	extra := failures - policy.FreeAttempts
+	if extra <= 0 {
+		return time.Time{}, false
+	}
+
+	delay := policy.MaxDelay
+	// beyond 2^20 the delay is capped anyway; also keeps the shift from overflowing
+	if extra <= 20 && policy.BaseDelay<<(extra-1) < policy.MaxDelay {
+		delay = policy.BaseDelay << (extra - 1)
+	}
+
+	return failedAt.Add(delay), false
+}
+
+// tracks failed password logins per account and per client IP in the database,
+// so every replica sees them; shared by password login and the authorization code flow
+type loginThrottle struct {
+	store   storage.LoginThrottleStoreProvider
+	account LoginThrottlePolicy
+	ip      LoginThrottlePolicy
+}
+
+type throttleSubject struct {
+	kind    string
+	subject string
+}
+
+// subjects of a login attempt; clientIP may be empty (not known), then only
+// the account is throttled
+func throttleSubjects(email string, clientIP string) []throttleSubject {
+	subjects := []throttleSubject{
+		{kind: domain.ThrottleAccount, subject: strings.ToLower(strings.TrimSpace(email))},
+	}
+	if clientIP != "" {
+		subjects = append(subjects, throttleSubject{kind: domain.ThrottleIP, subject: clientIP})
+	}
+	return subjects
+}
+
+func (throttle loginThrottle) policy(kind string) LoginThrottlePolicy {
+	if kind == domain.ThrottleIP {
+		return throttle.ip
+	}
+	return throttle.account
+}
+
+// ErrLoginThrottled while the account or the client IP is blocked; the password
+// is not even compared, so guesses made meanwhile tell nothing
+func (throttle loginThrottle) check(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	email string,
+	clientIP string,
+) error {
+
+	store := throttle.store(exec)
+	now := time.Now()
+
+	for _, target := range throttleSubjects(email, clientIP) {
+		state, err := store.Get(ctx, target.kind, target.subject)
+		if errors.Is(err, errs.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if state.BlockedUntil != nil && now.Before(*state.BlockedUntil) {
+			log.Printf(
+				"security event: login refused, too many failed attempts (%s=%s ip=%s blocked_until=%s)",
+				target.kind,
+				target.subject,
+				clientIP,
+				state.BlockedUntil.Format(time.RFC3339),
+			)
+			return errs.ErrLoginThrottled
+		}
+	}
+
+	return nil
+}
+
+// counts a failed password for the account and the client IP
+// and blocks them once the policy says so
+func (throttle loginThrottle) recordFailure(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	email string,
+	clientIP string,
+) error {
+
+	store := throttle.store(exec)
+	now := time.Now()
+
+	for _, target := range throttleSubjects(email, clientIP) {
+		policy := throttle.policy(target.kind)
+
+		failures, err := store.RecordFailure(ctx, target.kind, target.subject, now, now.Add(-policy.Window))
+		if err != nil {
+			return err
+		}
+
+		until, locked := policy.blockedUntil(failures, now)
+		if until.IsZero() {
+			continue
+		}
+
+		if err := store.Block(ctx, target.kind, target.subject, until); err != nil {
+			return err
+		}
+
+		if locked {
+			log.Printf(
+				"security event: login locked after %d failed attempts (%s=%s ip=%s until=%s)",
+				failures,
+				target.kind,
+				target.subject,
+				clientIP,
+				until.Format(time.RFC3339),
+			)
+		}
+	}
+
+	return nil
+}
+
+// a successful login starts over for the account and the client IP
+func (throttle loginThrottle) reset(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	email string,
+	clientIP string,
+) error {
+
+	store := throttle.store(exec)
+
+	for _, target := range throttleSubjects(email, clientIP) {
+		if err := store.Delete(ctx, target.kind, target.subject); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
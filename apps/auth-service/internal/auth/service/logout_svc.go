@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"errors"
 
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
 )
 
@@ -12,6 +15,7 @@ type LogoutService struct {
 	transactionMgr     TransactionMgr
 	refreshTokenStore  storage.RefreshTokenStoreProvider
 	refreshTokenHasher refresh.RefreshTokenHasher
+	audit              AuditSink
 }
 
 type TransactionMgr = storage.TransactionMgr
@@ -20,25 +24,58 @@ func NewLogoutService(
 	transactionMgr TransactionMgr,
 	refreshStore storage.RefreshTokenStoreProvider,
 	hasher refresh.RefreshTokenHasher,
+	auditSink AuditSink,
 ) *LogoutService {
 	return &LogoutService{
 		transactionMgr:     transactionMgr,
 		refreshTokenStore:  refreshStore,
 		refreshTokenHasher: hasher,
+		audit:              auditSink,
 	}
 }
 
+// RFC 7009 revocation. Only refresh tokens are stored, so tokenTypeHint does not matter:
+// access tokens are self-contained JWTs and simply expire, and like unknown tokens
+// they are not an error
+func (svc *LogoutService) Revoke(
+	ctx context.Context,
+	rawToken string,
+	tokenTypeHint string,
+) error {
+	return svc.revoke(ctx, rawToken, audit.TokenRevoked)
+}
+
 func (svc *LogoutService) Logout(
 	ctx context.Context,
 	rawToken string,
+) error {
+	return svc.revoke(ctx, rawToken, audit.Logout)
+}
+
+// eventType tells logout from RFC 7009 revocation in the audit log
+func (svc *LogoutService) revoke(
+	ctx context.Context,
+	rawToken string,
+	eventType string,
 ) (err error) {
 
 	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
 	if err != nil {
 		return err
 	}
+
+	// the revoked token; nothing is recorded for unknown tokens
+	var revoked *refresh.RefreshToken
+
 	defer func() {
 		finish(err)
+		if err == nil && revoked != nil {
+			recordAudit(ctx, svc.audit, audit.Event{
+				Type:      eventType,
+				UserID:    revoked.UserID,
+				SessionID: revoked.SessionID,
+			})
+		}
 	}()
 
 	hash, err := svc.refreshTokenHasher.Hash(rawToken)
@@ -55,10 +92,20 @@ func (svc *LogoutService) Logout(
 		return nil
 	}
 
+	// logged out before, or rotated: RFC 7009 wants the same answer as for the first call
+	if stored.RevokedAt != nil {
+		return nil
+	}
+
 	if err := store.Revoke(ctx, stored.ID); err != nil {
+		// revoked by a concurrent request in the meantime
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil
+		}
 		// DB write failure → must surface
 		return err
 	}
+	revoked = &stored
 
 	return nil
 }
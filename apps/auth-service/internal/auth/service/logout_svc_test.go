@@ -3,11 +3,17 @@ package service_test
 import (
 	"context"
 	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage/sqlite"
+	"github.com/Tata-Matata/family-space/apps/auth-service/migrations"
 )
 
 func TestLogoutService_Success(test *testing.T) {
@@ -19,6 +25,7 @@ func TestLogoutService_Success(test *testing.T) {
 		&fakeDB{},
 		refreshStoreProvider(store),
 		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeAuditSink{},
 	)
 
 	err := svc.Logout(context.Background(), "raw-token")
@@ -40,6 +47,7 @@ func TestLogoutService_TokenNotFound(test *testing.T) {
 		&fakeDB{},
 		refreshStoreProvider(store),
 		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeAuditSink{},
 	)
 
 	err := svc.Logout(context.Background(), "raw-token")
@@ -57,6 +65,7 @@ func TestLogoutService_InvalidToken(test *testing.T) {
 		&fakeDB{},
 		refreshStoreProvider(&fakeRefreshTokenStore{}),
 		hasher,
+		&fakeAuditSink{},
 	)
 
 	err := svc.Logout(context.Background(), "bad-token")
@@ -75,6 +84,7 @@ func TestLogoutService_RevokeFailure(test *testing.T) {
 		&fakeDB{},
 		refreshStoreProvider(store),
 		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeAuditSink{},
 	)
 
 	err := svc.Logout(context.Background(), "raw-token")
@@ -88,6 +98,7 @@ func TestLogoutService_BeginTransactionFailure(test *testing.T) {
 		&fakeDB{beginErr: errors.New("transaction failed at the start")},
 		refreshStoreProvider(&fakeRefreshTokenStore{}),
 		&fakeRefreshTokenHasher{},
+		&fakeAuditSink{},
 	)
 
 	err := svc.Logout(context.Background(), "raw-token")
@@ -95,3 +106,154 @@ func TestLogoutService_BeginTransactionFailure(test *testing.T) {
 		test.Fatalf("expected error")
 	}
 }
+
+func TestLogoutService_Revoke_IgnoresTokenTypeHint(test *testing.T) {
+	store := &fakeRefreshTokenStore{
+		token: refresh.RefreshToken{ID: "token-id"},
+	}
+
+	svc := service.NewLogoutService(
+		&fakeDB{},
+		refreshStoreProvider(store),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeAuditSink{},
+	)
+
+	// a wrong hint must not stop the refresh token from being revoked
+	err := svc.Revoke(context.Background(), "raw-token", "access_token")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if !store.revokeCalled {
+		test.Fatalf("expected revoke to be called")
+	}
+}
+
+func TestLogoutService_Audits(test *testing.T) {
+	store := &fakeRefreshTokenStore{
+		token: refresh.RefreshToken{ID: "token-id", UserID: "user", SessionID: "session"},
+	}
+	auditSink := &fakeAuditSink{}
+
+	svc := service.NewLogoutService(
+		&fakeDB{},
+		refreshStoreProvider(store),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		auditSink,
+	)
+
+	if err := svc.Logout(context.Background(), "raw-token"); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.Revoke(context.Background(), "raw-token", "refresh_token"); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	types := auditSink.types()
+	if len(types) != 2 || types[0] != audit.Logout || types[1] != audit.TokenRevoked {
+		test.Fatalf("expected a logout and a revocation, got %v", types)
+	}
+	if auditSink.events[0].UserID != "user" || auditSink.events[0].SessionID != "session" {
+		test.Fatalf("unexpected event: %+v", auditSink.events[0])
+	}
+}
+
+func TestLogoutService_UnknownTokenNotAudited(test *testing.T) {
+	auditSink := &fakeAuditSink{}
+
+	svc := service.NewLogoutService(
+		&fakeDB{},
+		refreshStoreProvider(&fakeRefreshTokenStore{getErr: errs.ErrNotFound}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		auditSink,
+	)
+
+	if err := svc.Logout(context.Background(), "raw-token"); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	if len(auditSink.events) != 0 {
+		test.Fatalf("expected no event, got %v", auditSink.types())
+	}
+}
+
+func TestLogoutService_AlreadyRevoked(test *testing.T) {
+	revokedAt := time.Now()
+	store := &fakeRefreshTokenStore{
+		token: refresh.RefreshToken{ID: "token-id", RevokedAt: &revokedAt},
+	}
+	auditSink := &fakeAuditSink{}
+
+	svc := service.NewLogoutService(
+		&fakeDB{},
+		refreshStoreProvider(store),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		auditSink,
+	)
+
+	if err := svc.Revoke(context.Background(), "raw-token", "refresh_token"); err != nil {
+		test.Fatalf("expected no error, got %v", err)
+	}
+	if store.revokeCalled || len(auditSink.events) != 0 {
+		test.Fatalf("expected nothing to be revoked or recorded, got %v", auditSink.types())
+	}
+}
+
+func TestLogoutService_RevokedConcurrently(test *testing.T) {
+	store := &fakeRefreshTokenStore{
+		token:     refresh.RefreshToken{ID: "token-id"},
+		revokeErr: errs.ErrNotFound,
+	}
+
+	svc := service.NewLogoutService(
+		&fakeDB{},
+		refreshStoreProvider(store),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeAuditSink{},
+	)
+
+	if err := svc.Logout(context.Background(), "raw-token"); err != nil {
+		test.Fatalf("expected no error, got %v", err)
+	}
+}
+
+// against the store: its Revoke finds only tokens that are not revoked yet
+func TestLogoutService_RevokeSameTokenTwice(test *testing.T) {
+	db, err := sqlite.Open(filepath.Join(test.TempDir(), "auth.db"))
+	if err != nil {
+		test.Fatalf("failed to open database: %v", err)
+	}
+	test.Cleanup(func() { db.Close() })
+	if _, err := migrations.Apply(context.Background(), db, migrations.SQLite); err != nil {
+		test.Fatalf("failed to migrate: %v", err)
+	}
+
+	now := time.Now()
+	err = sqlite.NewRefreshTokenStore(db).Create(context.Background(), refresh.RefreshToken{
+		ID:               "token-id",
+		UserID:           "user",
+		SessionID:        "session",
+		TokenHash:        "hash",
+		ExpiresAt:        now.Add(time.Hour),
+		CreatedAt:        now,
+		SessionCreatedAt: now,
+		SessionExpiresAt: now.Add(time.Hour),
+		LastUsedAt:       now,
+	})
+	if err != nil {
+		test.Fatalf("failed to store token: %v", err)
+	}
+
+	svc := service.NewLogoutService(
+		storage.NewTransactionMgr(db),
+		sqlite.NewRefreshTokenStore,
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeAuditSink{},
+	)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err := svc.Revoke(context.Background(), "raw-token", "refresh_token"); err != nil {
+			test.Fatalf("revocation %d: expected no error, got %v", attempt, err)
+		}
+	}
+}
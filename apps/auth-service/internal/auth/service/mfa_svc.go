@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/mfa"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type TOTPEnrollment = domain.TOTPEnrollment
+
+// MFAService enrolls the user an access token was issued to in TOTP.
+// Enrollment is two-step: the secret is stored pending and only turns
+// the second factor on once the user proves the authenticator app has it.
+// Confirming also hands out the first batch of recovery codes.
+// Both steps take the current password: a stolen access token alone
+// must not be able to put a second factor in front of the account.
+type MFAService struct {
+	transactionMgr    storage.TransactionMgr
+	userStoreProvider storage.UserStoreProvider
+	hash              password.PasswordHasher
+	totpStore         storage.TOTPStoreProvider
+	recoveryCodeStore storage.RecoveryCodeStoreProvider
+	secretBox         SecretBox
+	codeHasher        refresh.RefreshTokenHasher
+	accessVerifier    AccessTokenVerifier
+	// shown in the authenticator app next to the account
+	issuerName string
+}
+
+func NewMFAService(
+	transactionMgr storage.TransactionMgr,
+	userStore storage.UserStoreProvider,
+	hash password.PasswordHasher,
+	totpStore storage.TOTPStoreProvider,
+	recoveryCodeStore storage.RecoveryCodeStoreProvider,
+	secretBox SecretBox,
+	codeHasher refresh.RefreshTokenHasher,
+	accessVerifier AccessTokenVerifier,
+	issuerName string,
+) *MFAService {
+	return &MFAService{
+		transactionMgr:    transactionMgr,
+		userStoreProvider: userStore,
+		hash:              hash,
+		totpStore:         totpStore,
+		recoveryCodeStore: recoveryCodeStore,
+		secretBox:         secretBox,
+		codeHasher:        codeHasher,
+		accessVerifier:    accessVerifier,
+		issuerName:        issuerName,
+	}
+}
+
+// generates a new secret; calling it again before confirmation replaces the pending one
+func (svc *MFAService) BeginTOTPEnrollment(
+	ctx context.Context,
+	accessToken string,
+	currentPassword string,
+) (enrollment TOTPEnrollment, err error) {
+
+	claims, err := svc.accessVerifier.Verify(accessToken)
+	if err != nil {
+		return TOTPEnrollment{}, errs.ErrInvalidAccessToken
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	user, err := svc.currentUser(ctx, exec, claims.Subject, currentPassword)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	secret, err := mfa.GenerateSecret()
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	sealed, err := svc.secretBox.Seal(secret)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	err = svc.totpStore(exec).SavePending(ctx, domain.TOTPCredential{
+		UserID:          user.ID,
+		SecretEncrypted: sealed,
+		CreatedAt:       time.Now(),
+	})
+	if errors.Is(err, errs.ErrAlreadyExists) {
+		return TOTPEnrollment{}, errs.ErrMFAAlreadyEnabled
+	}
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	return TOTPEnrollment{
+		Secret: secret,
+		URI:    mfa.OTPAuthURI(svc.issuerName, user.Email, secret),
+	}, nil
+}
+
+// turns TOTP on with the first valid code from the authenticator app
+// (that code's period counts as used) and returns the recovery codes
+func (svc *MFAService) ConfirmTOTPEnrollment(
+	ctx context.Context,
+	accessToken string,
+	currentPassword string,
+	code string,
+) (recoveryCodes []string, err error) {
+
+	claims, err := svc.accessVerifier.Verify(accessToken)
+	if err != nil {
+		return nil, errs.ErrInvalidAccessToken
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	if _, err = svc.currentUser(ctx, exec, claims.Subject, currentPassword); err != nil {
+		return nil, err
+	}
+
+	store := svc.totpStore(exec)
+
+	credential, err := store.GetByUserID(ctx, claims.Subject)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, errs.ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if credential.ConfirmedAt != nil {
+		return nil, errs.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := svc.secretBox.Open(credential.SecretEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := mfa.ValidateTOTP(secret, code, time.Now(), 0)
+	if !ok {
+		return nil, errs.ErrInvalidOTP
+	}
+
+	if err = store.Confirm(ctx, claims.Subject, step); err != nil {
+		return nil, err
+	}
+
+	return issueRecoveryCodes(ctx, svc.recoveryCodeStore(exec), svc.codeHasher, claims.Subject)
+}
+
+// replaces all recovery codes (used or not) with a new batch,
+// e.g. when most are used up or the printout was lost. Takes the current
+// password or, if code is set, a code of the authenticator app instead.
+func (svc *MFAService) RegenerateRecoveryCodes(
+	ctx context.Context,
+	accessToken string,
+	currentPassword string,
+	code string,
+) (recoveryCodes []string, err error) {
+
+	claims, err := svc.accessVerifier.Verify(accessToken)
+	if err != nil {
+		return nil, errs.ErrInvalidAccessToken
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	credential, err := svc.totpStore(exec).GetByUserID(ctx, claims.Subject)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, errs.ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if credential.ConfirmedAt == nil {
+		return nil, errs.ErrMFANotEnrolled
+	}
+
+	// fresh recovery codes get past the second factor,
+	// so a stolen access token alone is not enough
+	if code != "" {
+		err = secondFactor{totp: svc.totpStore, secrets: svc.secretBox}.verifyTOTP(ctx, exec, claims.Subject, code)
+	} else {
+		_, err = svc.currentUser(ctx, exec, claims.Subject, currentPassword)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return issueRecoveryCodes(ctx, svc.recoveryCodeStore(exec), svc.codeHasher, claims.Subject)
+}
+
+// the user of the access token, if currentPassword is theirs
+func (svc *MFAService) currentUser(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	userID string,
+	currentPassword string,
+) (User, error) {
+
+	user, err := svc.userStoreProvider(exec).GetById(ctx, userID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return User{}, errs.ErrInvalidAccessToken
+	}
+	if err != nil {
+		return User{}, err
+	}
+
+	// a stolen access token alone is not enough
+	if err := svc.hash.Compare(user.PasswordHash, currentPassword); err != nil {
+		return User{}, errs.ErrInvalidCredentials
+	}
+
+	return user, nil
+}
@@ -0,0 +1,238 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/mfa"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+// RFC 6238 test secret ("12345678901234567890"), base32
+const TOTP_SECRET = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func confirmedTOTPCredential() *domain.TOTPCredential {
+	confirmedAt := time.Now()
+	return &domain.TOTPCredential{
+		UserID:          "u1",
+		SecretEncrypted: "sealed:" + TOTP_SECRET,
+		ConfirmedAt:     &confirmedAt,
+	}
+}
+
+func currentTOTPCode(test *testing.T) string {
+	code, err := mfa.TOTPCode(TOTP_SECRET, time.Now())
+	if err != nil {
+		test.Fatalf("unexpected TOTPCode error: %v", err)
+	}
+	return code
+}
+
+func newMFAService(totpStore *fakeTOTPStore) *service.MFAService {
+	return newRecoveryMFAService(totpStore, &fakeRecoveryCodeStore{})
+}
+
+func newRecoveryMFAService(totpStore *fakeTOTPStore, recoveryStore *fakeRecoveryCodeStore) *service.MFAService {
+	return newMFAServiceForUser(User{ID: "u1", Email: "a@b.com", PasswordHash: HASH}, totpStore, recoveryStore)
+}
+
+func newMFAServiceForUser(user User, totpStore *fakeTOTPStore, recoveryStore *fakeRecoveryCodeStore) *service.MFAService {
+	return service.NewMFAService(
+		&fakeDB{},
+		userStoreProvider(&fakeUserStore{user: user}),
+		&fakeHasher{},
+		totpStoreProvider(totpStore),
+		recoveryCodeStoreProvider(recoveryStore),
+		&fakeSecretBox{},
+		&fakeRefreshTokenHasher{hash: "code-hash"},
+		&fakeAccessTokenVerifier{
+			claims: &jwt.Claims{RegisteredClaims: gojwt.RegisteredClaims{Subject: "u1"}},
+		},
+		"family-space",
+	)
+}
+
+func TestMFAService_BeginTOTPEnrollment(test *testing.T) {
+	totpStore := &fakeTOTPStore{}
+	svc := newMFAService(totpStore)
+
+	enrollment, err := svc.BeginTOTPEnrollment(context.Background(), "access.jwt", "pw")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	// only the sealed secret is stored, pending until confirmed
+	if totpStore.pending.SecretEncrypted != "sealed:"+enrollment.Secret || totpStore.pending.ConfirmedAt != nil {
+		test.Fatalf("unexpected stored credential %+v", totpStore.pending)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/family-space:a@b.com?") {
+		test.Fatalf("unexpected otpauth URI %q", enrollment.URI)
+	}
+}
+
+func TestMFAService_BeginTOTPEnrollment_AlreadyEnabled(test *testing.T) {
+	svc := newMFAService(&fakeTOTPStore{saveErr: errs.ErrAlreadyExists})
+
+	_, err := svc.BeginTOTPEnrollment(context.Background(), "access.jwt", "pw")
+	if !errors.Is(err, errs.ErrMFAAlreadyEnabled) {
+		test.Fatalf("expected %v, got %v", errs.ErrMFAAlreadyEnabled, err)
+	}
+}
+
+// a stolen access token alone cannot put a second factor in front of the account
+func TestMFAService_BeginTOTPEnrollment_WrongPassword(test *testing.T) {
+	totpStore := &fakeTOTPStore{}
+	svc := newMFAServiceForUser(User{ID: "u1", PasswordHash: "other-hash"}, totpStore, &fakeRecoveryCodeStore{})
+
+	_, err := svc.BeginTOTPEnrollment(context.Background(), "access.jwt", "wrong")
+	if !errors.Is(err, errs.ErrInvalidCredentials) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidCredentials, err)
+	}
+	if totpStore.savedCalled {
+		test.Fatalf("no secret must be stored without the current password")
+	}
+}
+
+func TestMFAService_ConfirmTOTPEnrollment(test *testing.T) {
+	pending := confirmedTOTPCredential()
+	pending.ConfirmedAt = nil
+	totpStore := &fakeTOTPStore{credential: pending}
+	recoveryStore := &fakeRecoveryCodeStore{}
+	svc := newRecoveryMFAService(totpStore, recoveryStore)
+
+	recoveryCodes, err := svc.ConfirmTOTPEnrollment(context.Background(), "access.jwt", "pw", currentTOTPCode(test))
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	// the first batch of recovery codes is handed out with the confirmation
+	if len(recoveryCodes) != mfa.RecoveryCodeCount || len(recoveryStore.replaced) != mfa.RecoveryCodeCount {
+		test.Fatalf("expected %d recovery codes, got %d (stored %d)",
+			mfa.RecoveryCodeCount, len(recoveryCodes), len(recoveryStore.replaced))
+	}
+	if recoveryStore.replaced[0].CodeHash != "code-hash" || recoveryStore.replaced[0].UserID != "u1" {
+		test.Fatalf("expected only hashed codes to be stored, got %+v", recoveryStore.replaced[0])
+	}
+
+	// the confirming code cannot be used again for a login
+	if totpStore.confirmedStep == 0 {
+		test.Fatalf("expected the confirming period to be stored as used")
+	}
+}
+
+func TestMFAService_ConfirmTOTPEnrollment_InvalidCode(test *testing.T) {
+	pending := confirmedTOTPCredential()
+	pending.ConfirmedAt = nil
+	svc := newMFAService(&fakeTOTPStore{credential: pending})
+
+	_, err := svc.ConfirmTOTPEnrollment(context.Background(), "access.jwt", "pw", "000000")
+	if !errors.Is(err, errs.ErrInvalidOTP) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidOTP, err)
+	}
+}
+
+func TestMFAService_ConfirmTOTPEnrollment_WrongPassword(test *testing.T) {
+	pending := confirmedTOTPCredential()
+	pending.ConfirmedAt = nil
+	recoveryStore := &fakeRecoveryCodeStore{}
+	svc := newMFAServiceForUser(User{ID: "u1", PasswordHash: "other-hash"}, &fakeTOTPStore{credential: pending}, recoveryStore)
+
+	_, err := svc.ConfirmTOTPEnrollment(context.Background(), "access.jwt", "wrong", currentTOTPCode(test))
+	if !errors.Is(err, errs.ErrInvalidCredentials) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidCredentials, err)
+	}
+	if len(recoveryStore.replaced) != 0 {
+		test.Fatalf("no recovery codes must be issued without the current password")
+	}
+}
+
+func TestMFAService_ConfirmTOTPEnrollment_NotStarted(test *testing.T) {
+	svc := newMFAService(&fakeTOTPStore{})
+
+	_, err := svc.ConfirmTOTPEnrollment(context.Background(), "access.jwt", "pw", "123456")
+	if !errors.Is(err, errs.ErrMFANotEnrolled) {
+		test.Fatalf("expected %v, got %v", errs.ErrMFANotEnrolled, err)
+	}
+}
+
+func TestMFAService_RegenerateRecoveryCodes(test *testing.T) {
+	recoveryStore := &fakeRecoveryCodeStore{}
+	svc := newRecoveryMFAService(&fakeTOTPStore{credential: confirmedTOTPCredential()}, recoveryStore)
+
+	recoveryCodes, err := svc.RegenerateRecoveryCodes(context.Background(), "access.jwt", "pw", "")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(recoveryCodes) != mfa.RecoveryCodeCount || len(recoveryStore.replaced) != mfa.RecoveryCodeCount {
+		test.Fatalf("expected a new batch of %d codes, got %d", mfa.RecoveryCodeCount, len(recoveryCodes))
+	}
+}
+
+func TestMFAService_RegenerateRecoveryCodes_MFANotEnabled(test *testing.T) {
+	pending := confirmedTOTPCredential()
+	pending.ConfirmedAt = nil
+	svc := newMFAService(&fakeTOTPStore{credential: pending})
+
+	_, err := svc.RegenerateRecoveryCodes(context.Background(), "access.jwt", "pw", "")
+	if !errors.Is(err, errs.ErrMFANotEnrolled) {
+		test.Fatalf("expected %v, got %v", errs.ErrMFANotEnrolled, err)
+	}
+}
+
+func TestMFAService_RegenerateRecoveryCodes_WithTOTPCode(test *testing.T) {
+	recoveryStore := &fakeRecoveryCodeStore{}
+	totpStore := &fakeTOTPStore{credential: confirmedTOTPCredential()}
+	svc := newMFAServiceForUser(User{ID: "u1", PasswordHash: "other-hash"}, totpStore, recoveryStore)
+
+	recoveryCodes, err := svc.RegenerateRecoveryCodes(context.Background(), "access.jwt", "", currentTOTPCode(test))
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	if len(recoveryCodes) != mfa.RecoveryCodeCount {
+		test.Fatalf("expected a new batch of %d codes, got %d", mfa.RecoveryCodeCount, len(recoveryCodes))
+	}
+	// the code counts as used
+	if totpStore.updatedStep == 0 {
+		test.Fatalf("expected the period of the code to be stored as used")
+	}
+}
+
+// a bearer-token holder must not mint codes that get past the second factor
+func TestMFAService_RegenerateRecoveryCodes_WithoutProof(test *testing.T) {
+	cases := map[string]struct {
+		password string
+		code     string
+		want     error
+	}{
+		"wrong password": {password: "wrong", want: errs.ErrInvalidCredentials},
+		"wrong code":     {code: "000000", want: errs.ErrInvalidOTP},
+	}
+
+	for name, tc := range cases {
+		test.Run(name, func(test *testing.T) {
+			recoveryStore := &fakeRecoveryCodeStore{}
+			svc := newMFAServiceForUser(
+				User{ID: "u1", PasswordHash: "other-hash"},
+				&fakeTOTPStore{credential: confirmedTOTPCredential()},
+				recoveryStore,
+			)
+
+			_, err := svc.RegenerateRecoveryCodes(context.Background(), "access.jwt", tc.password, tc.code)
+			if !errors.Is(err, tc.want) {
+				test.Fatalf("expected %v, got %v", tc.want, err)
+			}
+			if len(recoveryStore.replaced) != 0 {
+				test.Fatalf("no recovery codes must be issued")
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/webauthn"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/clientinfo"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+// methods of a passkey login: a hardware-bound key unlocked by user verification
+// (PIN or biometrics), which already makes it multi-factor
+var passkeyAMR = []string{domain.AMRHardwareKey, domain.AMRUserVerification}
+
+// PasskeyService implements passwordless login with passkeys (WebAuthn).
+// Both ceremonies are two-step: Begin stores a single-use challenge server-side
+// and returns the options for the browser, Finish verifies the authenticator
+// response against it. A passkey login skips the TOTP step.
+type PasskeyService struct {
+	transactionMgr  storage.TransactionMgr
+	login           *LoginService
+	credentialStore storage.WebAuthnCredentialStoreProvider
+	challengeStore  storage.WebAuthnChallengeStoreProvider
+	relyingParty    *webauthn.RelyingParty
+	accessVerifier  AccessTokenVerifier
+	challengeTTL    time.Duration
+}
+
+// login provides membership lookup and token issuance
+func NewPasskeyService(
+	transactionMgr storage.TransactionMgr,
+	login *LoginService,
+	credentialStore storage.WebAuthnCredentialStoreProvider,
+	challengeStore storage.WebAuthnChallengeStoreProvider,
+	relyingParty *webauthn.RelyingParty,
+	accessVerifier AccessTokenVerifier,
+	challengeTTL time.Duration,
+) *PasskeyService {
+	return &PasskeyService{
+		transactionMgr:  transactionMgr,
+		login:           login,
+		credentialStore: credentialStore,
+		challengeStore:  challengeStore,
+		relyingParty:    relyingParty,
+		accessVerifier:  accessVerifier,
+		challengeTTL:    challengeTTL,
+	}
+}
+
+// starts registering a new passkey for the user an access token was issued to;
+// returns the ceremony ID to finish it with
+func (svc *PasskeyService) BeginRegistration(
+	ctx context.Context,
+	accessToken string,
+) (ceremonyID string, options webauthn.CreationOptions, err error) {
+
+	claims, err := svc.accessVerifier.Verify(accessToken)
+	if err != nil {
+		return "", webauthn.CreationOptions{}, errs.ErrInvalidAccessToken
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return "", webauthn.CreationOptions{}, err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	user, err := svc.login.userStoreProvider(exec).GetById(ctx, claims.Subject)
+	if errors.Is(err, errs.ErrNotFound) {
+		return "", webauthn.CreationOptions{}, errs.ErrInvalidAccessToken
+	}
+	if err != nil {
+		return "", webauthn.CreationOptions{}, err
+	}
+
+	existing, err := svc.credentialStore(exec).ListByUserID(ctx, user.ID)
+	if err != nil {
+		return "", webauthn.CreationOptions{}, err
+	}
+
+	challenge, err := svc.startCeremony(ctx, exec, domain.WebAuthnRegistration, user.ID)
+	if err != nil {
+		return "", webauthn.CreationOptions{}, err
+	}
+
+	options = svc.relyingParty.CreationOptions(challenge.Challenge, user.ID, user.Email, existing)
+	return challenge.ID, options, nil
+}
+
+// stores the passkey created by the authenticator
+func (svc *PasskeyService) FinishRegistration(
+	ctx context.Context,
+	accessToken string,
+	ceremonyID string,
+	response webauthn.RegistrationResponse,
+) (err error) {
+
+	claims, err := svc.accessVerifier.Verify(accessToken)
+	if err != nil {
+		return errs.ErrInvalidAccessToken
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	challenge, err := svc.consumeCeremony(ctx, exec, ceremonyID, domain.WebAuthnRegistration)
+	if err != nil {
+		return err
+	}
+	// a ceremony started by another user
+	if challenge.UserID != claims.Subject {
+		return errs.ErrInvalidPasskeyCeremony
+	}
+
+	credential, err := svc.relyingParty.VerifyRegistration(challenge.Challenge, response)
+	if err != nil {
+		return errs.ErrInvalidPasskey
+	}
+
+	credential.UserID = challenge.UserID
+	credential.CreatedAt = time.Now()
+
+	err = svc.credentialStore(exec).Create(ctx, credential)
+	if errors.Is(err, errs.ErrAlreadyExists) {
+		return errs.ErrInvalidPasskey
+	}
+	return err
+}
+
+// starts a passkey login; nobody is identified yet, the browser offers
+// every passkey it has for the site
+func (svc *PasskeyService) BeginLogin(
+	ctx context.Context,
+) (ceremonyID string, options webauthn.RequestOptions, err error) {
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return "", webauthn.RequestOptions{}, err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	challenge, err := svc.startCeremony(ctx, exec, domain.WebAuthnLogin, "")
+	if err != nil {
+		return "", webauthn.RequestOptions{}, err
+	}
+
+	return challenge.ID, svc.relyingParty.RequestOptions(challenge.Challenge), nil
+}
+
+// verifies the assertion and starts a session for the passkey's owner,
+// with the same tokens a password login returns
+func (svc *PasskeyService) FinishLogin(
+	ctx context.Context,
+	ceremonyID string,
+	response webauthn.AssertionResponse,
+	idTokenReq IDTokenRequest,
+	rememberMe bool,
+) (tokens IssuedTokens, err error) {
+
+	// checked before the ceremony is consumed
+	if err := svc.login.checkIDTokenClient(idTokenReq); err != nil {
+		return IssuedTokens{}, err
+	}
+
+	// not read-only: the sign counter and the refresh token are stored
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	// owner of the presented passkey, once it is known
+	var userID string
+
+	// the client of the login is in ctx, the handler takes no IP of its own
+	defer func() {
+		finish(err)
+		recordAudit(ctx, svc.login.audit, loginEvents(userID, clientinfo.FromContext(ctx).IP, passkeyAMR, false, err)...)
+	}()
+
+	challenge, err := svc.consumeCeremony(ctx, exec, ceremonyID, domain.WebAuthnLogin)
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	credentials := svc.credentialStore(exec)
+
+	credential, err := credentials.GetByID(ctx, response.RawID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return IssuedTokens{}, errs.ErrInvalidPasskey
+	}
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+	userID = credential.UserID
+
+	signCount, err := svc.relyingParty.VerifyAssertion(challenge.Challenge, credential, response)
+	if errors.Is(err, webauthn.ErrSignCountRegression) {
+		log.Printf(
+			"security event: passkey sign counter went back, possibly cloned (user=%s credential=%s)",
+			credential.UserID,
+			credential.ID,
+		)
+	}
+	if err != nil {
+		return IssuedTokens{}, errs.ErrInvalidPasskey
+	}
+
+	if err := credentials.UpdateSignCount(ctx, credential.ID, signCount); err != nil {
+		return IssuedTokens{}, err
+	}
+
+	user, err := svc.login.userStoreProvider(exec).GetById(ctx, credential.UserID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return IssuedTokens{}, errs.ErrInvalidPasskey
+	}
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	if err := svc.login.checkEmailVerified(user); err != nil {
+		return IssuedTokens{}, err
+	}
+
+	membership, err := svc.login.membershipProvider(exec).GetByUserID(ctx, user.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return IssuedTokens{}, errs.ErrInvalidPasskey
+	}
+	if err != nil {
+		return IssuedTokens{}, err
+	}
+
+	return svc.login.issueTokens(ctx, exec, user, membership, idTokenReq, passkeyAMR, time.Now(), rememberMe)
+}
+
+func (svc *PasskeyService) startCeremony(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	ceremony string,
+	userID string,
+) (domain.WebAuthnChallenge, error) {
+
+	raw, err := webauthn.NewChallenge()
+	if err != nil {
+		return domain.WebAuthnChallenge{}, err
+	}
+
+	now := time.Now()
+	challenge := domain.WebAuthnChallenge{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Ceremony:  ceremony,
+		Challenge: raw,
+		ExpiresAt: now.Add(svc.challengeTTL),
+		CreatedAt: now,
+	}
+
+	if err := svc.challengeStore(exec).Create(ctx, challenge); err != nil {
+		return domain.WebAuthnChallenge{}, err
+	}
+
+	return challenge, nil
+}
+
+// deletes the challenge so a successful ceremony cannot be replayed;
+// a failed one rolls back and may be retried until the challenge expires
+func (svc *PasskeyService) consumeCeremony(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	ceremonyID string,
+	ceremony string,
+) (domain.WebAuthnChallenge, error) {
+
+	challenge, err := svc.challengeStore(exec).Consume(ctx, ceremonyID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return domain.WebAuthnChallenge{}, errs.ErrInvalidPasskeyCeremony
+	}
+	if err != nil {
+		return domain.WebAuthnChallenge{}, err
+	}
+
+	if challenge.Ceremony != ceremony || time.Now().After(challenge.ExpiresAt) {
+		return domain.WebAuthnChallenge{}, errs.ErrInvalidPasskeyCeremony
+	}
+
+	return challenge, nil
+}
@@ -0,0 +1,340 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/webauthn"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/webauthn/webauthntest"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/clientinfo"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+const (
+	RP_ID     = "family.example"
+	RP_ORIGIN = "https://app.family.example"
+)
+
+type passkeyFixture struct {
+	svc         *service.PasskeyService
+	signer      *fakeSigner
+	credentials *fakeWebAuthnCredentialStore
+	challenges  *fakeWebAuthnChallengeStore
+	audit       *fakeAuditSink
+}
+
+func newPasskeyService(test *testing.T) passkeyFixture {
+	test.Helper()
+
+	fixture := passkeyFixture{
+		signer:      &fakeSigner{token: JWTToken},
+		credentials: &fakeWebAuthnCredentialStore{},
+		challenges:  &fakeWebAuthnChallengeStore{},
+		audit:       &fakeAuditSink{},
+	}
+
+	login := newAuditedLoginService(
+		fixture.signer,
+		&fakeTOTPStore{},
+		&fakeRecoveryCodeStore{},
+		&fakeRefreshTokenStore{},
+		&fakeMFAChallenges{},
+		fixture.audit,
+	)
+
+	fixture.svc = service.NewPasskeyService(
+		&fakeDB{exec: &fakeSQLExecutor{}},
+		login,
+		webAuthnCredentialStoreProvider(fixture.credentials),
+		webAuthnChallengeStoreProvider(fixture.challenges),
+		webauthn.NewRelyingParty(RP_ID, "Family Space", []string{RP_ORIGIN}),
+		&fakeAccessTokenVerifier{
+			claims: &jwt.Claims{RegisteredClaims: gojwt.RegisteredClaims{Subject: "u1"}},
+		},
+		5*time.Minute,
+	)
+
+	return fixture
+}
+
+// runs a registration ceremony with a new software authenticator
+func registerPasskey(test *testing.T, fixture passkeyFixture) *webauthntest.Authenticator {
+	test.Helper()
+
+	ctx := context.Background()
+	ceremonyID, options, err := fixture.svc.BeginRegistration(ctx, "access.token")
+	if err != nil {
+		test.Fatalf("unexpected BeginRegistration error: %v", err)
+	}
+
+	authenticator := webauthntest.NewAuthenticator(RP_ID, RP_ORIGIN)
+	response := authenticator.Register(options.Challenge, "u1")
+
+	if err := fixture.svc.FinishRegistration(ctx, "access.token", ceremonyID, response); err != nil {
+		test.Fatalf("unexpected FinishRegistration error: %v", err)
+	}
+
+	return authenticator
+}
+
+func TestPasskeyService_Registration(test *testing.T) {
+	fixture := newPasskeyService(test)
+
+	authenticator := registerPasskey(test, fixture)
+
+	stored, ok := fixture.credentials.credentials[authenticator.ID()]
+	if !ok {
+		test.Fatalf("expected credential to be stored")
+	}
+	if stored.UserID != "u1" || len(stored.PublicKey) == 0 || stored.CreatedAt.IsZero() {
+		test.Fatalf("unexpected stored credential %+v", stored)
+	}
+	if len(fixture.challenges.challenges) != 0 {
+		test.Fatalf("expected challenge to be consumed")
+	}
+}
+
+func TestPasskeyService_BeginRegistration_ExcludesExistingPasskeys(test *testing.T) {
+	fixture := newPasskeyService(test)
+	authenticator := registerPasskey(test, fixture)
+
+	_, options, err := fixture.svc.BeginRegistration(context.Background(), "access.token")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(options.ExcludeCredentials) != 1 || options.ExcludeCredentials[0].ID != authenticator.ID() {
+		test.Fatalf("unexpected excluded credentials %+v", options.ExcludeCredentials)
+	}
+	if options.User.Name != "a@b.com" {
+		test.Fatalf("unexpected user name %q", options.User.Name)
+	}
+}
+
+func TestPasskeyService_FinishRegistration_InvalidAttestation(test *testing.T) {
+	fixture := newPasskeyService(test)
+
+	ctx := context.Background()
+	ceremonyID, _, err := fixture.svc.BeginRegistration(ctx, "access.token")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	// answers a challenge it was not given
+	response := webauthntest.NewAuthenticator(RP_ID, RP_ORIGIN).Register("b3RoZXI", "u1")
+
+	err = fixture.svc.FinishRegistration(ctx, "access.token", ceremonyID, response)
+	if !errors.Is(err, errs.ErrInvalidPasskey) {
+		test.Fatalf("expected ErrInvalidPasskey, got %v", err)
+	}
+	if len(fixture.credentials.credentials) != 0 {
+		test.Fatalf("expected no credential to be stored")
+	}
+}
+
+func TestPasskeyService_FinishRegistration_InvalidAccessToken(test *testing.T) {
+	fixture := newPasskeyService(test)
+
+	svc := service.NewPasskeyService(
+		&fakeDB{exec: &fakeSQLExecutor{}},
+		newMFALoginService(&fakeSigner{}, &fakeTOTPStore{}, &fakeMFAChallenges{}),
+		webAuthnCredentialStoreProvider(fixture.credentials),
+		webAuthnChallengeStoreProvider(fixture.challenges),
+		webauthn.NewRelyingParty(RP_ID, "Family Space", []string{RP_ORIGIN}),
+		&fakeAccessTokenVerifier{err: errors.New("expired")},
+		5*time.Minute,
+	)
+
+	err := svc.FinishRegistration(context.Background(), "expired.token", "ceremony", webauthn.RegistrationResponse{})
+	if !errors.Is(err, errs.ErrInvalidAccessToken) {
+		test.Fatalf("expected ErrInvalidAccessToken, got %v", err)
+	}
+}
+
+func TestPasskeyService_FinishRegistration_UnknownCeremony(test *testing.T) {
+	fixture := newPasskeyService(test)
+
+	err := fixture.svc.FinishRegistration(context.Background(), "access.token", "unknown", webauthn.RegistrationResponse{})
+	if !errors.Is(err, errs.ErrInvalidPasskeyCeremony) {
+		test.Fatalf("expected ErrInvalidPasskeyCeremony, got %v", err)
+	}
+}
+
+func TestPasskeyService_Login(test *testing.T) {
+	fixture := newPasskeyService(test)
+	authenticator := registerPasskey(test, fixture)
+
+	ctx := context.Background()
+	ceremonyID, options, err := fixture.svc.BeginLogin(ctx)
+	if err != nil {
+		test.Fatalf("unexpected BeginLogin error: %v", err)
+	}
+	if options.RPID != RP_ID || options.UserVerification != "required" {
+		test.Fatalf("unexpected request options %+v", options)
+	}
+
+	tokens, err := fixture.svc.FinishLogin(ctx, ceremonyID, authenticator.Assert(options.Challenge), IDTokenRequest{}, false)
+	if err != nil {
+		test.Fatalf("unexpected FinishLogin error: %v", err)
+	}
+
+	if tokens.AccessToken != JWTToken || tokens.RefreshToken != RefreshToken {
+		test.Fatalf("unexpected tokens %+v", tokens)
+	}
+	if !slices.Equal(fixture.signer.gotAMR, []string{domain.AMRHardwareKey, domain.AMRUserVerification}) {
+		test.Fatalf("unexpected amr %v", fixture.signer.gotAMR)
+	}
+}
+
+func TestPasskeyService_Login_AuditsClientIP(test *testing.T) {
+	fixture := newPasskeyService(test)
+	authenticator := registerPasskey(test, fixture)
+
+	ctx := clientinfo.NewContext(context.Background(), clientinfo.Info{IP: "203.0.113.7"})
+	ceremonyID, options, _ := fixture.svc.BeginLogin(ctx)
+
+	// a failed attempt, then a successful one
+	if _, err := fixture.svc.FinishLogin(ctx, "unknown", authenticator.Assert(options.Challenge), IDTokenRequest{}, false); err == nil {
+		test.Fatal("expected unknown ceremony to fail")
+	}
+	if _, err := fixture.svc.FinishLogin(ctx, ceremonyID, authenticator.Assert(options.Challenge), IDTokenRequest{}, false); err != nil {
+		test.Fatalf("unexpected FinishLogin error: %v", err)
+	}
+
+	if !slices.Equal(fixture.audit.types(), []string{audit.LoginFailed, audit.LoginSucceeded}) {
+		test.Fatalf("unexpected events %v", fixture.audit.types())
+	}
+	for _, event := range fixture.audit.events {
+		if event.ClientIP != "203.0.113.7" {
+			test.Fatalf("expected the client IP of the request, got %+v", event)
+		}
+	}
+}
+
+func TestPasskeyService_Login_ChallengeUsedOnce(test *testing.T) {
+	fixture := newPasskeyService(test)
+	authenticator := registerPasskey(test, fixture)
+
+	ctx := context.Background()
+	ceremonyID, options, err := fixture.svc.BeginLogin(ctx)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	assertion := authenticator.Assert(options.Challenge)
+	if _, err := fixture.svc.FinishLogin(ctx, ceremonyID, assertion, IDTokenRequest{}, false); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = fixture.svc.FinishLogin(ctx, ceremonyID, assertion, IDTokenRequest{}, false)
+	if !errors.Is(err, errs.ErrInvalidPasskeyCeremony) {
+		test.Fatalf("expected ErrInvalidPasskeyCeremony, got %v", err)
+	}
+}
+
+func TestPasskeyService_Login_RegistrationCeremonyRejected(test *testing.T) {
+	fixture := newPasskeyService(test)
+	authenticator := registerPasskey(test, fixture)
+
+	ctx := context.Background()
+	ceremonyID, options, err := fixture.svc.BeginRegistration(ctx, "access.token")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = fixture.svc.FinishLogin(ctx, ceremonyID, authenticator.Assert(options.Challenge), IDTokenRequest{}, false)
+	if !errors.Is(err, errs.ErrInvalidPasskeyCeremony) {
+		test.Fatalf("expected ErrInvalidPasskeyCeremony, got %v", err)
+	}
+}
+
+func TestPasskeyService_Login_ExpiredCeremony(test *testing.T) {
+	fixture := newPasskeyService(test)
+	authenticator := registerPasskey(test, fixture)
+
+	ctx := context.Background()
+	ceremonyID, options, err := fixture.svc.BeginLogin(ctx)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	challenge := fixture.challenges.challenges[ceremonyID]
+	challenge.ExpiresAt = time.Now().Add(-time.Second)
+	fixture.challenges.challenges[ceremonyID] = challenge
+
+	_, err = fixture.svc.FinishLogin(ctx, ceremonyID, authenticator.Assert(options.Challenge), IDTokenRequest{}, false)
+	if !errors.Is(err, errs.ErrInvalidPasskeyCeremony) {
+		test.Fatalf("expected ErrInvalidPasskeyCeremony, got %v", err)
+	}
+}
+
+func TestPasskeyService_Login_UnknownPasskey(test *testing.T) {
+	fixture := newPasskeyService(test)
+
+	ctx := context.Background()
+	ceremonyID, options, err := fixture.svc.BeginLogin(ctx)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	unregistered := webauthntest.NewAuthenticator(RP_ID, RP_ORIGIN)
+	unregistered.UserHandle = []byte("u1")
+
+	_, err = fixture.svc.FinishLogin(ctx, ceremonyID, unregistered.Assert(options.Challenge), IDTokenRequest{}, false)
+	if !errors.Is(err, errs.ErrInvalidPasskey) {
+		test.Fatalf("expected ErrInvalidPasskey, got %v", err)
+	}
+	if fixture.signer.gotAMR != nil {
+		test.Fatalf("expected no tokens to be issued")
+	}
+}
+
+func TestPasskeyService_Login_StoresSignCount(test *testing.T) {
+	fixture := newPasskeyService(test)
+
+	ctx := context.Background()
+	ceremonyID, options, err := fixture.svc.BeginRegistration(ctx, "access.token")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	// an authenticator that keeps a counter
+	authenticator := webauthntest.NewAuthenticator(RP_ID, RP_ORIGIN)
+	authenticator.SignCount = 1
+	if err := fixture.svc.FinishRegistration(ctx, "access.token", ceremonyID, authenticator.Register(options.Challenge, "u1")); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	ceremonyID, requestOptions, err := fixture.svc.BeginLogin(ctx)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := fixture.svc.FinishLogin(ctx, ceremonyID, authenticator.Assert(requestOptions.Challenge), IDTokenRequest{}, false); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := fixture.credentials.credentials[authenticator.ID()].SignCount; got != 2 {
+		test.Fatalf("expected stored sign count 2, got %d", got)
+	}
+
+	// a clone of the authenticator, still at the old counter
+	authenticator.SignCount = 1
+	ceremonyID, requestOptions, err = fixture.svc.BeginLogin(ctx)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = fixture.svc.FinishLogin(ctx, ceremonyID, authenticator.Assert(requestOptions.Challenge), IDTokenRequest{}, false)
+	if !errors.Is(err, errs.ErrInvalidPasskey) {
+		test.Fatalf("expected ErrInvalidPasskey, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+// PasswordChangeService lets a logged-in user change the password.
+// The current password is required, and every other session ends:
+// whoever knew the old password is logged out.
+type PasswordChangeService struct {
+	transactionMgr    storage.TransactionMgr
+	userStoreProvider storage.UserStoreProvider
+	refreshStore      storage.RefreshTokenStoreProvider
+	memberStore       MembershipStoreProvider
+	familyStore       FamilyStoreProvider
+	hash              password.PasswordHasher
+	policy            PasswordPolicy
+	refreshHasher     refresh.RefreshTokenHasher
+	accessVerifier    AccessTokenVerifier
+	audit             AuditSink
+}
+
+func NewPasswordChangeService(
+	transactionMgr storage.TransactionMgr,
+	userStore storage.UserStoreProvider,
+	refreshStore storage.RefreshTokenStoreProvider,
+	memberStore MembershipStoreProvider,
+	familyStore FamilyStoreProvider,
+	hash password.PasswordHasher,
+	policy PasswordPolicy,
+	refreshHasher refresh.RefreshTokenHasher,
+	accessVerifier AccessTokenVerifier,
+	auditSink AuditSink,
+) *PasswordChangeService {
+	return &PasswordChangeService{
+		transactionMgr:    transactionMgr,
+		userStoreProvider: userStore,
+		refreshStore:      refreshStore,
+		memberStore:       memberStore,
+		familyStore:       familyStore,
+		hash:              hash,
+		policy:            policy,
+		refreshHasher:     refreshHasher,
+		accessVerifier:    accessVerifier,
+		audit:             auditSink,
+	}
+}
+
+// changes the password of the user the access token was issued to and revokes
+// all of the user's refresh tokens in the same transaction. With the caller's
+// refresh token (optional) its session is kept, so the device stays logged in.
+func (svc *PasswordChangeService) ChangePassword(
+	ctx context.Context,
+	accessToken string,
+	currentPassword string,
+	newPassword string,
+	refreshToken string,
+) (err error) {
+
+	claims, err := svc.accessVerifier.Verify(accessToken)
+	if err != nil {
+		return errs.ErrInvalidAccessToken
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	var user User
+	// the session that stays logged in, if any
+	var keepSessionID string
+
+	defer func() {
+		finish(err)
+		if err == nil {
+			recordAudit(ctx, svc.audit, audit.Event{
+				Type:      audit.PasswordChanged,
+				UserID:    user.ID,
+				SessionID: keepSessionID,
+			})
+		}
+	}()
+
+	userStore := svc.userStoreProvider(exec)
+
+	user, err = userStore.GetById(ctx, claims.Subject)
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrInvalidAccessToken
+	}
+	if err != nil {
+		return err
+	}
+
+	// a stolen access token alone is not enough
+	if err := svc.hash.Compare(user.PasswordHash, currentPassword); err != nil {
+		return errs.ErrInvalidCredentials
+	}
+
+	userInputs, err := personalInfo(ctx, exec, svc.memberStore, svc.familyStore, user)
+	if err != nil {
+		return err
+	}
+
+	if err := svc.policy.Validate(newPassword, userInputs...); err != nil {
+		return err
+	}
+
+	passwordHash, err := svc.hash.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := userStore.UpdatePasswordHash(ctx, user.ID, passwordHash); err != nil {
+		return err
+	}
+
+	keepSessionID, err = svc.currentSession(ctx, exec, user.ID, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	refreshStore := svc.refreshStore(exec)
+	if keepSessionID != "" {
+		err = refreshStore.RevokeOtherSessions(ctx, user.ID, keepSessionID)
+	} else {
+		err = refreshStore.RevokeAllForUser(ctx, user.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// session of the caller's refresh token; empty if there is none, or it is
+// not an active token of this user (then no session is kept)
+func (svc *PasswordChangeService) currentSession(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	userID string,
+	refreshToken string,
+) (string, error) {
+
+	if refreshToken == "" {
+		return "", nil
+	}
+
+	tokenHash, err := svc.refreshHasher.Hash(refreshToken)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := svc.refreshStore(exec).GetByHash(ctx, tokenHash)
+	if errors.Is(err, errs.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if token.UserID != userID || token.RevokedAt != nil {
+		return "", nil
+	}
+
+	return token.SessionID, nil
+}
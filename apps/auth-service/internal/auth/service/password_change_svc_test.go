@@ -0,0 +1,145 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+func newPasswordChangeService(
+	userStore *fakeUserStore,
+	refreshStore *fakeRefreshTokenStore,
+	verifier *fakeAccessTokenVerifier,
+	policy *fakePasswordPolicy,
+) *service.PasswordChangeService {
+	return service.NewPasswordChangeService(
+		&fakeDB{
+			exec: &fakeSQLExecutor{},
+		},
+		userStoreProvider(userStore),
+		refreshStoreProvider(refreshStore),
+		membershipStoreProvider(&fakeMembershipStore{membership: Membership{UserID: "u1", FamilyID: "f1"}}),
+		familyStoreProvider(&fakeFamilyStore{family: Family{ID: "f1", Name: "Smith"}}),
+		&fakeHasher{hash: "new-hash"},
+		policy,
+		&fakeRefreshTokenHasher{hash: "hash"},
+		verifier,
+		&fakeAuditSink{},
+	)
+}
+
+func validAccessToken(subject string) *fakeAccessTokenVerifier {
+	return &fakeAccessTokenVerifier{
+		claims: &jwt.Claims{RegisteredClaims: gojwt.RegisteredClaims{Subject: subject}},
+	}
+}
+
+func TestPasswordChangeService_RevokesAllSessions(test *testing.T) {
+	userStore := &fakeUserStore{user: User{ID: "u1", PasswordHash: HASH}}
+	refreshStore := &fakeRefreshTokenStore{getErr: errs.ErrNotFound}
+	changeSvc := newPasswordChangeService(userStore, refreshStore, validAccessToken("u1"), &fakePasswordPolicy{})
+
+	err := changeSvc.ChangePassword(context.Background(), "access", "old password", "new password", "")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if userStore.user.PasswordHash != "new-hash" {
+		test.Fatalf("password hash was not updated")
+	}
+	if !refreshStore.revokeAllCalled || refreshStore.keptSessionID != "" {
+		test.Fatalf("expected every session to be revoked")
+	}
+}
+
+func TestPasswordChangeService_KeepsCurrentSession(test *testing.T) {
+	userStore := &fakeUserStore{user: User{ID: "u1", PasswordHash: HASH}}
+	refreshStore := &fakeRefreshTokenStore{
+		token: refresh.RefreshToken{ID: "r1", UserID: "u1", SessionID: "s1"},
+	}
+	changeSvc := newPasswordChangeService(userStore, refreshStore, validAccessToken("u1"), &fakePasswordPolicy{})
+
+	err := changeSvc.ChangePassword(context.Background(), "access", "old password", "new password", "refresh")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if refreshStore.revokeAllCalled || refreshStore.keptSessionID != "s1" {
+		test.Fatalf("expected the other sessions to be revoked, keeping s1")
+	}
+}
+
+func TestPasswordChangeService_ForeignRefreshTokenKeepsNothing(test *testing.T) {
+	userStore := &fakeUserStore{user: User{ID: "u1", PasswordHash: HASH}}
+	refreshStore := &fakeRefreshTokenStore{
+		token: refresh.RefreshToken{ID: "r1", UserID: "u2", SessionID: "s1"},
+	}
+	changeSvc := newPasswordChangeService(userStore, refreshStore, validAccessToken("u1"), &fakePasswordPolicy{})
+
+	err := changeSvc.ChangePassword(context.Background(), "access", "old password", "new password", "refresh")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if !refreshStore.revokeAllCalled {
+		test.Fatalf("expected every session to be revoked")
+	}
+}
+
+func TestPasswordChangeService_WrongCurrentPassword(test *testing.T) {
+	userStore := &fakeUserStore{user: User{ID: "u1", PasswordHash: "wronghash"}}
+	refreshStore := &fakeRefreshTokenStore{}
+	changeSvc := newPasswordChangeService(userStore, refreshStore, validAccessToken("u1"), &fakePasswordPolicy{})
+
+	err := changeSvc.ChangePassword(context.Background(), "access", "guess", "new password", "")
+	if !errors.Is(err, errs.ErrInvalidCredentials) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidCredentials, err)
+	}
+
+	if userStore.user.PasswordHash != "wronghash" || refreshStore.revokeAllCalled {
+		test.Fatalf("a wrong current password must not change anything")
+	}
+}
+
+func TestPasswordChangeService_InvalidAccessToken(test *testing.T) {
+	changeSvc := newPasswordChangeService(
+		&fakeUserStore{},
+		&fakeRefreshTokenStore{},
+		&fakeAccessTokenVerifier{err: errors.New("expired")},
+		&fakePasswordPolicy{},
+	)
+
+	err := changeSvc.ChangePassword(context.Background(), "access", "old password", "new password", "")
+	if !errors.Is(err, errs.ErrInvalidAccessToken) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidAccessToken, err)
+	}
+}
+
+func TestPasswordChangeService_WeakPassword(test *testing.T) {
+	userStore := &fakeUserStore{user: User{ID: "u1", Email: "a@b.com", PasswordHash: HASH}}
+	refreshStore := &fakeRefreshTokenStore{}
+	policy := &fakePasswordPolicy{err: &password.PolicyError{
+		Violations: []password.Violation{{Rule: password.RulePersonalInfo}},
+	}}
+	changeSvc := newPasswordChangeService(userStore, refreshStore, validAccessToken("u1"), policy)
+
+	err := changeSvc.ChangePassword(context.Background(), "access", "old password", "smith123", "")
+	if !errors.Is(err, errs.ErrWeakPassword) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrWeakPassword, err)
+	}
+
+	if userStore.user.PasswordHash != HASH || refreshStore.revokeAllCalled {
+		test.Fatalf("a weak password must not change anything")
+	}
+	if len(policy.gotInputs) != 2 || policy.gotInputs[1] != "Smith" {
+		test.Fatalf("expected email and family name as personal info, got %v", policy.gotInputs)
+	}
+}
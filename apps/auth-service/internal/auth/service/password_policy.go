@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+// implemented by password.Policy; a weak password is a *password.PolicyError
+type PasswordPolicy interface {
+	Validate(password string, userInputs ...string) error
+}
+
+// values the user entered elsewhere, which a new password must not contain:
+// the email address and, if the user belongs to one, the family name
+func personalInfo(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	memberships MembershipStoreProvider,
+	families FamilyStoreProvider,
+	user User,
+) ([]string, error) {
+
+	inputs := []string{user.Email}
+
+	membership, err := memberships(exec).GetByUserID(ctx, user.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return inputs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	family, err := families(exec).GetByID(ctx, membership.FamilyID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return inputs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return append(inputs, family.Name), nil
+}
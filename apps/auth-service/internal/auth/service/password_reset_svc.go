@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/mail"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+// PasswordResetService implements "forgot password": a single-use link is emailed,
+// and setting a new password with it ends every session of the user.
+// Reset tokens are opaque random strings stored as HMAC hashes, like refresh tokens.
+type PasswordResetService struct {
+	transactionMgr    storage.TransactionMgr
+	userStoreProvider storage.UserStoreProvider
+	resetStore        storage.PasswordResetTokenStoreProvider
+	refreshStore      storage.RefreshTokenStoreProvider
+	memberStore       MembershipStoreProvider
+	familyStore       FamilyStoreProvider
+	hash              password.PasswordHasher
+	policy            PasswordPolicy
+	tokenGen          refresh.RefreshTokenGenerator
+	tokenHasher       refresh.RefreshTokenHasher
+	mailer            mail.Mailer
+	// page of the web UI that asks for the new password and posts it to /password/reset
+	resetURL string
+	tokenTTL time.Duration
+	audit    AuditSink
+}
+
+func NewPasswordResetService(
+	transactionMgr storage.TransactionMgr,
+	userStore storage.UserStoreProvider,
+	resetStore storage.PasswordResetTokenStoreProvider,
+	refreshStore storage.RefreshTokenStoreProvider,
+	memberStore MembershipStoreProvider,
+	familyStore FamilyStoreProvider,
+	hash password.PasswordHasher,
+	policy PasswordPolicy,
+	tokenGen refresh.RefreshTokenGenerator,
+	tokenHasher refresh.RefreshTokenHasher,
+	mailer mail.Mailer,
+	resetURL string,
+	tokenTTL time.Duration,
+	auditSink AuditSink,
+) *PasswordResetService {
+	return &PasswordResetService{
+		transactionMgr:    transactionMgr,
+		userStoreProvider: userStore,
+		resetStore:        resetStore,
+		refreshStore:      refreshStore,
+		memberStore:       memberStore,
+		familyStore:       familyStore,
+		hash:              hash,
+		policy:            policy,
+		tokenGen:          tokenGen,
+		tokenHasher:       tokenHasher,
+		mailer:            mailer,
+		resetURL:          resetURL,
+		tokenTTL:          tokenTTL,
+		audit:             auditSink,
+	}
+}
+
+// emails a reset link; an unknown address is silently ignored,
+// so the caller cannot tell whether an account exists
+func (svc *PasswordResetService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, token, err := svc.createResetToken(ctx, email)
+	if err != nil || token == "" {
+		return err
+	}
+
+	// after the commit: the token must exist before the link can be opened
+	link := svc.resetURL + "?token=" + url.QueryEscape(token)
+
+	return svc.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Open this link to choose a new password:\n%s\n\n"+
+				"The link can be used once and expires in %s. "+
+				"If you did not ask to reset your password, you can ignore this email.\n",
+			link,
+			svc.tokenTTL,
+		),
+	})
+}
+
+// sets the new password and revokes every refresh token of the user,
+// all in one transaction. A password the policy rejects leaves the token usable.
+func (svc *PasswordResetService) ResetPassword(
+	ctx context.Context,
+	token string,
+	newPassword string,
+) (err error) {
+
+	tokenHash, err := svc.tokenHasher.Hash(token)
+	if err != nil {
+		return err
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	var user User
+
+	defer func() {
+		finish(err)
+		if err == nil {
+			recordAudit(ctx, svc.audit, audit.Event{Type: audit.PasswordReset, UserID: user.ID})
+		}
+	}()
+
+	resetStore := svc.resetStore(exec)
+
+	resetToken, err := resetStore.GetByHash(ctx, tokenHash)
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+		return errs.ErrInvalidResetToken
+	}
+
+	userStore := svc.userStoreProvider(exec)
+
+	user, err = userStore.GetById(ctx, resetToken.UserID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	userInputs, err := personalInfo(ctx, exec, svc.memberStore, svc.familyStore, user)
+	if err != nil {
+		return err
+	}
+
+	if err := svc.policy.Validate(newPassword, userInputs...); err != nil {
+		return err
+	}
+
+	passwordHash, err := svc.hash.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	// a concurrent reset with the same token already won
+	err = resetStore.MarkUsed(ctx, resetToken.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	// other links that were requested before stop working too
+	if err := resetStore.InvalidateAllForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := userStore.UpdatePasswordHash(ctx, user.ID, passwordHash); err != nil {
+		return err
+	}
+
+	// whoever knew the old password is logged out everywhere
+	if err := svc.refreshStore(exec).RevokeAllForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// returns an empty token for an unknown address
+func (svc *PasswordResetService) createResetToken(
+	ctx context.Context,
+	email string,
+) (user User, token string, err error) {
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return User{}, "", err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	user, err = svc.userStoreProvider(exec).GetByEmail(ctx, email)
+	if errors.Is(err, errs.ErrNotFound) {
+		return User{}, "", nil
+	}
+	if err != nil {
+		return User{}, "", err
+	}
+
+	token, err = svc.tokenGen.Generate()
+	if err != nil {
+		return User{}, "", err
+	}
+
+	tokenHash, err := svc.tokenHasher.Hash(token)
+	if err != nil {
+		return User{}, "", err
+	}
+
+	now := time.Now()
+	err = svc.resetStore(exec).Create(ctx, domain.PasswordResetToken{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(svc.tokenTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return User{}, "", err
+	}
+
+	return user, token, nil
+}
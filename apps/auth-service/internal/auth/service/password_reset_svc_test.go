@@ -0,0 +1,178 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/mail"
+)
+
+const RESET_URL = "https://family.example/reset-password"
+const RESET_TOKEN = "reset-token"
+const RESET_HASH = "reset-hash"
+
+type passwordResetFixture struct {
+	userStore    *fakeUserStore
+	resetStore   *fakePasswordResetTokenStore
+	refreshStore *fakeRefreshTokenStore
+	policy       *fakePasswordPolicy
+	mailer       *mail.MemoryMailer
+}
+
+func newPasswordResetFixture() *passwordResetFixture {
+	return &passwordResetFixture{
+		userStore: &fakeUserStore{
+			user: User{ID: "u1", Email: "a@b.com", PasswordHash: "old-hash"},
+		},
+		resetStore:   &fakePasswordResetTokenStore{},
+		refreshStore: &fakeRefreshTokenStore{},
+		policy:       &fakePasswordPolicy{},
+		mailer:       mail.NewMemoryMailer(),
+	}
+}
+
+func (fixture *passwordResetFixture) service() *service.PasswordResetService {
+	return service.NewPasswordResetService(
+		&fakeDB{
+			exec: &fakeSQLExecutor{},
+		},
+		userStoreProvider(fixture.userStore),
+		passwordResetTokenStoreProvider(fixture.resetStore),
+		refreshStoreProvider(fixture.refreshStore),
+		membershipStoreProvider(&fakeMembershipStore{membership: Membership{UserID: "u1", FamilyID: "f1"}}),
+		familyStoreProvider(&fakeFamilyStore{family: Family{ID: "f1", Name: "Smith"}}),
+		&fakeHasher{hash: "new-hash"},
+		fixture.policy,
+		&fakeRefreshTokenGenerator{token: RESET_TOKEN},
+		&fakeRefreshTokenHasher{hash: RESET_HASH},
+		fixture.mailer,
+		RESET_URL,
+		time.Hour,
+		&fakeAuditSink{},
+	)
+}
+
+func (fixture *passwordResetFixture) storeToken(expiresAt time.Time) {
+	fixture.resetStore.Create(context.Background(), domain.PasswordResetToken{
+		ID:        "t1",
+		UserID:    "u1",
+		TokenHash: RESET_HASH,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+func TestPasswordResetService_RequestPasswordReset(test *testing.T) {
+	fixture := newPasswordResetFixture()
+
+	err := fixture.service().RequestPasswordReset(context.Background(), "a@b.com")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	stored, ok := fixture.resetStore.tokens[RESET_HASH]
+	if !ok || stored.UserID != "u1" {
+		test.Fatalf("expected the hashed token to be stored, got %+v", fixture.resetStore.tokens)
+	}
+	if stored.ExpiresAt.Sub(stored.CreatedAt) != time.Hour {
+		test.Fatalf("unexpected token lifetime %s", stored.ExpiresAt.Sub(stored.CreatedAt))
+	}
+
+	sent := fixture.mailer.Sent()
+	if len(sent) != 1 || sent[0].To != "a@b.com" {
+		test.Fatalf("expected one email to a@b.com, got %+v", sent)
+	}
+	if !strings.Contains(sent[0].Body, RESET_URL+"?token="+RESET_TOKEN) {
+		test.Fatalf("reset link missing from body: %q", sent[0].Body)
+	}
+}
+
+func TestPasswordResetService_RequestPasswordReset_UnknownEmail(test *testing.T) {
+	fixture := newPasswordResetFixture()
+	fixture.userStore.err = errs.ErrNotFound
+
+	err := fixture.service().RequestPasswordReset(context.Background(), "nobody@b.com")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fixture.resetStore.tokens) != 0 || len(fixture.mailer.Sent()) != 0 {
+		test.Fatalf("nothing expected for an unknown address")
+	}
+}
+
+func TestPasswordResetService_ResetPassword(test *testing.T) {
+	fixture := newPasswordResetFixture()
+	fixture.storeToken(time.Now().Add(time.Hour))
+
+	err := fixture.service().ResetPassword(context.Background(), RESET_TOKEN, "new password")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if fixture.userStore.user.PasswordHash != "new-hash" {
+		test.Fatalf("password hash was not updated")
+	}
+	if !fixture.refreshStore.revokeAllCalled {
+		test.Fatalf("expected all sessions to be revoked")
+	}
+
+	// single use
+	err = fixture.service().ResetPassword(context.Background(), RESET_TOKEN, "another password")
+	if !errors.Is(err, errs.ErrInvalidResetToken) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidResetToken, err)
+	}
+}
+
+func TestPasswordResetService_ResetPassword_Expired(test *testing.T) {
+	fixture := newPasswordResetFixture()
+	fixture.storeToken(time.Now().Add(-time.Minute))
+
+	err := fixture.service().ResetPassword(context.Background(), RESET_TOKEN, "new password")
+	if !errors.Is(err, errs.ErrInvalidResetToken) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidResetToken, err)
+	}
+
+	if fixture.userStore.user.PasswordHash != "old-hash" || fixture.refreshStore.revokeAllCalled {
+		test.Fatalf("an expired token must not change anything")
+	}
+}
+
+func TestPasswordResetService_ResetPassword_UnknownToken(test *testing.T) {
+	fixture := newPasswordResetFixture()
+
+	err := fixture.service().ResetPassword(context.Background(), RESET_TOKEN, "new password")
+	if !errors.Is(err, errs.ErrInvalidResetToken) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrInvalidResetToken, err)
+	}
+}
+
+func TestPasswordResetService_ResetPassword_WeakPassword(test *testing.T) {
+	fixture := newPasswordResetFixture()
+	fixture.policy.err = &password.PolicyError{
+		Violations: []password.Violation{{Rule: password.RuleBreached}},
+	}
+	fixture.storeToken(time.Now().Add(time.Hour))
+
+	err := fixture.service().ResetPassword(context.Background(), RESET_TOKEN, "password")
+	if !errors.Is(err, errs.ErrWeakPassword) {
+		test.Fatalf("expected %v, but got: %v", errs.ErrWeakPassword, err)
+	}
+
+	if fixture.userStore.user.PasswordHash != "old-hash" || fixture.refreshStore.revokeAllCalled {
+		test.Fatalf("a weak password must not change anything")
+	}
+	if fixture.resetStore.tokens[RESET_HASH].UsedAt != nil {
+		test.Fatalf("the token should stay usable for another attempt")
+	}
+	if len(fixture.policy.gotInputs) != 2 || fixture.policy.gotInputs[0] != "a@b.com" {
+		test.Fatalf("expected email and family name as personal info, got %v", fixture.policy.gotInputs)
+	}
+}
@@ -2,13 +2,14 @@ package service
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
-	"github.com/google/uuid"
 )
 
 // RefreshService handles refresh token rotation and access token issuance
@@ -18,9 +19,9 @@ type RefreshService struct {
 	userStoreProvider  storage.UserStoreProvider
 	membershipProvider storage.MembershipStoreProvider
 	refreshTokenHasher refresh.RefreshTokenHasher
-	refreshTokenGen    refresh.RefreshTokenGenerator
+	refreshIssuer      refreshTokenIssuer
 	tokenSigner        jwt.TokenSigner
-	refreshTokenTtl    time.Duration
+	audit              AuditSink
 }
 
 func NewRefreshService(
@@ -31,7 +32,8 @@ func NewRefreshService(
 	refreshHasher refresh.RefreshTokenHasher,
 	refreshGen refresh.RefreshTokenGenerator,
 	signer jwt.TokenSigner,
-	refreshTTL time.Duration,
+	sessionLifetimes SessionLifetimes,
+	auditSink AuditSink,
 ) *RefreshService {
 	return &RefreshService{
 		transactionMgr:     transactionMgr,
@@ -39,23 +41,37 @@ func NewRefreshService(
 		userStoreProvider:  userStore,
 		membershipProvider: membershipStore,
 		refreshTokenHasher: refreshHasher,
-		refreshTokenGen:    refreshGen,
-		tokenSigner:        signer,
-		refreshTokenTtl:    refreshTTL,
+		refreshIssuer: refreshTokenIssuer{
+			generator: refreshGen,
+			hasher:    refreshHasher,
+			lifetimes: sessionLifetimes,
+		},
+		tokenSigner: signer,
+		audit:       auditSink,
 	}
 }
 
 func (svc *RefreshService) Refresh(
 	ctx context.Context,
 	rawRefreshToken string,
-) (newAccessToken string, newRefreshToken string, err error) {
+) (tokens IssuedTokens, err error) {
 
 	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
 	if err != nil {
-		return "", "", err
+		return IssuedTokens{}, err
 	}
+
+	// the presented token, once found
+	var stored refresh.RefreshToken
+
 	defer func() {
-		finish(err)
+		// reuse is rejected, but the session revocation must still be committed
+		if errors.Is(err, errs.ErrRefreshTokenReused) {
+			finish(nil)
+		} else {
+			finish(err)
+		}
+		recordAudit(ctx, svc.audit, refreshEvents(stored, err)...)
 	}()
 
 	refreshStore := svc.refreshTokenStore(exec)
@@ -63,72 +79,98 @@ func (svc *RefreshService) Refresh(
 	// 1. Hash incoming refresh token
 	hash, err := svc.refreshTokenHasher.Hash(rawRefreshToken)
 	if err != nil {
-		return "", "", errs.ErrInvalidRefreshToken
+		return IssuedTokens{}, errs.ErrInvalidRefreshToken
 	}
 
 	// 2. Load refresh token record
-	stored, err := refreshStore.GetByHash(ctx, hash)
+	stored, err = refreshStore.GetByHash(ctx, hash)
 	if err != nil {
-		return "", "", errs.ErrInvalidRefreshToken
+		return IssuedTokens{}, errs.ErrInvalidRefreshToken
 	}
 
 	// 3. Validate refresh token
 	if stored.RevokedAt != nil {
-		return "", "", errs.ErrInvalidRefreshToken
+		// revoked token presented again: either the client or an attacker
+		// holds a copy that was already rotated, so end the whole session
+		return IssuedTokens{}, revokeReusedSession(ctx, refreshStore, stored)
 	}
 
-	if time.Now().After(stored.ExpiresAt) {
-		return "", "", errs.ErrInvalidRefreshToken
+	// idle timeout, and the session's absolute end: a token never outlives
+	// it, checked anyway so a longer token cannot extend the session
+	now := time.Now()
+	if now.After(stored.ExpiresAt) || now.After(stored.SessionExpiresAt) {
+		return IssuedTokens{}, errs.ErrInvalidRefreshToken
 	}
 
 	// 4. Revoke old refresh token (rotation)
-	if err := refreshStore.Revoke(ctx, stored.ID); err != nil {
-		return "", "", err
+	err = refreshStore.Revoke(ctx, stored.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		// another request rotated the same token since it was loaded:
+		// the very race of two copies that reuse detection is for
+		return IssuedTokens{}, revokeReusedSession(ctx, refreshStore, stored)
+	}
+	if err != nil {
+		return IssuedTokens{}, err
 	}
 
 	// 5. Load user + membership
 	userStore := svc.userStoreProvider(exec)
 	user, err := userStore.GetById(ctx, stored.UserID)
 	if err != nil {
-		return "", "", err
+		return IssuedTokens{}, err
 	}
 
 	membershipStore := svc.membershipProvider(exec)
 	membership, err := membershipStore.GetByUserID(ctx, user.ID)
 	if err != nil {
-		return "", "", err
+		return IssuedTokens{}, err
 	}
 
 	// 6. Issue new access token
-	accessToken, err := svc.tokenSigner.GenerateSignedAccessToken(user, membership)
+	// the session keeps the authentication methods of its login
+	tokens.AccessToken, err = svc.tokenSigner.GenerateSignedAccessToken(user, membership, stored.AMR, stored.SessionID)
 	if err != nil {
-		return "", "", err
+		return IssuedTokens{}, err
 	}
 
 	// 7. Generate + store new refresh token
-	refreshToken, err := svc.refreshTokenGen.Generate()
+	tokens.RefreshToken, tokens.RefreshExpiresAt, err = svc.refreshIssuer.rotate(ctx, refreshStore, stored)
 	if err != nil {
-		return "", "", err
+		return IssuedTokens{}, err
 	}
 
-	// hash new refresh token
-	newHash, err := svc.refreshTokenHasher.Hash(refreshToken)
-	if err != nil {
-		return "", "", err
+	return tokens, nil
+}
+
+// ends the session of a token presented after it was rotated; the caller
+// commits the revocation along with ErrRefreshTokenReused
+func revokeReusedSession(
+	ctx context.Context,
+	refreshStore storage.RefreshTokenStore,
+	stored refresh.RefreshToken,
+) error {
+	if err := refreshStore.RevokeSession(ctx, stored.SessionID); err != nil {
+		return err
 	}
+	return errs.ErrRefreshTokenReused
+}
 
-	// store new refresh token in DB
-	tokenStoredInDb := refresh.RefreshToken{
-		ID:        uuid.NewString(),
-		UserID:    user.ID,
-		TokenHash: newHash,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(svc.refreshTokenTtl),
+// a rotation or a detected reuse; unknown, expired tokens and internal errors
+// are not worth an event
+func refreshEvents(token refresh.RefreshToken, err error) []audit.Event {
+	event := audit.Event{
+		Type:      audit.TokenRefreshed,
+		UserID:    token.UserID,
+		SessionID: token.SessionID,
 	}
 
-	if err := refreshStore.Create(ctx, tokenStoredInDb); err != nil {
-		return "", "", err
+	switch {
+	case err == nil:
+	case errors.Is(err, errs.ErrRefreshTokenReused):
+		event.Type = audit.RefreshTokenReused
+	default:
+		return nil
 	}
 
-	return accessToken, refreshToken, nil
+	return []audit.Event{event}
 }
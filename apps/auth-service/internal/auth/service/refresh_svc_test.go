@@ -3,13 +3,17 @@ package service_test
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/clientinfo"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
 )
 
 func TestRefreshService_Success(test *testing.T) {
@@ -17,9 +21,15 @@ func TestRefreshService_Success(test *testing.T) {
 
 	refreshStore := &fakeRefreshTokenStore{
 		token: refresh.RefreshToken{
-			ID:        "old-id",
-			UserID:    "user-1",
-			ExpiresAt: now.Add(time.Hour),
+			ID:               "old-id",
+			UserID:           "user-1",
+			SessionID:        "session-1",
+			ExpiresAt:        now.Add(time.Hour),
+			SessionExpiresAt: now.Add(7 * 24 * time.Hour),
+			UserAgent:        "Mozilla/5.0 Firefox/128.0",
+			CreatedIP:        "203.0.113.7",
+			DeviceName:       "Laptop",
+			LastUsedIP:       "203.0.113.7",
 		},
 	}
 
@@ -35,18 +45,21 @@ func TestRefreshService_Success(test *testing.T) {
 		&fakeRefreshTokenHasher{hash: "hash"},
 		&fakeRefreshTokenGenerator{token: "new-refresh"},
 		&fakeSigner{token: "new-access"},
-		15*time.Minute,
+		service.DefaultSessionLifetimes,
+		&fakeAuditSink{},
 	)
 
-	access, refresh, err := svc.Refresh(context.Background(), "raw-token")
+	ctx := clientinfo.NewContext(context.Background(), clientinfo.Info{IP: "198.51.100.1"})
+
+	tokens, err := svc.Refresh(ctx, "raw-token")
 	if err != nil {
 		test.Fatalf("unexpected error: %v", err)
 	}
 
-	if access != "new-access" {
+	if tokens.AccessToken != "new-access" {
 		test.Fatalf("unexpected access token")
 	}
-	if refresh != "new-refresh" {
+	if tokens.RefreshToken != "new-refresh" {
 		test.Fatalf("unexpected refresh token")
 	}
 	if !refreshStore.revokeCalled {
@@ -55,6 +68,26 @@ func TestRefreshService_Success(test *testing.T) {
 	if !refreshStore.createCalled {
 		test.Fatalf("expected create to be called")
 	}
+
+	// rotated token stays in the session chain and points to its parent
+	if refreshStore.created.SessionID != "session-1" {
+		test.Fatalf("expected session-1, got %q", refreshStore.created.SessionID)
+	}
+	if refreshStore.created.ParentID == nil || *refreshStore.created.ParentID != "old-id" {
+		test.Fatalf("expected parent old-id, got %v", refreshStore.created.ParentID)
+	}
+	// the device stays, the last use moves to the client of the refresh
+	created := refreshStore.created
+	if created.UserAgent != "Mozilla/5.0 Firefox/128.0" || created.DeviceName != "Laptop" || created.CreatedIP != "203.0.113.7" {
+		test.Fatalf("expected the session's client to be copied, got %+v", created)
+	}
+	if created.LastUsedIP != "198.51.100.1" || created.LastUsedAt.IsZero() {
+		test.Fatalf("expected the refresh as last use, got %+v", created)
+	}
+	// for the Max-Age of the refresh cookie
+	if !tokens.RefreshExpiresAt.Equal(created.ExpiresAt) {
+		test.Fatalf("expected the expiry of the new token, got %v", tokens.RefreshExpiresAt)
+	}
 }
 
 func TestRefreshService_ExpiredToken(test *testing.T) {
@@ -74,15 +107,92 @@ func TestRefreshService_ExpiredToken(test *testing.T) {
 		&fakeRefreshTokenHasher{hash: "hash"},
 		&fakeRefreshTokenGenerator{},
 		&fakeSigner{},
-		15*time.Minute,
+		service.DefaultSessionLifetimes,
+		&fakeAuditSink{},
 	)
 
-	_, _, err := svc.Refresh(context.Background(), "raw-token")
+	_, err := svc.Refresh(context.Background(), "raw-token")
 	if !errors.Is(err, errs.ErrInvalidRefreshToken) {
 		test.Fatalf("expected invalid refresh token error")
 	}
 }
 
+func TestRefreshService_SessionLifetimeOver(test *testing.T) {
+	refreshStore := &fakeRefreshTokenStore{
+		token: refresh.RefreshToken{
+			ID:               "id",
+			UserID:           "user",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			SessionExpiresAt: time.Now().Add(-time.Minute),
+		},
+	}
+
+	svc := service.NewRefreshService(
+		&fakeDB{},
+		refreshStoreProvider(refreshStore),
+		userStoreProvider(&fakeUserStore{}),
+		membershipStoreProvider(&fakeMembershipStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{},
+		&fakeSigner{},
+		service.DefaultSessionLifetimes,
+		&fakeAuditSink{},
+	)
+
+	_, err := svc.Refresh(context.Background(), "raw-token")
+	if !errors.Is(err, errs.ErrInvalidRefreshToken) {
+		test.Fatalf("expected invalid refresh token error, got %v", err)
+	}
+	if refreshStore.createCalled {
+		test.Fatal("expected no token to be issued")
+	}
+}
+
+// the idle timeout slides with every refresh, but not past the session's end
+func TestRefreshService_CappedAtSessionLifetime(test *testing.T) {
+	now := time.Now()
+	sessionExpiresAt := now.Add(2 * time.Hour)
+
+	refreshStore := &fakeRefreshTokenStore{
+		token: refresh.RefreshToken{
+			ID:               "old-id",
+			UserID:           "user-1",
+			SessionID:        "session-1",
+			ExpiresAt:        now.Add(time.Hour),
+			SessionExpiresAt: sessionExpiresAt,
+			RememberMe:       true,
+		},
+	}
+
+	svc := service.NewRefreshService(
+		&fakeDB{},
+		refreshStoreProvider(refreshStore),
+		userStoreProvider(&fakeUserStore{
+			user: domain.User{ID: "user-1"},
+		}),
+		membershipStoreProvider(&fakeMembershipStore{
+			membership: domain.Membership{UserID: "user-1"},
+		}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: "new-refresh"},
+		&fakeSigner{token: "new-access"},
+		service.DefaultSessionLifetimes,
+		&fakeAuditSink{},
+	)
+
+	if _, err := svc.Refresh(context.Background(), "raw-token"); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	created := refreshStore.created
+	if !created.ExpiresAt.Equal(sessionExpiresAt) || !created.SessionExpiresAt.Equal(sessionExpiresAt) {
+		test.Fatalf("expected expiry capped at %v, got %+v", sessionExpiresAt, created)
+	}
+	if !created.RememberMe {
+		test.Fatal("expected the remember-me profile to be kept")
+	}
+}
+
 func TestRefreshService_RevokedToken(test *testing.T) {
 	now := time.Now()
 
@@ -92,8 +202,10 @@ func TestRefreshService_RevokedToken(test *testing.T) {
 			UserID:    "user",
 			ExpiresAt: now.Add(time.Hour),
 			RevokedAt: &now,
+			SessionID: "session",
 		},
 	}
+	auditSink := &fakeAuditSink{}
 
 	svc := service.NewRefreshService(
 		&fakeDB{},
@@ -103,21 +215,174 @@ func TestRefreshService_RevokedToken(test *testing.T) {
 		&fakeRefreshTokenHasher{hash: "hash"},
 		&fakeRefreshTokenGenerator{},
 		&fakeSigner{},
-		15*time.Minute,
+		service.DefaultSessionLifetimes,
+		auditSink,
 	)
 
-	_, _, err := svc.Refresh(context.Background(), "raw-token")
+	_, err := svc.Refresh(context.Background(), "raw-token")
 	if !errors.Is(err, errs.ErrInvalidRefreshToken) {
 		test.Fatalf("expected invalid refresh token error")
 	}
+	if !errors.Is(err, errs.ErrRefreshTokenReused) {
+		test.Fatalf("expected reuse to be detected")
+	}
+	if !refreshStore.revokeSessionCalled {
+		test.Fatalf("expected session to be revoked")
+	}
+	if refreshStore.createCalled {
+		test.Fatalf("reused token must not be rotated")
+	}
+	if len(auditSink.events) != 1 ||
+		auditSink.events[0].Type != audit.RefreshTokenReused ||
+		auditSink.events[0].SessionID != "session" {
+		test.Fatalf("expected the reuse to be audited, got %+v", auditSink.events)
+	}
 }
 
-func TestRefreshService_RevokeFailure(test *testing.T) {
+// a refresh token store shared by concurrent requests: every request loads
+// the token before any of them rotates it, then only one rotation wins
+type racingRefreshTokenStore struct {
+	fakeRefreshTokenStore
+	loaded  sync.WaitGroup
+	mutex   sync.Mutex
+	rotated bool
+}
+
+func (refreshStore *racingRefreshTokenStore) GetByHash(ctx context.Context, hash string) (refresh.RefreshToken, error) {
+	refreshStore.loaded.Done()
+	refreshStore.loaded.Wait()
+	return refreshStore.token, nil
+}
+
+func (refreshStore *racingRefreshTokenStore) Revoke(ctx context.Context, id string) error {
+	refreshStore.mutex.Lock()
+	defer refreshStore.mutex.Unlock()
+
+	// like the stores: only a token not yet revoked is revoked
+	if refreshStore.rotated {
+		return errs.ErrNotFound
+	}
+	refreshStore.rotated = true
+	return nil
+}
+
+func (refreshStore *racingRefreshTokenStore) RevokeSession(ctx context.Context, sessionID string) error {
+	refreshStore.mutex.Lock()
+	defer refreshStore.mutex.Unlock()
+	return refreshStore.fakeRefreshTokenStore.RevokeSession(ctx, sessionID)
+}
+
+func TestRefreshService_ConcurrentRotation(test *testing.T) {
+	now := time.Now()
+
+	refreshStore := &racingRefreshTokenStore{
+		fakeRefreshTokenStore: fakeRefreshTokenStore{
+			token: refresh.RefreshToken{
+				ID:               "id",
+				UserID:           "user",
+				SessionID:        "session",
+				ExpiresAt:        now.Add(time.Hour),
+				SessionExpiresAt: now.Add(24 * time.Hour),
+			},
+		},
+	}
+	refreshStore.loaded.Add(2)
+
+	db := &fakeDB{}
+	auditSink := &fakeAuditSink{}
+
+	svc := service.NewRefreshService(
+		db,
+		func(exec storage.SQLExecutor) storage.RefreshTokenStore { return refreshStore },
+		userStoreProvider(&fakeUserStore{user: User{ID: "user"}}),
+		membershipStoreProvider(&fakeMembershipStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{token: "new-token"},
+		&fakeSigner{token: "access"},
+		service.DefaultSessionLifetimes,
+		auditSink,
+	)
+
+	// the same token presented twice at once, e.g. by the client and a thief
+	results := make([]error, 2)
+	var requests sync.WaitGroup
+	for i := range results {
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+			_, results[i] = svc.Refresh(context.Background(), "raw-token")
+		}()
+	}
+	requests.Wait()
+
+	var rotated, reused int
+	for _, err := range results {
+		switch {
+		case err == nil:
+			rotated++
+		case errors.Is(err, errs.ErrRefreshTokenReused):
+			reused++
+		default:
+			test.Fatalf("expected a rotation and a detected reuse, got %v", err)
+		}
+	}
+	if rotated != 1 || reused != 1 {
+		test.Fatalf("expected one rotation and one reuse, got %v", results)
+	}
+
+	if !refreshStore.revokeSessionCalled {
+		test.Fatalf("expected the session to be revoked")
+	}
+	// the revocation is committed, not rolled back
+	for _, finished := range db.finished {
+		if finished != nil {
+			test.Fatalf("expected both transactions to commit, got %v", db.finished)
+		}
+	}
+	if len(auditSink.events) != 2 {
+		test.Fatalf("expected the rotation and the reuse to be audited, got %v", auditSink.types())
+	}
+}
+
+func TestRefreshService_ReusedToken_RevokeSessionFailure(test *testing.T) {
+	now := time.Now()
+
 	refreshStore := &fakeRefreshTokenStore{
 		token: refresh.RefreshToken{
 			ID:        "id",
 			UserID:    "user",
-			ExpiresAt: time.Now().Add(time.Hour),
+			SessionID: "session",
+			ExpiresAt: now.Add(time.Hour),
+			RevokedAt: &now,
+		},
+		revokeSessionErr: errors.New("db error"),
+	}
+
+	svc := service.NewRefreshService(
+		&fakeDB{},
+		refreshStoreProvider(refreshStore),
+		userStoreProvider(&fakeUserStore{}),
+		membershipStoreProvider(&fakeMembershipStore{}),
+		&fakeRefreshTokenHasher{hash: "hash"},
+		&fakeRefreshTokenGenerator{},
+		&fakeSigner{},
+		service.DefaultSessionLifetimes,
+		&fakeAuditSink{},
+	)
+
+	_, err := svc.Refresh(context.Background(), "raw-token")
+	if err == nil || errors.Is(err, errs.ErrInvalidRefreshToken) {
+		test.Fatalf("expected infrastructure error, got %v", err)
+	}
+}
+
+func TestRefreshService_RevokeFailure(test *testing.T) {
+	refreshStore := &fakeRefreshTokenStore{
+		token: refresh.RefreshToken{
+			ID:               "id",
+			UserID:           "user",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			SessionExpiresAt: time.Now().Add(24 * time.Hour),
 		},
 		revokeErr: errors.New("db error"),
 	}
@@ -130,10 +395,11 @@ func TestRefreshService_RevokeFailure(test *testing.T) {
 		&fakeRefreshTokenHasher{hash: "hash"},
 		&fakeRefreshTokenGenerator{},
 		&fakeSigner{},
-		15*time.Minute,
+		service.DefaultSessionLifetimes,
+		&fakeAuditSink{},
 	)
 
-	_, _, err := svc.Refresh(context.Background(), "raw-token")
+	_, err := svc.Refresh(context.Background(), "raw-token")
 	if err == nil {
 		test.Fatalf("expected error")
 	}
@@ -142,9 +408,10 @@ func TestRefreshService_RevokeFailure(test *testing.T) {
 func TestRefreshService_SignerFailure(test *testing.T) {
 	refreshStore := &fakeRefreshTokenStore{
 		token: refresh.RefreshToken{
-			ID:        "id",
-			UserID:    "user",
-			ExpiresAt: time.Now().Add(time.Hour),
+			ID:               "id",
+			UserID:           "user",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			SessionExpiresAt: time.Now().Add(24 * time.Hour),
 		},
 	}
 
@@ -156,10 +423,11 @@ func TestRefreshService_SignerFailure(test *testing.T) {
 		&fakeRefreshTokenHasher{hash: "hash"},
 		&fakeRefreshTokenGenerator{token: "new"},
 		&fakeSigner{err: errors.New("sign fail")},
-		15*time.Minute,
+		service.DefaultSessionLifetimes,
+		&fakeAuditSink{},
 	)
 
-	_, _, err := svc.Refresh(context.Background(), "raw-token")
+	_, err := svc.Refresh(context.Background(), "raw-token")
 	if err == nil {
 		test.Fatalf("expected error")
 	}
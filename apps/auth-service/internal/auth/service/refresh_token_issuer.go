@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/clientinfo"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// how long a session lasts: IdleTimeout without a refresh (every refresh
+// starts it over) and MaxLifetime from the login, however often it is refreshed
+type SessionLifetime struct {
+	IdleTimeout time.Duration
+	MaxLifetime time.Duration
+}
+
+// a login with "remember me" gets Long, any other Short
+type SessionLifetimes struct {
+	Short SessionLifetime
+	Long  SessionLifetime
+}
+
+var DefaultSessionLifetimes = SessionLifetimes{
+	Short: SessionLifetime{
+		IdleTimeout: 24 * time.Hour,
+		MaxLifetime: 7 * 24 * time.Hour,
+	},
+	Long: SessionLifetime{
+		IdleTimeout: 30 * 24 * time.Hour,
+		MaxLifetime: 90 * 24 * time.Hour,
+	},
+}
+
+func (lifetimes SessionLifetimes) of(rememberMe bool) SessionLifetime {
+	if rememberMe {
+		return lifetimes.Long
+	}
+	return lifetimes.Short
+}
+
+// generates, hashes and stores a new refresh token;
+// shared by login (first token of a session) and refresh (rotation)
+type refreshTokenIssuer struct {
+	generator refresh.RefreshTokenGenerator
+	hasher    refresh.RefreshTokenHasher
+	lifetimes SessionLifetimes
+}
+
+// issues the first refresh token of a new session (token chain);
+// amr lists the authentication methods of the login, rememberMe picks the
+// lifetime profile. The session remembers the client of the login (clientinfo in ctx)
+func (issuer refreshTokenIssuer) startSession(
+	ctx context.Context,
+	store storage.RefreshTokenStore,
+	userID string,
+	sessionID string,
+	amr []string,
+	rememberMe bool,
+) (string, time.Time, error) {
+	client := clientinfo.FromContext(ctx)
+	now := time.Now()
+	return issuer.issue(ctx, store, refresh.RefreshToken{
+		UserID:           userID,
+		SessionID:        sessionID,
+		AMR:              amr,
+		SessionCreatedAt: now,
+		SessionExpiresAt: now.Add(issuer.lifetimes.of(rememberMe).MaxLifetime),
+		RememberMe:       rememberMe,
+		UserAgent:        client.UserAgent,
+		CreatedIP:        client.IP,
+		DeviceName:       client.DeviceName,
+		LastUsedIP:       client.IP,
+	})
+}
+
+// issues the successor of parent within the same session;
+// the client of the refresh (clientinfo in ctx) becomes the last used IP
+func (issuer refreshTokenIssuer) rotate(
+	ctx context.Context,
+	store storage.RefreshTokenStore,
+	parent refresh.RefreshToken,
+) (string, time.Time, error) {
+	return issuer.issue(ctx, store, refresh.RefreshToken{
+		UserID:           parent.UserID,
+		SessionID:        parent.SessionID,
+		ParentID:         &parent.ID,
+		AMR:              parent.AMR,
+		SessionCreatedAt: parent.SessionCreatedAt,
+		SessionExpiresAt: parent.SessionExpiresAt,
+		RememberMe:       parent.RememberMe,
+		UserAgent:        parent.UserAgent,
+		CreatedIP:        parent.CreatedIP,
+		DeviceName:       parent.DeviceName,
+		LastUsedIP:       clientinfo.FromContext(ctx).IP,
+	})
+}
+
+// returns the raw token for the client and its expiry, only the hash is persisted
+func (issuer refreshTokenIssuer) issue(
+	ctx context.Context,
+	store storage.RefreshTokenStore,
+	token refresh.RefreshToken,
+) (string, time.Time, error) {
+
+	rawToken, err := issuer.generator.Generate()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	hash, err := issuer.hasher.Hash(rawToken)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	token.ID = uuid.NewString()
+	token.TokenHash = hash
+	token.CreatedAt = now
+	token.LastUsedAt = now
+	// idle timeout, but never past the session's end
+	token.ExpiresAt = now.Add(issuer.lifetimes.of(token.RememberMe).IdleTimeout)
+	if token.ExpiresAt.After(token.SessionExpiresAt) {
+		token.ExpiresAt = token.SessionExpiresAt
+	}
+
+	if err := store.Create(ctx, token); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return rawToken, token.ExpiresAt, nil
+}
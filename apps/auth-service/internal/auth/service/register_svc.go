@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"log"
 	"time"
 
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
@@ -14,6 +16,11 @@ import (
 type FamilyStoreProvider = storage.FamilyStoreProvider
 type MembershipStoreProvider = storage.MembershipStoreProvider
 
+// implemented by EmailVerificationService
+type VerificationEmailSender interface {
+	SendVerificationEmail(ctx context.Context, user User) error
+}
+
 type RegistrationService struct {
 	db   TransactionManager
 	hash password.PasswordHasher
@@ -21,6 +28,9 @@ type RegistrationService struct {
 	userStoreProvider   UserStoreProvider
 	familyStoreProvider FamilyStoreProvider
 	memberStoreProvider MembershipStoreProvider
+	verification        VerificationEmailSender
+	policy              PasswordPolicy
+	audit               AuditSink
 }
 
 func NewRegistrationService(
@@ -28,26 +38,59 @@ func NewRegistrationService(
 	hash password.PasswordHasher,
 	userStore UserStoreProvider,
 	familyStore FamilyStoreProvider,
-	memberStore MembershipStoreProvider) *RegistrationService {
+	memberStore MembershipStoreProvider,
+	verification VerificationEmailSender,
+	policy PasswordPolicy,
+	auditSink AuditSink) *RegistrationService {
 	return &RegistrationService{
 		db:                  db,
 		hash:                hash,
 		userStoreProvider:   userStore,
 		familyStoreProvider: familyStore,
 		memberStoreProvider: memberStore,
+		verification:        verification,
+		policy:              policy,
+		audit:               auditSink,
 	}
 }
 
+// creates the account, then emails the verification link
 func (svc *RegistrationService) Register(
 	ctx context.Context,
 	email string,
 	password string,
 	familyName string,
 ) error {
+	if err := svc.policy.Validate(password, email, familyName); err != nil {
+		return err
+	}
+
+	user, err := svc.createAccount(ctx, email, password, familyName)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, svc.audit, audit.Event{Type: audit.UserRegistered, UserID: user.ID})
+
+	// sent after commit: the account exists either way,
+	// and a lost email can be requested again
+	if err := svc.verification.SendVerificationEmail(ctx, user); err != nil {
+		log.Printf("failed to send verification email (user=%s): %v", user.ID, err)
+	}
+
+	return nil
+}
+
+func (svc *RegistrationService) createAccount(
+	ctx context.Context,
+	email string,
+	password string,
+	familyName string,
+) (user User, err error) {
 	// start a transaction; here the decision is made to use a transaction
 	exec, finish, err := svc.db.BeginTransaction(ctx, false)
 	if err != nil {
-		return err
+		return User{}, err
 	}
 	// commit or rollback at the end, depending on error presence
 	defer func() {
@@ -57,10 +100,10 @@ func (svc *RegistrationService) Register(
 	//USER creation with hashed password
 	hash, err := svc.hash.Hash(password)
 	if err != nil {
-		return err
+		return User{}, err
 	}
 
-	user := domain.User{
+	user = domain.User{
 		ID:           uuid.NewString(),
 		Email:        email,
 		PasswordHash: hash,
@@ -72,7 +115,7 @@ func (svc *RegistrationService) Register(
 
 	// create all entities within the transaction
 	if err = userStore.Create(ctx, user); err != nil {
-		return err
+		return User{}, err
 	}
 
 	//FAMILY
@@ -83,7 +126,7 @@ func (svc *RegistrationService) Register(
 	}
 	familyStore := svc.familyStoreProvider(exec)
 	if err = familyStore.Create(ctx, family); err != nil {
-		return err
+		return User{}, err
 	}
 
 	// MEMBERSHIP
@@ -96,8 +139,8 @@ func (svc *RegistrationService) Register(
 
 	memberStore := svc.memberStoreProvider(exec)
 	if err = memberStore.Create(ctx, membership); err != nil {
-		return err
+		return User{}, err
 	}
 
-	return nil
+	return user, nil
 }
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
@@ -15,6 +17,8 @@ func TestRegistrationService_Success(test *testing.T) {
 	memberStore := &fakeMembershipStore{}
 	familyStore := &fakeFamilyStore{}
 	hasher := &fakeHasher{}
+	verification := &fakeVerificationSender{}
+	auditSink := &fakeAuditSink{}
 
 	regSvc := service.NewRegistrationService(
 		&fakeDB{
@@ -30,6 +34,9 @@ func TestRegistrationService_Success(test *testing.T) {
 		func(exec storage.SQLExecutor) MembershipStore {
 			return memberStore
 		},
+		verification,
+		&fakePasswordPolicy{},
+		auditSink,
 	)
 
 	err := regSvc.Register(context.Background(), "a@b.com", "hash", "FamilyName")
@@ -37,6 +44,10 @@ func TestRegistrationService_Success(test *testing.T) {
 		test.Fatalf("unexpected error")
 	}
 
+	if types := auditSink.types(); len(types) != 1 || types[0] != audit.UserRegistered {
+		test.Fatalf("expected the registration to be audited, got %v", types)
+	}
+
 	if !hasher.called {
 		test.Fatalf("password was not hashed")
 	}
@@ -52,6 +63,33 @@ func TestRegistrationService_Success(test *testing.T) {
 	if !memberStore.called {
 		test.Fatalf("membership was not created")
 	}
+
+	if verification.sentTo != "a@b.com" {
+		test.Fatalf("verification email was not sent")
+	}
+}
+
+func TestRegistrationService_VerificationEmailFailure(test *testing.T) {
+	regSvc := service.NewRegistrationService(
+		&fakeDB{
+			exec: &fakeSQLExecutor{},
+		}, // db unused in unit test
+		&fakeHasher{},
+		userStoreProvider(&fakeUserStore{}),
+		func(exec storage.SQLExecutor) FamilyStore {
+			return &fakeFamilyStore{}
+		},
+		membershipStoreProvider(&fakeMembershipStore{}),
+		&fakeVerificationSender{err: errors.New("smtp relay down")},
+		&fakePasswordPolicy{},
+		&fakeAuditSink{},
+	)
+
+	// the account exists; the link can be requested again
+	err := regSvc.Register(context.Background(), "a@b.com", "hash", "FamilyName")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
 }
 
 func TestRegistrationService_UserExists(test *testing.T) {
@@ -71,6 +109,9 @@ func TestRegistrationService_UserExists(test *testing.T) {
 		func(exec storage.SQLExecutor) MembershipStore {
 			return &fakeMembershipStore{}
 		},
+		&fakeVerificationSender{},
+		&fakePasswordPolicy{},
+		&fakeAuditSink{},
 	)
 
 	err := regSvc.Register(context.Background(), "a@b.com", "hash", "FamilyName")
@@ -96,6 +137,9 @@ func TestRegistrationService_FamilyExists(test *testing.T) {
 		func(exec storage.SQLExecutor) MembershipStore {
 			return &fakeMembershipStore{}
 		},
+		&fakeVerificationSender{},
+		&fakePasswordPolicy{},
+		&fakeAuditSink{},
 	)
 
 	err := regSvc.Register(context.Background(), "a@b.com", "hash", "FamilyName")
@@ -105,6 +149,7 @@ func TestRegistrationService_FamilyExists(test *testing.T) {
 }
 
 func TestRegistrationService_MembershipExists(test *testing.T) {
+	verification := &fakeVerificationSender{}
 	regSvc := service.NewRegistrationService(
 		&fakeDB{
 			exec: &fakeSQLExecutor{},
@@ -121,10 +166,50 @@ func TestRegistrationService_MembershipExists(test *testing.T) {
 				err: errs.ErrAlreadyExists,
 			}
 		},
+		verification,
+		&fakePasswordPolicy{},
+		&fakeAuditSink{},
 	)
 
 	err := regSvc.Register(context.Background(), "a@b.com", "hash", "FamilyName")
 	if !errors.Is(err, errs.ErrAlreadyExists) {
 		test.Fatalf("expected %v", errs.ErrAlreadyExists)
 	}
+	if verification.sentTo != "" {
+		test.Fatalf("no verification email expected for a failed registration")
+	}
+}
+
+func TestRegistrationService_WeakPassword(test *testing.T) {
+	userStore := &fakeUserStore{}
+	hasher := &fakeHasher{}
+	policy := &fakePasswordPolicy{err: &password.PolicyError{
+		Violations: []password.Violation{{Rule: password.RuleMinLength}},
+	}}
+
+	regSvc := service.NewRegistrationService(
+		&fakeDB{
+			exec: &fakeSQLExecutor{},
+		}, // db unused in unit test
+		hasher,
+		userStoreProvider(userStore),
+		func(exec storage.SQLExecutor) FamilyStore {
+			return &fakeFamilyStore{}
+		},
+		membershipStoreProvider(&fakeMembershipStore{}),
+		&fakeVerificationSender{},
+		policy,
+		&fakeAuditSink{},
+	)
+
+	err := regSvc.Register(context.Background(), "a@b.com", "short", "FamilyName")
+	if !errors.Is(err, errs.ErrWeakPassword) {
+		test.Fatalf("expected %v, got %v", errs.ErrWeakPassword, err)
+	}
+	if hasher.called || userStore.called {
+		test.Fatalf("nothing should be hashed or stored for a weak password")
+	}
+	if len(policy.gotInputs) != 2 || policy.gotInputs[0] != "a@b.com" || policy.gotInputs[1] != "FamilyName" {
+		test.Fatalf("expected email and family name as personal info, got %v", policy.gotInputs)
+	}
 }
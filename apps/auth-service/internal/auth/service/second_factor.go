@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/mfa"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// implemented by mfa.SecretBox
+type SecretBox interface {
+	Seal(plaintext string) (string, error)
+	Open(sealed string) (string, error)
+}
+
+// methods of a password login completed with a recovery code
+var recoveryCodeAMR = []string{domain.AMRPassword, domain.AMRMFA}
+
+// checks the second factor of a login: a TOTP code or, for a lost
+// authenticator, a recovery code; shared by password login and the
+// authorization code flow
+type secondFactor struct {
+	totp          storage.TOTPStoreProvider
+	recoveryCodes storage.RecoveryCodeStoreProvider
+	refreshTokens storage.RefreshTokenStoreProvider
+	secrets       SecretBox
+	codeHasher    refresh.RefreshTokenHasher
+}
+
+// only a confirmed enrollment turns the second factor on
+func (factor secondFactor) enabled(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	userID string,
+) (bool, error) {
+
+	credential, err := factor.totp(exec).GetByUserID(ctx, userID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return credential.ConfirmedAt != nil, nil
+}
+
+// returns the authentication methods of the login (amr)
+func (factor secondFactor) verify(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	userID string,
+	code string,
+) ([]string, error) {
+
+	if mfa.IsRecoveryCode(code) {
+		if err := factor.useRecoveryCode(ctx, exec, userID, code); err != nil {
+			return nil, err
+		}
+		return recoveryCodeAMR, nil
+	}
+
+	if err := factor.verifyTOTP(ctx, exec, userID, code); err != nil {
+		return nil, err
+	}
+	return totpAMR, nil
+}
+
+// accepts each code once: the matched period is stored as the last used one
+func (factor secondFactor) verifyTOTP(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	userID string,
+	code string,
+) error {
+
+	store := factor.totp(exec)
+
+	credential, err := store.GetByUserID(ctx, userID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrInvalidOTP
+	}
+	if err != nil {
+		return err
+	}
+	if credential.ConfirmedAt == nil {
+		return errs.ErrInvalidOTP
+	}
+
+	secret, err := factor.secrets.Open(credential.SecretEncrypted)
+	if err != nil {
+		return err
+	}
+
+	step, ok := mfa.ValidateTOTP(secret, code, time.Now(), credential.LastUsedStep)
+	if !ok {
+		return errs.ErrInvalidOTP
+	}
+
+	err = store.UpdateLastUsedStep(ctx, userID, step)
+	if errors.Is(err, errs.ErrNotFound) {
+		// the same code was used concurrently
+		return errs.ErrInvalidOTP
+	}
+	return err
+}
+
+// spends a recovery code. A lost authenticator may mean a lost device,
+// so every existing session of the user is ended.
+func (factor secondFactor) useRecoveryCode(
+	ctx context.Context,
+	exec storage.SQLExecutor,
+	userID string,
+	code string,
+) error {
+
+	hash, err := factor.codeHasher.Hash(mfa.NormalizeRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+
+	store := factor.recoveryCodes(exec)
+
+	stored, err := store.GetByHash(ctx, userID, hash)
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrInvalidOTP
+	}
+	if err != nil {
+		return err
+	}
+
+	err = store.MarkUsed(ctx, stored.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		// already used
+		return errs.ErrInvalidOTP
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := factor.refreshTokens(exec).RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+
+	log.Printf(
+		"security event: recovery code used (user=%s code=%s), all sessions revoked",
+		userID,
+		stored.ID,
+	)
+	return nil
+}
+
+// generates a new batch of recovery codes, replacing all previous ones;
+// only hashes are stored, the raw codes are shown to the user once
+func issueRecoveryCodes(
+	ctx context.Context,
+	store storage.RecoveryCodeStore,
+	hasher refresh.RefreshTokenHasher,
+	userID string,
+) ([]string, error) {
+
+	codes, err := mfa.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	stored := make([]domain.RecoveryCode, len(codes))
+	for i, code := range codes {
+		hash, err := hasher.Hash(mfa.NormalizeRecoveryCode(code))
+		if err != nil {
+			return nil, err
+		}
+		stored[i] = domain.RecoveryCode{
+			ID:        uuid.NewString(),
+			UserID:    userID,
+			CodeHash:  hash,
+			CreatedAt: now,
+		}
+	}
+
+	if err := store.ReplaceAll(ctx, userID, stored); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type Session = domain.Session
+
+// label of sessions that neither named their device nor sent a user agent
+const unknownDevice = "Unknown device"
+
+// user agent markers, checked in order: Edge and Opera also claim to be
+// Chrome and Safari, Chrome claims to be Safari
+var (
+	userAgentBrowsers = []struct{ marker, name string }{
+		{"Edg", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+	userAgentSystems = []struct{ marker, name string }{
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"CrOS", "ChromeOS"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// SessionService lets a logged-in user see where they are logged in
+// and end sessions, e.g. of a lost phone
+type SessionService struct {
+	transactionMgr storage.TransactionMgr
+	refreshStore   storage.RefreshTokenStoreProvider
+	accessVerifier AccessTokenVerifier
+	audit          AuditSink
+}
+
+func NewSessionService(
+	transactionMgr storage.TransactionMgr,
+	refreshStore storage.RefreshTokenStoreProvider,
+	accessVerifier AccessTokenVerifier,
+	auditSink AuditSink,
+) *SessionService {
+	return &SessionService{
+		transactionMgr: transactionMgr,
+		refreshStore:   refreshStore,
+		accessVerifier: accessVerifier,
+		audit:          auditSink,
+	}
+}
+
+// active sessions of the user the access token was issued to, newest first
+func (svc *SessionService) List(
+	ctx context.Context,
+	accessToken string,
+) (sessions []Session, err error) {
+
+	claims, err := svc.accessVerifier.Verify(accessToken)
+	if err != nil {
+		return nil, errs.ErrInvalidAccessToken
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	tokens, err := svc.refreshStore(exec).ListByUser(ctx, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessions = []Session{}
+	for _, token := range tokens {
+		// nothing left to refresh with, the session is over
+		if !now.Before(token.ExpiresAt) {
+			continue
+		}
+		sessions = append(sessions, sessionOf(token, claims.SessionID))
+	}
+
+	return sessions, nil
+}
+
+// ends one session of the caller, the current one included;
+// ErrNotFound if the user has no such active session
+func (svc *SessionService) Revoke(
+	ctx context.Context,
+	accessToken string,
+	sessionID string,
+) (err error) {
+
+	claims, err := svc.accessVerifier.Verify(accessToken)
+	if err != nil {
+		return errs.ErrInvalidAccessToken
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		finish(err)
+		if err == nil {
+			recordAudit(ctx, svc.audit, audit.Event{
+				Type:      audit.SessionRevoked,
+				UserID:    claims.Subject,
+				SessionID: sessionID,
+				Details:   map[string]string{"by_session": claims.SessionID},
+			})
+		}
+	}()
+
+	return svc.refreshStore(exec).RevokeUserSession(ctx, claims.Subject, sessionID)
+}
+
+// ends every session of the caller except the one of the access token
+func (svc *SessionService) RevokeOthers(
+	ctx context.Context,
+	accessToken string,
+) (err error) {
+
+	claims, err := svc.accessVerifier.Verify(accessToken)
+	if err != nil {
+		return errs.ErrInvalidAccessToken
+	}
+
+	// tokens issued before they named their session; revoking "all but none"
+	// would log out the caller too, a refresh gets a token with sid
+	if claims.SessionID == "" {
+		return errs.ErrInvalidAccessToken
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		finish(err)
+		if err == nil {
+			recordAudit(ctx, svc.audit, audit.Event{
+				Type:      audit.OtherSessionsRevoked,
+				UserID:    claims.Subject,
+				SessionID: claims.SessionID,
+			})
+		}
+	}()
+
+	return svc.refreshStore(exec).RevokeOtherSessions(ctx, claims.Subject, claims.SessionID)
+}
+
+// the current token of a session was issued when it was last refreshed
+func sessionOf(token refresh.RefreshToken, currentSessionID string) Session {
+	return Session{
+		ID:          token.SessionID,
+		CreatedAt:   token.SessionCreatedAt,
+		LastUsedAt:  token.LastUsedAt,
+		ExpiresAt:   token.ExpiresAt,
+		AMR:         token.AMR,
+		DeviceLabel: deviceLabel(token.DeviceName, token.UserAgent),
+		UserAgent:   token.UserAgent,
+		CreatedIP:   token.CreatedIP,
+		LastUsedIP:  token.LastUsedIP,
+		Current:     token.SessionID == currentSessionID && currentSessionID != "",
+	}
+}
+
+// e.g. "Firefox on Windows"; a user agent that is no browser's names its
+// product ("okhttp/4.12.0")
+func deviceLabel(deviceName string, userAgent string) string {
+	if deviceName != "" {
+		return deviceName
+	}
+	if userAgent == "" {
+		return unknownDevice
+	}
+
+	var browser, system string
+	for _, candidate := range userAgentBrowsers {
+		if strings.Contains(userAgent, candidate.marker) {
+			browser = candidate.name
+			break
+		}
+	}
+	for _, candidate := range userAgentSystems {
+		if strings.Contains(userAgent, candidate.marker) {
+			system = candidate.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+
+	product, _, _ := strings.Cut(userAgent, " ")
+	return product
+}
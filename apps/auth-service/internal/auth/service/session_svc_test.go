@@ -0,0 +1,227 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+func sessionAccessTokenVerifier(sessionID string) *fakeAccessTokenVerifier {
+	return &fakeAccessTokenVerifier{
+		claims: &jwt.Claims{
+			RegisteredClaims: gojwt.RegisteredClaims{Subject: "u1"},
+			SessionID:        sessionID,
+		},
+	}
+}
+
+func TestSessionService_List(test *testing.T) {
+	now := time.Now()
+	refreshStore := &fakeRefreshTokenStore{
+		listed: []refresh.RefreshToken{
+			{
+				ID:               "t2",
+				SessionID:        "s2",
+				AMR:              []string{"pwd"},
+				ExpiresAt:        now.Add(time.Hour),
+				CreatedAt:        now.Add(-time.Minute),
+				SessionCreatedAt: now.Add(-time.Hour),
+				LastUsedAt:       now.Add(-time.Minute),
+				DeviceName:       "Kitchen tablet",
+				UserAgent:        "Mozilla/5.0 (Linux; Android 14) Chrome/126.0 Mobile Safari/537.36",
+				CreatedIP:        "203.0.113.7",
+				LastUsedIP:       "198.51.100.1",
+			},
+			{
+				ID:               "t1",
+				SessionID:        "s1",
+				AMR:              []string{"pwd", "otp", "mfa"},
+				ExpiresAt:        now.Add(time.Hour),
+				CreatedAt:        now.Add(-2 * time.Minute),
+				SessionCreatedAt: now.Add(-2 * time.Hour),
+			},
+			{
+				ID:        "t0",
+				SessionID: "expired",
+				ExpiresAt: now.Add(-time.Minute),
+				CreatedAt: now.Add(-48 * time.Hour),
+			},
+		},
+	}
+
+	svc := service.NewSessionService(
+		&fakeDB{},
+		refreshStoreProvider(refreshStore),
+		sessionAccessTokenVerifier("s1"),
+		&fakeAuditSink{},
+	)
+
+	sessions, err := svc.List(context.Background(), "access.jwt.token")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sessions) != 2 {
+		test.Fatalf("expected 2 active sessions, got %+v", sessions)
+	}
+
+	if sessions[0].ID != "s2" || sessions[0].Current {
+		test.Fatalf("unexpected first session %+v", sessions[0])
+	}
+	if !sessions[0].CreatedAt.Equal(now.Add(-time.Hour)) || !sessions[0].LastUsedAt.Equal(now.Add(-time.Minute)) {
+		test.Fatalf("expected session times from the current token, got %+v", sessions[0])
+	}
+	if sessions[0].DeviceLabel != "Kitchen tablet" || sessions[0].CreatedIP != "203.0.113.7" || sessions[0].LastUsedIP != "198.51.100.1" {
+		test.Fatalf("expected the session's client, got %+v", sessions[0])
+	}
+
+	if sessions[1].ID != "s1" || !sessions[1].Current {
+		test.Fatalf("expected the caller's session to be current, got %+v", sessions[1])
+	}
+	if sessions[1].DeviceLabel != "Unknown device" {
+		test.Fatalf("expected a placeholder label without user agent, got %q", sessions[1].DeviceLabel)
+	}
+}
+
+func TestSessionService_List_DeviceLabelFromUserAgent(test *testing.T) {
+	labels := map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0":                                                        "Firefox on Windows",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0":           "Edge on Windows",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1": "Safari on iPhone",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36":                   "Chrome on macOS",
+		"okhttp/4.12.0": "okhttp/4.12.0",
+	}
+
+	for userAgent, want := range labels {
+		svc := service.NewSessionService(
+			&fakeDB{},
+			refreshStoreProvider(&fakeRefreshTokenStore{
+				listed: []refresh.RefreshToken{
+					{SessionID: "s1", UserAgent: userAgent, ExpiresAt: time.Now().Add(time.Hour)},
+				},
+			}),
+			sessionAccessTokenVerifier("s1"),
+			&fakeAuditSink{},
+		)
+
+		sessions, err := svc.List(context.Background(), "access.jwt.token")
+		if err != nil {
+			test.Fatalf("unexpected error: %v", err)
+		}
+
+		if sessions[0].DeviceLabel != want {
+			test.Fatalf("expected %q for %q, got %q", want, userAgent, sessions[0].DeviceLabel)
+		}
+	}
+}
+
+func TestSessionService_List_InvalidToken(test *testing.T) {
+	svc := service.NewSessionService(
+		&fakeDB{},
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		&fakeAccessTokenVerifier{err: errors.New("token is expired")},
+		&fakeAuditSink{},
+	)
+
+	_, err := svc.List(context.Background(), "expired.jwt.token")
+	if !errors.Is(err, errs.ErrInvalidAccessToken) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidAccessToken, err)
+	}
+}
+
+func TestSessionService_Revoke(test *testing.T) {
+	refreshStore := &fakeRefreshTokenStore{}
+	auditSink := &fakeAuditSink{}
+
+	svc := service.NewSessionService(
+		&fakeDB{},
+		refreshStoreProvider(refreshStore),
+		sessionAccessTokenVerifier("s1"),
+		auditSink,
+	)
+
+	if err := svc.Revoke(context.Background(), "access.jwt.token", "s2"); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if refreshStore.revokedSessionID != "s2" {
+		test.Fatalf("expected session s2 to be revoked, got %q", refreshStore.revokedSessionID)
+	}
+
+	if len(auditSink.events) != 1 || auditSink.events[0].Type != audit.SessionRevoked || auditSink.events[0].SessionID != "s2" {
+		test.Fatalf("expected a %s event for s2, got %+v", audit.SessionRevoked, auditSink.events)
+	}
+}
+
+func TestSessionService_Revoke_NotFound(test *testing.T) {
+	auditSink := &fakeAuditSink{}
+
+	svc := service.NewSessionService(
+		&fakeDB{},
+		refreshStoreProvider(&fakeRefreshTokenStore{revokeUserSessionErr: errs.ErrNotFound}),
+		sessionAccessTokenVerifier("s1"),
+		auditSink,
+	)
+
+	err := svc.Revoke(context.Background(), "access.jwt.token", "someone-elses")
+	if !errors.Is(err, errs.ErrNotFound) {
+		test.Fatalf("expected %v, got %v", errs.ErrNotFound, err)
+	}
+
+	if len(auditSink.events) != 0 {
+		test.Fatalf("expected no audit event, got %v", auditSink.types())
+	}
+}
+
+func TestSessionService_RevokeOthers(test *testing.T) {
+	refreshStore := &fakeRefreshTokenStore{}
+	auditSink := &fakeAuditSink{}
+
+	svc := service.NewSessionService(
+		&fakeDB{},
+		refreshStoreProvider(refreshStore),
+		sessionAccessTokenVerifier("s1"),
+		auditSink,
+	)
+
+	if err := svc.RevokeOthers(context.Background(), "access.jwt.token"); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if refreshStore.keptSessionID != "s1" {
+		test.Fatalf("expected the current session to be kept, got %q", refreshStore.keptSessionID)
+	}
+
+	if types := auditSink.types(); len(types) != 1 || types[0] != audit.OtherSessionsRevoked {
+		test.Fatalf("expected a %s event, got %v", audit.OtherSessionsRevoked, types)
+	}
+}
+
+func TestSessionService_RevokeOthers_TokenWithoutSession(test *testing.T) {
+	refreshStore := &fakeRefreshTokenStore{}
+
+	svc := service.NewSessionService(
+		&fakeDB{},
+		refreshStoreProvider(refreshStore),
+		sessionAccessTokenVerifier(""),
+		&fakeAuditSink{},
+	)
+
+	err := svc.RevokeOthers(context.Background(), "access.jwt.token")
+	if !errors.Is(err, errs.ErrInvalidAccessToken) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidAccessToken, err)
+	}
+
+	if refreshStore.keptSessionID != "" {
+		test.Fatal("expected no session to be revoked")
+	}
+}
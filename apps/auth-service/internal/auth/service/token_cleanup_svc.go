@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+// TokenCleanupService deletes refresh tokens nobody can use anymore,
+// otherwise every rotation leaves a row behind forever; the same goes
+// for the challenge of every passkey ceremony that was never finished
+type TokenCleanupService struct {
+	transactionMgr storage.TransactionMgr
+	refreshStore   storage.RefreshTokenStoreProvider
+	challengeStore storage.WebAuthnChallengeStoreProvider
+	retention      time.Duration
+	batchSize      int
+}
+
+func NewTokenCleanupService(
+	transactionMgr storage.TransactionMgr,
+	refreshStore storage.RefreshTokenStoreProvider,
+	challengeStore storage.WebAuthnChallengeStoreProvider,
+	retention time.Duration,
+	batchSize int,
+) *TokenCleanupService {
+	return &TokenCleanupService{
+		transactionMgr: transactionMgr,
+		refreshStore:   refreshStore,
+		challengeStore: challengeStore,
+		retention:      retention,
+		batchSize:      batchSize,
+	}
+}
+
+// deletes tokens that expired (or were revoked with their whole session) more
+// than the retention window ago; each batch is a transaction of its own, so
+// logins and refreshes never wait for the whole purge
+func (svc *TokenCleanupService) PurgeRefreshTokens(ctx context.Context) (int64, error) {
+	before := time.Now().Add(-svc.retention)
+
+	return svc.purge(ctx, func(exec storage.SQLExecutor) (int64, error) {
+		return svc.refreshStore(exec).DeleteStale(ctx, before, svc.batchSize)
+	})
+}
+
+// deletes the challenges of passkey ceremonies that expired unfinished;
+// they cannot be used anymore, so there is no retention window
+func (svc *TokenCleanupService) PurgeWebAuthnChallenges(ctx context.Context) (int64, error) {
+	before := time.Now()
+
+	return svc.purge(ctx, func(exec storage.SQLExecutor) (int64, error) {
+		return svc.challengeStore(exec).DeleteExpired(ctx, before, svc.batchSize)
+	})
+}
+
+func (svc *TokenCleanupService) purge(
+	ctx context.Context,
+	deleteBatch func(exec storage.SQLExecutor) (int64, error),
+) (deleted int64, err error) {
+
+	for {
+		batch, err := svc.purgeBatch(ctx, deleteBatch)
+		deleted += batch
+		if err != nil {
+			return deleted, err
+		}
+
+		// nothing left
+		if batch < int64(svc.batchSize) {
+			return deleted, nil
+		}
+
+		// shutting down, the next run continues
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+	}
+}
+
+func (svc *TokenCleanupService) purgeBatch(
+	ctx context.Context,
+	deleteBatch func(exec storage.SQLExecutor) (int64, error),
+) (deleted int64, err error) {
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	return deleteBatch(exec)
+}
@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+)
+
+func TestTokenCleanupService_PurgesInBatches(test *testing.T) {
+	refreshStore := &fakeRefreshTokenStore{staleBatches: []int64{100, 100, 42}}
+	svc := service.NewTokenCleanupService(&fakeDB{}, refreshStoreProvider(refreshStore), webAuthnChallengeStoreProvider(&fakeWebAuthnChallengeStore{}), 7*24*time.Hour, 100)
+
+	deleted, err := svc.PurgeRefreshTokens(context.Background())
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if deleted != 242 {
+		test.Fatalf("expected 242 deleted tokens, got %d", deleted)
+	}
+	// a batch smaller than the limit was the last one
+	if refreshStore.staleCalls != 3 {
+		test.Fatalf("expected 3 batches, got %d", refreshStore.staleCalls)
+	}
+
+	retainedFrom := time.Now().Add(-7 * 24 * time.Hour)
+	if refreshStore.staleBefore.Sub(retainedFrom).Abs() > time.Minute {
+		test.Fatalf("expected tokens older than the retention window, got %v", refreshStore.staleBefore)
+	}
+}
+
+func TestTokenCleanupService_StopsOnError(test *testing.T) {
+	refreshStore := &fakeRefreshTokenStore{deleteStaleErr: errors.New("db error")}
+	svc := service.NewTokenCleanupService(&fakeDB{}, refreshStoreProvider(refreshStore), webAuthnChallengeStoreProvider(&fakeWebAuthnChallengeStore{}), time.Hour, 100)
+
+	_, err := svc.PurgeRefreshTokens(context.Background())
+	if err == nil {
+		test.Fatal("expected error")
+	}
+	if refreshStore.staleCalls != 1 {
+		test.Fatalf("expected no further batch, got %d calls", refreshStore.staleCalls)
+	}
+}
+
+func TestTokenCleanupService_StopsWhenCancelled(test *testing.T) {
+	refreshStore := &fakeRefreshTokenStore{staleBatches: []int64{100, 100, 100}}
+	svc := service.NewTokenCleanupService(&fakeDB{}, refreshStoreProvider(refreshStore), webAuthnChallengeStoreProvider(&fakeWebAuthnChallengeStore{}), time.Hour, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	deleted, err := svc.PurgeRefreshTokens(ctx)
+	if !errors.Is(err, context.Canceled) {
+		test.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if deleted != 100 || refreshStore.staleCalls != 1 {
+		test.Fatalf("expected to stop after the first batch, got %d rows in %d calls", deleted, refreshStore.staleCalls)
+	}
+}
+
+func TestTokenCleanupService_PurgesExpiredChallenges(test *testing.T) {
+	challengeStore := &fakeWebAuthnChallengeStore{expiredBatches: []int64{100, 7}}
+	svc := service.NewTokenCleanupService(
+		&fakeDB{},
+		refreshStoreProvider(&fakeRefreshTokenStore{}),
+		webAuthnChallengeStoreProvider(challengeStore),
+		7*24*time.Hour,
+		100,
+	)
+
+	deleted, err := svc.PurgeWebAuthnChallenges(context.Background())
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if deleted != 107 {
+		test.Fatalf("expected 107 deleted challenges, got %d", deleted)
+	}
+	// no retention window, an expired challenge is useless
+	if time.Since(challengeStore.expiredBefore).Abs() > time.Minute {
+		test.Fatalf("expected challenges expired by now, got %v", challengeStore.expiredBefore)
+	}
+}
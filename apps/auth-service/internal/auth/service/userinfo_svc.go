@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type UserInfo = domain.UserInfo
+
+// UserInfoService answers the OpenID Connect userinfo request
+// for the user an access token was issued to
+type UserInfoService struct {
+	transactionMgr     storage.TransactionMgr
+	userStoreProvider  storage.UserStoreProvider
+	membershipProvider storage.MembershipStoreProvider
+	accessVerifier     AccessTokenVerifier
+}
+
+func NewUserInfoService(
+	transactionMgr storage.TransactionMgr,
+	userStore storage.UserStoreProvider,
+	membershipStore storage.MembershipStoreProvider,
+	accessVerifier AccessTokenVerifier,
+) *UserInfoService {
+	return &UserInfoService{
+		transactionMgr:     transactionMgr,
+		userStoreProvider:  userStore,
+		membershipProvider: membershipStore,
+		accessVerifier:     accessVerifier,
+	}
+}
+
+// claims come from the stores, not the token, so they are current
+func (svc *UserInfoService) UserInfo(
+	ctx context.Context,
+	accessToken string,
+) (info UserInfo, err error) {
+
+	claims, err := svc.accessVerifier.Verify(accessToken)
+	if err != nil {
+		return UserInfo{}, errs.ErrInvalidAccessToken
+	}
+
+	exec, finish, err := svc.transactionMgr.BeginTransaction(ctx, true)
+	if err != nil {
+		return UserInfo{}, err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	user, err := svc.userStoreProvider(exec).GetById(ctx, claims.Subject)
+	if errors.Is(err, errs.ErrNotFound) {
+		// user was deleted after the token was issued
+		return UserInfo{}, errs.ErrInvalidAccessToken
+	}
+	if err != nil {
+		return UserInfo{}, err
+	}
+
+	membership, err := svc.membershipProvider(exec).GetByUserID(ctx, user.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return UserInfo{}, errs.ErrInvalidAccessToken
+	}
+	if err != nil {
+		return UserInfo{}, err
+	}
+
+	return UserInfo{
+		Subject:       user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		FamilyID:      membership.FamilyID,
+		Role:          membership.Role,
+	}, nil
+}
@@ -0,0 +1,90 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+)
+
+func validAccessTokenVerifier() *fakeAccessTokenVerifier {
+	return &fakeAccessTokenVerifier{
+		claims: &jwt.Claims{
+			RegisteredClaims: gojwt.RegisteredClaims{Subject: "u1"},
+		},
+	}
+}
+
+func TestUserInfoService_Success(test *testing.T) {
+	verifiedAt := time.Now()
+	svc := service.NewUserInfoService(
+		&fakeDB{},
+		userStoreProvider(&fakeUserStore{
+			user: User{ID: "u1", Email: "a@b.com", EmailVerifiedAt: &verifiedAt},
+		}),
+		membershipStoreProvider(&fakeMembershipStore{
+			membership: Membership{UserID: "u1", FamilyID: "f1", Role: "owner"},
+		}),
+		validAccessTokenVerifier(),
+	)
+
+	info, err := svc.UserInfo(context.Background(), "access.jwt.token")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if info.Subject != "u1" || info.Email != "a@b.com" || info.FamilyID != "f1" || info.Role != "owner" {
+		test.Fatalf("unexpected user info %+v", info)
+	}
+	if !info.EmailVerified {
+		test.Fatalf("expected the email to be verified")
+	}
+}
+
+func TestUserInfoService_InvalidToken(test *testing.T) {
+	svc := service.NewUserInfoService(
+		&fakeDB{},
+		userStoreProvider(&fakeUserStore{}),
+		membershipStoreProvider(&fakeMembershipStore{}),
+		&fakeAccessTokenVerifier{err: errors.New("token is expired")},
+	)
+
+	_, err := svc.UserInfo(context.Background(), "expired.jwt.token")
+	if !errors.Is(err, errs.ErrInvalidAccessToken) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidAccessToken, err)
+	}
+}
+
+func TestUserInfoService_UserDeleted(test *testing.T) {
+	svc := service.NewUserInfoService(
+		&fakeDB{},
+		userStoreProvider(&fakeUserStore{err: errs.ErrNotFound}),
+		membershipStoreProvider(&fakeMembershipStore{}),
+		validAccessTokenVerifier(),
+	)
+
+	_, err := svc.UserInfo(context.Background(), "access.jwt.token")
+	if !errors.Is(err, errs.ErrInvalidAccessToken) {
+		test.Fatalf("expected %v, got %v", errs.ErrInvalidAccessToken, err)
+	}
+}
+
+func TestUserInfoService_StoreFailure(test *testing.T) {
+	svc := service.NewUserInfoService(
+		&fakeDB{},
+		userStoreProvider(&fakeUserStore{err: errors.New("db failure")}),
+		membershipStoreProvider(&fakeMembershipStore{}),
+		validAccessTokenVerifier(),
+	)
+
+	_, err := svc.UserInfo(context.Background(), "access.jwt.token")
+	if err == nil || errors.Is(err, errs.ErrInvalidAccessToken) {
+		test.Fatalf("expected infrastructure error, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package webauthn
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeCBOR(test *testing.T) {
+	// {1: 2, 3: -7, "k": h'0102', "a": [true, null]}, then a trailing byte
+	data := []byte{
+		0xa4,
+		0x01, 0x02,
+		0x03, 0x26,
+		0x61, 'k', 0x42, 0x01, 0x02,
+		0x61, 'a', 0x82, 0xf5, 0xf6,
+		0xff,
+	}
+
+	decoded, rest, err := decodeCBOR(data)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(rest, []byte{0xff}) {
+		test.Fatalf("unexpected rest %x", rest)
+	}
+
+	item, ok := decoded.(map[any]any)
+	if !ok {
+		test.Fatalf("expected map, got %T", decoded)
+	}
+	if item[int64(1)] != int64(2) || item[int64(3)] != int64(-7) {
+		test.Fatalf("unexpected integers %v", item)
+	}
+	if !bytes.Equal(item["k"].([]byte), []byte{0x01, 0x02}) {
+		test.Fatalf("unexpected byte string %v", item["k"])
+	}
+	if array := item["a"].([]any); len(array) != 2 || array[0] != true || array[1] != nil {
+		test.Fatalf("unexpected array %v", item["a"])
+	}
+}
+
+func TestDecodeCBOR_Invalid(test *testing.T) {
+	cases := map[string][]byte{
+		"empty":              {},
+		"truncated bytes":    {0x42, 0x01},
+		"indefinite length":  {0x5f, 0x41, 0x01, 0xff},
+		"truncated map":      {0xa1, 0x01},
+		"too deep":           bytes.Repeat([]byte{0x81}, maxCBORDepth+2),
+		"unsupported simple": {0xf9, 0x00, 0x00},
+		"truncated argument": {0x19, 0x01},
+	}
+
+	for name, data := range cases {
+		if _, _, err := decodeCBOR(data); err == nil {
+			test.Fatalf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"math/big"
+)
+
+// COSE algorithms (RFC 9053) offered to authenticators, in order of preference
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+var ErrUnsupportedKey = errors.New("unsupported credential public key")
+
+// COSE key parameters (RFC 9052, RFC 9053); negative labels depend on the key type
+const (
+	coseKeyType = 1
+	coseKeyAlg  = 3
+	coseCurve   = -1
+	coseX       = -2
+	coseY       = -3
+	coseRSAN    = -1
+	coseRSAE    = -2
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+// public key of a COSE_Key map, with its algorithm
+func parseCOSEKey(key map[any]any) (crypto.PublicKey, int64, error) {
+	kty, _ := key[int64(coseKeyType)].(int64)
+	alg, _ := key[int64(coseKeyAlg)].(int64)
+
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		crv, _ := key[int64(coseCurve)].(int64)
+		x, _ := key[int64(coseX)].([]byte)
+		y, _ := key[int64(coseY)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, ErrUnsupportedKey
+		}
+		point := append(append([]byte{0x04}, x...), y...)
+		publicKey, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return publicKey, alg, nil
+
+	case kty == coseKtyOKP && alg == AlgEdDSA:
+		crv, _ := key[int64(coseCurve)].(int64)
+		x, _ := key[int64(coseX)].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return ed25519.PublicKey(x), alg, nil
+
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := key[int64(coseRSAN)].([]byte)
+		e, _ := key[int64(coseRSAE)].([]byte)
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) < 256 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, alg, nil
+	}
+
+	return nil, 0, ErrUnsupportedKey
+}
+
+// signature of an authenticator over data (authenticatorData || hash(clientDataJSON));
+// publicKey is the stored PKIX DER
+func verifySignature(publicKey []byte, alg int64, data []byte, signature []byte) bool {
+	parsed, err := x509.ParsePKIXPublicKey(publicKey)
+	if err != nil {
+		return false
+	}
+
+	digest := sha256.Sum256(data)
+
+	switch key := parsed.(type) {
+	case *ecdsa.PublicKey:
+		return alg == AlgES256 && ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return alg == AlgEdDSA && ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		return alg == AlgRS256 && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
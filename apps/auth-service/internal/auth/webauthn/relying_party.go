@@ -0,0 +1,409 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+var (
+	ErrInvalidResponse = errors.New("invalid WebAuthn response")
+	// the authenticator reported a counter not above the stored one: a sign of a cloned key
+	ErrSignCountRegression = errors.New("WebAuthn sign counter did not increase")
+)
+
+// authenticator data flags (WebAuthn Level 2, section 6.1)
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+)
+
+// time the browser gives the user to touch the authenticator
+const ceremonyTimeoutMillis = 5 * 60 * 1000
+
+var base64URL = base64.RawURLEncoding
+
+// RelyingParty verifies the WebAuthn registration (navigator.credentials.create)
+// and assertion (navigator.credentials.get) ceremonies for passkeys.
+// Attestation is not requested: any authenticator the browser offers is accepted.
+// User verification (PIN, biometrics) is required, so a passkey alone is a full login.
+type RelyingParty struct {
+	// effective domain of the site, e.g. "family.example"
+	id   string
+	name string
+	// full origins the browser may report, e.g. "https://app.family.example"
+	origins []string
+}
+
+func NewRelyingParty(id string, name string, origins []string) *RelyingParty {
+	return &RelyingParty{
+		id:      id,
+		name:    name,
+		origins: origins,
+	}
+}
+
+// random challenge, base64url as it appears in the client data
+func NewChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64URL.EncodeToString(b), nil
+}
+
+// options for navigator.credentials.create, in the JSON form accepted by
+// PublicKeyCredential.parseCreationOptionsFromJSON
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     rpEntity               `json:"rp"`
+	User                   userEntity             `json:"user"`
+	PubKeyCredParams       []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// options for navigator.credentials.get (PublicKeyCredential.parseRequestOptionsFromJSON);
+// no allowCredentials: the browser offers every passkey of the site
+type RequestOptions struct {
+	Challenge        string `json:"challenge"`
+	RPID             string `json:"rpId"`
+	Timeout          int64  `json:"timeout"`
+	UserVerification string `json:"userVerification"`
+}
+
+type rpEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type userEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type credentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// credential returned by navigator.credentials.create, as PublicKeyCredential.toJSON
+// encodes it (binary fields base64url)
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// credential returned by navigator.credentials.get
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	// only present during registration
+	credentialID []byte
+	publicKey    map[any]any
+}
+
+// user.id is the user ID (the user handle returned on login);
+// existing passkeys of the user are excluded so an authenticator is not registered twice
+func (rp *RelyingParty) CreationOptions(
+	challenge string,
+	userID string,
+	userName string,
+	existing []domain.WebAuthnCredential,
+) CreationOptions {
+
+	params := make([]credentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, credentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	exclude := make([]CredentialDescriptor, 0, len(existing))
+	for _, credential := range existing {
+		exclude = append(exclude, CredentialDescriptor{
+			Type:       "public-key",
+			ID:         credential.ID,
+			Transports: credential.Transports,
+		})
+	}
+
+	return CreationOptions{
+		Challenge: challenge,
+		RP:        rpEntity{ID: rp.id, Name: rp.name},
+		User: userEntity{
+			ID:          base64URL.EncodeToString([]byte(userID)),
+			Name:        userName,
+			DisplayName: userName,
+		},
+		PubKeyCredParams:   params,
+		Timeout:            ceremonyTimeoutMillis,
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}
+}
+
+func (rp *RelyingParty) RequestOptions(challenge string) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		RPID:             rp.id,
+		Timeout:          ceremonyTimeoutMillis,
+		UserVerification: "required",
+	}
+}
+
+// checks a registration response against the challenge issued for it
+// and returns the new credential (without user and timestamps)
+func (rp *RelyingParty) VerifyRegistration(
+	challenge string,
+	response RegistrationResponse,
+) (domain.WebAuthnCredential, error) {
+
+	if response.Type != "public-key" {
+		return domain.WebAuthnCredential{}, invalid("credential type")
+	}
+
+	if _, err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return domain.WebAuthnCredential{}, err
+	}
+
+	rawAttestation, err := base64URL.DecodeString(response.Response.AttestationObject)
+	if err != nil {
+		return domain.WebAuthnCredential{}, invalid("attestation object encoding")
+	}
+
+	decoded, rest, err := decodeCBOR(rawAttestation)
+	attestation, ok := decoded.(map[any]any)
+	if err != nil || !ok || len(rest) != 0 {
+		return domain.WebAuthnCredential{}, invalid("attestation object")
+	}
+
+	// attestation "none" was requested; browsers then strip any statement
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[any]any)
+	if format != "none" || len(statement) != 0 {
+		return domain.WebAuthnCredential{}, invalid("attestation format " + format)
+	}
+
+	rawAuthData, _ := attestation["authData"].([]byte)
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return domain.WebAuthnCredential{}, err
+	}
+	if authData.flags&flagAttestedCredData == 0 {
+		return domain.WebAuthnCredential{}, invalid("no attested credential data")
+	}
+
+	credentialID := base64URL.EncodeToString(authData.credentialID)
+	if response.RawID != credentialID {
+		return domain.WebAuthnCredential{}, invalid("credential ID mismatch")
+	}
+
+	publicKey, alg, err := parseCOSEKey(authData.publicKey)
+	if err != nil {
+		return domain.WebAuthnCredential{}, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return domain.WebAuthnCredential{}, err
+	}
+
+	return domain.WebAuthnCredential{
+		ID:         credentialID,
+		PublicKey:  der,
+		Algorithm:  alg,
+		SignCount:  authData.signCount,
+		Transports: response.Response.Transports,
+	}, nil
+}
+
+// checks an assertion made with a stored credential against the challenge issued
+// for it and returns the new sign counter to store
+func (rp *RelyingParty) VerifyAssertion(
+	challenge string,
+	credential domain.WebAuthnCredential,
+	response AssertionResponse,
+) (uint32, error) {
+
+	if response.Type != "public-key" || response.RawID != credential.ID {
+		return 0, invalid("credential")
+	}
+
+	// a passkey carries the user.id it was registered with
+	if response.Response.UserHandle != "" {
+		userHandle, err := base64URL.DecodeString(response.Response.UserHandle)
+		if err != nil || string(userHandle) != credential.UserID {
+			return 0, invalid("user handle")
+		}
+	}
+
+	rawClientData, err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := base64URL.DecodeString(response.Response.AuthenticatorData)
+	if err != nil {
+		return 0, invalid("authenticator data encoding")
+	}
+
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+
+	signature, err := base64URL.DecodeString(response.Response.Signature)
+	if err != nil {
+		return 0, invalid("signature encoding")
+	}
+
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+	if !verifySignature(credential.PublicKey, credential.Algorithm, signed, signature) {
+		return 0, invalid("signature")
+	}
+
+	// counters of 0 mean the authenticator does not keep one (synced passkeys)
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, ErrSignCountRegression
+	}
+
+	return authData.signCount, nil
+}
+
+// returns the raw client data JSON, which the assertion signature covers
+func (rp *RelyingParty) verifyClientData(encoded string, ceremony string, challenge string) ([]byte, error) {
+	raw, err := base64URL.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid("client data encoding")
+	}
+
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, invalid("client data")
+	}
+
+	if data.Type != ceremony {
+		return nil, invalid("ceremony type")
+	}
+	if data.Challenge != challenge {
+		return nil, invalid("challenge")
+	}
+	if !slices.Contains(rp.origins, data.Origin) || data.CrossOrigin {
+		return nil, invalid("origin " + data.Origin)
+	}
+
+	return raw, nil
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(raw []byte) (authenticatorData, error) {
+	data, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return authenticatorData{}, err
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.id))
+	if !bytes.Equal(data.rpIDHash, rpIDHash[:]) {
+		return authenticatorData{}, invalid("relying party ID")
+	}
+	if data.flags&flagUserPresent == 0 || data.flags&flagUserVerified == 0 {
+		return authenticatorData{}, invalid("user not verified")
+	}
+
+	return data, nil
+}
+
+// rpIdHash (32) | flags (1) | signCount (4) | [aaguid (16) | idLength (2) | id | COSE key] | [extensions]
+func parseAuthenticatorData(raw []byte) (authenticatorData, error) {
+	if len(raw) < 37 {
+		return authenticatorData{}, invalid("authenticator data length")
+	}
+
+	data := authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	if data.flags&flagAttestedCredData == 0 {
+		return data, nil
+	}
+
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return authenticatorData{}, invalid("attested credential data length")
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return authenticatorData{}, invalid("credential ID length")
+	}
+	data.credentialID = rest[:idLength]
+
+	// extensions may follow the key; they are not used
+	decoded, _, err := decodeCBOR(rest[idLength:])
+	publicKey, ok := decoded.(map[any]any)
+	if err != nil || !ok {
+		return authenticatorData{}, invalid("credential public key")
+	}
+	data.publicKey = publicKey
+
+	return data, nil
+}
+
+func invalid(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidResponse, reason)
+}
@@ -0,0 +1,207 @@
+package webauthn_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/webauthn"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/webauthn/webauthntest"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+const (
+	RP_ID     = "family.example"
+	RP_ORIGIN = "https://app.family.example"
+	USER_ID   = "user-1"
+	CHALLENGE = "c2VydmVyLWNoYWxsZW5nZQ"
+)
+
+func newRelyingParty() *webauthn.RelyingParty {
+	return webauthn.NewRelyingParty(RP_ID, "Family Space", []string{RP_ORIGIN})
+}
+
+// registers a new software authenticator and returns it with its stored credential
+func registerPasskey(test *testing.T) (*webauthntest.Authenticator, domain.WebAuthnCredential) {
+	authenticator := webauthntest.NewAuthenticator(RP_ID, RP_ORIGIN)
+
+	credential, err := newRelyingParty().VerifyRegistration(CHALLENGE, authenticator.Register(CHALLENGE, USER_ID))
+	if err != nil {
+		test.Fatalf("unexpected registration error: %v", err)
+	}
+	credential.UserID = USER_ID
+
+	return authenticator, credential
+}
+
+func TestVerifyRegistration(test *testing.T) {
+	authenticator, credential := registerPasskey(test)
+
+	if credential.ID != authenticator.ID() {
+		test.Fatalf("expected credential ID %q, got %q", authenticator.ID(), credential.ID)
+	}
+	if credential.Algorithm != webauthn.AlgES256 {
+		test.Fatalf("expected ES256, got %d", credential.Algorithm)
+	}
+	if len(credential.PublicKey) == 0 || len(credential.Transports) != 2 {
+		test.Fatalf("unexpected credential %+v", credential)
+	}
+}
+
+func TestVerifyRegistration_Rejected(test *testing.T) {
+	cases := map[string]func(*webauthntest.Authenticator) webauthn.RegistrationResponse{
+		"wrong challenge": func(auth *webauthntest.Authenticator) webauthn.RegistrationResponse {
+			return auth.Register("b3RoZXItY2hhbGxlbmdl", USER_ID)
+		},
+		"wrong origin": func(auth *webauthntest.Authenticator) webauthn.RegistrationResponse {
+			auth.Origin = "https://evil.example"
+			return auth.Register(CHALLENGE, USER_ID)
+		},
+		"wrong relying party": func(auth *webauthntest.Authenticator) webauthn.RegistrationResponse {
+			auth.RPID = "evil.example"
+			return auth.Register(CHALLENGE, USER_ID)
+		},
+		"user not verified": func(auth *webauthntest.Authenticator) webauthn.RegistrationResponse {
+			auth.Flags = webauthntest.FlagUserPresent
+			return auth.Register(CHALLENGE, USER_ID)
+		},
+		"assertion instead of attestation": func(auth *webauthntest.Authenticator) webauthn.RegistrationResponse {
+			response := auth.Register(CHALLENGE, USER_ID)
+			response.Response.ClientDataJSON = auth.Assert(CHALLENGE).Response.ClientDataJSON
+			return response
+		},
+		"credential ID mismatch": func(auth *webauthntest.Authenticator) webauthn.RegistrationResponse {
+			response := auth.Register(CHALLENGE, USER_ID)
+			response.RawID = "b3RoZXI"
+			return response
+		},
+	}
+
+	for name, respond := range cases {
+		test.Run(name, func(test *testing.T) {
+			authenticator := webauthntest.NewAuthenticator(RP_ID, RP_ORIGIN)
+
+			_, err := newRelyingParty().VerifyRegistration(CHALLENGE, respond(authenticator))
+			if !errors.Is(err, webauthn.ErrInvalidResponse) {
+				test.Fatalf("expected ErrInvalidResponse, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion(test *testing.T) {
+	authenticator, credential := registerPasskey(test)
+
+	signCount, err := newRelyingParty().VerifyAssertion(CHALLENGE, credential, authenticator.Assert(CHALLENGE))
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	if signCount != 0 {
+		test.Fatalf("expected sign count 0 for a synced passkey, got %d", signCount)
+	}
+}
+
+func TestVerifyAssertion_SignCount(test *testing.T) {
+	authenticator := webauthntest.NewAuthenticator(RP_ID, RP_ORIGIN)
+	authenticator.SignCount = 5
+
+	credential, err := newRelyingParty().VerifyRegistration(CHALLENGE, authenticator.Register(CHALLENGE, USER_ID))
+	if err != nil {
+		test.Fatalf("unexpected registration error: %v", err)
+	}
+	credential.UserID = USER_ID
+
+	signCount, err := newRelyingParty().VerifyAssertion(CHALLENGE, credential, authenticator.Assert(CHALLENGE))
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	if signCount != 6 {
+		test.Fatalf("expected sign count 6, got %d", signCount)
+	}
+
+	// a cloned authenticator replays an old counter
+	credential.SignCount = 10
+	_, err = newRelyingParty().VerifyAssertion(CHALLENGE, credential, authenticator.Assert(CHALLENGE))
+	if !errors.Is(err, webauthn.ErrSignCountRegression) {
+		test.Fatalf("expected ErrSignCountRegression, got %v", err)
+	}
+}
+
+func TestVerifyAssertion_Rejected(test *testing.T) {
+	cases := map[string]func(*webauthntest.Authenticator) webauthn.AssertionResponse{
+		"wrong challenge": func(auth *webauthntest.Authenticator) webauthn.AssertionResponse {
+			return auth.Assert("b3RoZXItY2hhbGxlbmdl")
+		},
+		"wrong origin": func(auth *webauthntest.Authenticator) webauthn.AssertionResponse {
+			auth.Origin = "https://evil.example"
+			return auth.Assert(CHALLENGE)
+		},
+		"user not verified": func(auth *webauthntest.Authenticator) webauthn.AssertionResponse {
+			auth.Flags = webauthntest.FlagUserPresent
+			return auth.Assert(CHALLENGE)
+		},
+		"registration instead of assertion": func(auth *webauthntest.Authenticator) webauthn.AssertionResponse {
+			response := auth.Assert(CHALLENGE)
+			response.Response.ClientDataJSON = auth.Register(CHALLENGE, USER_ID).Response.ClientDataJSON
+			return response
+		},
+		"other user": func(auth *webauthntest.Authenticator) webauthn.AssertionResponse {
+			auth.UserHandle = []byte("user-2")
+			return auth.Assert(CHALLENGE)
+		},
+		"signature of another challenge": func(auth *webauthntest.Authenticator) webauthn.AssertionResponse {
+			response := auth.Assert(CHALLENGE)
+			other := auth.Assert("b3RoZXItY2hhbGxlbmdl")
+			response.Response.Signature = other.Response.Signature
+			return response
+		},
+	}
+
+	for name, respond := range cases {
+		test.Run(name, func(test *testing.T) {
+			authenticator, credential := registerPasskey(test)
+
+			_, err := newRelyingParty().VerifyAssertion(CHALLENGE, credential, respond(authenticator))
+			if !errors.Is(err, webauthn.ErrInvalidResponse) {
+				test.Fatalf("expected ErrInvalidResponse, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion_OtherCredential(test *testing.T) {
+	_, credential := registerPasskey(test)
+	other, _ := registerPasskey(test)
+
+	_, err := newRelyingParty().VerifyAssertion(CHALLENGE, credential, other.Assert(CHALLENGE))
+	if !errors.Is(err, webauthn.ErrInvalidResponse) {
+		test.Fatalf("expected ErrInvalidResponse, got %v", err)
+	}
+}
+
+func TestCreationOptions(test *testing.T) {
+	_, credential := registerPasskey(test)
+
+	options := newRelyingParty().CreationOptions(CHALLENGE, USER_ID, "alice@example.com", []domain.WebAuthnCredential{credential})
+
+	raw, err := json.Marshal(options)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	user := decoded["user"].(map[string]any)
+	if user["id"] != "dXNlci0x" || user["name"] != "alice@example.com" {
+		test.Fatalf("unexpected user entity %v", user)
+	}
+	if excluded := decoded["excludeCredentials"].([]any); len(excluded) != 1 {
+		test.Fatalf("expected 1 excluded credential, got %v", excluded)
+	}
+	if decoded["attestation"] != "none" {
+		test.Fatalf("unexpected attestation %v", decoded["attestation"])
+	}
+}
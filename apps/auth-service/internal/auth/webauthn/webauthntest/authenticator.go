@@ -0,0 +1,189 @@
+// Package webauthntest is a software passkey authenticator for tests:
+// it answers registration and assertion ceremonies the way a browser
+// and a platform authenticator would, with a P-256 (ES256) key.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/webauthn"
+)
+
+var base64URL = base64.RawURLEncoding
+
+// authenticator data flags
+const (
+	FlagUserPresent      byte = 0x01
+	FlagUserVerified     byte = 0x04
+	FlagAttestedCredData byte = 0x40
+)
+
+type Authenticator struct {
+	RPID   string
+	Origin string
+	// flags set on the next responses; UP and UV by default
+	Flags byte
+	// incremented before every assertion unless 0 (synced passkey)
+	SignCount uint32
+
+	CredentialID []byte
+	UserHandle   []byte
+	key          *ecdsa.PrivateKey
+}
+
+func NewAuthenticator(rpID string, origin string) *Authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		panic(err)
+	}
+
+	return &Authenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		Flags:        FlagUserPresent | FlagUserVerified,
+		CredentialID: credentialID,
+		key:          key,
+	}
+}
+
+// base64url credential ID, as stored by the relying party
+func (auth *Authenticator) ID() string {
+	return base64URL.EncodeToString(auth.CredentialID)
+}
+
+// answers navigator.credentials.create for the user ID the options carried
+func (auth *Authenticator) Register(challenge string, userID string) webauthn.RegistrationResponse {
+	auth.UserHandle = []byte(userID)
+
+	ecdhKey, err := auth.key.PublicKey.ECDH()
+	if err != nil {
+		panic(err)
+	}
+	point := ecdhKey.Bytes()
+
+	// COSE_Key: kty EC2, alg ES256, crv P-256, x, y
+	coseKey := encodeMap(
+		encodeInt(1), encodeInt(2),
+		encodeInt(3), encodeInt(webauthn.AlgES256),
+		encodeInt(-1), encodeInt(1),
+		encodeInt(-2), encodeBytes(point[1:33]),
+		encodeInt(-3), encodeBytes(point[33:]),
+	)
+
+	attested := make([]byte, 16, 18+len(auth.CredentialID)+len(coseKey)) // zero AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(auth.CredentialID)))
+	attested = append(attested, auth.CredentialID...)
+	attested = append(attested, coseKey...)
+
+	authData := append(auth.authenticatorData(auth.Flags|FlagAttestedCredData), attested...)
+
+	attestationObject := encodeMap(
+		encodeText("fmt"), encodeText("none"),
+		encodeText("attStmt"), encodeMap(),
+		encodeText("authData"), encodeBytes(authData),
+	)
+
+	var response webauthn.RegistrationResponse
+	response.ID = auth.ID()
+	response.RawID = auth.ID()
+	response.Type = "public-key"
+	response.Response.ClientDataJSON = auth.clientData("webauthn.create", challenge)
+	response.Response.AttestationObject = base64URL.EncodeToString(attestationObject)
+	response.Response.Transports = []string{"internal", "hybrid"}
+	return response
+}
+
+// answers navigator.credentials.get
+func (auth *Authenticator) Assert(challenge string) webauthn.AssertionResponse {
+	if auth.SignCount != 0 {
+		auth.SignCount++
+	}
+
+	authData := auth.authenticatorData(auth.Flags)
+	clientDataJSON := auth.clientData("webauthn.get", challenge)
+
+	rawClientData, _ := base64URL.DecodeString(clientDataJSON)
+	clientDataHash := sha256.Sum256(rawClientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, auth.key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	var response webauthn.AssertionResponse
+	response.ID = auth.ID()
+	response.RawID = auth.ID()
+	response.Type = "public-key"
+	response.Response.ClientDataJSON = clientDataJSON
+	response.Response.AuthenticatorData = base64URL.EncodeToString(authData)
+	response.Response.Signature = base64URL.EncodeToString(signature)
+	response.Response.UserHandle = base64URL.EncodeToString(auth.UserHandle)
+	return response
+}
+
+func (auth *Authenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(auth.RPID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, auth.SignCount)
+}
+
+func (auth *Authenticator) clientData(ceremony string, challenge string) string {
+	raw, _ := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      auth.Origin,
+		"crossOrigin": false,
+	})
+	return base64URL.EncodeToString(raw)
+}
+
+// minimal CBOR encoder: just what the responses above need
+
+func encodeHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+}
+
+func encodeInt(value int64) []byte {
+	if value < 0 {
+		return encodeHead(1, uint64(-1-value))
+	}
+	return encodeHead(0, uint64(value))
+}
+
+func encodeBytes(value []byte) []byte {
+	return append(encodeHead(2, uint64(len(value))), value...)
+}
+
+func encodeText(value string) []byte {
+	return append(encodeHead(3, uint64(len(value))), value...)
+}
+
+// keys and values alternate
+func encodeMap(items ...[]byte) []byte {
+	encoded := encodeHead(5, uint64(len(items)/2))
+	for _, item := range items {
+		encoded = append(encoded, item...)
+	}
+	return encoded
+}
@@ -0,0 +1,102 @@
+package cleanup
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"time"
+)
+
+// counters and last run of every job, served on /debug/vars as "cleanup",
+// e.g. "refresh_tokens.deleted"
+var metrics = expvar.NewMap("cleanup")
+
+// Job deletes what is no longer needed and reports how many rows
+type Job func(ctx context.Context) (int64, error)
+
+// Lock keeps a job from running on several replicas at once
+type Lock interface {
+	// locked is false if another replica holds the lock; unlock is set only when locked
+	TryLock(ctx context.Context) (unlock func(), locked bool, err error)
+}
+
+// Scheduler runs a job in process every interval, starting right away
+type Scheduler struct {
+	name     string
+	interval time.Duration
+	job      Job
+	lock     Lock
+}
+
+// lock may be nil when there is only one process (sqlite)
+func NewScheduler(name string, interval time.Duration, job Job, lock Lock) *Scheduler {
+	return &Scheduler{
+		name:     name,
+		interval: interval,
+		job:      job,
+		lock:     lock,
+	}
+}
+
+// blocks until ctx is done
+func (scheduler *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(scheduler.interval)
+	defer ticker.Stop()
+
+	for {
+		scheduler.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// failures are logged and counted, the next run tries again
+func (scheduler *Scheduler) RunOnce(ctx context.Context) {
+	if scheduler.lock != nil {
+		unlock, locked, err := scheduler.lock.TryLock(ctx)
+		if err != nil {
+			log.Printf("cleanup %s: failed to take lock: %v", scheduler.name, err)
+			scheduler.add("failures", 1)
+			return
+		}
+		if !locked {
+			// another replica is at it
+			scheduler.add("skipped", 1)
+			return
+		}
+		defer unlock()
+	}
+
+	started := time.Now()
+	deleted, err := scheduler.job(ctx)
+	took := time.Since(started)
+
+	scheduler.add("runs", 1)
+	scheduler.add("deleted", deleted)
+	scheduler.set("last_run", started.Unix())
+	scheduler.set("last_deleted", deleted)
+	scheduler.set("last_duration_ms", took.Milliseconds())
+
+	if err != nil {
+		// rows of finished batches stay deleted
+		log.Printf("cleanup %s: failed after deleting %d rows in %s: %v", scheduler.name, deleted, took, err)
+		scheduler.add("failures", 1)
+		return
+	}
+
+	log.Printf("cleanup %s: deleted %d rows in %s", scheduler.name, deleted, took)
+}
+
+func (scheduler *Scheduler) add(metric string, delta int64) {
+	metrics.Add(scheduler.name+"."+metric, delta)
+}
+
+func (scheduler *Scheduler) set(metric string, value int64) {
+	gauge := new(expvar.Int)
+	gauge.Set(value)
+	metrics.Set(scheduler.name+"."+metric, gauge)
+}
@@ -0,0 +1,114 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+)
+
+type fakeLock struct {
+	locked   bool
+	err      error
+	unlocked bool
+}
+
+func (lock *fakeLock) TryLock(ctx context.Context) (func(), bool, error) {
+	if lock.err != nil || !lock.locked {
+		return nil, false, lock.err
+	}
+	return func() { lock.unlocked = true }, true, nil
+}
+
+func metric(test *testing.T, name string) int64 {
+	test.Helper()
+
+	value, ok := metrics.Get(name).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return value.Value()
+}
+
+func TestScheduler_RunOnce(test *testing.T) {
+	lock := &fakeLock{locked: true}
+	scheduler := NewScheduler("test_run", time.Hour, func(ctx context.Context) (int64, error) {
+		return 42, nil
+	}, lock)
+
+	scheduler.RunOnce(context.Background())
+	scheduler.RunOnce(context.Background())
+
+	if !lock.unlocked {
+		test.Fatal("expected the lock to be released")
+	}
+	if metric(test, "test_run.runs") != 2 || metric(test, "test_run.deleted") != 84 || metric(test, "test_run.last_deleted") != 42 {
+		test.Fatalf("unexpected metrics %s", metrics.String())
+	}
+	if metric(test, "test_run.last_run") == 0 {
+		test.Fatal("expected the time of the last run")
+	}
+}
+
+func TestScheduler_SkipsWhileAnotherReplicaRuns(test *testing.T) {
+	called := false
+	scheduler := NewScheduler("test_skip", time.Hour, func(ctx context.Context) (int64, error) {
+		called = true
+		return 0, nil
+	}, &fakeLock{locked: false})
+
+	scheduler.RunOnce(context.Background())
+
+	if called {
+		test.Fatal("expected the job not to run without the lock")
+	}
+	if metric(test, "test_skip.skipped") != 1 || metric(test, "test_skip.runs") != 0 {
+		test.Fatalf("unexpected metrics %s", metrics.String())
+	}
+}
+
+func TestScheduler_CountsFailures(test *testing.T) {
+	scheduler := NewScheduler("test_fail", time.Hour, func(ctx context.Context) (int64, error) {
+		return 10, errors.New("db error")
+	}, nil)
+
+	scheduler.RunOnce(context.Background())
+
+	// the batches before the error still count
+	if metric(test, "test_fail.failures") != 1 || metric(test, "test_fail.deleted") != 10 {
+		test.Fatalf("unexpected metrics %s", metrics.String())
+	}
+
+	NewScheduler("test_fail", time.Hour, nil, &fakeLock{err: errors.New("connection refused")}).RunOnce(context.Background())
+
+	if metric(test, "test_fail.failures") != 2 {
+		test.Fatalf("expected a lock failure to count, got %s", metrics.String())
+	}
+}
+
+func TestScheduler_RunStopsWithContext(test *testing.T) {
+	runs := 0
+	scheduler := NewScheduler("test_stop", time.Hour, func(ctx context.Context) (int64, error) {
+		runs++
+		return 0, nil
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		test.Fatal("expected Run to return")
+	}
+	if runs != 1 {
+		test.Fatalf("expected one run at start, got %d", runs)
+	}
+}
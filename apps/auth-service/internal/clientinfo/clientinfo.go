@@ -0,0 +1,26 @@
+// Package clientinfo carries what the HTTP layer knows about the client of a
+// request (address, user agent, device name) through the context to the
+// services that record it, e.g. on a refresh token session.
+package clientinfo
+
+import "context"
+
+// any field may be empty: not every request comes from a browser or names its device
+type Info struct {
+	IP        string
+	UserAgent string
+	// chosen by the client ("Anna's phone"), not verified
+	DeviceName string
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// zero Info if the context has none (background jobs, tests)
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(contextKey{}).(Info)
+	return info
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// one entry of the audit log. Hash covers all other fields, PrevHash included,
+// so every entry vouches for the ones before it (see audit.Verify).
+// UserID, SessionID and ClientIP are empty when not known.
+type AuditEvent struct {
+	Seq        int64
+	Type       string
+	UserID     string
+	SessionID  string
+	ClientIP   string
+	Details    map[string]string
+	OccurredAt time.Time
+	PrevHash   string
+	Hash       string
+}
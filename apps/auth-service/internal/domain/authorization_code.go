@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// issued by /authorize, exchanged once at /token (OAuth 2.0 authorization code grant);
+// only the HMAC hash of the code is stored
+type AuthorizationCode struct {
+	ID            string
+	CodeHash      string
+	ClientID      string
+	UserID        string
+	RedirectURI   string
+	Scope         string
+	Nonce         string
+	CodeChallenge string // PKCE, always S256
+	AMR           []string
+	AuthTime      time.Time
+	ExpiresAt     time.Time
+	UsedAt        *time.Time
+	CreatedAt     time.Time
+	// the session started by the exchange gets the long lifetime
+	RememberMe bool
+}
+
+// parameters of an /authorize request the user is asked to log in for
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	// "remember me" checkbox of the login form
+	RememberMe bool
+}
+
+// parameters of an authorization_code grant at /token
+type CodeExchange struct {
+	Code         string
+	ClientID     string
+	RedirectURI  string
+	CodeVerifier string
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// what a LoginThrottle counts the failed password logins of
+const (
+	// Subject is the normalised email address, so unknown addresses are
+	// throttled like registered ones and the response reveals nothing
+	ThrottleAccount = "account"
+	// Subject is the client IP
+	ThrottleIP = "ip"
+)
+
+// failed password logins of one account or client IP since the last success;
+// logins are refused until BlockedUntil (backoff or lockout)
+type LoginThrottle struct {
+	Kind         string
+	Subject      string
+	Failures     int
+	LastFailedAt time.Time
+	BlockedUntil *time.Time
+}
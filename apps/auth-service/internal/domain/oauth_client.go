@@ -0,0 +1,9 @@
+package domain
+
+// an app allowed to use the authorization code flow (SPA, native or household app);
+// all clients are public clients, PKCE replaces the client secret
+type OAuthClient struct {
+	ID           string   `json:"client_id"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// emailed by "forgot password"; only the HMAC hash is stored, like refresh tokens.
+// UsedAt is set when the password was reset with it (or another token of the user).
+type PasswordResetToken struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
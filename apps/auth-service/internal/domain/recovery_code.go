@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// single-use alternative to the TOTP code, for a lost authenticator;
+// only the HMAC hash is stored. UsedAt records when it was spent.
+type RecoveryCode struct {
+	ID        string
+	UserID    string
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// a login as the user sees it in /sessions: the chain of refresh tokens
+// rotated from it, described by its current token
+type Session struct {
+	ID         string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	// authentication methods of the login (RFC 8176)
+	AMR []string
+	// device name the client chose, or else a summary of its user agent
+	DeviceLabel string
+	// client of the login and of the latest refresh; empty if not known
+	UserAgent  string
+	CreatedIP  string
+	LastUsedIP string
+	// the session of the access token that asked
+	Current bool
+}
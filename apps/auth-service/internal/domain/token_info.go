@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// token types as named by OAuth 2.0 (token_type_hint, introspection)
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// what the service knows about a presented token (RFC 7662 introspection);
+// only Active is meaningful for inactive tokens
+type TokenInfo struct {
+	Active    bool
+	TokenType string
+	Subject   string
+	FamilyID  string
+	Role      string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// tokens handed to the client after a successful login;
+// IDToken is only set for OpenID Connect clients.
+// RefreshExpiresAt is when RefreshToken expires unless it is used before.
+// MFAToken alone is set when the password step passed but a second factor
+// is still required
+type IssuedTokens struct {
+	AccessToken  string
+	RefreshToken string
+	IDToken      string
+	MFAToken     string
+
+	RefreshExpiresAt time.Time
+}
+
+// OpenID Connect part of a login: the client the ID token is issued to
+// and the nonce it sent. Empty ClientID means no ID token is wanted.
+type IDTokenRequest struct {
+	ClientID string
+	Nonce    string
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// a user's TOTP second factor; the secret is stored encrypted.
+// ConfirmedAt is nil while enrollment waits for the first valid code.
+type TOTPCredential struct {
+	UserID          string
+	SecretEncrypted string
+	ConfirmedAt     *time.Time
+	// last accepted 30s period, codes at or before it are replays
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// shown to the user once when enrollment starts: the secret for manual entry
+// and the otpauth:// URI for the QR code
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// authentication methods (amr claim, RFC 8176)
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	AMRMFA      = "mfa"
+	// passkey: proof of possession of a hardware-bound key plus user verification
+	AMRHardwareKey      = "hwk"
+	AMRUserVerification = "user"
+)
+
+// state carried by the MFA challenge token between the password step
+// and the second factor step of a login
+type MFAChallenge struct {
+	UserID   string
+	AuthTime time.Time
+	IDToken  IDTokenRequest
+	// the login asked for the long session lifetime
+	RememberMe bool
+}
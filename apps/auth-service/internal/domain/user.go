@@ -6,5 +6,13 @@ type User struct {
 	ID           string
 	Email        string
 	PasswordHash string
-	CreatedAt    time.Time
+	// nil until the user proves access to the address
+	EmailVerifiedAt *time.Time
+	CreatedAt       time.Time
+}
+
+// claim of a verification link: the user and the address the link was sent to
+type EmailVerification struct {
+	UserID string
+	Email  string
 }
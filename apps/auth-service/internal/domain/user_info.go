@@ -0,0 +1,10 @@
+package domain
+
+// claims about the authenticated user, served at /userinfo (OpenID Connect)
+type UserInfo struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FamilyID      string
+	Role          string
+}
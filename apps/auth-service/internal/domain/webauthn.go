@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// a passkey registered by a user. ID is the base64url credential ID chosen
+// by the authenticator; PublicKey is PKIX DER, Algorithm its COSE algorithm.
+type WebAuthnCredential struct {
+	ID        string
+	UserID    string
+	PublicKey []byte
+	Algorithm int64
+	// authenticators that keep a counter increase it on every assertion;
+	// passkeys synced between devices always report 0
+	SignCount  uint32
+	Transports []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// ceremonies a WebAuthn challenge is issued for
+const (
+	WebAuthnRegistration = "registration"
+	WebAuthnLogin        = "login"
+)
+
+// server-side state of a started WebAuthn ceremony, single use.
+// UserID is empty for a login: the passkey tells who the user is.
+type WebAuthnChallenge struct {
+	ID        string
+	UserID    string
+	Ceremony  string
+	Challenge string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
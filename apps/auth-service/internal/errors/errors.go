@@ -1,11 +1,43 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrNotFound            = errors.New("not found")
 	ErrAlreadyExists       = errors.New("already exists")
 	ErrInvalidCredentials  = errors.New("invalid credentials")
 	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrInvalidAccessToken  = errors.New("invalid access token")
 	ErrUserAlreadyExists   = errors.New("user already exists")
+	// email verification
+	ErrEmailNotVerified         = errors.New("email not verified")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	// password reset and policy
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+	ErrWeakPassword      = errors.New("password does not meet the policy")
+	// multi-factor authentication
+	ErrMFARequired         = errors.New("second factor required")
+	ErrInvalidOTP          = errors.New("invalid one-time code")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired mfa challenge")
+	ErrMFAAlreadyEnabled   = errors.New("mfa already enabled")
+	ErrMFANotEnrolled      = errors.New("no pending mfa enrollment")
+	// passkeys (WebAuthn)
+	ErrInvalidPasskey         = errors.New("invalid passkey")
+	ErrInvalidPasskeyCeremony = errors.New("invalid or expired passkey ceremony")
+	// OAuth 2.0 authorization code flow
+	ErrUnknownClient               = errors.New("unknown client")
+	ErrInvalidRedirectURI          = errors.New("redirect uri not allowed")
+	ErrInvalidAuthorizationRequest = errors.New("invalid authorization request")
+	ErrInvalidGrant                = errors.New("invalid grant")
+	// audit log edited, reordered or cut short (see audit.Verify)
+	ErrAuditChainBroken = errors.New("audit chain broken")
+	// password login refused while the account or client IP is blocked after
+	// failed attempts; to the client it is just another invalid credential
+	ErrLoginThrottled = fmt.Errorf("%w: too many failed attempts", ErrInvalidCredentials)
+	// already rotated refresh token presented again;
+	// to the client it is just another invalid refresh token
+	ErrRefreshTokenReused = fmt.Errorf("%w: token reused", ErrInvalidRefreshToken)
 )
@@ -0,0 +1,49 @@
+// Package mail sends the emails of the account flows (verification, password reset).
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidMessage = errors.New("invalid mail message")
+
+// plain text only
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// implemented by SMTPMailer (production), FileMailer (local dev) and MemoryMailer (tests)
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// RFC 5322 message with the headers every implementation writes
+func format(from string, message Message, date time.Time) ([]byte, error) {
+	// a line break in a header would let user input add headers (recipients)
+	for _, header := range []string{from, message.To, message.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, ErrInvalidMessage
+		}
+	}
+	if message.To == "" {
+		return nil, ErrInvalidMessage
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", from)
+	fmt.Fprintf(&builder, "To: %s\r\n", message.To)
+	fmt.Fprintf(&builder, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&builder, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return []byte(builder.String()), nil
+}
@@ -0,0 +1,95 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(test *testing.T) {
+	date := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	raw, err := format("Family Space <no-reply@family.example>", Message{
+		To:      "anna@example.com",
+		Subject: "Verify your email",
+		Body:    "line 1\nline 2",
+	}, date)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "From: Family Space <no-reply@family.example>\r\n" +
+		"To: anna@example.com\r\n" +
+		"Subject: Verify your email\r\n" +
+		"Date: Fri, 02 Jan 2026 03:04:05 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		"line 1\r\nline 2"
+	if string(raw) != expected {
+		test.Fatalf("unexpected message:\n%q", raw)
+	}
+}
+
+func TestFormat_HeaderInjection(test *testing.T) {
+	cases := []Message{
+		{To: "anna@example.com\r\nBcc: eve@example.com", Subject: "s"},
+		{To: "anna@example.com", Subject: "s\nBcc: eve@example.com"},
+		{To: "", Subject: "s"},
+	}
+
+	for _, message := range cases {
+		if _, err := format("from@example.com", message, time.Now()); !errors.Is(err, ErrInvalidMessage) {
+			test.Fatalf("expected ErrInvalidMessage for %+v, got %v", message, err)
+		}
+	}
+}
+
+func TestMemoryMailer(test *testing.T) {
+	mailer := NewMemoryMailer()
+
+	message := Message{To: "anna@example.com", Subject: "hello", Body: "body"}
+	if err := mailer.Send(context.Background(), message); err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := mailer.Sent()
+	if len(sent) != 1 || sent[0] != message {
+		test.Fatalf("unexpected sent messages %+v", sent)
+	}
+
+	mailer.Err = errors.New("relay down")
+	if err := mailer.Send(context.Background(), message); err == nil {
+		test.Fatalf("expected error")
+	}
+}
+
+func TestFileMailer(test *testing.T) {
+	dir := test.TempDir()
+
+	mailer, err := NewFileMailer(dir, "no-reply@family.example")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	err = mailer.Send(context.Background(), Message{To: "anna@example.com", Subject: "hello", Body: "body"})
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".eml") {
+		test.Fatalf("expected one .eml file, got %v (%v)", entries, err)
+	}
+
+	raw, err := os.ReadFile(dir + "/" + entries[0].Name())
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(raw), "To: anna@example.com\r\n") || !strings.HasSuffix(string(raw), "\r\n\r\nbody") {
+		test.Fatalf("unexpected file content %q", raw)
+	}
+}
@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryMailer keeps sent messages for tests to inspect. Safe for concurrent use.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+	// returned by Send when set, to test delivery failures
+	Err error
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (mailer *MemoryMailer) Send(ctx context.Context, message Message) error {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	if mailer.Err != nil {
+		return mailer.Err
+	}
+	// same validation as the real mailers
+	if _, err := format("", message, time.Time{}); err != nil {
+		return err
+	}
+
+	mailer.messages = append(mailer.messages, message)
+	return nil
+}
+
+// copy of the messages sent so far, oldest first
+func (mailer *MemoryMailer) Sent() []Message {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	return append([]Message(nil), mailer.messages...)
+}
@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer delivers through a relay (submission port, STARTTLS when offered).
+// net/smtp has no context support: ctx is only checked before connecting.
+type SMTPMailer struct {
+	addr string
+	from string
+	// nil without credentials (e.g. a relay that trusts the cluster network)
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(host string, port string, username string, password string, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		// refuses to send the password over an unencrypted connection (except to localhost)
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+		auth: auth,
+	}
+}
+
+func (mailer *SMTPMailer) Send(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	raw, err := format(mailer.from, message, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return smtp.SendMail(mailer.addr, mailer.auth, mailer.from, []string{message.To}, raw)
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+// DBStore keeps the buckets in the database, so the limit holds across replicas;
+// every request costs one write
+type DBStore struct {
+	transactionMgr storage.TransactionMgr
+	buckets        storage.RateLimitBucketStoreProvider
+}
+
+func NewDBStore(transactionMgr storage.TransactionMgr, buckets storage.RateLimitBucketStoreProvider) *DBStore {
+	return &DBStore{
+		transactionMgr: transactionMgr,
+		buckets:        buckets,
+	}
+}
+
+func (store *DBStore) Take(ctx context.Context, key string, limit Limit) (decision Decision, err error) {
+	exec, finish, err := store.transactionMgr.BeginTransaction(ctx, false)
+	if err != nil {
+		return Decision{}, err
+	}
+	defer func() {
+		finish(err)
+	}()
+
+	tokens, allowed, err := store.buckets(exec).Take(
+		ctx,
+		key,
+		float64(limit.Requests),
+		limit.refillRate(),
+		time.Now(),
+	)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	return decide(limit, tokens, allowed), nil
+}
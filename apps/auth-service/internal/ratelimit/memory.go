@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// how often idle buckets are dropped
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens     float64
+	refilledAt time.Time
+	limit      Limit
+}
+
+// MemoryStore keeps the buckets in process: each replica limits on its own,
+// so N replicas allow up to N times the limit
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.now()
+	store.sweep(now)
+
+	current, ok := store.buckets[key]
+	if !ok {
+		current = &bucket{tokens: float64(limit.Requests), refilledAt: now}
+		store.buckets[key] = current
+	}
+
+	tokens, allowed := take(limit, current.tokens, current.refilledAt, now)
+	current.tokens = tokens
+	current.refilledAt = now
+	current.limit = limit
+
+	return decide(limit, tokens, allowed), nil
+}
+
+// a bucket that has refilled completely is the same as none
+func (store *MemoryStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < sweepInterval {
+		return
+	}
+	store.lastSweep = now
+
+	for key, idle := range store.buckets {
+		if now.Sub(idle.refilledAt) >= idle.limit.Period {
+			delete(store.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package ratelimit limits requests with token buckets: every key (client IP,
+// login email) has a bucket of Limit.Requests tokens that refills evenly over
+// Limit.Period, and each request takes one token.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Requests per Period, which may all be spent at once (the burst);
+// the zero Limit means unlimited
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// parses "10/1m" (10 requests a minute); "" and "off" mean unlimited
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "off" {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q: expected requests/period, e.g. 10/1m", value)
+	}
+
+	limit := Limit{}
+	var err error
+	if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests < 1 {
+		return Limit{}, fmt.Errorf("rate limit %q: requests must be a positive number", value)
+	}
+	if limit.Period, err = time.ParseDuration(period); err != nil || limit.Period <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: period must be a positive duration", value)
+	}
+
+	return limit, nil
+}
+
+func (limit Limit) Unlimited() bool {
+	return limit.Requests == 0
+}
+
+// tokens per second
+func (limit Limit) refillRate() float64 {
+	return float64(limit.Requests) / limit.Period.Seconds()
+}
+
+// outcome of taking a token, with what the RateLimit headers report
+type Decision struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// until the bucket is full again
+	Reset time.Duration
+	// until the next request is allowed; 0 if this one was
+	RetryAfter time.Duration
+}
+
+// the decision for a bucket left with tokens after the request
+func decide(limit Limit, tokens float64, allowed bool) Decision {
+	rate := limit.refillRate()
+
+	decision := Decision{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(limit.Requests) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		decision.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+
+	return decision
+}
+
+// implemented by MemoryStore (one replica) and DBStore (shared by all replicas)
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+// the token bucket itself, shared by the stores: refills tokens for the time
+// since refilledAt and takes one if there is one
+func take(limit Limit, tokens float64, refilledAt time.Time, now time.Time) (float64, bool) {
+	elapsed := math.Max(0, now.Sub(refilledAt).Seconds())
+	tokens = math.Min(float64(limit.Requests), tokens+elapsed*limit.refillRate())
+
+	if tokens < 1 {
+		return tokens, false
+	}
+	return tokens - 1, true
+}
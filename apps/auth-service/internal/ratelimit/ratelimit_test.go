@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(test *testing.T) {
+	limit, err := ParseLimit("10/1m")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+	if limit.Requests != 10 || limit.Period != time.Minute {
+		test.Fatalf("unexpected limit %+v", limit)
+	}
+
+	for _, value := range []string{"", "off"} {
+		limit, err := ParseLimit(value)
+		if err != nil || !limit.Unlimited() {
+			test.Fatalf("expected %q to be unlimited, got %+v, %v", value, limit, err)
+		}
+	}
+
+	for _, value := range []string{"10", "0/1m", "ten/1m", "10/soon", "10/-1m"} {
+		if _, err := ParseLimit(value); err == nil {
+			test.Fatalf("expected an error for %q", value)
+		}
+	}
+}
+
+func newTestMemoryStore(now *time.Time) *MemoryStore {
+	store := NewMemoryStore()
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+func TestMemoryStore_BurstThenRefill(test *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestMemoryStore(&now)
+	limit := Limit{Requests: 3, Period: time.Minute}
+
+	for want := 2; want >= 0; want-- {
+		decision, _ := store.Take(context.Background(), "ip:192.0.2.1", limit)
+		if !decision.Allowed || decision.Remaining != want {
+			test.Fatalf("expected allowed with %d remaining, got %+v", want, decision)
+		}
+	}
+
+	decision, _ := store.Take(context.Background(), "ip:192.0.2.1", limit)
+	if decision.Allowed {
+		test.Fatalf("expected the fourth request to be denied")
+	}
+	// one token every 20 seconds
+	if decision.RetryAfter != 20*time.Second || decision.Reset != time.Minute {
+		test.Fatalf("unexpected retry after %s, reset %s", decision.RetryAfter, decision.Reset)
+	}
+
+	// other keys have their own bucket
+	if decision, _ := store.Take(context.Background(), "ip:192.0.2.2", limit); !decision.Allowed {
+		test.Fatalf("expected another key to be allowed")
+	}
+
+	now = now.Add(20 * time.Second)
+	if decision, _ := store.Take(context.Background(), "ip:192.0.2.1", limit); !decision.Allowed {
+		test.Fatalf("expected a refilled token to be allowed")
+	}
+}
+
+func TestMemoryStore_DropsIdleBuckets(test *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestMemoryStore(&now)
+	limit := Limit{Requests: 3, Period: time.Minute}
+
+	store.Take(context.Background(), "ip:192.0.2.1", limit)
+
+	now = now.Add(2 * time.Minute)
+	store.Take(context.Background(), "ip:192.0.2.2", limit)
+
+	if _, ok := store.buckets["ip:192.0.2.1"]; ok || len(store.buckets) != 1 {
+		test.Fatalf("expected the idle bucket to be dropped, got %d buckets", len(store.buckets))
+	}
+}
@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+// append-only; the chain head (last seq and hash) is kept in its own row,
+// so cutting events off the end of the log is detected too
+type AuditEventStore interface {
+	// reserves the sequence number of the next event and returns it with the hash
+	// of the current head ("" for the first event); the head stays locked until the
+	// transaction ends, so concurrent appends cannot fork the chain
+	NextLink(ctx context.Context) (seq int64, prevHash string, err error)
+	// stores the event and makes it the head; Seq must come from NextLink
+	Append(ctx context.Context, event domain.AuditEvent) error
+	// ErrNotFound while the log is empty
+	Head(ctx context.Context) (seq int64, hash string, err error)
+	// events with Seq > afterSeq in order, at most limit
+	ListAfter(ctx context.Context, afterSeq int64, limit int) ([]domain.AuditEvent, error)
+}
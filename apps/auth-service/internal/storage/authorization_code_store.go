@@ -0,0 +1,15 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+type AuthorizationCodeStore interface {
+	Create(ctx context.Context, code domain.AuthorizationCode) error
+	GetByHash(ctx context.Context, hash string) (domain.AuthorizationCode, error)
+	// marks the code as exchanged; ErrNotFound if it already was,
+	// so two concurrent exchanges cannot both succeed
+	MarkUsed(ctx context.Context, id string) error
+}
@@ -10,4 +10,5 @@ import (
 // just capabilities needed by the stores, no implementation details
 type FamilyStore interface {
 	Create(ctx context.Context, family domain.Family) error
+	GetByID(ctx context.Context, id string) (domain.Family, error)
 }
@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+type LoginThrottleStore interface {
+	// ErrNotFound if there was no failed login since the last success
+	Get(ctx context.Context, kind string, subject string) (domain.LoginThrottle, error)
+	// counts a failed login atomically and returns the new count;
+	// failures before resetBefore are forgotten and the count starts over
+	RecordFailure(ctx context.Context, kind string, subject string, failedAt time.Time, resetBefore time.Time) (int, error)
+	Block(ctx context.Context, kind string, subject string, until time.Time) error
+	// after a successful login
+	Delete(ctx context.Context, kind string, subject string) error
+}
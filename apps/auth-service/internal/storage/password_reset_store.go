@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+type PasswordResetTokenStore interface {
+	Create(ctx context.Context, token domain.PasswordResetToken) error
+	GetByHash(ctx context.Context, hash string) (domain.PasswordResetToken, error)
+	// ErrNotFound if the token was already used, so two concurrent resets cannot both succeed
+	MarkUsed(ctx context.Context, id string) error
+	// marks every unused token of the user used (after a reset, older links stop working)
+	InvalidateAllForUser(ctx context.Context, userID string) error
+}
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log"
+)
+
+// AdvisoryLock is a session level postgres advisory lock: held by one
+// connection until unlocked, or until the connection ends with its replica
+type AdvisoryLock struct {
+	db  *sql.DB
+	key int64
+}
+
+func NewAdvisoryLock(db *sql.DB, key int64) *AdvisoryLock {
+	return &AdvisoryLock{db: db, key: key}
+}
+
+// does not wait: locked is false while another replica holds the lock
+func (lock *AdvisoryLock) TryLock(ctx context.Context) (unlock func(), locked bool, err error) {
+	// the lock belongs to the connection, so it is taken out of the pool
+	conn, err := lock.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, lock.key).Scan(&locked)
+	if err != nil || !locked {
+		conn.Close()
+		return nil, false, err
+	}
+
+	unlock = func() {
+		// also on shutdown, when ctx is already done
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lock.key); err != nil {
+			log.Printf("failed to release advisory lock %d: %v", lock.key, err)
+			// back in the pool the connection would keep holding the lock
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+
+	return unlock, true, nil
+}
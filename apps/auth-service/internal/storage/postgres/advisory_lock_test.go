@@ -0,0 +1,34 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage/postgres"
+)
+
+func TestAdvisoryLock_OneHolderAtATime(test *testing.T) {
+	db := newTestDB(test)
+	ctx := context.Background()
+
+	// two replicas
+	first := postgres.NewAdvisoryLock(db, 42)
+	second := postgres.NewAdvisoryLock(db, 42)
+
+	unlock, locked, err := first.TryLock(ctx)
+	require.NoError(test, err)
+	require.True(test, locked)
+
+	_, locked, err = second.TryLock(ctx)
+	require.NoError(test, err)
+	require.False(test, locked)
+
+	unlock()
+
+	unlock, locked, err = second.TryLock(ctx)
+	require.NoError(test, err)
+	require.True(test, locked)
+	unlock()
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type AuthorizationCodeStore struct {
+	exec storage.SQLExecutor
+}
+
+func NewAuthorizationCodeStore(exec storage.SQLExecutor) storage.AuthorizationCodeStore {
+	return &AuthorizationCodeStore{exec: exec}
+}
+
+func (store *AuthorizationCodeStore) Create(
+	ctx context.Context,
+	code domain.AuthorizationCode,
+) error {
+
+	query := `
+		INSERT INTO authorization_codes (
+			id, code_hash, client_id, user_id, redirect_uri, scope, nonce,
+			code_challenge, amr, auth_time, expires_at, used_at, created_at, remember_me
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := store.exec.ExecContext(
+		ctx,
+		query,
+		code.ID,
+		code.CodeHash,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		code.Scope,
+		code.Nonce,
+		code.CodeChallenge,
+		strings.Join(code.AMR, " "),
+		code.AuthTime,
+		code.ExpiresAt,
+		code.UsedAt,
+		code.CreatedAt,
+		code.RememberMe,
+	)
+
+	return err
+}
+
+func (store *AuthorizationCodeStore) GetByHash(
+	ctx context.Context,
+	hash string,
+) (domain.AuthorizationCode, error) {
+
+	query := `
+		SELECT id, code_hash, client_id, user_id, redirect_uri, scope, nonce,
+		       code_challenge, amr, auth_time, expires_at, used_at, created_at, remember_me
+		FROM authorization_codes
+		WHERE code_hash = $1
+	`
+
+	var code domain.AuthorizationCode
+	var amr string
+	var used sql.NullTime
+
+	err := store.exec.QueryRowContext(ctx, query, hash).Scan(
+		&code.ID,
+		&code.CodeHash,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		&code.Scope,
+		&code.Nonce,
+		&code.CodeChallenge,
+		&amr,
+		&code.AuthTime,
+		&code.ExpiresAt,
+		&used,
+		&code.CreatedAt,
+		&code.RememberMe,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.AuthorizationCode{}, errs.ErrNotFound
+		}
+		return domain.AuthorizationCode{}, err
+	}
+
+	if used.Valid {
+		code.UsedAt = &used.Time
+	}
+	code.AMR = strings.Fields(amr)
+
+	return code, nil
+}
+
+func (store *AuthorizationCodeStore) MarkUsed(
+	ctx context.Context,
+	id string,
+) error {
+
+	query := `
+		UPDATE authorization_codes
+		SET used_at = $1
+		WHERE id = $2
+		  AND used_at IS NULL
+	`
+
+	res, err := store.exec.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
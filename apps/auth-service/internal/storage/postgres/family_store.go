@@ -2,6 +2,8 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 
 	"github.com/jackc/pgconn"
 
@@ -54,3 +56,30 @@ func (store *FamilyStore) Create(
 
 	return nil
 }
+
+func (store *FamilyStore) GetByID(
+	ctx context.Context,
+	id string,
+) (domain.Family, error) {
+
+	const query = `
+		SELECT id, name, created_at
+		FROM families
+		WHERE id = $1
+	`
+
+	var family domain.Family
+	err := store.sql.QueryRowContext(ctx, query, id).Scan(
+		&family.ID,
+		&family.Name,
+		&family.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Family{}, errs.ErrNotFound
+		}
+		return domain.Family{}, err
+	}
+
+	return family, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage/postgres"
 )
 
@@ -42,3 +43,19 @@ func TestFamilyStore_Create_AlreadyExists(test *testing.T) {
 
 	require.ErrorContains(test, err, "duplicate key value violates unique constraint")
 }
+
+func TestFamilyStore_GetByID(test *testing.T) {
+	db := newTestDB(test)
+	store := postgres.NewFamilyStore(db)
+
+	ctx := context.Background()
+	family := newTestFamily()
+	require.NoError(test, store.Create(ctx, family))
+
+	got, err := store.GetByID(ctx, family.ID)
+	require.NoError(test, err)
+	require.Equal(test, family.Name, got.Name)
+
+	_, err = store.GetByID(ctx, uuid.NewString())
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
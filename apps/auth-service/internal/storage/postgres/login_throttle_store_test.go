@@ -0,0 +1,121 @@
+package postgres_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage/postgres"
+)
+
+func TestLoginThrottleStore_RecordFailure(test *testing.T) {
+	store := postgres.NewLoginThrottleStore(newTestDB(test))
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	_, err := store.Get(ctx, domain.ThrottleAccount, "a@b.com")
+	require.ErrorIs(test, err, errs.ErrNotFound)
+
+	for want := 1; want <= 3; want++ {
+		failures, err := store.RecordFailure(ctx, domain.ThrottleAccount, "a@b.com", now, now.Add(-time.Hour))
+		require.NoError(test, err)
+		require.Equal(test, want, failures)
+	}
+
+	// counted separately
+	failures, err := store.RecordFailure(ctx, domain.ThrottleIP, "a@b.com", now, now.Add(-time.Hour))
+	require.NoError(test, err)
+	require.Equal(test, 1, failures)
+
+	got, err := store.Get(ctx, domain.ThrottleAccount, "a@b.com")
+	require.NoError(test, err)
+	require.Equal(test, 3, got.Failures)
+	require.WithinDuration(test, now, got.LastFailedAt, time.Second)
+	require.Nil(test, got.BlockedUntil)
+}
+
+func TestLoginThrottleStore_RecordFailure_StartsOverAfterWindow(test *testing.T) {
+	store := postgres.NewLoginThrottleStore(newTestDB(test))
+
+	ctx := context.Background()
+	earlier := time.Now().UTC().Add(-2 * time.Hour)
+	now := time.Now().UTC()
+
+	_, err := store.RecordFailure(ctx, domain.ThrottleIP, "192.0.2.1", earlier, earlier.Add(-time.Hour))
+	require.NoError(test, err)
+	_, err = store.RecordFailure(ctx, domain.ThrottleIP, "192.0.2.1", earlier, earlier.Add(-time.Hour))
+	require.NoError(test, err)
+
+	failures, err := store.RecordFailure(ctx, domain.ThrottleIP, "192.0.2.1", now, now.Add(-time.Hour))
+	require.NoError(test, err)
+	require.Equal(test, 1, failures)
+}
+
+func TestLoginThrottleStore_BlockAndDelete(test *testing.T) {
+	store := postgres.NewLoginThrottleStore(newTestDB(test))
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	until := now.Add(15 * time.Minute)
+
+	_, err := store.RecordFailure(ctx, domain.ThrottleAccount, "a@b.com", now, now.Add(-time.Hour))
+	require.NoError(test, err)
+	require.NoError(test, store.Block(ctx, domain.ThrottleAccount, "a@b.com", until))
+
+	got, err := store.Get(ctx, domain.ThrottleAccount, "a@b.com")
+	require.NoError(test, err)
+	require.NotNil(test, got.BlockedUntil)
+	require.WithinDuration(test, until, *got.BlockedUntil, time.Second)
+
+	require.NoError(test, store.Delete(ctx, domain.ThrottleAccount, "a@b.com"))
+
+	_, err = store.Get(ctx, domain.ThrottleAccount, "a@b.com")
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
+
+// replicas count the failures of one account in transactions of their own
+func TestLoginThrottleStore_RecordFailure_Concurrent(test *testing.T) {
+	db := newTestDB(test)
+	transactionMgr := storage.NewTransactionMgr(db)
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	const attempts = 10
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			exec, finish, err := transactionMgr.BeginTransaction(ctx, false)
+			if err != nil {
+				results <- err
+				return
+			}
+			_, err = postgres.NewLoginThrottleStore(exec).RecordFailure(
+				ctx, domain.ThrottleAccount, "a@b.com", now, now.Add(-time.Hour),
+			)
+			finish(err)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	for err := range results {
+		require.NoError(test, err)
+	}
+
+	got, err := postgres.NewLoginThrottleStore(db).Get(ctx, domain.ThrottleAccount, "a@b.com")
+	require.NoError(test, err)
+	require.Equal(test, attempts, got.Failures)
+}
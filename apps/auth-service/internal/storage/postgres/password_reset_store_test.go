@@ -0,0 +1,78 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage/postgres"
+)
+
+func newTestPasswordResetToken(userID string) domain.PasswordResetToken {
+	now := time.Now().UTC()
+	return domain.PasswordResetToken{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		TokenHash: uuid.NewString(),
+		ExpiresAt: now.Add(time.Hour),
+		CreatedAt: now,
+	}
+}
+
+func TestPasswordResetTokenStore_CreateAndGetByHash(test *testing.T) {
+	store := postgres.NewPasswordResetTokenStore(newTestDB(test))
+
+	ctx := context.Background()
+	token := newTestPasswordResetToken(uuid.NewString())
+	require.NoError(test, store.Create(ctx, token))
+
+	got, err := store.GetByHash(ctx, token.TokenHash)
+	require.NoError(test, err)
+	require.Equal(test, token.ID, got.ID)
+	require.Equal(test, token.UserID, got.UserID)
+	require.WithinDuration(test, token.ExpiresAt, got.ExpiresAt, time.Second)
+	require.Nil(test, got.UsedAt)
+
+	_, err = store.GetByHash(ctx, "unknown")
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
+
+func TestPasswordResetTokenStore_MarkUsed_OnlyOnce(test *testing.T) {
+	store := postgres.NewPasswordResetTokenStore(newTestDB(test))
+
+	ctx := context.Background()
+	token := newTestPasswordResetToken(uuid.NewString())
+	require.NoError(test, store.Create(ctx, token))
+
+	require.NoError(test, store.MarkUsed(ctx, token.ID))
+	require.ErrorIs(test, store.MarkUsed(ctx, token.ID), errs.ErrNotFound)
+
+	got, err := store.GetByHash(ctx, token.TokenHash)
+	require.NoError(test, err)
+	require.NotNil(test, got.UsedAt)
+}
+
+func TestPasswordResetTokenStore_InvalidateAllForUser(test *testing.T) {
+	store := postgres.NewPasswordResetTokenStore(newTestDB(test))
+
+	ctx := context.Background()
+	userID := uuid.NewString()
+	first := newTestPasswordResetToken(userID)
+	second := newTestPasswordResetToken(userID)
+	other := newTestPasswordResetToken(uuid.NewString())
+	for _, token := range []domain.PasswordResetToken{first, second, other} {
+		require.NoError(test, store.Create(ctx, token))
+	}
+
+	require.NoError(test, store.InvalidateAllForUser(ctx, userID))
+
+	require.ErrorIs(test, store.MarkUsed(ctx, first.ID), errs.ErrNotFound)
+	require.ErrorIs(test, store.MarkUsed(ctx, second.ID), errs.ErrNotFound)
+	// tokens of other users stay valid
+	require.NoError(test, store.MarkUsed(ctx, other.ID))
+}
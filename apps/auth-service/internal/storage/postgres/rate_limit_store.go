@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type RateLimitBucketStore struct {
+	exec storage.SQLExecutor
+}
+
+func NewRateLimitBucketStore(exec storage.SQLExecutor) storage.RateLimitBucketStore {
+	return &RateLimitBucketStore{exec: exec}
+}
+
+// $1 key, $2 capacity, $3 refill per second, $4 now (unix seconds);
+// in the update, every column reads the values of the row before it
+func (store *RateLimitBucketStore) Take(
+	ctx context.Context,
+	key string,
+	capacity float64,
+	refillPerSecond float64,
+	now time.Time,
+) (float64, bool, error) {
+
+	query := `
+		INSERT INTO rate_limit_buckets AS bucket (
+			bucket_key, tokens, refilled_at, allowed
+		) VALUES ($1, $2::float8 - 1, $4::float8, TRUE)
+		ON CONFLICT (bucket_key) DO UPDATE
+		SET tokens = CASE
+				WHEN LEAST($2::float8, bucket.tokens + GREATEST(0, $4::float8 - bucket.refilled_at) * $3::float8) >= 1
+				THEN LEAST($2::float8, bucket.tokens + GREATEST(0, $4::float8 - bucket.refilled_at) * $3::float8) - 1
+				ELSE LEAST($2::float8, bucket.tokens + GREATEST(0, $4::float8 - bucket.refilled_at) * $3::float8)
+			END,
+			allowed = LEAST($2::float8, bucket.tokens + GREATEST(0, $4::float8 - bucket.refilled_at) * $3::float8) >= 1,
+			refilled_at = $4::float8
+		RETURNING tokens, allowed
+	`
+
+	var tokens float64
+	var allowed bool
+
+	err := store.exec.QueryRowContext(
+		ctx,
+		query,
+		key,
+		capacity,
+		refillPerSecond,
+		float64(now.UnixNano())/float64(time.Second),
+	).Scan(&tokens, &allowed)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return tokens, allowed, nil
+}
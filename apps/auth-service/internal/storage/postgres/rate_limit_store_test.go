@@ -0,0 +1,48 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage/postgres"
+)
+
+func TestRateLimitBucketStore_Take(test *testing.T) {
+	store := postgres.NewRateLimitBucketStore(newTestDB(test))
+
+	ctx := context.Background()
+	now := time.Now()
+
+	// capacity 2, one token per second
+	for want := 1.0; want >= 0; want-- {
+		tokens, allowed, err := store.Take(ctx, "login:ip:192.0.2.1", 2, 1, now)
+		require.NoError(test, err)
+		require.True(test, allowed)
+		require.InDelta(test, want, tokens, 0.001)
+	}
+
+	tokens, allowed, err := store.Take(ctx, "login:ip:192.0.2.1", 2, 1, now)
+	require.NoError(test, err)
+	require.False(test, allowed)
+	require.InDelta(test, 0, tokens, 0.001)
+
+	// half a second refills half a token: still denied
+	tokens, allowed, err = store.Take(ctx, "login:ip:192.0.2.1", 2, 1, now.Add(500*time.Millisecond))
+	require.NoError(test, err)
+	require.False(test, allowed)
+	require.InDelta(test, 0.5, tokens, 0.001)
+
+	tokens, allowed, err = store.Take(ctx, "login:ip:192.0.2.1", 2, 1, now.Add(time.Second))
+	require.NoError(test, err)
+	require.True(test, allowed)
+	require.InDelta(test, 0, tokens, 0.001)
+
+	// never more than the capacity
+	tokens, allowed, err = store.Take(ctx, "login:ip:192.0.2.1", 2, 1, now.Add(time.Hour))
+	require.NoError(test, err)
+	require.True(test, allowed)
+	require.InDelta(test, 1, tokens, 0.001)
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type RecoveryCodeStore struct {
+	exec storage.SQLExecutor
+}
+
+func NewRecoveryCodeStore(exec storage.SQLExecutor) storage.RecoveryCodeStore {
+	return &RecoveryCodeStore{exec: exec}
+}
+
+// callers run this in a transaction, so the user is never left without codes
+func (store *RecoveryCodeStore) ReplaceAll(
+	ctx context.Context,
+	userID string,
+	codes []domain.RecoveryCode,
+) error {
+
+	_, err := store.exec.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO recovery_codes (
+			id, user_id, code_hash, used_at, created_at
+		) VALUES ($1, $2, $3, $4, $5)
+	`
+
+	for _, code := range codes {
+		_, err := store.exec.ExecContext(
+			ctx,
+			query,
+			code.ID,
+			code.UserID,
+			code.CodeHash,
+			code.UsedAt,
+			code.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *RecoveryCodeStore) GetByHash(
+	ctx context.Context,
+	userID string,
+	hash string,
+) (domain.RecoveryCode, error) {
+
+	query := `
+		SELECT id, user_id, code_hash, used_at, created_at
+		FROM recovery_codes
+		WHERE user_id = $1
+		  AND code_hash = $2
+	`
+
+	var code domain.RecoveryCode
+	var used sql.NullTime
+
+	err := store.exec.QueryRowContext(ctx, query, userID, hash).Scan(
+		&code.ID,
+		&code.UserID,
+		&code.CodeHash,
+		&used,
+		&code.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.RecoveryCode{}, errs.ErrNotFound
+		}
+		return domain.RecoveryCode{}, err
+	}
+
+	if used.Valid {
+		code.UsedAt = &used.Time
+	}
+
+	return code, nil
+}
+
+func (store *RecoveryCodeStore) MarkUsed(
+	ctx context.Context,
+	id string,
+) error {
+
+	query := `
+		UPDATE recovery_codes
+		SET used_at = $1
+		WHERE id = $2
+		  AND used_at IS NULL
+	`
+
+	res, err := store.exec.ExecContext(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage/postgres"
+)
+
+func newTestRecoveryCodes(userID string, count int) []domain.RecoveryCode {
+	codes := make([]domain.RecoveryCode, count)
+	for i := range codes {
+		codes[i] = domain.RecoveryCode{
+			ID:        uuid.NewString(),
+			UserID:    userID,
+			CodeHash:  uuid.NewString(),
+			CreatedAt: time.Now().UTC(),
+		}
+	}
+	return codes
+}
+
+func TestRecoveryCodeStore_ReplaceAllAndGetByHash(test *testing.T) {
+	store := postgres.NewRecoveryCodeStore(newTestDB(test))
+
+	ctx := context.Background()
+	userID := uuid.NewString()
+	first := newTestRecoveryCodes(userID, 2)
+	require.NoError(test, store.ReplaceAll(ctx, userID, first))
+
+	got, err := store.GetByHash(ctx, userID, first[1].CodeHash)
+	require.NoError(test, err)
+	require.Equal(test, first[1].ID, got.ID)
+	require.Nil(test, got.UsedAt)
+
+	// a new batch invalidates the old one
+	second := newTestRecoveryCodes(userID, 2)
+	require.NoError(test, store.ReplaceAll(ctx, userID, second))
+
+	_, err = store.GetByHash(ctx, userID, first[1].CodeHash)
+	require.ErrorIs(test, err, errs.ErrNotFound)
+
+	_, err = store.GetByHash(ctx, userID, second[0].CodeHash)
+	require.NoError(test, err)
+}
+
+func TestRecoveryCodeStore_GetByHash_OtherUser(test *testing.T) {
+	store := postgres.NewRecoveryCodeStore(newTestDB(test))
+
+	ctx := context.Background()
+	codes := newTestRecoveryCodes(uuid.NewString(), 1)
+	require.NoError(test, store.ReplaceAll(ctx, codes[0].UserID, codes))
+
+	_, err := store.GetByHash(ctx, uuid.NewString(), codes[0].CodeHash)
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
+
+func TestRecoveryCodeStore_MarkUsed_OnlyOnce(test *testing.T) {
+	store := postgres.NewRecoveryCodeStore(newTestDB(test))
+
+	ctx := context.Background()
+	codes := newTestRecoveryCodes(uuid.NewString(), 1)
+	require.NoError(test, store.ReplaceAll(ctx, codes[0].UserID, codes))
+
+	require.NoError(test, store.MarkUsed(ctx, codes[0].ID))
+	require.ErrorIs(test, store.MarkUsed(ctx, codes[0].ID), errs.ErrNotFound)
+
+	got, err := store.GetByHash(ctx, codes[0].UserID, codes[0].CodeHash)
+	require.NoError(test, err)
+	require.NotNil(test, got.UsedAt)
+}
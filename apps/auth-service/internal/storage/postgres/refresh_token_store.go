@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
@@ -28,12 +29,23 @@ func (store *RefreshTokenStore) Create(
 		INSERT INTO refresh_tokens (
 			id,
 			user_id,
+			session_id,
+			parent_id,
+			amr,
 			token_hash,
 			expires_at,
 			revoked_at,
-			created_at
+			created_at,
+			session_created_at,
+			session_expires_at,
+			remember_me,
+			user_agent,
+			created_ip,
+			device_name,
+			last_used_ip,
+			last_used_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
 
 	_, err := store.exec.ExecContext(
@@ -41,10 +53,21 @@ func (store *RefreshTokenStore) Create(
 		query,
 		token.ID,
 		token.UserID,
+		token.SessionID,
+		token.ParentID,
+		strings.Join(token.AMR, " "),
 		token.TokenHash,
 		token.ExpiresAt,
 		token.RevokedAt,
 		token.CreatedAt,
+		token.SessionCreatedAt,
+		token.SessionExpiresAt,
+		token.RememberMe,
+		token.UserAgent,
+		token.CreatedIP,
+		token.DeviceName,
+		token.LastUsedIP,
+		token.LastUsedAt,
 	)
 
 	return err
@@ -59,33 +82,69 @@ func (store *RefreshTokenStore) GetByHash(
 		SELECT
 			id,
 			user_id,
+			session_id,
+			parent_id,
+			amr,
 			token_hash,
 			expires_at,
 			revoked_at,
-			created_at
+			created_at,
+			session_created_at,
+			session_expires_at,
+			remember_me,
+			user_agent,
+			created_ip,
+			device_name,
+			last_used_ip,
+			last_used_at
 		FROM refresh_tokens
 		WHERE token_hash = $1
 	`
 
+	token, err := scanRefreshToken(store.exec.QueryRowContext(ctx, query, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return refresh.RefreshToken{}, errs.ErrNotFound
+		}
+		return refresh.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+func scanRefreshToken(row interface{ Scan(...any) error }) (refresh.RefreshToken, error) {
 	var token refresh.RefreshToken
+	var parent sql.NullString
+	var amr string
 	var revoked sql.NullTime
 
-	err := store.exec.QueryRowContext(ctx, query, hash).Scan(
+	err := row.Scan(
 		&token.ID,
 		&token.UserID,
+		&token.SessionID,
+		&parent,
+		&amr,
 		&token.TokenHash,
 		&token.ExpiresAt,
 		&revoked,
 		&token.CreatedAt,
+		&token.SessionCreatedAt,
+		&token.SessionExpiresAt,
+		&token.RememberMe,
+		&token.UserAgent,
+		&token.CreatedIP,
+		&token.DeviceName,
+		&token.LastUsedIP,
+		&token.LastUsedAt,
 	)
-
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return refresh.RefreshToken{}, errs.ErrNotFound
-		}
 		return refresh.RefreshToken{}, err
 	}
 
+	if parent.Valid {
+		token.ParentID = &parent.String
+	}
+	token.AMR = strings.Fields(amr)
 	if revoked.Valid {
 		token.RevokedAt = &revoked.Time
 	}
@@ -121,3 +180,171 @@ func (store *RefreshTokenStore) Revoke(
 
 	return nil
 }
+
+func (store *RefreshTokenStore) RevokeSession(
+	ctx context.Context,
+	sessionID string,
+) error {
+
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $1
+		WHERE session_id = $2
+		  AND revoked_at IS NULL
+	`
+
+	// revoking an already fully revoked session is not an error
+	_, err := store.exec.ExecContext(ctx, query, time.Now().UTC(), sessionID)
+	return err
+}
+
+func (store *RefreshTokenStore) RevokeAllForUser(
+	ctx context.Context,
+	userID string,
+) error {
+
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $1
+		WHERE user_id = $2
+		  AND revoked_at IS NULL
+	`
+
+	// a user without active sessions is not an error
+	_, err := store.exec.ExecContext(ctx, query, time.Now().UTC(), userID)
+	return err
+}
+
+func (store *RefreshTokenStore) RevokeOtherSessions(
+	ctx context.Context,
+	userID string,
+	keepSessionID string,
+) error {
+
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $1
+		WHERE user_id = $2
+		  AND session_id <> $3
+		  AND revoked_at IS NULL
+	`
+
+	_, err := store.exec.ExecContext(ctx, query, time.Now().UTC(), userID, keepSessionID)
+	return err
+}
+
+// the current token of each session is the only one not revoked;
+// expired tokens are included, their ExpiresAt is checked by the caller
+func (store *RefreshTokenStore) ListByUser(
+	ctx context.Context,
+	userID string,
+) ([]refresh.RefreshToken, error) {
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			session_id,
+			parent_id,
+			amr,
+			token_hash,
+			expires_at,
+			revoked_at,
+			created_at,
+			session_created_at,
+			session_expires_at,
+			remember_me,
+			user_agent,
+			created_ip,
+			device_name,
+			last_used_ip,
+			last_used_at
+		FROM refresh_tokens
+		WHERE user_id = $1
+		  AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := store.exec.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []refresh.RefreshToken
+	for rows.Next() {
+		token, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (store *RefreshTokenStore) RevokeUserSession(
+	ctx context.Context,
+	userID string,
+	sessionID string,
+) error {
+
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $1
+		WHERE user_id = $2
+		  AND session_id = $3
+		  AND revoked_at IS NULL
+	`
+
+	res, err := store.exec.ExecContext(ctx, query, time.Now().UTC(), userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// unknown, someone else's or already ended
+	if rows == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+// revoked tokens of a session that is still going are kept until they expire:
+// presented again they reveal a stolen token (reuse detection).
+// Rows locked by a concurrent rotation are skipped, the next run gets them
+func (store *RefreshTokenStore) DeleteStale(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) (int64, error) {
+
+	query := `
+		DELETE FROM refresh_tokens
+		WHERE id IN (
+			SELECT stale.id
+			FROM refresh_tokens AS stale
+			WHERE stale.expires_at < $1
+			   OR (stale.revoked_at < $1 AND NOT EXISTS (
+					SELECT 1
+					FROM refresh_tokens AS active
+					WHERE active.session_id = stale.session_id
+					  AND active.revoked_at IS NULL
+			   ))
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	res, err := store.exec.ExecContext(ctx, query, before.UTC(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
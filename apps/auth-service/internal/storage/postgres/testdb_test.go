@@ -9,6 +9,8 @@ import (
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/migrations"
 )
 
 func newTestDB(test *testing.T) *sql.DB {
@@ -27,9 +29,13 @@ func newTestDB(test *testing.T) *sql.DB {
 
 	require.NoError(test, db.PingContext(ctx))
 
+	// the schema the service runs on
+	_, err = migrations.Apply(ctx, db, migrations.Postgres)
+	require.NoError(test, err)
+
 	// Clean DB before each test
 	_, err = db.Exec(`
-		TRUNCATE TABLE refresh_tokens RESTART IDENTITY CASCADE;
+		TRUNCATE TABLE refresh_tokens, authorization_codes, totp_credentials, recovery_codes, webauthn_credentials, webauthn_challenges, password_reset_tokens, login_throttles, rate_limit_buckets, audit_events, audit_chain RESTART IDENTITY CASCADE;
 	`)
 	require.NoError(test, err)
 
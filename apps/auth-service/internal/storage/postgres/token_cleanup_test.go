@@ -0,0 +1,71 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/cleanup"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage/postgres"
+)
+
+// the job as main wires it for postgres: advisory lock, postgres store, several batches
+func TestRefreshTokenCleanup_Job(test *testing.T) {
+	db := newTestDB(test)
+	store := postgres.NewRefreshTokenStore(db)
+
+	ctx := context.Background()
+	longAgo := time.Now().UTC().Add(-8 * 24 * time.Hour)
+
+	var stale []refresh.RefreshToken
+	for i := 0; i < 3; i++ {
+		token := newTestToken()
+		token.ExpiresAt = longAgo
+		require.NoError(test, store.Create(ctx, token))
+		stale = append(stale, token)
+	}
+	current := newTestToken()
+	require.NoError(test, store.Create(ctx, current))
+
+	cleanupService := service.NewTokenCleanupService(
+		storage.NewTransactionMgr(db),
+		postgres.NewRefreshTokenStore,
+		postgres.NewWebAuthnChallengeStore,
+		7*24*time.Hour,
+		2,
+	)
+
+	// the job itself, the scheduler only logs its errors
+	deleted, err := cleanupService.PurgeRefreshTokens(ctx)
+	require.NoError(test, err)
+	require.Equal(test, int64(3), deleted)
+
+	for _, token := range stale {
+		_, err := store.GetByHash(ctx, token.TokenHash)
+		require.ErrorIs(test, err, errs.ErrNotFound)
+	}
+	_, err = store.GetByHash(ctx, current.TokenHash)
+	require.NoError(test, err)
+
+	// a scheduled run takes the advisory lock first
+	expired := newTestToken()
+	expired.ExpiresAt = longAgo
+	require.NoError(test, store.Create(ctx, expired))
+
+	scheduler := cleanup.NewScheduler(
+		"refresh_tokens_test",
+		time.Hour,
+		cleanupService.PurgeRefreshTokens,
+		postgres.NewAdvisoryLock(db, 42),
+	)
+	scheduler.RunOnce(ctx)
+
+	_, err = store.GetByHash(ctx, expired.TokenHash)
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
@@ -0,0 +1,86 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage/postgres"
+)
+
+func newTestTOTPCredential() domain.TOTPCredential {
+	return domain.TOTPCredential{
+		UserID:          uuid.NewString(),
+		SecretEncrypted: "sealed-secret",
+		CreatedAt:       time.Now().UTC(),
+	}
+}
+
+func TestTOTPStore_SavePendingAndConfirm(test *testing.T) {
+	store := postgres.NewTOTPStore(newTestDB(test))
+
+	ctx := context.Background()
+	credential := newTestTOTPCredential()
+	require.NoError(test, store.SavePending(ctx, credential))
+
+	got, err := store.GetByUserID(ctx, credential.UserID)
+	require.NoError(test, err)
+	require.Equal(test, "sealed-secret", got.SecretEncrypted)
+	require.Nil(test, got.ConfirmedAt)
+
+	require.NoError(test, store.Confirm(ctx, credential.UserID, 100))
+
+	got, err = store.GetByUserID(ctx, credential.UserID)
+	require.NoError(test, err)
+	require.NotNil(test, got.ConfirmedAt)
+	require.Equal(test, int64(100), got.LastUsedStep)
+
+	// confirming twice is not possible
+	require.ErrorIs(test, store.Confirm(ctx, credential.UserID, 101), errs.ErrNotFound)
+}
+
+func TestTOTPStore_SavePending_ReplacesPendingOnly(test *testing.T) {
+	store := postgres.NewTOTPStore(newTestDB(test))
+
+	ctx := context.Background()
+	credential := newTestTOTPCredential()
+	require.NoError(test, store.SavePending(ctx, credential))
+
+	// restarting enrollment replaces the pending secret
+	credential.SecretEncrypted = "new-secret"
+	require.NoError(test, store.SavePending(ctx, credential))
+
+	got, err := store.GetByUserID(ctx, credential.UserID)
+	require.NoError(test, err)
+	require.Equal(test, "new-secret", got.SecretEncrypted)
+
+	// a confirmed credential is never overwritten
+	require.NoError(test, store.Confirm(ctx, credential.UserID, 1))
+	credential.SecretEncrypted = "attacker-secret"
+	require.ErrorIs(test, store.SavePending(ctx, credential), errs.ErrAlreadyExists)
+}
+
+func TestTOTPStore_GetByUserID_NotFound(test *testing.T) {
+	store := postgres.NewTOTPStore(newTestDB(test))
+
+	_, err := store.GetByUserID(context.Background(), "missing-user")
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
+
+func TestTOTPStore_UpdateLastUsedStep_RejectsReplay(test *testing.T) {
+	store := postgres.NewTOTPStore(newTestDB(test))
+
+	ctx := context.Background()
+	credential := newTestTOTPCredential()
+	require.NoError(test, store.SavePending(ctx, credential))
+	require.NoError(test, store.Confirm(ctx, credential.UserID, 100))
+
+	require.NoError(test, store.UpdateLastUsedStep(ctx, credential.UserID, 101))
+	require.ErrorIs(test, store.UpdateLastUsedStep(ctx, credential.UserID, 101), errs.ErrNotFound)
+	require.ErrorIs(test, store.UpdateLastUsedStep(ctx, credential.UserID, 99), errs.ErrNotFound)
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
@@ -26,9 +27,10 @@ func (store *UserStore) Create(ctx context.Context, user domain.User) error {
 			id,
 			email,
 			password_hash,
+			email_verified_at,
 			created_at
 		)
-		VALUES ($1, $2, $3, $4)
+		VALUES ($1, $2, $3, $4, $5)
 	`
 
 	_, err := store.sql.ExecContext(
@@ -37,6 +39,7 @@ func (store *UserStore) Create(ctx context.Context, user domain.User) error {
 		user.ID,
 		user.Email,
 		user.PasswordHash,
+		user.EmailVerifiedAt,
 		user.CreatedAt,
 	)
 
@@ -53,14 +56,13 @@ func (store *UserStore) GetByEmail(
 			id,
 			email,
 			password_hash,
+			email_verified_at,
 			created_at
 		FROM users
 		WHERE email = $1
 	`
 
-	var user domain.User
-	err := store.sql.QueryRowContext(ctx, query, email).
-		Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	user, err := scanUser(store.sql.QueryRowContext(ctx, query, email))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -82,14 +84,13 @@ func (store *UserStore) GetById(
 			id,
 			email,
 			password_hash,
+			email_verified_at,
 			created_at
 		FROM users
 		WHERE id = $1
 	`
 
-	var user domain.User
-	err := store.sql.QueryRowContext(ctx, query, id).
-		Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	user, err := scanUser(store.sql.QueryRowContext(ctx, query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -100,3 +101,77 @@ func (store *UserStore) GetById(
 
 	return user, nil
 }
+
+func (store *UserStore) MarkEmailVerified(
+	ctx context.Context,
+	id string,
+) error {
+
+	const query = `
+		UPDATE users
+		SET email_verified_at = $1
+		WHERE id = $2
+		  AND email_verified_at IS NULL
+	`
+
+	res, err := store.sql.ExecContext(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func (store *UserStore) UpdatePasswordHash(
+	ctx context.Context,
+	id string,
+	passwordHash string,
+) error {
+
+	const query = `
+		UPDATE users
+		SET password_hash = $1
+		WHERE id = $2
+	`
+
+	res, err := store.sql.ExecContext(ctx, query, passwordHash, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanUser(row *sql.Row) (domain.User, error) {
+	var user domain.User
+	var verified sql.NullTime
+
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &verified, &user.CreatedAt)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if verified.Valid {
+		user.EmailVerifiedAt = &verified.Time
+	}
+
+	return user, nil
+}
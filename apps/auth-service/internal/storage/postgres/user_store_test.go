@@ -53,3 +53,43 @@ func TestUserStore_GetById_NotFound(test *testing.T) {
 	_, err := store.GetById(context.Background(), uuid.NewString())
 	require.ErrorIs(test, err, errs.ErrNotFound)
 }
+
+func TestUserStore_MarkEmailVerified(test *testing.T) {
+	db := newTestDB(test)
+	store := postgres.NewUserStore(db)
+
+	ctx := context.Background()
+	user := newTestUser()
+	require.NoError(test, store.Create(ctx, user))
+
+	got, err := store.GetById(ctx, user.ID)
+	require.NoError(test, err)
+	require.Nil(test, got.EmailVerifiedAt)
+
+	require.NoError(test, store.MarkEmailVerified(ctx, user.ID))
+
+	got, err = store.GetById(ctx, user.ID)
+	require.NoError(test, err)
+	require.NotNil(test, got.EmailVerifiedAt)
+
+	// already verified, or no such user
+	require.ErrorIs(test, store.MarkEmailVerified(ctx, user.ID), errs.ErrNotFound)
+	require.ErrorIs(test, store.MarkEmailVerified(ctx, uuid.NewString()), errs.ErrNotFound)
+}
+
+func TestUserStore_UpdatePasswordHash(test *testing.T) {
+	db := newTestDB(test)
+	store := postgres.NewUserStore(db)
+
+	ctx := context.Background()
+	user := newTestUser()
+	require.NoError(test, store.Create(ctx, user))
+
+	require.NoError(test, store.UpdatePasswordHash(ctx, user.ID, "new-hash"))
+
+	got, err := store.GetById(ctx, user.ID)
+	require.NoError(test, err)
+	require.Equal(test, "new-hash", got.PasswordHash)
+
+	require.ErrorIs(test, store.UpdatePasswordHash(ctx, uuid.NewString(), "new-hash"), errs.ErrNotFound)
+}
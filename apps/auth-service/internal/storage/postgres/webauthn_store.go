@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type WebAuthnCredentialStore struct {
+	exec storage.SQLExecutor
+}
+
+func NewWebAuthnCredentialStore(exec storage.SQLExecutor) storage.WebAuthnCredentialStore {
+	return &WebAuthnCredentialStore{exec: exec}
+}
+
+func (store *WebAuthnCredentialStore) Create(
+	ctx context.Context,
+	credential domain.WebAuthnCredential,
+) error {
+
+	query := `
+		INSERT INTO webauthn_credentials (
+			id, user_id, public_key, algorithm, sign_count, transports, created_at, last_used_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := store.exec.ExecContext(
+		ctx,
+		query,
+		credential.ID,
+		credential.UserID,
+		credential.PublicKey,
+		credential.Algorithm,
+		credential.SignCount,
+		strings.Join(credential.Transports, " "),
+		credential.CreatedAt,
+		credential.LastUsedAt,
+	)
+	if err != nil {
+		// Postgres unique violation
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return errs.ErrAlreadyExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (store *WebAuthnCredentialStore) GetByID(
+	ctx context.Context,
+	id string,
+) (domain.WebAuthnCredential, error) {
+
+	query := `
+		SELECT id, user_id, public_key, algorithm, sign_count, transports, created_at, last_used_at
+		FROM webauthn_credentials
+		WHERE id = $1
+	`
+
+	credential, err := scanWebAuthnCredential(store.exec.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.WebAuthnCredential{}, errs.ErrNotFound
+		}
+		return domain.WebAuthnCredential{}, err
+	}
+
+	return credential, nil
+}
+
+func (store *WebAuthnCredentialStore) ListByUserID(
+	ctx context.Context,
+	userID string,
+) ([]domain.WebAuthnCredential, error) {
+
+	query := `
+		SELECT id, user_id, public_key, algorithm, sign_count, transports, created_at, last_used_at
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := store.exec.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var credentials []domain.WebAuthnCredential
+	for rows.Next() {
+		credential, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, rows.Err()
+}
+
+func (store *WebAuthnCredentialStore) UpdateSignCount(
+	ctx context.Context,
+	id string,
+	signCount uint32,
+) error {
+
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count = $1, last_used_at = $2
+		WHERE id = $3
+	`
+
+	res, err := store.exec.ExecContext(ctx, query, signCount, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+// *sql.Row or *sql.Rows
+func scanWebAuthnCredential(row interface{ Scan(...any) error }) (domain.WebAuthnCredential, error) {
+	var credential domain.WebAuthnCredential
+	var transports string
+	var lastUsed sql.NullTime
+
+	err := row.Scan(
+		&credential.ID,
+		&credential.UserID,
+		&credential.PublicKey,
+		&credential.Algorithm,
+		&credential.SignCount,
+		&transports,
+		&credential.CreatedAt,
+		&lastUsed,
+	)
+	if err != nil {
+		return domain.WebAuthnCredential{}, err
+	}
+
+	credential.Transports = strings.Fields(transports)
+	if lastUsed.Valid {
+		credential.LastUsedAt = &lastUsed.Time
+	}
+
+	return credential, nil
+}
+
+type WebAuthnChallengeStore struct {
+	exec storage.SQLExecutor
+}
+
+func NewWebAuthnChallengeStore(exec storage.SQLExecutor) storage.WebAuthnChallengeStore {
+	return &WebAuthnChallengeStore{exec: exec}
+}
+
+func (store *WebAuthnChallengeStore) Create(
+	ctx context.Context,
+	challenge domain.WebAuthnChallenge,
+) error {
+
+	query := `
+		INSERT INTO webauthn_challenges (
+			id, user_id, ceremony, challenge, expires_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := store.exec.ExecContext(
+		ctx,
+		query,
+		challenge.ID,
+		challenge.UserID,
+		challenge.Ceremony,
+		challenge.Challenge,
+		challenge.ExpiresAt,
+		challenge.CreatedAt,
+	)
+
+	return err
+}
+
+func (store *WebAuthnChallengeStore) Consume(
+	ctx context.Context,
+	id string,
+) (domain.WebAuthnChallenge, error) {
+
+	query := `
+		DELETE FROM webauthn_challenges
+		WHERE id = $1
+		RETURNING id, user_id, ceremony, challenge, expires_at, created_at
+	`
+
+	var challenge domain.WebAuthnChallenge
+
+	err := store.exec.QueryRowContext(ctx, query, id).Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.Ceremony,
+		&challenge.Challenge,
+		&challenge.ExpiresAt,
+		&challenge.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.WebAuthnChallenge{}, errs.ErrNotFound
+		}
+		return domain.WebAuthnChallenge{}, err
+	}
+
+	return challenge, nil
+}
+
+func (store *WebAuthnChallengeStore) DeleteExpired(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) (int64, error) {
+
+	query := `
+		DELETE FROM webauthn_challenges
+		WHERE id IN (
+			SELECT id
+			FROM webauthn_challenges
+			WHERE expires_at < $1
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	res, err := store.exec.ExecContext(ctx, query, before.UTC(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,14 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// token buckets of the rate limiter, shared by all replicas
+type RateLimitBucketStore interface {
+	// refills the bucket of key (full if new) for the time since its last request
+	// and takes one token if there is one, in one statement so concurrent requests
+	// on several replicas cannot spend the same token; returns the tokens left
+	Take(ctx context.Context, key string, capacity float64, refillPerSecond float64, now time.Time) (tokens float64, allowed bool, err error)
+}
@@ -0,0 +1,15 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+type RecoveryCodeStore interface {
+	// deletes all codes of the user (used or not) and stores the new batch
+	ReplaceAll(ctx context.Context, userID string, codes []domain.RecoveryCode) error
+	GetByHash(ctx context.Context, userID string, hash string) (domain.RecoveryCode, error)
+	// ErrNotFound if the code was already used, so it cannot be spent twice
+	MarkUsed(ctx context.Context, id string) error
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
 )
@@ -12,4 +13,18 @@ type RefreshTokenStore interface {
 	Create(ctx context.Context, token RefreshToken) error
 	GetByHash(ctx context.Context, hash string) (RefreshToken, error)
 	Revoke(ctx context.Context, id string) error
+	// revokes every not yet revoked token of the session (token chain)
+	RevokeSession(ctx context.Context, sessionID string) error
+	// revokes every not yet revoked token of the user, i.e. all sessions
+	RevokeAllForUser(ctx context.Context, userID string) error
+	// same, but the tokens of keepSessionID stay valid (the caller's own session)
+	RevokeOtherSessions(ctx context.Context, userID string, keepSessionID string) error
+	// the not yet revoked tokens of the user, newest first: one per session
+	// (its current token); expired ones are included
+	ListByUser(ctx context.Context, userID string) ([]RefreshToken, error)
+	// revokes the session only if it is the user's; ErrNotFound if it has no active token
+	RevokeUserSession(ctx context.Context, userID string, sessionID string) error
+	// deletes at most limit tokens that expired before the given time, or were
+	// revoked before it and their session has no active token left; returns the count
+	DeleteStale(ctx context.Context, before time.Time, limit int) (int64, error)
 }
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type AuditEventStore struct {
+	exec storage.SQLExecutor
+}
+
+func NewAuditEventStore(exec storage.SQLExecutor) storage.AuditEventStore {
+	return &AuditEventStore{exec: exec}
+}
+
+// the upsert takes the write lock of the database, which serialises appends
+func (store *AuditEventStore) NextLink(ctx context.Context) (int64, string, error) {
+
+	query := `
+		INSERT INTO audit_chain (id, last_seq, last_hash)
+		VALUES (1, 1, '')
+		ON CONFLICT (id) DO UPDATE
+		SET last_seq = audit_chain.last_seq + 1
+		RETURNING last_seq, last_hash
+	`
+
+	var seq int64
+	var prevHash string
+
+	err := store.exec.QueryRowContext(ctx, query).Scan(&seq, &prevHash)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return seq, prevHash, nil
+}
+
+func (store *AuditEventStore) Append(ctx context.Context, event domain.AuditEvent) error {
+
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_events (
+			seq, event_type, user_id, session_id, client_ip,
+			details, occurred_at, prev_hash, hash
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = store.exec.ExecContext(
+		ctx,
+		query,
+		event.Seq,
+		event.Type,
+		event.UserID,
+		event.SessionID,
+		event.ClientIP,
+		string(details),
+		event.OccurredAt.UTC(),
+		event.PrevHash,
+		event.Hash,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.exec.ExecContext(
+		ctx,
+		`UPDATE audit_chain SET last_hash = ? WHERE id = 1 AND last_seq = ?`,
+		event.Hash,
+		event.Seq,
+	)
+	return err
+}
+
+func (store *AuditEventStore) Head(ctx context.Context) (int64, string, error) {
+
+	var seq int64
+	var hash string
+
+	err := store.exec.QueryRowContext(
+		ctx,
+		`SELECT last_seq, last_hash FROM audit_chain WHERE id = 1`,
+	).Scan(&seq, &hash)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", errs.ErrNotFound
+		}
+		return 0, "", err
+	}
+
+	return seq, hash, nil
+}
+
+func (store *AuditEventStore) ListAfter(
+	ctx context.Context,
+	afterSeq int64,
+	limit int,
+) ([]domain.AuditEvent, error) {
+
+	query := `
+		SELECT seq, event_type, user_id, session_id, client_ip,
+			details, occurred_at, prev_hash, hash
+		FROM audit_events
+		WHERE seq > ?
+		ORDER BY seq
+		LIMIT ?
+	`
+
+	rows, err := store.exec.QueryContext(ctx, query, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []domain.AuditEvent
+	for rows.Next() {
+		var event domain.AuditEvent
+		var details string
+
+		if err := rows.Scan(
+			&event.Seq,
+			&event.Type,
+			&event.UserID,
+			&event.SessionID,
+			&event.ClientIP,
+			&details,
+			&event.OccurredAt,
+			&event.PrevHash,
+			&event.Hash,
+		); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(details), &event.Details); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+func setupAuditTestDB(test *testing.T) storage.AuditEventStore {
+	test.Helper()
+
+	db := openTestDB(test, "test_audit.db")
+
+	return NewAuditEventStore(db)
+}
+
+func TestAuditEventStore_AppendAndList(test *testing.T) {
+	store := setupAuditTestDB(test)
+	ctx := context.Background()
+
+	_, _, err := store.Head(ctx)
+	require.ErrorIs(test, err, errs.ErrNotFound)
+
+	occurredAt := time.Date(2026, 1, 1, 12, 0, 0, 123456000, time.UTC)
+
+	for i, hash := range []string{"hash-1", "hash-2"} {
+		seq, prevHash, err := store.NextLink(ctx)
+		require.NoError(test, err)
+		require.Equal(test, int64(i+1), seq)
+
+		event := domain.AuditEvent{
+			Seq:        seq,
+			Type:       "login.succeeded",
+			UserID:     "user-1",
+			ClientIP:   "192.0.2.1",
+			Details:    map[string]string{"method": "password"},
+			OccurredAt: occurredAt,
+			PrevHash:   prevHash,
+			Hash:       hash,
+		}
+		require.NoError(test, store.Append(ctx, event))
+	}
+
+	seq, hash, err := store.Head(ctx)
+	require.NoError(test, err)
+	require.Equal(test, int64(2), seq)
+	require.Equal(test, "hash-2", hash)
+
+	events, err := store.ListAfter(ctx, 0, 10)
+	require.NoError(test, err)
+	require.Len(test, events, 2)
+	require.Equal(test, "", events[0].PrevHash)
+	require.Equal(test, "hash-1", events[1].PrevHash)
+	require.Equal(test, map[string]string{"method": "password"}, events[1].Details)
+	require.True(test, occurredAt.Equal(events[1].OccurredAt))
+	require.Equal(test, "", events[1].SessionID)
+
+	events, err = store.ListAfter(ctx, 1, 10)
+	require.NoError(test, err)
+	require.Len(test, events, 1)
+	require.Equal(test, int64(2), events[0].Seq)
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+func setupAuthorizationCodeTestDB(test *testing.T) storage.AuthorizationCodeStore {
+	test.Helper()
+
+	db := openTestDB(test, "test_authorization_codes.db")
+
+	return NewAuthorizationCodeStore(db)
+}
+
+func newTestAuthorizationCode() domain.AuthorizationCode {
+	now := time.Now().UTC()
+	return domain.AuthorizationCode{
+		ID:            uuid.NewString(),
+		CodeHash:      uuid.NewString(),
+		ClientID:      "wiki",
+		UserID:        uuid.NewString(),
+		RedirectURI:   "https://wiki.family.example/callback",
+		Scope:         "openid",
+		Nonce:         "nonce",
+		CodeChallenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		AuthTime:      now,
+		ExpiresAt:     now.Add(time.Minute),
+		CreatedAt:     now,
+		RememberMe:    true,
+	}
+}
+
+func TestAuthorizationCodeStore_CreateAndGetByHash(test *testing.T) {
+	store := setupAuthorizationCodeTestDB(test)
+
+	ctx := context.Background()
+	code := newTestAuthorizationCode()
+	require.NoError(test, store.Create(ctx, code))
+
+	got, err := store.GetByHash(ctx, code.CodeHash)
+	require.NoError(test, err)
+
+	require.Equal(test, code.ID, got.ID)
+	require.Equal(test, code.ClientID, got.ClientID)
+	require.Equal(test, code.RedirectURI, got.RedirectURI)
+	require.Equal(test, code.CodeChallenge, got.CodeChallenge)
+	require.True(test, got.RememberMe)
+	require.Nil(test, got.UsedAt)
+}
+
+func TestAuthorizationCodeStore_GetByHash_NotFound(test *testing.T) {
+	store := setupAuthorizationCodeTestDB(test)
+
+	_, err := store.GetByHash(context.Background(), "missing-hash")
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
+
+func TestAuthorizationCodeStore_MarkUsed_OnlyOnce(test *testing.T) {
+	store := setupAuthorizationCodeTestDB(test)
+
+	ctx := context.Background()
+	code := newTestAuthorizationCode()
+	require.NoError(test, store.Create(ctx, code))
+
+	require.NoError(test, store.MarkUsed(ctx, code.ID))
+
+	got, err := store.GetByHash(ctx, code.CodeHash)
+	require.NoError(test, err)
+	require.NotNil(test, got.UsedAt)
+
+	// second exchange of the same code
+	require.ErrorIs(test, store.MarkUsed(ctx, code.ID), errs.ErrNotFound)
+}
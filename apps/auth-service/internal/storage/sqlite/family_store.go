@@ -2,6 +2,8 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"strings"
 
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
@@ -42,3 +44,22 @@ func (store *FamilyStore) Create(ctx context.Context, family domain.Family) erro
 
 	return nil
 }
+
+func (store *FamilyStore) GetByID(ctx context.Context, id string) (domain.Family, error) {
+	const query = `
+	  SELECT id, name, created_at
+	  FROM families
+	  WHERE id = ?
+	`
+
+	var family domain.Family
+	err := store.sql.QueryRowContext(ctx, query, id).Scan(&family.ID, &family.Name, &family.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Family{}, errs.ErrNotFound
+		}
+		return domain.Family{}, err
+	}
+
+	return family, nil
+}
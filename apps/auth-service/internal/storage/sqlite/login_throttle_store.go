@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type LoginThrottleStore struct {
+	exec storage.SQLExecutor
+}
+
+func NewLoginThrottleStore(exec storage.SQLExecutor) storage.LoginThrottleStore {
+	return &LoginThrottleStore{exec: exec}
+}
+
+func (store *LoginThrottleStore) Get(
+	ctx context.Context,
+	kind string,
+	subject string,
+) (domain.LoginThrottle, error) {
+
+	query := `
+		SELECT kind, subject, failures, last_failed_at, blocked_until
+		FROM login_throttles
+		WHERE kind = ?
+		  AND subject = ?
+	`
+
+	var throttle domain.LoginThrottle
+	var blocked sql.NullTime
+
+	err := store.exec.QueryRowContext(ctx, query, kind, subject).Scan(
+		&throttle.Kind,
+		&throttle.Subject,
+		&throttle.Failures,
+		&throttle.LastFailedAt,
+		&blocked,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.LoginThrottle{}, errs.ErrNotFound
+		}
+		return domain.LoginThrottle{}, err
+	}
+
+	if blocked.Valid {
+		throttle.BlockedUntil = &blocked.Time
+	}
+
+	return throttle, nil
+}
+
+// one statement, so concurrent failures on several replicas are all counted;
+// times are stored in UTC because sqlite compares them as text
+func (store *LoginThrottleStore) RecordFailure(
+	ctx context.Context,
+	kind string,
+	subject string,
+	failedAt time.Time,
+	resetBefore time.Time,
+) (int, error) {
+
+	query := `
+		INSERT INTO login_throttles (
+			kind, subject, failures, last_failed_at, blocked_until
+		) VALUES (?, ?, 1, ?, NULL)
+		ON CONFLICT (kind, subject) DO UPDATE
+		SET failures = CASE
+				WHEN login_throttles.last_failed_at < ? THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failed_at = excluded.last_failed_at
+		RETURNING failures
+	`
+
+	var failures int
+	err := store.exec.QueryRowContext(ctx, query, kind, subject, failedAt.UTC(), resetBefore.UTC()).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+// stored in UTC like the times of RecordFailure
+func (store *LoginThrottleStore) Block(
+	ctx context.Context,
+	kind string,
+	subject string,
+	until time.Time,
+) error {
+
+	query := `
+		UPDATE login_throttles
+		SET blocked_until = ?
+		WHERE kind = ?
+		  AND subject = ?
+	`
+
+	_, err := store.exec.ExecContext(ctx, query, until.UTC(), kind, subject)
+	return err
+}
+
+func (store *LoginThrottleStore) Delete(
+	ctx context.Context,
+	kind string,
+	subject string,
+) error {
+
+	query := `
+		DELETE FROM login_throttles
+		WHERE kind = ?
+		  AND subject = ?
+	`
+
+	_, err := store.exec.ExecContext(ctx, query, kind, subject)
+	return err
+}
@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+func setupLoginThrottleTestDB(test *testing.T) storage.LoginThrottleStore {
+	test.Helper()
+
+	db := openTestDB(test, "test_login_throttle.db")
+
+	return NewLoginThrottleStore(db)
+}
+
+func TestLoginThrottleStore_RecordFailure(test *testing.T) {
+	store := setupLoginThrottleTestDB(test)
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	_, err := store.Get(ctx, domain.ThrottleAccount, "a@b.com")
+	require.ErrorIs(test, err, errs.ErrNotFound)
+
+	for want := 1; want <= 3; want++ {
+		failures, err := store.RecordFailure(ctx, domain.ThrottleAccount, "a@b.com", now, now.Add(-time.Hour))
+		require.NoError(test, err)
+		require.Equal(test, want, failures)
+	}
+
+	// counted separately
+	failures, err := store.RecordFailure(ctx, domain.ThrottleIP, "a@b.com", now, now.Add(-time.Hour))
+	require.NoError(test, err)
+	require.Equal(test, 1, failures)
+
+	got, err := store.Get(ctx, domain.ThrottleAccount, "a@b.com")
+	require.NoError(test, err)
+	require.Equal(test, 3, got.Failures)
+	require.WithinDuration(test, now, got.LastFailedAt, time.Second)
+	require.Nil(test, got.BlockedUntil)
+}
+
+func TestLoginThrottleStore_RecordFailure_StartsOverAfterWindow(test *testing.T) {
+	store := setupLoginThrottleTestDB(test)
+
+	ctx := context.Background()
+	earlier := time.Now().UTC().Add(-2 * time.Hour)
+	now := time.Now().UTC()
+
+	_, err := store.RecordFailure(ctx, domain.ThrottleIP, "192.0.2.1", earlier, earlier.Add(-time.Hour))
+	require.NoError(test, err)
+	_, err = store.RecordFailure(ctx, domain.ThrottleIP, "192.0.2.1", earlier, earlier.Add(-time.Hour))
+	require.NoError(test, err)
+
+	failures, err := store.RecordFailure(ctx, domain.ThrottleIP, "192.0.2.1", now, now.Add(-time.Hour))
+	require.NoError(test, err)
+	require.Equal(test, 1, failures)
+}
+
+func TestLoginThrottleStore_BlockAndDelete(test *testing.T) {
+	store := setupLoginThrottleTestDB(test)
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	// of another zone, still stored in UTC
+	until := now.Add(15 * time.Minute).In(time.FixedZone("UTC+2", 2*60*60))
+
+	_, err := store.RecordFailure(ctx, domain.ThrottleAccount, "a@b.com", now, now.Add(-time.Hour))
+	require.NoError(test, err)
+	require.NoError(test, store.Block(ctx, domain.ThrottleAccount, "a@b.com", until))
+
+	got, err := store.Get(ctx, domain.ThrottleAccount, "a@b.com")
+	require.NoError(test, err)
+	require.NotNil(test, got.BlockedUntil)
+	require.WithinDuration(test, until, *got.BlockedUntil, time.Second)
+	require.Equal(test, time.UTC, got.BlockedUntil.Location())
+
+	require.NoError(test, store.Delete(ctx, domain.ThrottleAccount, "a@b.com"))
+
+	_, err = store.Get(ctx, domain.ThrottleAccount, "a@b.com")
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
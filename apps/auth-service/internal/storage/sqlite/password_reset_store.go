@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type PasswordResetTokenStore struct {
+	exec storage.SQLExecutor
+}
+
+func NewPasswordResetTokenStore(exec storage.SQLExecutor) storage.PasswordResetTokenStore {
+	return &PasswordResetTokenStore{exec: exec}
+}
+
+func (store *PasswordResetTokenStore) Create(
+	ctx context.Context,
+	token domain.PasswordResetToken,
+) error {
+
+	query := `
+		INSERT INTO password_reset_tokens (
+			id, user_id, token_hash, expires_at, used_at, created_at
+		) VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := store.exec.ExecContext(
+		ctx,
+		query,
+		token.ID,
+		token.UserID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.UsedAt,
+		token.CreatedAt,
+	)
+
+	return err
+}
+
+func (store *PasswordResetTokenStore) GetByHash(
+	ctx context.Context,
+	hash string,
+) (domain.PasswordResetToken, error) {
+
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = ?
+	`
+
+	var token domain.PasswordResetToken
+	var used sql.NullTime
+
+	err := store.exec.QueryRowContext(ctx, query, hash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&used,
+		&token.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.PasswordResetToken{}, errs.ErrNotFound
+		}
+		return domain.PasswordResetToken{}, err
+	}
+
+	if used.Valid {
+		token.UsedAt = &used.Time
+	}
+
+	return token, nil
+}
+
+func (store *PasswordResetTokenStore) MarkUsed(
+	ctx context.Context,
+	id string,
+) error {
+
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = ?
+		WHERE id = ?
+		  AND used_at IS NULL
+	`
+
+	res, err := store.exec.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func (store *PasswordResetTokenStore) InvalidateAllForUser(
+	ctx context.Context,
+	userID string,
+) error {
+
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = ?
+		WHERE user_id = ?
+		  AND used_at IS NULL
+	`
+
+	_, err := store.exec.ExecContext(ctx, query, time.Now(), userID)
+	return err
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type RateLimitBucketStore struct {
+	exec storage.SQLExecutor
+}
+
+func NewRateLimitBucketStore(exec storage.SQLExecutor) storage.RateLimitBucketStore {
+	return &RateLimitBucketStore{exec: exec}
+}
+
+// ?1 key, ?2 capacity, ?3 refill per second, ?4 now (unix seconds);
+// in the update, every column reads the values of the row before it
+func (store *RateLimitBucketStore) Take(
+	ctx context.Context,
+	key string,
+	capacity float64,
+	refillPerSecond float64,
+	now time.Time,
+) (float64, bool, error) {
+
+	query := `
+		INSERT INTO rate_limit_buckets (
+			bucket_key, tokens, refilled_at, allowed
+		) VALUES (?1, ?2 - 1, ?4, 1)
+		ON CONFLICT (bucket_key) DO UPDATE
+		SET tokens = CASE
+				WHEN MIN(?2, tokens + MAX(0, ?4 - refilled_at) * ?3) >= 1
+				THEN MIN(?2, tokens + MAX(0, ?4 - refilled_at) * ?3) - 1
+				ELSE MIN(?2, tokens + MAX(0, ?4 - refilled_at) * ?3)
+			END,
+			allowed = MIN(?2, tokens + MAX(0, ?4 - refilled_at) * ?3) >= 1,
+			refilled_at = ?4
+		RETURNING tokens, allowed
+	`
+
+	var tokens float64
+	var allowed bool
+
+	err := store.exec.QueryRowContext(
+		ctx,
+		query,
+		key,
+		capacity,
+		refillPerSecond,
+		unixSeconds(now),
+	).Scan(&tokens, &allowed)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return tokens, allowed, nil
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
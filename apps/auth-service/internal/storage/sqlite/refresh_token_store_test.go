@@ -0,0 +1,297 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+func setupRefreshTokenTestDB(test *testing.T) storage.RefreshTokenStore {
+	test.Helper()
+
+	db := openTestDB(test, "test_refresh_tokens.db")
+
+	return NewRefreshTokenStore(db)
+}
+
+func newTestToken() refresh.RefreshToken {
+	now := time.Now().UTC()
+	return refresh.RefreshToken{
+		ID:        uuid.NewString(),
+		UserID:    uuid.NewString(),
+		SessionID: uuid.NewString(),
+		TokenHash: uuid.NewString(),
+		ExpiresAt: now.Add(24 * time.Hour),
+		CreatedAt: now,
+
+		SessionCreatedAt: now,
+		SessionExpiresAt: now.Add(7 * 24 * time.Hour),
+		LastUsedAt:       now,
+	}
+}
+
+func TestRefreshTokenStore_CreateAndGetByHash(test *testing.T) {
+	store := setupRefreshTokenTestDB(test)
+
+	ctx := context.Background()
+	token := newTestToken()
+	require.NoError(test, store.Create(ctx, token))
+
+	got, err := store.GetByHash(ctx, token.TokenHash)
+	require.NoError(test, err)
+
+	require.Equal(test, token.ID, got.ID)
+	require.Equal(test, token.SessionID, got.SessionID)
+	require.Nil(test, got.ParentID)
+	require.Nil(test, got.RevokedAt)
+}
+
+func TestRefreshTokenStore_GetByHash_NotFound(test *testing.T) {
+	store := setupRefreshTokenTestDB(test)
+
+	_, err := store.GetByHash(context.Background(), "missing-hash")
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
+
+func TestRefreshTokenStore_RevokeSession(test *testing.T) {
+	store := setupRefreshTokenTestDB(test)
+
+	ctx := context.Background()
+	first := newTestToken()
+	rotated := newTestToken()
+	rotated.SessionID = first.SessionID
+	rotated.ParentID = &first.ID
+	other := newTestToken()
+
+	require.NoError(test, store.Create(ctx, first))
+	require.NoError(test, store.Revoke(ctx, first.ID))
+	require.NoError(test, store.Create(ctx, rotated))
+	require.NoError(test, store.Create(ctx, other))
+
+	require.NoError(test, store.RevokeSession(ctx, first.SessionID))
+
+	got, err := store.GetByHash(ctx, rotated.TokenHash)
+	require.NoError(test, err)
+	require.NotNil(test, got.RevokedAt)
+	require.Equal(test, first.ID, *got.ParentID)
+
+	got, err = store.GetByHash(ctx, other.TokenHash)
+	require.NoError(test, err)
+	require.Nil(test, got.RevokedAt)
+}
+
+func TestRefreshTokenStore_RevokeAllForUser(test *testing.T) {
+	store := setupRefreshTokenTestDB(test)
+
+	ctx := context.Background()
+	first := newTestToken()
+	secondSession := newTestToken()
+	secondSession.UserID = first.UserID
+	otherUser := newTestToken()
+
+	require.NoError(test, store.Create(ctx, first))
+	require.NoError(test, store.Create(ctx, secondSession))
+	require.NoError(test, store.Create(ctx, otherUser))
+
+	require.NoError(test, store.RevokeAllForUser(ctx, first.UserID))
+
+	for _, token := range []string{first.TokenHash, secondSession.TokenHash} {
+		got, err := store.GetByHash(ctx, token)
+		require.NoError(test, err)
+		require.NotNil(test, got.RevokedAt)
+	}
+
+	got, err := store.GetByHash(ctx, otherUser.TokenHash)
+	require.NoError(test, err)
+	require.Nil(test, got.RevokedAt)
+}
+
+func TestRefreshTokenStore_RevokeOtherSessions(test *testing.T) {
+	store := setupRefreshTokenTestDB(test)
+
+	ctx := context.Background()
+	current := newTestToken()
+	otherSession := newTestToken()
+	otherSession.UserID = current.UserID
+	otherUser := newTestToken()
+
+	require.NoError(test, store.Create(ctx, current))
+	require.NoError(test, store.Create(ctx, otherSession))
+	require.NoError(test, store.Create(ctx, otherUser))
+
+	require.NoError(test, store.RevokeOtherSessions(ctx, current.UserID, current.SessionID))
+
+	got, err := store.GetByHash(ctx, otherSession.TokenHash)
+	require.NoError(test, err)
+	require.NotNil(test, got.RevokedAt)
+
+	for _, token := range []string{current.TokenHash, otherUser.TokenHash} {
+		got, err := store.GetByHash(ctx, token)
+		require.NoError(test, err)
+		require.Nil(test, got.RevokedAt)
+	}
+}
+
+func TestRefreshTokenStore_ListByUser(test *testing.T) {
+	store := setupRefreshTokenTestDB(test)
+
+	ctx := context.Background()
+	first := newTestToken()
+	first.SessionCreatedAt = first.CreatedAt.Add(-time.Hour)
+	rotated := newTestToken()
+	rotated.UserID = first.UserID
+	rotated.SessionID = first.SessionID
+	rotated.ParentID = &first.ID
+	rotated.CreatedAt = first.CreatedAt.Add(time.Minute)
+	rotated.SessionCreatedAt = first.SessionCreatedAt
+	secondSession := newTestToken()
+	secondSession.UserID = first.UserID
+	otherUser := newTestToken()
+
+	require.NoError(test, store.Create(ctx, first))
+	require.NoError(test, store.Revoke(ctx, first.ID))
+	require.NoError(test, store.Create(ctx, rotated))
+	require.NoError(test, store.Create(ctx, secondSession))
+	require.NoError(test, store.Create(ctx, otherUser))
+
+	tokens, err := store.ListByUser(ctx, first.UserID)
+	require.NoError(test, err)
+
+	require.Len(test, tokens, 2)
+	require.Equal(test, rotated.ID, tokens[0].ID)
+	require.WithinDuration(test, first.SessionCreatedAt, tokens[0].SessionCreatedAt, time.Second)
+	require.Equal(test, secondSession.ID, tokens[1].ID)
+}
+
+func TestRefreshTokenStore_RevokeUserSession(test *testing.T) {
+	store := setupRefreshTokenTestDB(test)
+
+	ctx := context.Background()
+	token := newTestToken()
+	otherUser := newTestToken()
+
+	require.NoError(test, store.Create(ctx, token))
+	require.NoError(test, store.Create(ctx, otherUser))
+
+	// another user's session is not found, not revoked
+	err := store.RevokeUserSession(ctx, token.UserID, otherUser.SessionID)
+	require.ErrorIs(test, err, errs.ErrNotFound)
+
+	require.NoError(test, store.RevokeUserSession(ctx, token.UserID, token.SessionID))
+
+	got, err := store.GetByHash(ctx, token.TokenHash)
+	require.NoError(test, err)
+	require.NotNil(test, got.RevokedAt)
+
+	got, err = store.GetByHash(ctx, otherUser.TokenHash)
+	require.NoError(test, err)
+	require.Nil(test, got.RevokedAt)
+
+	// already ended
+	err = store.RevokeUserSession(ctx, token.UserID, token.SessionID)
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
+
+func TestRefreshTokenStore_ClientMetadata(test *testing.T) {
+	store := setupRefreshTokenTestDB(test)
+
+	ctx := context.Background()
+	token := newTestToken()
+	token.UserAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) Safari/604.1"
+	token.CreatedIP = "203.0.113.7"
+	token.DeviceName = "Anna's phone"
+	token.LastUsedIP = "198.51.100.1"
+	token.LastUsedAt = token.CreatedAt.Add(time.Minute)
+
+	require.NoError(test, store.Create(ctx, token))
+
+	got, err := store.GetByHash(ctx, token.TokenHash)
+	require.NoError(test, err)
+
+	require.Equal(test, token.UserAgent, got.UserAgent)
+	require.Equal(test, token.CreatedIP, got.CreatedIP)
+	require.Equal(test, token.DeviceName, got.DeviceName)
+	require.Equal(test, token.LastUsedIP, got.LastUsedIP)
+	require.WithinDuration(test, token.LastUsedAt, got.LastUsedAt, time.Second)
+
+	tokens, err := store.ListByUser(ctx, token.UserID)
+	require.NoError(test, err)
+	require.Len(test, tokens, 1)
+	require.Equal(test, token.DeviceName, tokens[0].DeviceName)
+	require.Equal(test, token.LastUsedIP, tokens[0].LastUsedIP)
+}
+
+func TestRefreshTokenStore_SessionLifetime(test *testing.T) {
+	store := setupRefreshTokenTestDB(test)
+
+	ctx := context.Background()
+	token := newTestToken()
+	token.RememberMe = true
+	token.SessionExpiresAt = token.CreatedAt.Add(90 * 24 * time.Hour)
+
+	require.NoError(test, store.Create(ctx, token))
+
+	got, err := store.GetByHash(ctx, token.TokenHash)
+	require.NoError(test, err)
+
+	require.True(test, got.RememberMe)
+	require.WithinDuration(test, token.SessionExpiresAt, got.SessionExpiresAt, time.Second)
+}
+
+func TestRefreshTokenStore_DeleteStale(test *testing.T) {
+	store := setupRefreshTokenTestDB(test)
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	longAgo := now.Add(-8 * 24 * time.Hour)
+	before := now.Add(-7 * 24 * time.Hour)
+
+	expired := newTestToken()
+	expired.ExpiresAt = longAgo
+
+	recentlyExpired := newTestToken()
+	recentlyExpired.ExpiresAt = now.Add(-time.Hour)
+
+	// logged out long ago
+	ended := newTestToken()
+	ended.RevokedAt = &longAgo
+
+	// rotated long ago, its session is still going
+	current := newTestToken()
+	rotated := newTestToken()
+	rotated.SessionID = current.SessionID
+	rotated.RevokedAt = &longAgo
+
+	for _, token := range []refresh.RefreshToken{expired, recentlyExpired, ended, current, rotated} {
+		require.NoError(test, store.Create(ctx, token))
+	}
+
+	// batches of at most limit rows
+	deleted, err := store.DeleteStale(ctx, before, 1)
+	require.NoError(test, err)
+	require.Equal(test, int64(1), deleted)
+
+	deleted, err = store.DeleteStale(ctx, before, 10)
+	require.NoError(test, err)
+	require.Equal(test, int64(1), deleted)
+
+	deleted, err = store.DeleteStale(ctx, before, 10)
+	require.NoError(test, err)
+	require.Zero(test, deleted)
+
+	for _, token := range []refresh.RefreshToken{expired, ended} {
+		_, err := store.GetByHash(ctx, token.TokenHash)
+		require.ErrorIs(test, err, errs.ErrNotFound)
+	}
+	for _, token := range []refresh.RefreshToken{recentlyExpired, current, rotated} {
+		_, err := store.GetByHash(ctx, token.TokenHash)
+		require.NoError(test, err)
+	}
+}
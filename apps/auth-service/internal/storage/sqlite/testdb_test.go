@@ -0,0 +1,32 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/migrations"
+)
+
+// fresh database with the schema of the migrations, the one the service runs on
+func openTestDB(test *testing.T, dbPath string) *sql.DB {
+	test.Helper()
+
+	os.Remove(dbPath)
+
+	db, err := Open(dbPath)
+	require.NoError(test, err)
+
+	test.Cleanup(func() {
+		db.Close()
+		os.Remove(dbPath)
+	})
+
+	_, err = migrations.Apply(context.Background(), db, migrations.SQLite)
+	require.NoError(test, err)
+
+	return db
+}
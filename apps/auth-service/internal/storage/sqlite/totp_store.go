@@ -0,0 +1,159 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+type TOTPStore struct {
+	exec storage.SQLExecutor
+}
+
+func NewTOTPStore(exec storage.SQLExecutor) storage.TOTPStore {
+	return &TOTPStore{exec: exec}
+}
+
+func (store *TOTPStore) SavePending(
+	ctx context.Context,
+	credential domain.TOTPCredential,
+) error {
+
+	// a confirmed credential is never overwritten
+	query := `
+		INSERT INTO totp_credentials (
+			user_id, secret_encrypted, confirmed_at, last_used_step, created_at
+		) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = excluded.secret_encrypted,
+		    last_used_step = excluded.last_used_step,
+		    created_at = excluded.created_at
+		WHERE totp_credentials.confirmed_at IS NULL
+	`
+
+	res, err := store.exec.ExecContext(
+		ctx,
+		query,
+		credential.UserID,
+		credential.SecretEncrypted,
+		credential.ConfirmedAt,
+		credential.LastUsedStep,
+		credential.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+func (store *TOTPStore) GetByUserID(
+	ctx context.Context,
+	userID string,
+) (domain.TOTPCredential, error) {
+
+	query := `
+		SELECT user_id, secret_encrypted, confirmed_at, last_used_step, created_at
+		FROM totp_credentials
+		WHERE user_id = ?
+	`
+
+	var credential domain.TOTPCredential
+	var confirmed sql.NullTime
+
+	err := store.exec.QueryRowContext(ctx, query, userID).Scan(
+		&credential.UserID,
+		&credential.SecretEncrypted,
+		&confirmed,
+		&credential.LastUsedStep,
+		&credential.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.TOTPCredential{}, errs.ErrNotFound
+		}
+		return domain.TOTPCredential{}, err
+	}
+
+	if confirmed.Valid {
+		credential.ConfirmedAt = &confirmed.Time
+	}
+
+	return credential, nil
+}
+
+func (store *TOTPStore) Confirm(
+	ctx context.Context,
+	userID string,
+	lastUsedStep int64,
+) error {
+
+	query := `
+		UPDATE totp_credentials
+		SET confirmed_at = ?, last_used_step = ?
+		WHERE user_id = ?
+		  AND confirmed_at IS NULL
+	`
+
+	res, err := store.exec.ExecContext(ctx, query, time.Now(), lastUsedStep, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func (store *TOTPStore) UpdateLastUsedStep(
+	ctx context.Context,
+	userID string,
+	step int64,
+) error {
+
+	// the condition makes concurrent use of the same code fail for all but one
+	query := `
+		UPDATE totp_credentials
+		SET last_used_step = ?
+		WHERE user_id = ?
+		  AND last_used_step < ?
+	`
+
+	res, err := store.exec.ExecContext(ctx, query, step, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
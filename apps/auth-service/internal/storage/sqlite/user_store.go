@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
 	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
@@ -22,8 +23,8 @@ func NewUserStore(sqlExec storage.SQLExecutor) storage.UserStore {
 
 func (store *UserStore) Create(ctx context.Context, user domain.User) error {
 	const q = `
-		INSERT INTO users (id, email, password_hash, created_at)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO users (id, email, password_hash, email_verified_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
 	`
 
 	_, err := store.sql.ExecContext(
@@ -32,6 +33,7 @@ func (store *UserStore) Create(ctx context.Context, user domain.User) error {
 		user.ID,
 		user.Email,
 		user.PasswordHash,
+		user.EmailVerifiedAt,
 		user.CreatedAt,
 	)
 
@@ -44,14 +46,12 @@ func (store *UserStore) Create(ctx context.Context, user domain.User) error {
 func (store *UserStore) GetByEmail(ctx context.Context, email string) (domain.User, error) {
 
 	const query = `
-	  SELECT id, email, password_hash, created_at
+	  SELECT id, email, password_hash, email_verified_at, created_at
 	  FROM users
 	  WHERE email = ?
 	`
 
-	var user domain.User
-	err := store.sql.QueryRowContext(ctx, query, email).
-		Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	user, err := scanUser(store.sql.QueryRowContext(ctx, query, email))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -66,14 +66,12 @@ func (store *UserStore) GetByEmail(ctx context.Context, email string) (domain.Us
 func (store *UserStore) GetById(ctx context.Context, id string) (domain.User, error) {
 
 	const query = `
-	  SELECT id, email, password_hash, created_at
+	  SELECT id, email, password_hash, email_verified_at, created_at
 	  FROM users
 	  WHERE id = ?
 	`
 
-	var user domain.User
-	err := store.sql.QueryRowContext(ctx, query, id).
-		Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	user, err := scanUser(store.sql.QueryRowContext(ctx, query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -84,3 +82,70 @@ func (store *UserStore) GetById(ctx context.Context, id string) (domain.User, er
 
 	return user, nil
 }
+
+func (store *UserStore) MarkEmailVerified(ctx context.Context, id string) error {
+
+	const query = `
+	  UPDATE users
+	  SET email_verified_at = ?
+	  WHERE id = ?
+	    AND email_verified_at IS NULL
+	`
+
+	res, err := store.sql.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func (store *UserStore) UpdatePasswordHash(ctx context.Context, id string, passwordHash string) error {
+
+	const query = `
+	  UPDATE users
+	  SET password_hash = ?
+	  WHERE id = ?
+	`
+
+	res, err := store.sql.ExecContext(ctx, query, passwordHash, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanUser(row *sql.Row) (domain.User, error) {
+	var user domain.User
+	var verified sql.NullTime
+
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &verified, &user.CreatedAt)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if verified.Valid {
+		user.EmailVerifiedAt = &verified.Time
+	}
+
+	return user, nil
+}
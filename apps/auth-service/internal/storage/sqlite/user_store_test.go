@@ -3,7 +3,6 @@ package sqlite
 import (
 	"context"
 	"database/sql"
-	"os"
 	"testing"
 	"time"
 
@@ -16,26 +15,7 @@ import (
 func setupTestDB(test *testing.T) storage.UserStore {
 	test.Helper()
 
-	dbPath := "test_auth.db"
-	os.Remove(dbPath)
-
-	db, err := Open(dbPath)
-	require.NoError(test, err)
-
-	_, err = db.Exec(`
-	  CREATE TABLE users (
-		id TEXT PRIMARY KEY,
-		email TEXT NOT NULL UNIQUE,
-		password_hash TEXT NOT NULL,
-		created_at TIMESTAMP NOT NULL
-	  );
-	`)
-	require.NoError(test, err)
-
-	test.Cleanup(func() {
-		db.Close()
-		os.Remove(dbPath)
-	})
+	db := openTestDB(test, "test_auth.db")
 	var _ storage.SQLExecutor = db
 	var _ storage.SQLExecutor = (*sql.Tx)(nil)
 
@@ -74,3 +54,51 @@ func TestUserStore_DuplicateEmail(test *testing.T) {
 
 	require.ErrorIs(test, err, errs.ErrAlreadyExists)
 }
+
+func TestUserStore_MarkEmailVerified(test *testing.T) {
+	store := setupTestDB(test)
+
+	ctx := context.Background()
+	user := domain.User{
+		ID:           "user-1",
+		Email:        "anna@example.com",
+		PasswordHash: "hash",
+		CreatedAt:    time.Now(),
+	}
+	require.NoError(test, store.Create(ctx, user))
+
+	loaded, err := store.GetById(ctx, user.ID)
+	require.NoError(test, err)
+	require.Nil(test, loaded.EmailVerifiedAt)
+
+	require.NoError(test, store.MarkEmailVerified(ctx, user.ID))
+
+	loaded, err = store.GetById(ctx, user.ID)
+	require.NoError(test, err)
+	require.NotNil(test, loaded.EmailVerifiedAt)
+
+	// already verified, or no such user
+	require.ErrorIs(test, store.MarkEmailVerified(ctx, user.ID), errs.ErrNotFound)
+	require.ErrorIs(test, store.MarkEmailVerified(ctx, "user-2"), errs.ErrNotFound)
+}
+
+func TestUserStore_UpdatePasswordHash(test *testing.T) {
+	store := setupTestDB(test)
+
+	ctx := context.Background()
+	user := domain.User{
+		ID:           "user-1",
+		Email:        "anna@example.com",
+		PasswordHash: "hash",
+		CreatedAt:    time.Now(),
+	}
+	require.NoError(test, store.Create(ctx, user))
+
+	require.NoError(test, store.UpdatePasswordHash(ctx, user.ID, "new-hash"))
+
+	loaded, err := store.GetById(ctx, user.ID)
+	require.NoError(test, err)
+	require.Equal(test, "new-hash", loaded.PasswordHash)
+
+	require.ErrorIs(test, store.UpdatePasswordHash(ctx, "user-2", "new-hash"), errs.ErrNotFound)
+}
@@ -0,0 +1,151 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+	errs "github.com/Tata-Matata/family-space/apps/auth-service/internal/errors"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+)
+
+func setupWebAuthnTestDB(test *testing.T) (storage.WebAuthnCredentialStore, storage.WebAuthnChallengeStore) {
+	test.Helper()
+
+	db := openTestDB(test, "test_webauthn.db")
+
+	return NewWebAuthnCredentialStore(db), NewWebAuthnChallengeStore(db)
+}
+
+func newTestWebAuthnCredential(userID string) domain.WebAuthnCredential {
+	return domain.WebAuthnCredential{
+		ID:         uuid.NewString(),
+		UserID:     userID,
+		PublicKey:  []byte{0x30, 0x59, 0x30, 0x13},
+		Algorithm:  -7,
+		Transports: []string{"internal", "hybrid"},
+		CreatedAt:  time.Now().UTC(),
+	}
+}
+
+func TestWebAuthnCredentialStore_CreateAndGetByID(test *testing.T) {
+	credentials, _ := setupWebAuthnTestDB(test)
+
+	ctx := context.Background()
+	credential := newTestWebAuthnCredential(uuid.NewString())
+	require.NoError(test, credentials.Create(ctx, credential))
+
+	got, err := credentials.GetByID(ctx, credential.ID)
+	require.NoError(test, err)
+	require.Equal(test, credential.UserID, got.UserID)
+	require.Equal(test, credential.PublicKey, got.PublicKey)
+	require.Equal(test, credential.Algorithm, got.Algorithm)
+	require.Equal(test, credential.Transports, got.Transports)
+	require.Nil(test, got.LastUsedAt)
+
+	require.ErrorIs(test, credentials.Create(ctx, credential), errs.ErrAlreadyExists)
+
+	_, err = credentials.GetByID(ctx, uuid.NewString())
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
+
+func TestWebAuthnCredentialStore_ListByUserID(test *testing.T) {
+	credentials, _ := setupWebAuthnTestDB(test)
+
+	ctx := context.Background()
+	userID := uuid.NewString()
+	require.NoError(test, credentials.Create(ctx, newTestWebAuthnCredential(userID)))
+	require.NoError(test, credentials.Create(ctx, newTestWebAuthnCredential(userID)))
+	require.NoError(test, credentials.Create(ctx, newTestWebAuthnCredential(uuid.NewString())))
+
+	got, err := credentials.ListByUserID(ctx, userID)
+	require.NoError(test, err)
+	require.Len(test, got, 2)
+
+	got, err = credentials.ListByUserID(ctx, uuid.NewString())
+	require.NoError(test, err)
+	require.Empty(test, got)
+}
+
+func TestWebAuthnCredentialStore_UpdateSignCount(test *testing.T) {
+	credentials, _ := setupWebAuthnTestDB(test)
+
+	ctx := context.Background()
+	credential := newTestWebAuthnCredential(uuid.NewString())
+	require.NoError(test, credentials.Create(ctx, credential))
+
+	require.NoError(test, credentials.UpdateSignCount(ctx, credential.ID, 7))
+
+	got, err := credentials.GetByID(ctx, credential.ID)
+	require.NoError(test, err)
+	require.Equal(test, uint32(7), got.SignCount)
+	require.NotNil(test, got.LastUsedAt)
+
+	require.ErrorIs(test, credentials.UpdateSignCount(ctx, uuid.NewString(), 1), errs.ErrNotFound)
+}
+
+func TestWebAuthnChallengeStore_ConsumeOnlyOnce(test *testing.T) {
+	_, challenges := setupWebAuthnTestDB(test)
+
+	ctx := context.Background()
+	challenge := domain.WebAuthnChallenge{
+		ID:        uuid.NewString(),
+		Ceremony:  domain.WebAuthnLogin,
+		Challenge: "challenge",
+		ExpiresAt: time.Now().Add(5 * time.Minute).UTC(),
+		CreatedAt: time.Now().UTC(),
+	}
+	require.NoError(test, challenges.Create(ctx, challenge))
+
+	got, err := challenges.Consume(ctx, challenge.ID)
+	require.NoError(test, err)
+	require.Equal(test, challenge.Challenge, got.Challenge)
+	require.Equal(test, domain.WebAuthnLogin, got.Ceremony)
+	require.WithinDuration(test, challenge.ExpiresAt, got.ExpiresAt, time.Second)
+
+	_, err = challenges.Consume(ctx, challenge.ID)
+	require.ErrorIs(test, err, errs.ErrNotFound)
+}
+
+func TestWebAuthnChallengeStore_DeleteExpired(test *testing.T) {
+	_, challenges := setupWebAuthnTestDB(test)
+
+	ctx := context.Background()
+	// stored times of another zone compare by the instant
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	newChallenge := func(expiresAt time.Time) domain.WebAuthnChallenge {
+		return domain.WebAuthnChallenge{
+			ID:        uuid.NewString(),
+			Ceremony:  domain.WebAuthnLogin,
+			Challenge: "challenge",
+			ExpiresAt: expiresAt.In(zone),
+			CreatedAt: time.Now().In(zone),
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		require.NoError(test, challenges.Create(ctx, newChallenge(time.Now().Add(-time.Minute))))
+	}
+	current := newChallenge(time.Now().Add(5 * time.Minute))
+	require.NoError(test, challenges.Create(ctx, current))
+
+	// in batches of at most the limit
+	deleted, err := challenges.DeleteExpired(ctx, time.Now(), 2)
+	require.NoError(test, err)
+	require.Equal(test, int64(2), deleted)
+
+	deleted, err = challenges.DeleteExpired(ctx, time.Now(), 2)
+	require.NoError(test, err)
+	require.Equal(test, int64(1), deleted)
+
+	deleted, err = challenges.DeleteExpired(ctx, time.Now(), 2)
+	require.NoError(test, err)
+	require.Equal(test, int64(0), deleted)
+
+	_, err = challenges.Consume(ctx, current.ID)
+	require.NoError(test, err)
+}
@@ -6,3 +6,12 @@ type UserStoreProvider func(exec SQLExecutor) UserStore
 type FamilyStoreProvider func(exec SQLExecutor) FamilyStore
 type MembershipStoreProvider func(exec SQLExecutor) MembershipStore
 type RefreshTokenStoreProvider func(exec SQLExecutor) RefreshTokenStore
+type AuthorizationCodeStoreProvider func(exec SQLExecutor) AuthorizationCodeStore
+type TOTPStoreProvider func(exec SQLExecutor) TOTPStore
+type RecoveryCodeStoreProvider func(exec SQLExecutor) RecoveryCodeStore
+type WebAuthnCredentialStoreProvider func(exec SQLExecutor) WebAuthnCredentialStore
+type WebAuthnChallengeStoreProvider func(exec SQLExecutor) WebAuthnChallengeStore
+type PasswordResetTokenStoreProvider func(exec SQLExecutor) PasswordResetTokenStore
+type LoginThrottleStoreProvider func(exec SQLExecutor) LoginThrottleStore
+type RateLimitBucketStoreProvider func(exec SQLExecutor) RateLimitBucketStore
+type AuditEventStoreProvider func(exec SQLExecutor) AuditEventStore
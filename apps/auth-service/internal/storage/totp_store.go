@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+type TOTPStore interface {
+	// stores a new, unconfirmed credential; replaces a pending (unconfirmed) one
+	SavePending(ctx context.Context, credential domain.TOTPCredential) error
+	GetByUserID(ctx context.Context, userID string) (domain.TOTPCredential, error)
+	Confirm(ctx context.Context, userID string, lastUsedStep int64) error
+	// ErrNotFound if step is not newer than the stored one (concurrent replay)
+	UpdateLastUsedStep(ctx context.Context, userID string, step int64) error
+}
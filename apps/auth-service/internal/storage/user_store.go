@@ -12,4 +12,8 @@ type UserStore interface {
 	Create(ctx context.Context, user domain.User) error
 	GetByEmail(ctx context.Context, email string) (domain.User, error)
 	GetById(ctx context.Context, id string) (domain.User, error)
+	// ErrNotFound if the user does not exist or was already verified
+	MarkEmailVerified(ctx context.Context, id string) error
+	// ErrNotFound if the user does not exist
+	UpdatePasswordHash(ctx context.Context, id string, passwordHash string) error
 }
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/domain"
+)
+
+type WebAuthnCredentialStore interface {
+	// ErrAlreadyExists if the credential ID is already registered
+	Create(ctx context.Context, credential domain.WebAuthnCredential) error
+	GetByID(ctx context.Context, id string) (domain.WebAuthnCredential, error)
+	ListByUserID(ctx context.Context, userID string) ([]domain.WebAuthnCredential, error)
+	// also records the time of use
+	UpdateSignCount(ctx context.Context, id string, signCount uint32) error
+}
+
+type WebAuthnChallengeStore interface {
+	Create(ctx context.Context, challenge domain.WebAuthnChallenge) error
+	// returns and deletes the challenge, so a ceremony can be finished only once;
+	// ErrNotFound if it does not exist (anymore)
+	Consume(ctx context.Context, id string) (domain.WebAuthnChallenge, error)
+	// deletes at most limit challenges that expired before the given time,
+	// ceremonies that were started and never finished; returns the count
+	DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error)
+}
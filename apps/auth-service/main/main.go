@@ -3,36 +3,85 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"expvar"
 	"log"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/audit"
 	api "github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/http"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/jwt"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/mfa"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/oauth"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/password"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/refresh"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/service"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/auth/webauthn"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/cleanup"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/mail"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/ratelimit"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage"
+	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage/postgres"
 	"github.com/Tata-Matata/family-space/apps/auth-service/internal/storage/sqlite"
+	"github.com/Tata-Matata/family-space/apps/auth-service/migrations"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
+// iss and aud of access tokens, shared by signer and verifier
+const (
+	tokenIssuer   = "family-space-auth"
+	tokenAudience = "family-space-api"
+)
+
+// postgres advisory lock of the refresh token cleanup, any number
+// not used for another lock of the database
+const refreshTokenCleanupLockKey int64 = 0x61757468_01
+
+// postgres advisory lock of the passkey challenge cleanup
+const webAuthnChallengeCleanupLockKey int64 = 0x61757468_02
+
 func main() {
 
-	// Load RSA private key for JWT signing
-	path := os.Getenv("JWT_PRIVATE_KEY_PATH")
-	privateKey, err := jwt.LoadRSAPrivateKey(path)
+	accessTTL := 15 * time.Minute
+	// the other tokens signed with the key ring
+	emailVerificationTTL := 24 * time.Hour
+	mfaChallengeTTL := 5 * time.Minute
 
-	if err != nil {
-		log.Fatalf("failed to load JWT private key: %v", err)
+	// JWT_SIGNING_ALG selects RS256 (default), ES256 or EdDSA
+	signingAlg := os.Getenv("JWT_SIGNING_ALG")
+	if signingAlg == "" {
+		signingAlg = jwt.AlgRS256
 	}
-	signer := jwt.NewRS256Signer(
-		privateKey,
-		"family-space-auth", // iss
-		"family-space-api",  // aud
-		15*time.Minute,
+
+	// active key for JWT signing plus verify-only retiring keys
+	keyRing := initKeyRing(signingAlg, max(accessTTL, emailVerificationTTL, mfaChallengeTTL))
+	signer, err := jwt.NewAccessTokenSigner(
+		signingAlg,
+		keyRing,
+		tokenIssuer,
+		tokenAudience,
+		accessTTL,
 	)
+	if err != nil {
+		log.Fatalf("failed to create JWT signer: %v", err)
+	}
+	accessVerifier := jwt.NewAccessTokenVerifier(keyRing, tokenIssuer, tokenAudience)
+
+	// OpenID Connect: public base URL of this service, iss of ID tokens
+	oidcIssuer := strings.TrimSuffix(os.Getenv("OIDC_ISSUER_URL"), "/")
+	if oidcIssuer == "" {
+		log.Fatal("OIDC_ISSUER_URL must be set")
+	}
+	idTokenSigner, err := jwt.NewIDTokenSigner(signingAlg, keyRing, oidcIssuer, accessTTL)
+	if err != nil {
+		log.Fatalf("failed to create ID token signer: %v", err)
+	}
 
 	// Initialize SQLite storage
 	var db *sql.DB
@@ -46,74 +95,468 @@ func main() {
 	default:
 		log.Fatal("DB_DRIVER must be set to select database (sqlite or postgres)")
 	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// SCHEMA: pending migrations of the driver run before anything uses the database
+	applied, err := migrations.Apply(context.Background(), db, os.Getenv("DB_DRIVER"))
+	if err != nil {
+		log.Fatalf("failed to migrate database: %v", err)
+	}
+	if len(applied) > 0 {
+		log.Printf("applied migrations %s", strings.Join(applied, ", "))
+	}
 
 	transactionMgr := storage.NewTransactionMgr(db)
 
+	// stores of the selected driver, the queries differ between sqlite and postgres
+	stores := initStoreProviders(os.Getenv("DB_DRIVER"))
+
+	// AUDIT LOG of security events, hash chained
+	auditSink := audit.NewDBSink(transactionMgr, stores.auditEvents)
+
+	// `auth-service verify-audit` checks the audit log for tampering and exits
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		verifyAuditLog(auditSink)
+		return
+	}
+
+	// EMAIL VERIFICATION: the link in the email points to EMAIL_VERIFICATION_URL,
+	// a page of the web UI that posts the token to /verify-email
+	verifyURL := os.Getenv("EMAIL_VERIFICATION_URL")
+	if verifyURL == "" {
+		verifyURL = oidcIssuer + "/verify-email"
+	}
+	verificationTokens, err := jwt.NewEmailVerificationTokens(signingAlg, keyRing, tokenIssuer, emailVerificationTTL)
+	if err != nil {
+		log.Fatalf("failed to create email verification tokens: %v", err)
+	}
+	mailer := initMailer()
+	verificationService := service.NewEmailVerificationService(
+		transactionMgr,
+		stores.users,
+		verificationTokens,
+		mailer,
+		verifyURL,
+	)
+
 	// REGISTER SERVICE
-	hasher := password.NewBcryptHasher(0)
+	// new passwords are hashed with Argon2id; bcrypt hashes of existing
+	// users still verify and are replaced on their next login
+	hasher := password.NewMultiHasher(
+		password.NewArgon2idHasher(password.DefaultArgon2idParams),
+		password.NewBcryptHasher(0),
+	)
+	passwordPolicy := initPasswordPolicy()
 
 	registrationService := service.NewRegistrationService(
 		transactionMgr,
 		hasher,
 		//function reference
-		sqlite.NewUserStore,
-		sqlite.NewFamilyStore,
-		sqlite.NewMembershipStore,
+		stores.users,
+		stores.families,
+		stores.memberships,
+		verificationService,
+		passwordPolicy,
+		auditSink,
 	)
 	registerHandler := api.NewRegisterHandler(
 		registrationService,
 	)
 
+	// REFRESH TOKENS (issued at login, rotated on refresh)
+	refreshKey := os.Getenv("REFRESH_TOKEN_HMAC_KEY")
+	if refreshKey == "" {
+		log.Fatal("REFRESH_TOKEN_HMAC_KEY must be set")
+	}
+	refreshHasher := refresh.NewHMACRefreshTokenHasher([]byte(refreshKey))
+	refreshGen := &refresh.SecureRefreshTokenGenerator{}
+	sessionLifetimes := initSessionLifetimes()
+	// nil unless REFRESH_TOKEN_TRANSPORT=cookie (browser UI); the cookie lives
+	// as long as the longest token, the server decides when it is expired
+	refreshCookie := initRefreshCookie()
+
+	// MFA: TOTP secrets are encrypted at rest; the challenge token links
+	// the password step of a login to the one-time code step
+	secretBox := initMFASecretBox()
+	mfaChallenges, err := jwt.NewMFAChallengeTokens(signingAlg, keyRing, tokenIssuer, mfaChallengeTTL)
+	if err != nil {
+		log.Fatalf("failed to create MFA challenge tokens: %v", err)
+	}
+
+	// apps of the authorization code flow; ID tokens are only issued to them
+	oauthClients := initOAuthClients()
+
 	// LOGIN SERVICE
 	loginService := service.NewLoginService(
 		transactionMgr,
 		hasher,
 
-		sqlite.NewUserStore,
-		sqlite.NewMembershipStore,
+		stores.users,
+		stores.memberships,
+		stores.refreshTokens,
+		refreshHasher,
+		refreshGen,
 		signer,
+		idTokenSigner,
+		oauthClients,
+		stores.totpSecrets,
+		stores.recoveryCodes,
+		secretBox,
+		mfaChallenges,
+		sessionLifetimes,
+		initEmailVerificationPolicy(),
+		stores.loginThrottles,
+		initLoginThrottling(),
+		auditSink,
 	)
 	loginHandler := api.NewLoginHandler(
 		loginService,
-		15*time.Minute,
+		accessTTL,
+		refreshCookie,
+	)
+	loginMFAHandler := api.NewLoginMFAHandler(
+		loginService,
+		accessTTL,
+		refreshCookie,
 	)
 
-	// REFRESH SERVICE
-	refreshKey := os.Getenv("REFRESH_TOKEN_HMAC_KEY")
-	if refreshKey == "" {
-		log.Fatal("REFRESH_TOKEN_HMAC_KEY must be set")
+	// MFA ENROLLMENT with the current password (recovery codes are hashed like refresh tokens)
+	mfaService := service.NewMFAService(
+		transactionMgr,
+		stores.users,
+		hasher,
+		stores.totpSecrets,
+		stores.recoveryCodes,
+		secretBox,
+		refreshHasher,
+		accessVerifier,
+		"family-space",
+	)
+
+	// PASSKEYS (WebAuthn): passwordless login, same tokens as a password login
+	passkeyService := service.NewPasskeyService(
+		transactionMgr,
+		loginService,
+		stores.webAuthnCredentials,
+		stores.webAuthnChallenges,
+		initRelyingParty(oidcIssuer),
+		accessVerifier,
+		5*time.Minute,
+	)
+
+	// PASSWORD RESET: the emailed link points to PASSWORD_RESET_URL, a page
+	// of the web UI that asks for the new password and posts it to /password/reset
+	resetURL := os.Getenv("PASSWORD_RESET_URL")
+	if resetURL == "" {
+		resetURL = oidcIssuer + "/reset-password"
 	}
+	passwordResetService := service.NewPasswordResetService(
+		transactionMgr,
+		stores.users,
+		stores.passwordResets,
+		stores.refreshTokens,
+		stores.memberships,
+		stores.families,
+		hasher,
+		passwordPolicy,
+		refreshGen,
+		refreshHasher,
+		mailer,
+		resetURL,
+		time.Hour,
+		auditSink,
+	)
+
+	// PASSWORD CHANGE (with an access token and the current password)
+	passwordChangeService := service.NewPasswordChangeService(
+		transactionMgr,
+		stores.users,
+		stores.refreshTokens,
+		stores.memberships,
+		stores.families,
+		hasher,
+		passwordPolicy,
+		refreshHasher,
+		accessVerifier,
+		auditSink,
+	)
+
+	// REFRESH SERVICE
 	refreshService := service.NewRefreshService(
 		transactionMgr,
-		sqlite.NewRefreshTokenStore,
-		sqlite.NewUserStore,
-		sqlite.NewMembershipStore,
-		refresh.NewHMACRefreshTokenHasher([]byte(refreshKey)),
-		&refresh.SecureRefreshTokenGenerator{},
+		stores.refreshTokens,
+		stores.users,
+		stores.memberships,
+		refreshHasher,
+		refreshGen,
 		signer,
-		30*24*time.Hour,
+		sessionLifetimes,
+		auditSink,
 	)
 	refreshHandler := api.NewRefreshHandler(
 		refreshService,
-		15*time.Minute,
+		accessTTL,
+		refreshCookie,
+	)
+
+	// LOGOUT SERVICE
+	logoutService := service.NewLogoutService(
+		transactionMgr,
+		stores.refreshTokens,
+		refreshHasher,
+		auditSink,
+	)
+	logoutHandler := api.NewLogoutHandler(
+		logoutService,
+		refreshCookie,
+	)
+
+	// RFC 7009 revocation shares the logout service
+	revokeHandler := api.NewRevokeHandler(logoutService)
+
+	// INTROSPECTION SERVICE (internal callers only)
+	introspectionService := service.NewIntrospectionService(
+		transactionMgr,
+		stores.refreshTokens,
+		stores.memberships,
+		refreshHasher,
+		accessVerifier,
+	)
+	introspectHandler := api.NewIntrospectHandler(
+		introspectionService,
+		initIntrospectionClients(),
+	)
+
+	// AUTHORIZATION CODE FLOW (OAuth 2.0 with PKCE, for SPAs, native and household apps)
+	authorizationService := service.NewAuthorizationService(
+		transactionMgr,
+		loginService,
+		stores.authorizationCodes,
+		oauthClients,
+		refreshHasher,
+		refreshGen,
+		time.Minute,
+	)
+	authorizeHandler := api.NewAuthorizeHandler(authorizationService)
+	tokenHandler := api.NewTokenHandler(
+		authorizationService,
+		refreshService,
+		accessTTL,
+	)
+
+	// USERINFO SERVICE (OpenID Connect)
+	userInfoService := service.NewUserInfoService(
+		transactionMgr,
+		stores.users,
+		stores.memberships,
+		accessVerifier,
+	)
+	userInfoHandler := api.NewUserInfoHandler(userInfoService)
+
+	// SESSIONS (list and end the caller's logins)
+	sessionService := service.NewSessionService(
+		transactionMgr,
+		stores.refreshTokens,
+		accessVerifier,
+		auditSink,
 	)
 
+	// CLEANUP of refresh tokens that expired or were revoked and of unfinished passkey
+	// ceremonies, in the background; with postgres only the replica holding the
+	// advisory lock of a job runs it
+	for _, scheduler := range initCleanup(db, transactionMgr, stores) {
+		go scheduler.Run(context.Background())
+	}
+
+	// RATE LIMITING (per client IP; login, authorize, forgot password and resend verification also per email)
+	rateLimits := initRateLimits(transactionMgr, stores)
+
 	// SETUP HTTP SERVER
 	mux := http.NewServeMux()
-	mux.Handle("/register", registerHandler)
-	mux.Handle("/login", loginHandler)
-	mux.Handle("/refresh", refreshHandler)
+	mux.Handle("/register", rateLimits.wrap("register", registerHandler, api.ByClientIP))
+	mux.Handle("/verify-email", api.NewVerifyEmailHandler(verificationService))
+	// sends mail like forgot password and shares its buckets
+	mux.Handle("/verify-email/resend", rateLimits.wrap(
+		"password",
+		api.NewResendVerificationHandler(verificationService),
+		api.ByClientIP,
+		api.ByEmail,
+	))
+	mux.Handle("/password/forgot", rateLimits.wrap(
+		"password",
+		api.NewForgotPasswordHandler(passwordResetService),
+		api.ByClientIP,
+		api.ByEmail,
+	))
+	mux.Handle("/password/reset", rateLimits.wrap(
+		"password",
+		api.NewResetPasswordHandler(passwordResetService),
+		api.ByClientIP,
+	))
+	mux.Handle("/password/change", rateLimits.wrap(
+		"password",
+		api.NewChangePasswordHandler(passwordChangeService, refreshCookie),
+		api.ByClientIP,
+	))
+	mux.Handle("/login", rateLimits.wrap("login", loginHandler, api.ByClientIP, api.ByEmail))
+	// the second step of a login and the login page of the code flow share the login buckets
+	mux.Handle("/login/mfa", rateLimits.wrap("login", loginMFAHandler, api.ByClientIP))
+	// take the current password (or a TOTP code) like /password/change and share its buckets
+	mux.Handle("/mfa/totp/enroll", rateLimits.wrap("password", api.NewTOTPEnrollHandler(mfaService), api.ByClientIP))
+	mux.Handle("/mfa/totp/confirm", rateLimits.wrap("password", api.NewTOTPConfirmHandler(mfaService), api.ByClientIP))
+	mux.Handle("/mfa/recovery-codes", rateLimits.wrap("password", api.NewRecoveryCodesHandler(mfaService), api.ByClientIP))
+	mux.Handle("/passkeys/register/begin", api.NewPasskeyRegisterBeginHandler(passkeyService))
+	mux.Handle("/passkeys/register/finish", api.NewPasskeyRegisterFinishHandler(passkeyService))
+	// unauthenticated and stores a challenge, limited like the other login steps
+	mux.Handle("/passkeys/login/begin", rateLimits.wrap(
+		"login",
+		api.NewPasskeyLoginBeginHandler(passkeyService),
+		api.ByClientIP,
+	))
+	mux.Handle("/passkeys/login/finish", api.NewPasskeyLoginFinishHandler(passkeyService, accessTTL, refreshCookie))
+	mux.Handle("/refresh", rateLimits.wrap("refresh", refreshHandler, api.ByClientIP))
+	mux.Handle("/logout", logoutHandler)
+	mux.Handle("/authorize", rateLimits.wrap("login", authorizeHandler, api.ByClientIP, api.ByEmail))
+	mux.Handle("/token", tokenHandler)
+	mux.Handle("/revoke", revokeHandler)
+	mux.Handle("/introspect", introspectHandler)
 	mux.Handle("/health", api.NewHealthHandler())
+	mux.Handle("/userinfo", userInfoHandler)
+	mux.Handle("/sessions", api.NewSessionsHandler(sessionService))
+	mux.Handle("/sessions/revoke-others", api.NewRevokeOtherSessionsHandler(sessionService))
+	mux.Handle("/sessions/{id}", api.NewRevokeSessionHandler(sessionService))
+	mux.Handle("/.well-known/jwks.json", api.NewJWKSHandler(keyRing, 5*time.Minute))
+	mux.Handle("/.well-known/openid-configuration", api.NewOIDCDiscoveryHandler(oidcIssuer, signingAlg))
 
 	srv := &http.Server{
-		Addr:    ":8080",
-		Handler: mux,
+		Addr: ":8080",
+		// client IPs (login throttling, sessions) come from X-Forwarded-For of the gateway
+		Handler: api.NewTrustedProxies(initTrustedProxies(), api.NewClientInfo(mux)),
+	}
+
+	// METRICS (expvar, e.g. the cleanup counters) on their own port, not through the gateway
+	if metricsAddr := os.Getenv("METRICS_ADDR"); metricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/debug/vars", expvar.Handler())
+		go func() {
+			log.Fatal(http.ListenAndServe(metricsAddr, metricsMux))
+		}()
 	}
 
 	log.Fatal(srv.ListenAndServe())
 
 }
 
+// JWT_PRIVATE_KEY_PATH is the active signing key (matching signingAlg); keys it replaced
+// are listed (public PEM, comma separated) in JWT_RETIRING_PUBLIC_KEY_PATHS and stay published
+// for JWT_KEY_OVERLAP, so outstanding tokens remain verifiable. The overlap defaults to,
+// and must not be shorter than, the longest TTL of a token signed with the ring
+func initKeyRing(signingAlg string, longestTTL time.Duration) *jwt.KeyRing {
+	privateKey, err := jwt.LoadSigningKey(signingAlg, os.Getenv("JWT_PRIVATE_KEY_PATH"))
+	if err != nil {
+		log.Fatalf("failed to load JWT private key: %v", err)
+	}
+
+	overlap := longestTTL
+	if value := os.Getenv("JWT_KEY_OVERLAP"); value != "" {
+		overlap, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("invalid JWT_KEY_OVERLAP: %v", err)
+		}
+	}
+
+	keyRing, err := jwt.NewKeyRing(privateKey, overlap)
+	if err != nil {
+		log.Fatalf("failed to create JWT key ring: %v", err)
+	}
+	if err := keyRing.RequireOverlap(longestTTL); err != nil {
+		log.Fatalf("invalid JWT_KEY_OVERLAP: %v", err)
+	}
+
+	retiringPaths := os.Getenv("JWT_RETIRING_PUBLIC_KEY_PATHS")
+	if retiringPaths == "" {
+		return keyRing
+	}
+
+	for _, path := range strings.Split(retiringPaths, ",") {
+		// may use a different algorithm than the active key (algorithm migration)
+		publicKey, err := jwt.LoadPublicKey(strings.TrimSpace(path))
+		if err != nil {
+			log.Fatalf("failed to load retiring JWT public key %s: %v", path, err)
+		}
+		if err := keyRing.AddRetiringKey(publicKey, time.Now().Add(overlap)); err != nil {
+			log.Fatalf("failed to add retiring JWT public key %s: %v", path, err)
+		}
+	}
+
+	return keyRing
+}
+
+// exits non-zero if the chain is broken; the head hash it prints is worth
+// keeping outside the database, a full rewrite of the table is not detected otherwise
+func verifyAuditLog(auditSink *audit.DBSink) {
+	checked, head, err := auditSink.Verify(context.Background())
+	if err != nil {
+		log.Fatalf("audit log verification failed: %v", err)
+	}
+	log.Printf("audit log intact: %d events, head %s", checked, head)
+}
+
+// store implementations of a database driver
+type storeProviders struct {
+	users               storage.UserStoreProvider
+	families            storage.FamilyStoreProvider
+	memberships         storage.MembershipStoreProvider
+	refreshTokens       storage.RefreshTokenStoreProvider
+	loginThrottles      storage.LoginThrottleStoreProvider
+	rateLimitBuckets    storage.RateLimitBucketStoreProvider
+	auditEvents         storage.AuditEventStoreProvider
+	authorizationCodes  storage.AuthorizationCodeStoreProvider
+	totpSecrets         storage.TOTPStoreProvider
+	recoveryCodes       storage.RecoveryCodeStoreProvider
+	webAuthnCredentials storage.WebAuthnCredentialStoreProvider
+	webAuthnChallenges  storage.WebAuthnChallengeStoreProvider
+	passwordResets      storage.PasswordResetTokenStoreProvider
+}
+
+func initStoreProviders(driver string) storeProviders {
+	if driver == "postgres" {
+		return storeProviders{
+			users:               postgres.NewUserStore,
+			families:            postgres.NewFamilyStore,
+			memberships:         postgres.NewMembershipStore,
+			refreshTokens:       postgres.NewRefreshTokenStore,
+			loginThrottles:      postgres.NewLoginThrottleStore,
+			rateLimitBuckets:    postgres.NewRateLimitBucketStore,
+			auditEvents:         postgres.NewAuditEventStore,
+			authorizationCodes:  postgres.NewAuthorizationCodeStore,
+			totpSecrets:         postgres.NewTOTPStore,
+			recoveryCodes:       postgres.NewRecoveryCodeStore,
+			webAuthnCredentials: postgres.NewWebAuthnCredentialStore,
+			webAuthnChallenges:  postgres.NewWebAuthnChallengeStore,
+			passwordResets:      postgres.NewPasswordResetTokenStore,
+		}
+	}
+
+	return storeProviders{
+		users:               sqlite.NewUserStore,
+		families:            sqlite.NewFamilyStore,
+		memberships:         sqlite.NewMembershipStore,
+		refreshTokens:       sqlite.NewRefreshTokenStore,
+		loginThrottles:      sqlite.NewLoginThrottleStore,
+		rateLimitBuckets:    sqlite.NewRateLimitBucketStore,
+		auditEvents:         sqlite.NewAuditEventStore,
+		authorizationCodes:  sqlite.NewAuthorizationCodeStore,
+		totpSecrets:         sqlite.NewTOTPStore,
+		recoveryCodes:       sqlite.NewRecoveryCodeStore,
+		webAuthnCredentials: sqlite.NewWebAuthnCredentialStore,
+		webAuthnChallenges:  sqlite.NewWebAuthnChallengeStore,
+		passwordResets:      sqlite.NewPasswordResetTokenStore,
+	}
+}
+
 func initSqlite() (*sql.DB, error) {
 
 	var db *sql.DB
@@ -154,3 +597,389 @@ func initPostgres() (*sql.DB, error) {
 	log.Println("using Postgres database")
 	return db, nil
 }
+
+// refresh token travels in the JSON body (mobile clients) unless cookie mode is selected
+func initRefreshCookie() *api.RefreshCookie {
+	switch os.Getenv("REFRESH_TOKEN_TRANSPORT") {
+	case "", "body":
+		return nil
+	case "cookie":
+	default:
+		log.Fatal("REFRESH_TOKEN_TRANSPORT must be body or cookie")
+	}
+
+	var sameSite http.SameSite
+	switch os.Getenv("REFRESH_COOKIE_SAMESITE") {
+	case "", "strict":
+		sameSite = http.SameSiteStrictMode
+	case "lax":
+		sameSite = http.SameSiteLaxMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	default:
+		log.Fatal("REFRESH_COOKIE_SAMESITE must be strict, lax or none")
+	}
+
+	log.Println("refresh tokens are sent as HttpOnly cookies")
+	return api.NewRefreshCookie("refresh_token", sameSite)
+}
+
+// OAUTH_CLIENTS_FILE is a JSON list of apps allowed to use the authorization code flow;
+// without it the flow is disabled (every client is unknown)
+func initOAuthClients() *oauth.ClientRegistry {
+	path := os.Getenv("OAUTH_CLIENTS_FILE")
+	if path == "" {
+		return oauth.NewClientRegistry(nil)
+	}
+
+	clients, err := oauth.LoadClientRegistry(path)
+	if err != nil {
+		log.Fatalf("failed to load OAuth clients: %v", err)
+	}
+	return clients
+}
+
+// MFA_ENCRYPTION_KEY is the base64 AES-256 key for TOTP secrets at rest;
+// changing it makes existing enrollments unusable
+func initMFASecretBox() *mfa.SecretBox {
+	key, err := base64.StdEncoding.DecodeString(os.Getenv("MFA_ENCRYPTION_KEY"))
+	if err != nil || len(key) != 32 {
+		log.Fatal("MFA_ENCRYPTION_KEY must be set to 32 base64 encoded bytes")
+	}
+
+	box, err := mfa.NewSecretBox(key)
+	if err != nil {
+		log.Fatalf("failed to create MFA secret box: %v", err)
+	}
+	return box
+}
+
+// MAIL_TRANSPORT=smtp delivers through SMTP_HOST:SMTP_PORT (default 587) with optional
+// SMTP_USERNAME/SMTP_PASSWORD; the default (file) writes .eml files to MAIL_DIR for development
+func initMailer() mail.Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Family Space <no-reply@localhost>"
+	}
+
+	switch os.Getenv("MAIL_TRANSPORT") {
+	case "", "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		mailer, err := mail.NewFileMailer(dir, from)
+		if err != nil {
+			log.Fatalf("failed to create file mailer: %v", err)
+		}
+		log.Printf("emails are written to %s", dir)
+		return mailer
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			log.Fatal("SMTP_HOST must be set when MAIL_TRANSPORT=smtp")
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return mail.NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+	default:
+		log.Fatal("MAIL_TRANSPORT must be file or smtp")
+		return nil
+	}
+}
+
+// EMAIL_VERIFICATION=required blocks login until the address is verified
+func initEmailVerificationPolicy() service.EmailVerificationPolicy {
+	switch os.Getenv("EMAIL_VERIFICATION") {
+	case "", "optional":
+		return service.EmailVerificationOptional
+	case "required":
+		return service.EmailVerificationRequired
+	default:
+		log.Fatal("EMAIL_VERIFICATION must be optional or required")
+		return service.EmailVerificationOptional
+	}
+}
+
+// PASSWORD_MIN_LENGTH counts characters (default 8); PASSWORD_MAX_LENGTH counts
+// bytes (default 72, the most bcrypt hashes, so an old hash never sees a cut-off
+// password). BREACHED_PASSWORDS_DIR enables the breached password check.
+func initPasswordPolicy() *password.Policy {
+	minLength := 8
+	if value := os.Getenv("PASSWORD_MIN_LENGTH"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			log.Fatal("PASSWORD_MIN_LENGTH must be a positive number")
+		}
+		minLength = parsed
+	}
+
+	maxLength := 72
+	if value := os.Getenv("PASSWORD_MAX_LENGTH"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < minLength {
+			log.Fatal("PASSWORD_MAX_LENGTH must be a number not below PASSWORD_MIN_LENGTH")
+		}
+		maxLength = parsed
+	}
+
+	var breached password.BreachedPasswords
+	if dir := os.Getenv("BREACHED_PASSWORDS_DIR"); dir != "" {
+		breachedDir, err := password.NewBreachedPasswordDir(dir)
+		if err != nil {
+			log.Fatalf("failed to open breached passwords: %v", err)
+		}
+		breached = breachedDir
+	} else {
+		log.Print("BREACHED_PASSWORDS_DIR not set, passwords are not checked against breaches")
+	}
+
+	return password.NewPolicy(minLength, maxLength, breached)
+}
+
+// limits per route, RATE_LIMIT_<ROUTE> as requests/period ("off" disables)
+var defaultRateLimits = map[string]string{
+	"login":    "10/1m",
+	"register": "5/1h",
+	"refresh":  "60/1m",
+	"password": "5/15m",
+}
+
+type rateLimits struct {
+	store  api.RateLimitStore
+	limits map[string]ratelimit.Limit
+}
+
+// RATE_LIMIT_STORE=database shares the buckets between replicas;
+// the default memory store limits every replica on its own
+func initRateLimits(transactionMgr storage.TransactionMgr, stores storeProviders) rateLimits {
+	limits := rateLimits{limits: map[string]ratelimit.Limit{}}
+
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "", "memory":
+		limits.store = ratelimit.NewMemoryStore()
+	case "database":
+		limits.store = ratelimit.NewDBStore(transactionMgr, stores.rateLimitBuckets)
+	default:
+		log.Fatal("RATE_LIMIT_STORE must be memory or database")
+	}
+
+	for route, fallback := range defaultRateLimits {
+		value, ok := os.LookupEnv("RATE_LIMIT_" + strings.ToUpper(route))
+		if !ok {
+			value = fallback
+		}
+
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			log.Fatalf("invalid RATE_LIMIT_%s: %v", strings.ToUpper(route), err)
+		}
+		limits.limits[route] = limit
+	}
+
+	return limits
+}
+
+func (limits rateLimits) wrap(route string, next http.Handler, keys ...api.RateLimitKey) http.Handler {
+	return api.NewRateLimit(limits.store, route, limits.limits[route], next, keys...)
+}
+
+// failed password logins lock an account after LOGIN_LOCKOUT_AFTER attempts
+// (default 10) for LOGIN_LOCKOUT_DURATION (default 15m); the backoff before
+// and the looser per-IP limits use the defaults of the service
+func initLoginThrottling() service.LoginThrottling {
+	throttling := service.DefaultLoginThrottling
+
+	if value := os.Getenv("LOGIN_LOCKOUT_AFTER"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts <= throttling.Account.FreeAttempts {
+			log.Fatalf("LOGIN_LOCKOUT_AFTER must be a number above %d", throttling.Account.FreeAttempts)
+		}
+		throttling.Account.LockoutAfter = attempts
+	}
+
+	if value := os.Getenv("LOGIN_LOCKOUT_DURATION"); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			log.Fatal("LOGIN_LOCKOUT_DURATION must be a positive duration")
+		}
+		throttling.Account.LockoutDuration = duration
+		throttling.IP.LockoutDuration = duration
+	}
+
+	return throttling
+}
+
+// sessions end after SESSION_IDLE_TIMEOUT without a refresh (default 24h) and
+// SESSION_MAX_LIFETIME after the login at the latest (default 7d, as 168h);
+// logins with remember me use SESSION_REMEMBER_IDLE_TIMEOUT (default 720h)
+// and SESSION_REMEMBER_MAX_LIFETIME (default 2160h)
+func initSessionLifetimes() service.SessionLifetimes {
+	lifetimes := service.DefaultSessionLifetimes
+
+	lifetimes.Short.IdleTimeout = durationEnv("SESSION_IDLE_TIMEOUT", lifetimes.Short.IdleTimeout)
+	lifetimes.Short.MaxLifetime = durationEnv("SESSION_MAX_LIFETIME", lifetimes.Short.MaxLifetime)
+	lifetimes.Long.IdleTimeout = durationEnv("SESSION_REMEMBER_IDLE_TIMEOUT", lifetimes.Long.IdleTimeout)
+	lifetimes.Long.MaxLifetime = durationEnv("SESSION_REMEMBER_MAX_LIFETIME", lifetimes.Long.MaxLifetime)
+
+	if lifetimes.Short.IdleTimeout > lifetimes.Short.MaxLifetime {
+		log.Fatal("SESSION_IDLE_TIMEOUT must not exceed SESSION_MAX_LIFETIME")
+	}
+	if lifetimes.Long.IdleTimeout > lifetimes.Long.MaxLifetime {
+		log.Fatal("SESSION_REMEMBER_IDLE_TIMEOUT must not exceed SESSION_REMEMBER_MAX_LIFETIME")
+	}
+
+	return lifetimes
+}
+
+// REFRESH_TOKEN_CLEANUP_INTERVAL (default 1h, off disables the cleanup) is how
+// often refresh tokens are deleted that expired, or were revoked with their whole
+// session, more than REFRESH_TOKEN_RETENTION ago (default 168h), at most
+// REFRESH_TOKEN_CLEANUP_BATCH_SIZE rows (default 500) per transaction
+func initCleanup(
+	db *sql.DB,
+	transactionMgr storage.TransactionMgr,
+	stores storeProviders,
+) []*cleanup.Scheduler {
+	retention := durationEnv("REFRESH_TOKEN_RETENTION", 7*24*time.Hour)
+
+	batchSize := 500
+	if value := os.Getenv("REFRESH_TOKEN_CLEANUP_BATCH_SIZE"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			log.Fatal("REFRESH_TOKEN_CLEANUP_BATCH_SIZE must be a positive number")
+		}
+		batchSize = size
+	}
+
+	// sqlite is a single process, replicas share a postgres database
+	lock := func(key int64) cleanup.Lock {
+		if os.Getenv("DB_DRIVER") == "postgres" {
+			return postgres.NewAdvisoryLock(db, key)
+		}
+		return nil
+	}
+
+	cleanupService := service.NewTokenCleanupService(
+		transactionMgr,
+		stores.refreshTokens,
+		stores.webAuthnChallenges,
+		retention,
+		batchSize,
+	)
+
+	// anyone can start a passkey login, so unfinished ceremonies are always purged
+	schedulers := []*cleanup.Scheduler{
+		cleanup.NewScheduler(
+			"webauthn_challenges",
+			durationEnv("WEBAUTHN_CHALLENGE_CLEANUP_INTERVAL", 10*time.Minute),
+			cleanupService.PurgeWebAuthnChallenges,
+			lock(webAuthnChallengeCleanupLockKey),
+		),
+	}
+
+	if os.Getenv("REFRESH_TOKEN_CLEANUP_INTERVAL") == "off" {
+		log.Println("refresh token cleanup is disabled")
+		return schedulers
+	}
+
+	return append(schedulers, cleanup.NewScheduler(
+		"refresh_tokens",
+		durationEnv("REFRESH_TOKEN_CLEANUP_INTERVAL", time.Hour),
+		cleanupService.PurgeRefreshTokens,
+		lock(refreshTokenCleanupLockKey),
+	))
+}
+
+// positive duration from the environment, fallback if unset
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("%s must be a positive duration", name)
+	}
+	return duration
+}
+
+// TRUSTED_PROXIES lists the comma separated addresses or CIDR ranges of the
+// gateway, whose X-Forwarded-For header names the client; without it the
+// header is ignored and the direct peer counts as the client
+func initTrustedProxies() []netip.Prefix {
+	var proxies []netip.Prefix
+
+	for _, value := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				log.Fatalf("invalid TRUSTED_PROXIES entry %q: %v", value, err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			log.Fatalf("invalid TRUSTED_PROXIES entry %q: %v", value, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies
+}
+
+// passkeys are bound to WEBAUTHN_RP_ID (default: host of the OIDC issuer);
+// WEBAUTHN_ORIGINS lists the comma separated origins of the web UI
+// (default: origin of the OIDC issuer)
+func initRelyingParty(oidcIssuer string) *webauthn.RelyingParty {
+	issuerURL, err := url.Parse(oidcIssuer)
+	if err != nil || issuerURL.Host == "" {
+		log.Fatal("OIDC_ISSUER_URL must be an absolute URL")
+	}
+
+	rpID := os.Getenv("WEBAUTHN_RP_ID")
+	if rpID == "" {
+		rpID = issuerURL.Hostname()
+	}
+
+	origins := []string{issuerURL.Scheme + "://" + issuerURL.Host}
+	if value := os.Getenv("WEBAUTHN_ORIGINS"); value != "" {
+		origins = nil
+		for _, origin := range strings.Split(value, ",") {
+			origins = append(origins, strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		}
+	}
+
+	return webauthn.NewRelyingParty(rpID, "Family Space", origins)
+}
+
+// INTROSPECTION_CLIENTS lists internal callers as comma separated client_id:secret pairs;
+// without it every introspection request is rejected
+func initIntrospectionClients() map[string]string {
+	clients := map[string]string{}
+
+	value := os.Getenv("INTROSPECTION_CLIENTS")
+	if value == "" {
+		return clients
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		clientID, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || clientID == "" || secret == "" {
+			log.Fatal("INTROSPECTION_CLIENTS must be client_id:secret pairs")
+		}
+		clients[clientID] = secret
+	}
+
+	return clients
+}
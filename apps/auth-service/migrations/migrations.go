@@ -0,0 +1,120 @@
+// Package migrations brings the database schema up to date at startup.
+// The files of a dialect run in name order, each at most once: applied
+// versions are recorded in schema_migrations, so ALTER TABLE statements
+// that cannot be repeated (sqlite ADD COLUMN) never run twice.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+)
+
+// replicas starting together wait for the one migrating (postgres only)
+const postgresLockKey int64 = 0x61757468_00
+
+//go:embed sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+// Apply runs the migrations of the dialect that are not recorded yet,
+// all in one transaction; it returns the versions it applied
+func Apply(ctx context.Context, db *sql.DB, dialect string) (applied []string, err error) {
+	names, err := fs.Glob(files, dialect+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no migrations for dialect %q", dialect)
+	}
+	sort.Strings(names)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if dialect == Postgres {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresLockKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version TEXT PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`); err != nil {
+		return nil, err
+	}
+
+	done, err := appliedVersions(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	record := `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`
+	if dialect == Postgres {
+		record = `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)`
+	}
+
+	for _, name := range names {
+		version := strings.TrimSuffix(name[len(dialect)+1:], ".sql")
+		if done[version] {
+			continue
+		}
+
+		script, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		// no arguments: both drivers run every statement of the file
+		if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+			return nil, fmt.Errorf("migration %s: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, record, version, time.Now().UTC()); err != nil {
+			return nil, err
+		}
+
+		applied = append(applied, version)
+	}
+
+	return applied, nil
+}
+
+func appliedVersions(ctx context.Context, tx *sql.Tx) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		done[version] = true
+	}
+
+	return done, rows.Err()
+}
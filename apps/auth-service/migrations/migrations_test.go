@@ -0,0 +1,44 @@
+package migrations_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Tata-Matata/family-space/apps/auth-service/migrations"
+)
+
+func TestApply_SQLite(test *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(test.TempDir(), "auth.db"))
+	require.NoError(test, err)
+	test.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+
+	applied, err := migrations.Apply(ctx, db, migrations.SQLite)
+	require.NoError(test, err)
+	require.NotEmpty(test, applied)
+	require.Equal(test, "0001_init", applied[0])
+
+	// the latest schema is there
+	_, err = db.Exec(`SELECT session_expires_at, remember_me FROM refresh_tokens`)
+	require.NoError(test, err)
+
+	// ADD COLUMN would fail if run twice
+	applied, err = migrations.Apply(ctx, db, migrations.SQLite)
+	require.NoError(test, err)
+	require.Empty(test, applied)
+}
+
+func TestApply_UnknownDialect(test *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(test.TempDir(), "auth.db"))
+	require.NoError(test, err)
+	test.Cleanup(func() { db.Close() })
+
+	_, err = migrations.Apply(context.Background(), db, "mysql")
+	require.Error(test, err)
+}
@@ -0,0 +1,11 @@
+-- refresh token chains for reuse detection:
+-- tokens rotated from one login share a session_id, parent_id links to the rotated token
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_id TEXT;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS parent_id TEXT;
+
+-- tokens issued before chains existed become single-token sessions
+UPDATE refresh_tokens SET session_id = id WHERE session_id IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN session_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id);
@@ -0,0 +1,15 @@
+-- set once the user opened the link of the verification email;
+-- accounts created before verification existed count as verified
+-- (only when the column is added, so running this again changes nothing)
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'users' AND column_name = 'email_verified_at'
+    ) THEN
+        ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+        UPDATE users SET email_verified_at = created_at;
+    END IF;
+END
+$$;
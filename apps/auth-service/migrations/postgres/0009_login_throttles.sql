@@ -0,0 +1,11 @@
+-- failed password logins per account (normalised email) and per client IP,
+-- shared by all replicas; a successful login deletes the rows
+
+CREATE TABLE IF NOT EXISTS login_throttles (
+    kind TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    last_failed_at TIMESTAMPTZ NOT NULL,
+    blocked_until TIMESTAMPTZ,
+    PRIMARY KEY (kind, subject)
+);
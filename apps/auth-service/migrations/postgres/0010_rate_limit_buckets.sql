@@ -0,0 +1,9 @@
+-- token buckets of the database-backed rate limiter (RATE_LIMIT_STORE=database);
+-- refilled_at is in unix seconds, so the refill is plain arithmetic in SQL
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    bucket_key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    refilled_at DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL
+);
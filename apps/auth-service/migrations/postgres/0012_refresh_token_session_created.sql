@@ -0,0 +1,16 @@
+-- when the login that started a session happened; copied to every rotated token,
+-- so /sessions can show it after the first token is gone
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_created_at TIMESTAMPTZ;
+
+-- sessions from before keep the oldest token still stored
+UPDATE refresh_tokens
+SET session_created_at = (
+    SELECT MIN(chain.created_at)
+    FROM refresh_tokens AS chain
+    WHERE chain.session_id = refresh_tokens.session_id
+)
+WHERE session_created_at IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN session_created_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
@@ -0,0 +1,12 @@
+-- the client of a session: user agent, IP and device name of the login (copied on
+-- rotation), IP and time of the latest rotation. Empty when not known
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS created_ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS device_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS last_used_ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMPTZ;
+
+-- every token so far was used when it was issued
+UPDATE refresh_tokens SET last_used_at = created_at WHERE last_used_at IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN last_used_at SET NOT NULL;
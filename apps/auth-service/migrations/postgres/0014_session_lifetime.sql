@@ -0,0 +1,19 @@
+-- sessions end after an idle timeout (each rotation starts it over) and at
+-- session_expires_at, fixed at login; remember_me picks the long profile of both
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_expires_at TIMESTAMPTZ;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS remember_me BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- sessions from before end when their current token expires; their tokens
+-- were issued for 30 days, like the long profile
+UPDATE refresh_tokens
+SET session_expires_at = (
+        SELECT MAX(chain.expires_at)
+        FROM refresh_tokens AS chain
+        WHERE chain.session_id = refresh_tokens.session_id
+    ),
+    remember_me = TRUE
+WHERE session_expires_at IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN session_expires_at SET NOT NULL;
+
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS remember_me BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,10 @@
+-- refresh token chains for reuse detection:
+-- tokens rotated from one login share a session_id, parent_id links to the rotated token
+
+ALTER TABLE refresh_tokens ADD COLUMN session_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN parent_id TEXT;
+
+-- tokens issued before chains existed become single-token sessions
+UPDATE refresh_tokens SET session_id = id WHERE session_id = '';
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id);
@@ -0,0 +1,14 @@
+-- TOTP second factor (secret encrypted with AES-GCM, see MFA_ENCRYPTION_KEY)
+
+CREATE TABLE IF NOT EXISTS totp_credentials (
+    user_id TEXT PRIMARY KEY,
+    secret_encrypted TEXT NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- authentication methods of the login that started a session (amr claim),
+-- space separated; kept across refresh token rotation
+ALTER TABLE refresh_tokens ADD COLUMN amr TEXT NOT NULL DEFAULT 'pwd';
+ALTER TABLE authorization_codes ADD COLUMN amr TEXT NOT NULL DEFAULT 'pwd';
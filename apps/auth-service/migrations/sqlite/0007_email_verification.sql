@@ -0,0 +1,7 @@
+-- set once the user opened the link of the verification email;
+-- accounts created before verification existed count as verified
+-- (sqlite fails on a second ADD COLUMN, so the backfill runs only once)
+
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
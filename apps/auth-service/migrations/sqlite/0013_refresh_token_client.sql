@@ -0,0 +1,11 @@
+-- the client of a session: user agent, IP and device name of the login (copied on
+-- rotation), IP and time of the latest rotation. Empty when not known
+
+ALTER TABLE refresh_tokens ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN created_ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN device_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN last_used_ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN last_used_at TIMESTAMP;
+
+-- every token so far was used when it was issued
+UPDATE refresh_tokens SET last_used_at = created_at WHERE last_used_at IS NULL;
@@ -0,0 +1,18 @@
+-- sessions end after an idle timeout (each rotation starts it over) and at
+-- session_expires_at, fixed at login; remember_me picks the long profile of both
+
+ALTER TABLE refresh_tokens ADD COLUMN session_expires_at TIMESTAMP;
+ALTER TABLE refresh_tokens ADD COLUMN remember_me INTEGER NOT NULL DEFAULT 0;
+
+-- sessions from before end when their current token expires; their tokens
+-- were issued for 30 days, like the long profile
+UPDATE refresh_tokens
+SET session_expires_at = (
+        SELECT MAX(chain.expires_at)
+        FROM refresh_tokens AS chain
+        WHERE chain.session_id = refresh_tokens.session_id
+    ),
+    remember_me = 1
+WHERE session_expires_at IS NULL;
+
+ALTER TABLE authorization_codes ADD COLUMN remember_me INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- the cleanup job deletes tokens that expired or were revoked longer ago
+-- than the retention window, in batches found through these indexes
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_revoked_at ON refresh_tokens (revoked_at);